@@ -0,0 +1,384 @@
+//Package api is an http.Handler exposing the state of a grid engine cell and actions upon its jobs as JSON.
+//
+//	GET    /jobs               running and pending jobs, filtered and sorted by query parameters (see filters.JobQuery)
+//	POST   /jobs               submit a job described by a gogridengine.SubmitRequest
+//	GET    /jobs/{id}          every task of a job, or a single task when addressed as job.task
+//	DELETE /jobs/{id}          delete a job
+//	POST   /jobs/{id}/hold     hold a job
+//	POST   /jobs/{id}/release  release a held job
+//	GET    /hosts              queue instances, optionally filtered by ?queue= and ?available=
+//	GET    /queues             slot totals per cluster queue
+//	GET    /summary            cluster capacity alongside job counts
+//
+//Job actions can be disabled altogether (Options.ReadOnly) or require a bearer token (Options.Token). Commands are only submitted as binaries when allow-listed.
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/metrumresearchgroup/gogridengine"
+	"github.com/metrumresearchgroup/gogridengine/filters"
+	log "github.com/sirupsen/logrus"
+)
+
+//ErrInvalidJobID is returned for job identifiers that are neither a job number nor job.task
+const ErrInvalidJobID = gogridengine.Error("Job identifiers must be a job number or job.task")
+
+//ErrJobNotFound is returned when the requested job isn't known to the cell
+const ErrJobNotFound = gogridengine.Error("The job does not exist")
+
+//ErrReadOnly is returned for actions on a read only handler
+const ErrReadOnly = gogridengine.Error("Job actions are disabled on this server")
+
+//ErrUnauthorized is returned for actions made without the handler's token
+const ErrUnauthorized = gogridengine.Error("A valid bearer token is required for job actions")
+
+//ErrBinaryNotAllowed is returned when submitting a command as a binary (qsub -b y) which isn't allowed on the handler
+const ErrBinaryNotAllowed = gogridengine.Error("The command may not be submitted as a binary on this server")
+
+//Options tune the handler
+type Options struct {
+	//ReadOnly disables submitting, deleting, holding and releasing jobs
+	ReadOnly bool
+	//Token must be presented as "Authorization: Bearer <token>" to submit, delete, hold or release jobs. Empty leaves job actions unauthenticated, for handlers served over mutual TLS
+	Token string
+	//AllowedBinaries lists the commands which may be submitted as binaries (SubmitRequest.Binary). Binary submissions of any other command are rejected
+	AllowedBinaries []string
+	//Location is the time zone qstat reports times in, used by the submitted_* and started_* job filters. Defaults to the local time zone
+	Location *time.Location
+}
+
+//Handler serves the API for a single cell
+type Handler struct {
+	client *gogridengine.Client
+	opts   Options
+}
+
+//ErrorResponse is the body of every unsuccessful response
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+//ActionResponse is the body of a successful delete, hold or release, carrying the output of the grid engine command
+type ActionResponse struct {
+	Output string `json:"output"`
+}
+
+//SummaryResponse is the body of /summary
+type SummaryResponse struct {
+	Cluster gogridengine.ClusterSummary `json:"cluster"`
+	Jobs    gogridengine.JobSummary     `json:"jobs"`
+}
+
+//NewHandler creates a handler querying and acting upon the cell through the client
+func NewHandler(client *gogridengine.Client, opts Options) *Handler {
+	if opts.Location == nil {
+		opts.Location = time.Local
+	}
+
+	return &Handler{
+		client: client,
+		opts:   opts,
+	}
+}
+
+//ServeHTTP routes the request to its endpoint
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	segments := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+
+	switch {
+	case len(segments) == 1 && segments[0] == "jobs":
+		switch r.Method {
+		case http.MethodGet:
+			h.listJobs(w, r)
+		case http.MethodPost:
+			h.submitJob(w, r)
+		default:
+			methodNotAllowed(w, http.MethodGet, http.MethodPost)
+		}
+	case len(segments) == 2 && segments[0] == "jobs":
+		switch r.Method {
+		case http.MethodGet:
+			h.getJob(w, segments[1])
+		case http.MethodDelete:
+			h.jobAction(w, r, segments[1], h.client.Delete)
+		default:
+			methodNotAllowed(w, http.MethodGet, http.MethodDelete)
+		}
+	case len(segments) == 3 && segments[0] == "jobs" && (segments[2] == "hold" || segments[2] == "release"):
+		if r.Method != http.MethodPost {
+			methodNotAllowed(w, http.MethodPost)
+			return
+		}
+
+		action := h.client.Hold
+		if segments[2] == "release" {
+			action = h.client.Release
+		}
+
+		h.jobAction(w, r, segments[1], action)
+	case len(segments) == 1 && segments[0] == "hosts":
+		h.onlyGet(w, r, h.listHosts)
+	case len(segments) == 1 && segments[0] == "queues":
+		h.onlyGet(w, r, h.listQueues)
+	case len(segments) == 1 && segments[0] == "summary":
+		h.onlyGet(w, r, h.summary)
+	default:
+		writeError(w, http.StatusNotFound, errors.New("no such endpoint"))
+	}
+}
+
+func (h *Handler) onlyGet(w http.ResponseWriter, r *http.Request, endpoint func(w http.ResponseWriter, r *http.Request)) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	endpoint(w, r)
+}
+
+func (h *Handler) listJobs(w http.ResponseWriter, r *http.Request) {
+	query, err := filters.ParseJobQueryIn(r.URL.Query(), h.opts.Location)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	jobs, err := h.client.Jobs()
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, query.Apply(jobs))
+}
+
+func (h *Handler) getJob(w http.ResponseWriter, id string) {
+	key, err := parseJobID(id)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	jobs, err := h.client.Jobs()
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	matching := jobs.Filter(func(j gogridengine.Job) bool {
+		return j.JBJobNumber == key.JobNumber && (key.TaskID == 0 || j.Tasks.TaskID == key.TaskID)
+	})
+
+	if len(matching) == 0 {
+		writeError(w, http.StatusNotFound, ErrJobNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, matching)
+}
+
+func (h *Handler) submitJob(w http.ResponseWriter, r *http.Request) {
+	if !h.authorizeAction(w, r) {
+		return
+	}
+
+	var request gogridengine.SubmitRequest
+
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if request.Binary && !h.binaryAllowed(request.Script) {
+		writeError(w, http.StatusForbidden, ErrBinaryNotAllowed)
+		return
+	}
+
+	result, err := h.client.Submit(r.Context(), request)
+
+	switch err {
+	case gogridengine.ErrInvalidSubmission, gogridengine.ErrOptionScript, gogridengine.ErrInvalidSubmitValue:
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	w.Header().Set("Location", "/jobs/"+strconv.FormatInt(result.JobNumber, 10))
+	writeJSON(w, http.StatusCreated, result)
+}
+
+func (h *Handler) jobAction(w http.ResponseWriter, r *http.Request, id string, action func(ctx context.Context, ids []string) (string, error)) {
+	if !h.authorizeAction(w, r) {
+		return
+	}
+
+	if _, err := parseJobID(id); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	output, err := action(r.Context(), []string{id})
+
+	if err != nil {
+		var commandErr *gogridengine.CommandError
+		if errors.As(err, &commandErr) && strings.Contains(string(commandErr.Result.Stderr), "does not exist") {
+			writeError(w, http.StatusNotFound, ErrJobNotFound)
+			return
+		}
+
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ActionResponse{Output: output})
+}
+
+//authorizeAction writes the error response and returns false when job actions are disabled or the request doesn't carry the token
+func (h *Handler) authorizeAction(w http.ResponseWriter, r *http.Request) bool {
+	if h.opts.ReadOnly {
+		writeError(w, http.StatusForbidden, ErrReadOnly)
+		return false
+	}
+
+	if h.opts.Token == "" {
+		return true
+	}
+
+	header := r.Header.Get("Authorization")
+	token := strings.TrimPrefix(header, "Bearer ")
+	if token == header || subtle.ConstantTimeCompare([]byte(token), []byte(h.opts.Token)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		writeError(w, http.StatusUnauthorized, ErrUnauthorized)
+		return false
+	}
+
+	return true
+}
+
+func (h *Handler) binaryAllowed(command string) bool {
+	for _, allowed := range h.opts.AllowedBinaries {
+		if command == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (h *Handler) listHosts(w http.ResponseWriter, r *http.Request) {
+	ji, err := h.client.JobInfo()
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	queue := r.URL.Query().Get("queue")
+
+	var available *bool
+	if value := r.URL.Query().Get("available"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		available = &parsed
+	}
+
+	hosts := []gogridengine.Host{}
+
+	for _, host := range ji.QueueInfo.Queues {
+		if name, _ := gogridengine.SplitQueueInstance(host.Name); queue != "" && name != queue {
+			continue
+		}
+
+		if available != nil && gogridengine.IsHostAvailable(host) != *available {
+			continue
+		}
+
+		hosts = append(hosts, host)
+	}
+
+	writeJSON(w, http.StatusOK, hosts)
+}
+
+func (h *Handler) listQueues(w http.ResponseWriter, r *http.Request) {
+	ji, err := h.client.JobInfo()
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, gogridengine.SummarizeQueues(ji))
+}
+
+func (h *Handler) summary(w http.ResponseWriter, r *http.Request) {
+	ji, err := h.client.JobInfo()
+	if err != nil {
+		writeError(w, http.StatusBadGateway, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, SummaryResponse{
+		Cluster: gogridengine.NewClusterSummary(ji),
+		Jobs:    ji.Jobs().Summarize(),
+	})
+}
+
+//parseJobID accepts job numbers and job.task identifiers
+func parseJobID(id string) (gogridengine.JobKey, error) {
+	pieces := strings.SplitN(id, ".", 2)
+
+	number, err := strconv.ParseInt(pieces[0], 10, 64)
+	if err != nil || number <= 0 {
+		return gogridengine.JobKey{}, ErrInvalidJobID
+	}
+
+	key := gogridengine.JobKey{JobNumber: number}
+
+	if len(pieces) == 2 {
+		task, err := strconv.ParseInt(pieces[1], 10, 64)
+		if err != nil || task <= 0 {
+			return gogridengine.JobKey{}, ErrInvalidJobID
+		}
+		key.TaskID = task
+	}
+
+	return key, nil
+}
+
+func methodNotAllowed(w http.ResponseWriter, allowed ...string) {
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	writeError(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	if status >= http.StatusInternalServerError {
+		log.Error("API request failed: ", err)
+	}
+
+	writeJSON(w, status, ErrorResponse{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Error("Unable to write the API response: ", err)
+	}
+}
@@ -0,0 +1,399 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/metrumresearchgroup/gogridengine"
+	"github.com/metrumresearchgroup/gogridengine/simulator"
+	"github.com/stretchr/testify/assert"
+)
+
+//newTestServer serves the API over a simulated cluster with two running jobs and a pending one
+func newTestServer(t *testing.T, opts Options) (*httptest.Server, *simulator.Cluster) {
+	cluster := simulator.New(simulator.Options{
+		Hosts: []simulator.HostSpec{
+			{Name: "node1", Slots: 4, Memory: 16000000000},
+			{Name: "node2", Slots: 4, Memory: 16000000000, State: "d"},
+		},
+		Start: time.Date(2019, 12, 18, 14, 0, 0, 0, time.UTC),
+		User:  "darrellb",
+	})
+
+	for _, spec := range []simulator.JobSpec{
+		{Name: "Run1", Owner: "darrellb", Slots: 2},
+		{Name: "Run2", Owner: "devinp", Slots: 2},
+		{Name: "Waiting", Owner: "devinp", Slots: 2},
+	} {
+		_, err := cluster.Submit(spec)
+		assert.Nil(t, err)
+	}
+
+	server := httptest.NewServer(NewHandler(gogridengine.NewClient(cluster), opts))
+	t.Cleanup(server.Close)
+
+	return server, cluster
+}
+
+func request(t *testing.T, method string, url string, body interface{}) *http.Response {
+	var content bytes.Buffer
+	if body != nil {
+		assert.Nil(t, json.NewEncoder(&content).Encode(body))
+	}
+
+	req, err := http.NewRequest(method, url, &content)
+	assert.Nil(t, err)
+
+	response, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	t.Cleanup(func() { response.Body.Close() })
+
+	return response
+}
+
+func decode(t *testing.T, response *http.Response, into interface{}) {
+	assert.Equal(t, "application/json", response.Header.Get("Content-Type"))
+	assert.Nil(t, json.NewDecoder(response.Body).Decode(into))
+}
+
+func TestListJobs(t *testing.T) {
+	server, _ := newTestServer(t, Options{})
+
+	tests := []struct {
+		name  string
+		query string
+		want  []int64
+	}{
+		{
+			name: "everything",
+			want: []int64{1, 2, 3},
+		},
+		{
+			name:  "owner",
+			query: "?owner=devinp",
+			want:  []int64{2, 3},
+		},
+		{
+			name:  "several owners",
+			query: "?owner=devinp&owner=darrellb",
+			want:  []int64{1, 2, 3},
+		},
+		{
+			name:  "owner and phase",
+			query: "?owner=devinp&phase=pending",
+			want:  []int64{3},
+		},
+		{
+			name:  "sorted descending",
+			query: "?sort=-job_number",
+			want:  []int64{3, 2, 1},
+		},
+		{
+			name:  "nothing matching",
+			query: "?owner=nobody",
+			want:  []int64{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := request(t, http.MethodGet, server.URL+"/jobs"+tt.query, nil)
+			assert.Equal(t, http.StatusOK, response.StatusCode)
+
+			var jobs []gogridengine.Job
+			decode(t, response, &jobs)
+
+			numbers := []int64{}
+			for _, j := range jobs {
+				numbers = append(numbers, j.JBJobNumber)
+			}
+			assert.Equal(t, tt.want, numbers)
+		})
+	}
+}
+
+func TestListJobsInvalidQuery(t *testing.T) {
+	server, _ := newTestServer(t, Options{})
+
+	response := request(t, http.MethodGet, server.URL+"/jobs?sort=colour", nil)
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+	var body ErrorResponse
+	decode(t, response, &body)
+	assert.Contains(t, body.Error, "colour")
+
+	response = request(t, http.MethodGet, server.URL+"/jobs?name=Run%5B", nil)
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+	decode(t, response, &body)
+	assert.Contains(t, body.Error, path.ErrBadPattern.Error())
+}
+
+func TestGetJob(t *testing.T) {
+	server, _ := newTestServer(t, Options{})
+
+	tests := []struct {
+		name   string
+		id     string
+		status int
+	}{
+		{name: "running", id: "1", status: http.StatusOK},
+		{name: "pending", id: "3", status: http.StatusOK},
+		{name: "unknown", id: "42", status: http.StatusNotFound},
+		{name: "invalid", id: "one", status: http.StatusBadRequest},
+		{name: "invalid task", id: "1.x", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := request(t, http.MethodGet, server.URL+"/jobs/"+tt.id, nil)
+			assert.Equal(t, tt.status, response.StatusCode)
+		})
+	}
+}
+
+func TestSubmitJob(t *testing.T) {
+	server, cluster := newTestServer(t, Options{})
+
+	response := request(t, http.MethodPost, server.URL+"/jobs", gogridengine.SubmitRequest{Script: "run.sh", Name: "model", Tasks: "1-4:1"})
+	assert.Equal(t, http.StatusCreated, response.StatusCode)
+	assert.Equal(t, "/jobs/4", response.Header.Get("Location"))
+
+	var result gogridengine.SubmitResult
+	decode(t, response, &result)
+	assert.Equal(t, gogridengine.SubmitResult{JobNumber: 4, Tasks: "1-4:1"}, result)
+	assert.Equal(t, []int64{1, 2, 3, 4}, cluster.Jobs())
+
+	response = request(t, http.MethodPost, server.URL+"/jobs", gogridengine.SubmitRequest{})
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+	response = request(t, http.MethodPost, server.URL+"/jobs", map[string]string{"scrip": "run.sh"})
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+
+	response = request(t, http.MethodPost, server.URL+"/jobs", gogridengine.SubmitRequest{Script: "run.sh", Memory: "lots"})
+	assert.Equal(t, http.StatusBadGateway, response.StatusCode)
+}
+
+func TestJobActions(t *testing.T) {
+	server, cluster := newTestServer(t, Options{})
+
+	response := request(t, http.MethodPost, server.URL+"/jobs/3/hold", nil)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	var body ActionResponse
+	decode(t, response, &body)
+	assert.Contains(t, body.Output, "modified hold of job 3")
+
+	response = request(t, http.MethodGet, server.URL+"/jobs?state_exact=hqw", nil)
+	var held []gogridengine.Job
+	decode(t, response, &held)
+	assert.Len(t, held, 1)
+
+	response = request(t, http.MethodPost, server.URL+"/jobs/3/release", nil)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	response = request(t, http.MethodDelete, server.URL+"/jobs/1", nil)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.NotContains(t, cluster.Jobs(), int64(1))
+
+	response = request(t, http.MethodDelete, server.URL+"/jobs/42", nil)
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+
+	response = request(t, http.MethodPut, server.URL+"/jobs/3/hold", nil)
+	assert.Equal(t, http.StatusMethodNotAllowed, response.StatusCode)
+	assert.Equal(t, http.MethodPost, response.Header.Get("Allow"))
+}
+
+func TestReadOnly(t *testing.T) {
+	server, cluster := newTestServer(t, Options{ReadOnly: true})
+
+	for _, r := range []struct {
+		method string
+		path   string
+	}{
+		{http.MethodPost, "/jobs"},
+		{http.MethodDelete, "/jobs/1"},
+		{http.MethodPost, "/jobs/3/hold"},
+		{http.MethodPost, "/jobs/3/release"},
+	} {
+		response := request(t, r.method, server.URL+r.path, gogridengine.SubmitRequest{Script: "run.sh"})
+		assert.Equal(t, http.StatusForbidden, response.StatusCode, r.path)
+	}
+
+	assert.Equal(t, []int64{1, 2, 3}, cluster.Jobs())
+
+	response := request(t, http.MethodGet, server.URL+"/jobs", nil)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+}
+
+func TestToken(t *testing.T) {
+	server, cluster := newTestServer(t, Options{Token: "s3cret"})
+
+	for _, token := range []string{"", "Bearer wrong", "s3cret"} {
+		req, err := http.NewRequest(http.MethodDelete, server.URL+"/jobs/1", nil)
+		assert.Nil(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+
+		response, err := http.DefaultClient.Do(req)
+		assert.Nil(t, err)
+		response.Body.Close()
+
+		assert.Equal(t, http.StatusUnauthorized, response.StatusCode, token)
+		assert.Equal(t, "Bearer", response.Header.Get("WWW-Authenticate"))
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, server.URL+"/jobs/1", nil)
+	assert.Nil(t, err)
+	req.Header.Set("Authorization", "Bearer s3cret")
+
+	response, err := http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, []int64{2, 3}, cluster.Jobs())
+
+	//Reads don't need the token
+	response = request(t, http.MethodGet, server.URL+"/jobs", nil)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+}
+
+func TestSubmitBinary(t *testing.T) {
+	server, cluster := newTestServer(t, Options{AllowedBinaries: []string{"Rscript"}})
+
+	response := request(t, http.MethodPost, server.URL+"/jobs", gogridengine.SubmitRequest{Script: "rm", Args: []string{"-rf", "/"}, Binary: true})
+	assert.Equal(t, http.StatusForbidden, response.StatusCode)
+
+	var body ErrorResponse
+	decode(t, response, &body)
+	assert.Equal(t, ErrBinaryNotAllowed.Error(), body.Error)
+	assert.Equal(t, []int64{1, 2, 3}, cluster.Jobs())
+
+	response = request(t, http.MethodPost, server.URL+"/jobs", gogridengine.SubmitRequest{Script: "Rscript", Args: []string{"model.R"}, Binary: true})
+	assert.Equal(t, http.StatusCreated, response.StatusCode)
+	assert.Equal(t, []int64{1, 2, 3, 4}, cluster.Jobs())
+
+	//Without an allow list no binary is accepted
+	server, _ = newTestServer(t, Options{})
+	response = request(t, http.MethodPost, server.URL+"/jobs", gogridengine.SubmitRequest{Script: "Rscript", Binary: true})
+	assert.Equal(t, http.StatusForbidden, response.StatusCode)
+}
+
+func TestSubmitInjection(t *testing.T) {
+	server, cluster := newTestServer(t, Options{AllowedBinaries: []string{"Rscript"}})
+
+	tests := []struct {
+		name    string
+		request gogridengine.SubmitRequest
+		wantErr error
+	}{
+		{
+			name:    "option as script",
+			request: gogridengine.SubmitRequest{Script: "-b", Args: []string{"y", "/bin/sh", "-c", "id"}},
+			wantErr: gogridengine.ErrOptionScript,
+		},
+		{
+			name:    "option as binary",
+			request: gogridengine.SubmitRequest{Script: "-b", Args: []string{"y", "Rscript"}, Binary: true},
+			wantErr: ErrBinaryNotAllowed,
+		},
+		{
+			name:    "environment value",
+			request: gogridengine.SubmitRequest{Script: "run.sh", Environment: map[string]string{"A": "x,LD_PRELOAD=/tmp/x.so"}},
+			wantErr: gogridengine.ErrInvalidSubmitValue,
+		},
+		{
+			name:    "environment name",
+			request: gogridengine.SubmitRequest{Script: "run.sh", Environment: map[string]string{"LD_PRELOAD=/tmp/x.so,A": "x"}},
+			wantErr: gogridengine.ErrInvalidSubmitValue,
+		},
+		{
+			name:    "resource value",
+			request: gogridengine.SubmitRequest{Script: "run.sh", Resources: map[string]string{"gpu": "1,h_vmem=1T"}},
+			wantErr: gogridengine.ErrInvalidSubmitValue,
+		},
+		{
+			name:    "memory",
+			request: gogridengine.SubmitRequest{Script: "run.sh", Memory: "1G,h_rt=99:00:00"},
+			wantErr: gogridengine.ErrInvalidSubmitValue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := request(t, http.MethodPost, server.URL+"/jobs", tt.request)
+
+			var body ErrorResponse
+			decode(t, response, &body)
+			assert.Equal(t, tt.wantErr.Error(), body.Error)
+			assert.NotEqual(t, http.StatusCreated, response.StatusCode)
+			assert.Equal(t, []int64{1, 2, 3}, cluster.Jobs())
+		})
+	}
+}
+
+func TestListHosts(t *testing.T) {
+	server, _ := newTestServer(t, Options{})
+
+	tests := []struct {
+		name   string
+		query  string
+		want   []string
+		status int
+	}{
+		{name: "everything", want: []string{"all.q@node1", "all.q@node2"}, status: http.StatusOK},
+		{name: "available", query: "?available=true", want: []string{"all.q@node1"}, status: http.StatusOK},
+		{name: "unavailable", query: "?available=false", want: []string{"all.q@node2"}, status: http.StatusOK},
+		{name: "other queue", query: "?queue=gpu.q", want: []string{}, status: http.StatusOK},
+		{name: "invalid", query: "?available=maybe", status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response := request(t, http.MethodGet, server.URL+"/hosts"+tt.query, nil)
+			assert.Equal(t, tt.status, response.StatusCode)
+
+			if tt.status != http.StatusOK {
+				return
+			}
+
+			var hosts []gogridengine.Host
+			decode(t, response, &hosts)
+
+			names := []string{}
+			for _, h := range hosts {
+				names = append(names, h.Name)
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
+
+func TestListQueuesAndSummary(t *testing.T) {
+	server, _ := newTestServer(t, Options{})
+
+	response := request(t, http.MethodGet, server.URL+"/queues", nil)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	var queues []gogridengine.QueueSummary
+	decode(t, response, &queues)
+	assert.Equal(t, []gogridengine.QueueSummary{
+		{Name: "all.q", QueueInstances: 2, Available: 1, SlotsUsed: 4, SlotsTotal: 8, RunningJobs: 2},
+	}, queues)
+
+	response = request(t, http.MethodGet, server.URL+"/summary", nil)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	var summary SummaryResponse
+	decode(t, response, &summary)
+	assert.Equal(t, 3, summary.Jobs.Total.Jobs)
+}
+
+func TestUnknownEndpoint(t *testing.T) {
+	server, _ := newTestServer(t, Options{})
+
+	response := request(t, http.MethodGet, server.URL+"/nodes", nil)
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+}
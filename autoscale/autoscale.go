@@ -0,0 +1,310 @@
+//Package autoscale recommends how many nodes of each instance shape are needed to place pending work, and which idle hosts can be drained.
+package autoscale
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/metrumresearchgroup/gogridengine"
+)
+
+//ErrNoInstanceShapes is returned when a plan is requested without any instance shapes to choose from
+const ErrNoInstanceShapes = gogridengine.Error("At least one instance shape is required to build a scaling plan")
+
+//InstanceShape describes a type of node that can be added to the cluster (eg: an EC2 instance type)
+type InstanceShape struct {
+	Name        string  `json:"name"`
+	Slots       int32   `json:"slots"`
+	MemoryBytes int64   `json:"memory_bytes"`
+	HourlyCost  float64 `json:"hourly_cost"`
+}
+
+//Options tune how the planner treats pending jobs and existing capacity
+type Options struct {
+	//DefaultJobMemory is the memory (in bytes) assumed for each slot of a pending job that has no h_vmem / mem_free hard request. qstat only reports hard requests when run with -r.
+	DefaultJobMemory int64
+	//IgnoreExistingCapacity skips placing pending jobs onto free slots of the current hosts before sizing new nodes
+	IgnoreExistingCapacity bool
+}
+
+//NodeRequest is a recommendation to add Count nodes of a given shape
+type NodeRequest struct {
+	Shape      InstanceShape `json:"shape"`
+	Count      int           `json:"count"`
+	HourlyCost float64       `json:"hourly_cost"`
+}
+
+//DrainCandidate is an idle host that can be removed from the cluster without disturbing any work
+type DrainCandidate struct {
+	Host           string   `json:"host"`
+	QueueInstances []string `json:"queue_instances"`
+	Reason         string   `json:"reason"`
+}
+
+//Plan is the outcome of a scaling evaluation along with the reasoning that led to it
+type Plan struct {
+	PendingJobs   int   `json:"pending_jobs"`
+	PendingSlots  int64 `json:"pending_slots"`
+	PendingMemory int64 `json:"pending_memory"`
+	//PlacedOnExisting counts pending jobs that fit onto free capacity of the current hosts
+	PlacedOnExisting int              `json:"placed_on_existing"`
+	ScaleUp          []NodeRequest    `json:"scale_up"`
+	Drain            []DrainCandidate `json:"drain"`
+	//Unplaceable jobs don't fit on any of the provided instance shapes
+	Unplaceable gogridengine.JobList `json:"unplaceable"`
+	HourlyCost  float64              `json:"hourly_cost"`
+	Reasons     []string             `json:"reasons"`
+}
+
+//bin is a node (existing or planned) with remaining capacity that pending jobs can be packed into
+type bin struct {
+	name        string
+	slots       int64
+	memory      int64
+	queues      []string
+	idle        bool
+	usedByPlan  bool
+	unavailable bool
+	state       string
+}
+
+//demand is a pending job along with its resolved resource requirements
+type demand struct {
+	job    gogridengine.Job
+	slots  int64
+	memory int64
+}
+
+//NewPlan computes the nodes required to place every pending job of the JobInfo using the provided shapes, along with the idle hosts that can be drained safely
+func NewPlan(ji gogridengine.JobInfo, shapes []InstanceShape, opts Options) (Plan, error) {
+	var plan Plan
+
+	if len(shapes) == 0 {
+		return Plan{}, ErrNoInstanceShapes
+	}
+
+	demands := pendingDemand(ji, opts, &plan)
+
+	//Largest jobs first gives first-fit packing a much better result
+	sort.SliceStable(demands, func(i, j int) bool {
+		if demands[i].slots != demands[j].slots {
+			return demands[i].slots > demands[j].slots
+		}
+		return demands[i].memory > demands[j].memory
+	})
+
+	existing := existingBins(ji)
+	var remaining []demand
+
+	for _, d := range demands {
+		if opts.IgnoreExistingCapacity {
+			remaining = append(remaining, d)
+			continue
+		}
+
+		if b := firstFit(existing, d); b != nil {
+			b.usedByPlan = true
+			plan.PlacedOnExisting++
+			continue
+		}
+
+		remaining = append(remaining, d)
+	}
+
+	if plan.PlacedOnExisting > 0 {
+		plan.Reasons = append(plan.Reasons, fmt.Sprintf("%d pending job(s) fit onto free capacity of existing hosts", plan.PlacedOnExisting))
+	}
+
+	var planned []*bin
+	counts := make(map[string]int)
+
+	for _, d := range remaining {
+		if b := firstFit(planned, d); b != nil {
+			continue
+		}
+
+		shape, ok := cheapestShapeFor(shapes, d)
+		if !ok {
+			plan.Unplaceable = append(plan.Unplaceable, d.job)
+			plan.Reasons = append(plan.Reasons, fmt.Sprintf("Job %d.%d needs %d slot(s) and %d bytes of memory, more than any instance shape provides", d.job.JBJobNumber, d.job.Tasks.TaskID, d.slots, d.memory))
+			continue
+		}
+
+		b := &bin{
+			name:   shape.Name,
+			slots:  int64(shape.Slots) - d.slots,
+			memory: shape.MemoryBytes - d.memory,
+		}
+		planned = append(planned, b)
+		counts[shape.Name]++
+	}
+
+	for _, s := range shapes {
+		if counts[s.Name] == 0 {
+			continue
+		}
+
+		request := NodeRequest{
+			Shape:      s,
+			Count:      counts[s.Name],
+			HourlyCost: float64(counts[s.Name]) * s.HourlyCost,
+		}
+		plan.ScaleUp = append(plan.ScaleUp, request)
+		plan.HourlyCost += request.HourlyCost
+		plan.Reasons = append(plan.Reasons, fmt.Sprintf("Add %d x %s (%d slots, %d bytes) at %.4f/hour for the pending jobs that don't fit on existing hosts", request.Count, s.Name, s.Slots, s.MemoryBytes, request.HourlyCost))
+		//Only count the first shape with a given name
+		counts[s.Name] = 0
+	}
+
+	for _, b := range existing {
+		if !b.idle {
+			continue
+		}
+
+		if b.usedByPlan {
+			plan.Reasons = append(plan.Reasons, fmt.Sprintf("Host %s is idle but is needed for pending jobs; keeping it", b.name))
+			continue
+		}
+
+		reason := fmt.Sprintf("Host %s has no used or reserved slots and no pending job needs it", b.name)
+		if b.unavailable {
+			reason = fmt.Sprintf("%s (queue state %s)", reason, b.state)
+		}
+
+		plan.Drain = append(plan.Drain, DrainCandidate{
+			Host:           b.name,
+			QueueInstances: b.queues,
+			Reason:         reason,
+		})
+		plan.Reasons = append(plan.Reasons, reason+"; it can be drained")
+	}
+
+	if len(demands) == 0 {
+		plan.Reasons = append(plan.Reasons, "No pending jobs; no additional nodes are required")
+	}
+
+	return plan, nil
+}
+
+func pendingDemand(ji gogridengine.JobInfo, opts Options, plan *Plan) []demand {
+	var demands []demand
+
+	for _, j := range ji.PendingJobs.JobList {
+		if gogridengine.JobPhase(j) != gogridengine.PhasePending {
+			continue
+		}
+
+		d := demand{
+			job:   j,
+			slots: int64(j.Slots),
+		}
+
+		if d.slots < 1 {
+			d.slots = 1
+		}
+
+		//SGE memory requests are per slot
+		if mem, err := j.RequestedMemory(); err == nil {
+			d.memory = mem.Bytes * d.slots
+		} else {
+			d.memory = opts.DefaultJobMemory * d.slots
+		}
+
+		plan.PendingJobs++
+		plan.PendingSlots += d.slots
+		plan.PendingMemory += d.memory
+
+		demands = append(demands, d)
+	}
+
+	return demands
+}
+
+//existingBins groups queue instances by physical host, tracking free slots and memory of hosts able to accept work
+func existingBins(ji gogridengine.JobInfo) []*bin {
+	var bins []*bin
+	byHost := make(map[string]*bin)
+
+	for _, h := range ji.QueueInfo.Queues {
+		_, hostname := gogridengine.SplitQueueInstance(h.Name)
+
+		b, ok := byHost[hostname]
+		if !ok {
+			b = &bin{
+				name:   hostname,
+				idle:   true,
+				memory: math.MaxInt64,
+			}
+
+			//Hosts that don't report free memory are assumed to have enough of it
+			if free, err := h.Resources.FreeMemory(); err == nil {
+				b.memory = free.Bytes
+			}
+
+			byHost[hostname] = b
+			bins = append(bins, b)
+		}
+
+		b.queues = append(b.queues, h.Name)
+
+		if h.SlotsUsed > 0 || h.SlotsReserved > 0 || len(h.JobList) > 0 {
+			b.idle = false
+		}
+
+		if !gogridengine.IsHostAvailable(h) {
+			b.unavailable = true
+			b.state = h.State
+			continue
+		}
+
+		if free := int64(h.SlotsTotal - h.SlotsUsed - h.SlotsReserved); free > 0 {
+			b.slots += free
+		}
+	}
+
+	return bins
+}
+
+func firstFit(bins []*bin, d demand) *bin {
+	for _, b := range bins {
+		if b.unavailable {
+			continue
+		}
+
+		if b.slots >= d.slots && b.memory >= d.memory {
+			b.slots -= d.slots
+			b.memory -= d.memory
+			return b
+		}
+	}
+
+	return nil
+}
+
+//cheapestShapeFor picks the shape with the lowest cost per slot that can hold the job, falling back to the lowest absolute cost on ties
+func cheapestShapeFor(shapes []InstanceShape, d demand) (InstanceShape, bool) {
+	var best InstanceShape
+	found := false
+
+	for _, s := range shapes {
+		if int64(s.Slots) < d.slots || s.MemoryBytes < d.memory {
+			continue
+		}
+
+		if !found {
+			best = s
+			found = true
+			continue
+		}
+
+		perSlot := s.HourlyCost / float64(s.Slots)
+		bestPerSlot := best.HourlyCost / float64(best.Slots)
+
+		if perSlot < bestPerSlot || (perSlot == bestPerSlot && s.HourlyCost < best.HourlyCost) {
+			best = s
+		}
+	}
+
+	return best, found
+}
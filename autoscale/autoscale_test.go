@@ -0,0 +1,167 @@
+package autoscale
+
+import (
+	"testing"
+
+	"github.com/metrumresearchgroup/gogridengine"
+	"github.com/stretchr/testify/assert"
+)
+
+var shapes = []InstanceShape{
+	{
+		Name:        "c5.2xlarge",
+		Slots:       8,
+		MemoryBytes: 16000000000,
+		HourlyCost:  0.34,
+	},
+	{
+		Name:        "c5.4xlarge",
+		Slots:       16,
+		MemoryBytes: 32000000000,
+		HourlyCost:  0.68,
+	},
+	{
+		Name:        "r5.xlarge",
+		Slots:       4,
+		MemoryBytes: 32000000000,
+		HourlyCost:  0.252,
+	},
+}
+
+func pending(job int64, slots int32, memory string) gogridengine.Job {
+	j := gogridengine.Job{
+		JBJobNumber: job,
+		State:       "qw",
+		Slots:       slots,
+	}
+
+	if memory != "" {
+		j.HardRequests = []gogridengine.ResourceRequest{
+			{
+				Name:  "h_vmem",
+				Value: memory,
+			},
+		}
+	}
+
+	return j
+}
+
+func TestNewPlanRequiresShapes(t *testing.T) {
+	_, err := NewPlan(gogridengine.JobInfo{}, nil, Options{})
+	assert.Equal(t, ErrNoInstanceShapes, err)
+}
+
+func TestNewPlanScaleUp(t *testing.T) {
+	ji := gogridengine.JobInfo{
+		QueueInfo: gogridengine.QueueInfo{
+			Queues: []gogridengine.Host{
+				{
+					Name:       "all.q@ip-10-0-1-113.ec2.internal",
+					SlotsUsed:  6,
+					SlotsTotal: 8,
+					Resources: gogridengine.ResourceList{
+						{Name: "mem_free", Value: "8.000G"},
+					},
+					JobList: []gogridengine.Job{
+						{JBJobNumber: 1, State: "r", Slots: 6},
+					},
+				},
+			},
+		},
+		PendingJobs: gogridengine.PendingJob{
+			JobList: []gogridengine.Job{
+				//Fits onto the two free slots of the existing host
+				pending(2, 2, "1G"),
+				pending(3, 8, "1G"),
+				pending(4, 8, "1G"),
+				//Doesn't fit anywhere
+				pending(5, 64, ""),
+				//Errored jobs aren't demand
+				{JBJobNumber: 6, State: "Eqw", Slots: 8},
+			},
+		},
+	}
+
+	plan, err := NewPlan(ji, shapes, Options{DefaultJobMemory: 1000000000})
+	assert.Nil(t, err)
+
+	assert.Equal(t, 4, plan.PendingJobs)
+	assert.Equal(t, int64(82), plan.PendingSlots)
+	assert.Equal(t, 1, plan.PlacedOnExisting)
+	assert.Len(t, plan.Unplaceable, 1)
+	assert.Equal(t, int64(5), plan.Unplaceable[0].JBJobNumber)
+
+	//Both 8 slot jobs should be packed onto the cheapest per-slot shape
+	assert.Len(t, plan.ScaleUp, 1)
+	assert.Equal(t, "c5.2xlarge", plan.ScaleUp[0].Shape.Name)
+	assert.Equal(t, 2, plan.ScaleUp[0].Count)
+	assert.InDelta(t, 0.68, plan.HourlyCost, 0.0001)
+	assert.Empty(t, plan.Drain)
+	assert.NotEmpty(t, plan.Reasons)
+}
+
+func TestNewPlanMemoryBoundJobs(t *testing.T) {
+	ji := gogridengine.JobInfo{
+		PendingJobs: gogridengine.PendingJob{
+			JobList: []gogridengine.Job{
+				//Memory requests are per slot, so this needs 24G
+				pending(1, 2, "12G"),
+			},
+		},
+	}
+
+	plan, err := NewPlan(ji, shapes, Options{})
+	assert.Nil(t, err)
+
+	assert.Len(t, plan.ScaleUp, 1)
+	assert.Equal(t, "c5.4xlarge", plan.ScaleUp[0].Shape.Name)
+	assert.Equal(t, int64(24*1024*1024*1024), plan.PendingMemory)
+}
+
+func TestNewPlanDrain(t *testing.T) {
+	ji := gogridengine.JobInfo{
+		QueueInfo: gogridengine.QueueInfo{
+			Queues: []gogridengine.Host{
+				{
+					Name:       "all.q@ip-10-0-1-80.ec2.internal",
+					SlotsTotal: 8,
+				},
+				{
+					Name:       "all.q@ip-10-0-1-113.ec2.internal",
+					SlotsUsed:  1,
+					SlotsTotal: 8,
+					JobList: []gogridengine.Job{
+						{JBJobNumber: 1, State: "r", Slots: 1},
+					},
+				},
+				{
+					Name:       "all.q@ip-10-0-1-203.ec2.internal",
+					SlotsTotal: 8,
+				},
+				{
+					Name:       "all.q@ip-10-0-1-204.ec2.internal",
+					SlotsTotal: 8,
+					State:      "auo",
+				},
+			},
+		},
+		PendingJobs: gogridengine.PendingJob{
+			JobList: []gogridengine.Job{
+				pending(2, 8, ""),
+			},
+		},
+	}
+
+	plan, err := NewPlan(ji, shapes, Options{})
+	assert.Nil(t, err)
+
+	assert.Empty(t, plan.ScaleUp)
+	assert.Equal(t, 1, plan.PlacedOnExisting)
+
+	//The first idle host takes the pending job, so only the second idle host and the unreachable one are drained
+	assert.Len(t, plan.Drain, 2)
+	assert.Equal(t, "ip-10-0-1-203.ec2.internal", plan.Drain[0].Host)
+	assert.Equal(t, "ip-10-0-1-204.ec2.internal", plan.Drain[1].Host)
+	assert.Contains(t, plan.Drain[1].Reason, "auo")
+}
@@ -0,0 +1,187 @@
+package gogridengine
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//ErrCacheRefreshPanicked is returned to requests waiting on a refresh of a CachedDataSource whose underlying source panicked
+const ErrCacheRefreshPanicked = Error("Refreshing the cached qstat content panicked")
+
+//CacheStats are counters describing how a CachedDataSource has been serving requests
+type CacheStats struct {
+	//Hits were served from cached content without touching the underlying source
+	Hits uint64 `json:"hits"`
+	//ErrorHits were answered with the cached failure of a recent refresh (or stale content) without touching the underlying source
+	ErrorHits uint64 `json:"error_hits"`
+	//Misses required a refresh from the underlying source
+	Misses uint64 `json:"misses"`
+	//Coalesced requests waited on a refresh already in flight rather than starting their own
+	Coalesced uint64 `json:"coalesced"`
+	//Errors counts refreshes of the underlying source that failed
+	Errors uint64 `json:"errors"`
+	//StaleServed counts requests answered with stale content because a refresh failed
+	StaleServed uint64 `json:"stale_served"`
+}
+
+//CachedDataSource is an XmlResourceGetter that caches the content of another source for a TTL.
+//Concurrent requests for expired content are coalesced onto a single refresh, so the underlying source (and qmaster) sees at most one request per TTL.
+//Failed refreshes are cached for the TTL too, so a qmaster that is down isn't queried any more often. Meanwhile the last good content is served for up to MaxStale instead of the error.
+type CachedDataSource struct {
+	Source XmlResourceGetter
+	TTL    time.Duration
+	//MaxStale is how long past its TTL content may be served when refreshes fail. Zero serves stale content indefinitely, a negative value never does.
+	MaxStale time.Duration
+
+	mu        sync.Mutex
+	content   string
+	fetched   time.Time
+	hasValue  bool
+	inflight  *cacheCall
+	stats     CacheStats
+	lastError error
+	//failed is when the last refresh failed, zero once a refresh succeeds
+	failed time.Time
+	now    func() time.Time
+}
+
+//cacheCall is a single refresh of the underlying source shared by every request arriving while it is in flight
+type cacheCall struct {
+	done    chan struct{}
+	content string
+	err     error
+}
+
+//NewCachedDataSource wraps the provided source with a cache holding content for the provided TTL
+func NewCachedDataSource(source XmlResourceGetter, ttl time.Duration) *CachedDataSource {
+	return &CachedDataSource{
+		Source: source,
+		TTL:    ttl,
+	}
+}
+
+//Get returns cached content while it is fresh, otherwise it refreshes from the underlying source
+func (c *CachedDataSource) Get() (string, error) {
+	c.mu.Lock()
+
+	if c.hasValue && c.age() < c.TTL {
+		c.stats.Hits++
+		content := c.content
+		c.mu.Unlock()
+		return content, nil
+	}
+
+	if !c.failed.IsZero() && c.clock().Sub(c.failed) < c.TTL {
+		c.stats.ErrorHits++
+		err := c.lastError
+		c.mu.Unlock()
+		return c.resolve(&cacheCall{err: err})
+	}
+
+	if call := c.inflight; call != nil {
+		c.stats.Coalesced++
+		c.mu.Unlock()
+		<-call.done
+		return c.resolve(call)
+	}
+
+	c.stats.Misses++
+	call := &cacheCall{
+		done: make(chan struct{}),
+	}
+	c.inflight = call
+	c.mu.Unlock()
+
+	c.refresh(call)
+
+	return c.resolve(call)
+}
+
+//refresh reads the underlying source for the call. The call is completed even should the source panic, so requests waiting on it aren't stuck
+func (c *CachedDataSource) refresh(call *cacheCall) {
+	completed := false
+
+	defer func() {
+		if !completed {
+			call.content, call.err = "", ErrCacheRefreshPanicked
+		}
+
+		c.mu.Lock()
+		c.inflight = nil
+		if call.err == nil {
+			c.content = call.content
+			c.fetched = c.clock()
+			c.hasValue = true
+			c.lastError = nil
+			c.failed = time.Time{}
+		} else {
+			c.stats.Errors++
+			c.lastError = call.err
+			c.failed = c.clock()
+			log.Error("Refreshing the cached qstat content failed: ", call.err)
+		}
+		c.mu.Unlock()
+
+		close(call.done)
+	}()
+
+	call.content, call.err = c.Source.Get()
+	completed = true
+}
+
+//resolve turns a finished refresh into a response, falling back to stale content on failure
+func (c *CachedDataSource) resolve(call *cacheCall) (string, error) {
+	if call.err == nil {
+		return call.content, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.hasValue && c.MaxStale >= 0 && (c.MaxStale == 0 || c.age() < c.TTL+c.MaxStale) {
+		c.stats.StaleServed++
+		return c.content, nil
+	}
+
+	return "", call.err
+}
+
+//Stats returns a copy of the cache counters
+func (c *CachedDataSource) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.stats
+}
+
+//LastError returns the error of the most recent refresh, or nil if it succeeded
+func (c *CachedDataSource) LastError() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.lastError
+}
+
+//Invalidate drops the cached content, and any cached failure, so the next Get refreshes from the underlying source
+func (c *CachedDataSource) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.hasValue = false
+	c.content = ""
+	c.failed = time.Time{}
+}
+
+func (c *CachedDataSource) age() time.Duration {
+	return c.clock().Sub(c.fetched)
+}
+
+func (c *CachedDataSource) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+
+	return time.Now()
+}
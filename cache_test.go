@@ -0,0 +1,246 @@
+package gogridengine
+
+import (
+	"errors"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//countingSource counts calls, optionally blocking each one until released
+type countingSource struct {
+	mu      sync.Mutex
+	calls   int
+	content string
+	err     error
+	release chan struct{}
+}
+
+func (s *countingSource) Get() (string, error) {
+	s.mu.Lock()
+	s.calls++
+	content, err := s.content, s.err
+	s.mu.Unlock()
+
+	if s.release != nil {
+		<-s.release
+	}
+
+	return content, err
+}
+
+func (s *countingSource) Calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.calls
+}
+
+func TestCachedDataSourceTTL(t *testing.T) {
+	now := time.Date(2019, 9, 15, 15, 26, 36, 0, time.UTC)
+	source := &countingSource{content: "<job_info></job_info>"}
+
+	cache := NewCachedDataSource(source, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	for i := 0; i < 5; i++ {
+		content, err := cache.Get()
+		assert.Nil(t, err)
+		assert.Equal(t, source.content, content)
+	}
+
+	assert.Equal(t, 1, source.Calls())
+
+	now = now.Add(2 * time.Minute)
+	_, err := cache.Get()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, source.Calls())
+
+	cache.Invalidate()
+	_, err = cache.Get()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, source.Calls())
+
+	stats := cache.Stats()
+	assert.Equal(t, uint64(4), stats.Hits)
+	assert.Equal(t, uint64(0), stats.ErrorHits)
+	assert.Equal(t, uint64(3), stats.Misses)
+}
+
+func TestCachedDataSourceCoalescing(t *testing.T) {
+	source := &countingSource{
+		content: "<job_info></job_info>",
+		release: make(chan struct{}),
+	}
+
+	cache := NewCachedDataSource(source, time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			content, err := cache.Get()
+			assert.Nil(t, err)
+			assert.Equal(t, "<job_info></job_info>", content)
+		}()
+	}
+
+	//Wait for every request to be either leading or waiting on the refresh
+	for {
+		stats := cache.Stats()
+		if stats.Misses+stats.Coalesced == 10 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	close(source.release)
+	wg.Wait()
+
+	assert.Equal(t, 1, source.Calls())
+	assert.Equal(t, uint64(9), cache.Stats().Coalesced)
+}
+
+func TestCachedDataSourceServesStale(t *testing.T) {
+	now := time.Date(2019, 9, 15, 15, 26, 36, 0, time.UTC)
+	source := &countingSource{content: "<job_info></job_info>"}
+
+	cache := NewCachedDataSource(source, time.Minute)
+	cache.MaxStale = 5 * time.Minute
+	cache.now = func() time.Time { return now }
+
+	_, err := cache.Get()
+	assert.Nil(t, err)
+
+	source.mu.Lock()
+	source.err = errors.New("qmaster unreachable")
+	source.content = ""
+	source.mu.Unlock()
+
+	now = now.Add(2 * time.Minute)
+	content, err := cache.Get()
+	assert.Nil(t, err)
+	assert.Equal(t, "<job_info></job_info>", content)
+	assert.NotNil(t, cache.LastError())
+
+	//Past MaxStale the error comes through
+	now = now.Add(10 * time.Minute)
+	_, err = cache.Get()
+	assert.NotNil(t, err)
+
+	stats := cache.Stats()
+	assert.Equal(t, uint64(2), stats.Errors)
+	assert.Equal(t, uint64(1), stats.StaleServed)
+}
+
+func TestCachedDataSourceCachesFailures(t *testing.T) {
+	now := time.Date(2019, 9, 15, 15, 26, 36, 0, time.UTC)
+	source := &countingSource{err: errors.New("qmaster unreachable")}
+
+	cache := NewCachedDataSource(source, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	//Without anything to fall back on, the failure is returned for the TTL without asking qmaster again
+	for i := 0; i < 5; i++ {
+		_, err := cache.Get()
+		assert.EqualError(t, err, "qmaster unreachable")
+		now = now.Add(10 * time.Second)
+	}
+	assert.Equal(t, 1, source.Calls())
+
+	now = now.Add(time.Minute)
+	_, err := cache.Get()
+	assert.NotNil(t, err)
+	assert.Equal(t, 2, source.Calls())
+
+	//Invalidating retries straight away
+	source.mu.Lock()
+	source.err = nil
+	source.content = "<job_info></job_info>"
+	source.mu.Unlock()
+
+	cache.Invalidate()
+	content, err := cache.Get()
+	assert.Nil(t, err)
+	assert.Equal(t, "<job_info></job_info>", content)
+	assert.Equal(t, 3, source.Calls())
+	assert.Nil(t, cache.LastError())
+
+	stats := cache.Stats()
+	assert.Equal(t, uint64(2), stats.Errors)
+	//Failures served from the cache aren't hits
+	assert.Equal(t, uint64(0), stats.Hits)
+	assert.Equal(t, uint64(4), stats.ErrorHits)
+	assert.Equal(t, uint64(3), stats.Misses)
+}
+
+//panickingSource panics on the first call once released, then serves content
+type panickingSource struct {
+	calls   int
+	release chan struct{}
+}
+
+func (s *panickingSource) Get() (string, error) {
+	s.calls++
+	if s.calls == 1 {
+		<-s.release
+		panic("qstat went away")
+	}
+
+	return "<job_info></job_info>", nil
+}
+
+func TestCachedDataSourcePanickingSource(t *testing.T) {
+	source := &panickingSource{release: make(chan struct{})}
+	cache := NewCachedDataSource(source, time.Minute)
+
+	leader := make(chan interface{})
+	go func() {
+		defer func() { leader <- recover() }()
+		cache.Get()
+	}()
+
+	for cache.Stats().Misses == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	waiter := make(chan error)
+	go func() {
+		_, err := cache.Get()
+		waiter <- err
+	}()
+
+	for cache.Stats().Coalesced == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	close(source.release)
+
+	//The panic reaches the caller that refreshed, those waiting on it get an error
+	assert.Equal(t, "qstat went away", <-leader)
+	assert.Equal(t, ErrCacheRefreshPanicked, <-waiter)
+
+	cache.Invalidate()
+	content, err := cache.Get()
+	assert.Nil(t, err)
+	assert.Equal(t, "<job_info></job_info>", content)
+}
+
+func TestGetJobsFromSource(t *testing.T) {
+	content, err := ioutil.ReadFile("test_data/small.xml")
+	assert.Nil(t, err)
+
+	source := &countingSource{content: string(content)}
+	cache := NewCachedDataSource(source, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		jobs, err := GetJobsFromSource(cache)
+		assert.Nil(t, err)
+		assert.Len(t, jobs, 9)
+	}
+
+	assert.Equal(t, 1, source.Calls())
+}
@@ -0,0 +1,351 @@
+package gogridengine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//ErrInvalidSubmission is returned when submitting a job without a script or command
+const ErrInvalidSubmission = Error("A script or command is required to submit a job")
+
+//ErrOptionScript is returned when submitting a script or command starting with -, which qsub would read as an option
+const ErrOptionScript = Error("The script or command of a job may not start with -")
+
+//ErrInvalidSubmitValue is returned when a resource or environment variable of a submission holds a comma or =, which would add entries to the qsub -l / -v lists
+const ErrInvalidSubmitValue = Error("Resource and environment variable names and values may not contain commas or =")
+
+//ErrUnexpectedSubmitOutput is returned when qsub succeeds but its output doesn't name the job submitted
+const ErrUnexpectedSubmitOutput = Error("Unable to read the job number from the qsub output")
+
+//CommandError is returned when a grid engine binary fails, carrying what it wrote so callers can act on the reason (eg: a job that doesn't exist)
+type CommandError struct {
+	Name   string
+	Result CommandResult
+	Err    error
+}
+
+func (e *CommandError) Error() string {
+	details := strings.TrimSpace(string(e.Result.Stderr) + string(e.Result.Stdout))
+
+	return fmt.Sprintf("%s failed: %s: %s", e.Name, details, e.Err)
+}
+
+//Unwrap exposes the underlying execution error
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+//Client queries and acts upon a single grid engine cell through a CommandRunner
+type Client struct {
+	//Runner executes the grid engine binaries. Defaults to DefaultRunner
+	Runner CommandRunner
+	//Source provides the qstat XML. Defaults to a QstatDataSource using the Runner. Cached sources are invalidated after every action
+	Source XmlResourceGetter
+	//Timeout bounds every command run by an action. Defaults to 30 seconds
+	Timeout time.Duration
+}
+
+//NewClient creates a client running commands through the provided runner
+func NewClient(runner CommandRunner) *Client {
+	return &Client{
+		Runner: runner,
+	}
+}
+
+//JobInfo returns the current state of the cell
+func (c *Client) JobInfo() (JobInfo, error) {
+	return c.JobInfoContext(context.Background())
+}
+
+//JobInfoContext returns the current state of the cell, giving up when the context is done.
+//Sources implementing XmlResourceContextGetter (such as the default QstatDataSource) stop the qstat command, any other source is left to finish in the background.
+func (c *Client) JobInfoContext(ctx context.Context) (JobInfo, error) {
+	content, err := getContext(ctx, c.DataSource())
+
+	if err != nil {
+		return JobInfo{}, err
+	}
+
+	return NewJobInfo(content)
+}
+
+//Jobs returns every running and pending job of the cell
+func (c *Client) Jobs() (JobList, error) {
+	ji, err := c.JobInfo()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ji.Jobs(), nil
+}
+
+//SubmitRequest describes a job to submit through qsub
+type SubmitRequest struct {
+	//Script is the job script, or the command when Binary is set
+	Script string   `json:"script"`
+	Args   []string `json:"args,omitempty"`
+	//Binary submits Script as a command rather than a script (qsub -b y)
+	Binary bool   `json:"binary,omitempty"`
+	Name   string `json:"name,omitempty"`
+	Queue  string `json:"queue,omitempty"`
+	//Slots are requested through the ParallelEnvironment, which defaults to smp
+	Slots               int32  `json:"slots,omitempty"`
+	ParallelEnvironment string `json:"parallel_environment,omitempty"`
+	//Memory is requested as h_vmem (eg: 4G)
+	Memory string `json:"memory,omitempty"`
+	//Runtime is requested as h_rt, in seconds or hh:mm:ss
+	Runtime string `json:"runtime,omitempty"`
+	//Resources are any other hard resource requests (qsub -l)
+	Resources map[string]string `json:"resources,omitempty"`
+	//Tasks makes the job an array job (eg: 1-10:1)
+	Tasks            string            `json:"tasks,omitempty"`
+	Priority         int               `json:"priority,omitempty"`
+	Hold             bool              `json:"hold,omitempty"`
+	WorkingDirectory string            `json:"working_directory,omitempty"`
+	Environment      map[string]string `json:"environment,omitempty"`
+}
+
+//Arguments renders the request as qsub arguments. The job number is always requested in the terse form
+func (r SubmitRequest) Arguments() []string {
+	arguments := []string{"-terse"}
+
+	if r.Name != "" {
+		arguments = append(arguments, "-N", r.Name)
+	}
+
+	if r.Queue != "" {
+		arguments = append(arguments, "-q", r.Queue)
+	}
+
+	if r.Slots > 1 {
+		pe := r.ParallelEnvironment
+		if pe == "" {
+			pe = "smp"
+		}
+		arguments = append(arguments, "-pe", pe, strconv.Itoa(int(r.Slots)))
+	}
+
+	if resources := r.resources(); len(resources) > 0 {
+		arguments = append(arguments, "-l", joinSorted(resources))
+	}
+
+	if r.Tasks != "" {
+		arguments = append(arguments, "-t", r.Tasks)
+	}
+
+	if r.Priority != 0 {
+		arguments = append(arguments, "-p", strconv.Itoa(r.Priority))
+	}
+
+	if r.Hold {
+		arguments = append(arguments, "-h")
+	}
+
+	if r.WorkingDirectory != "" {
+		arguments = append(arguments, "-wd", r.WorkingDirectory)
+	}
+
+	if len(r.Environment) > 0 {
+		arguments = append(arguments, "-v", joinSorted(r.Environment))
+	}
+
+	if r.Binary {
+		arguments = append(arguments, "-b", "y")
+	}
+
+	arguments = append(arguments, r.Script)
+
+	return append(arguments, r.Args...)
+}
+
+//Validate checks the request can be rendered as qsub arguments without any of its values being read as options or list entries
+func (r SubmitRequest) Validate() error {
+	if r.Script == "" {
+		return ErrInvalidSubmission
+	}
+
+	if strings.HasPrefix(r.Script, "-") {
+		return ErrOptionScript
+	}
+
+	for _, values := range []map[string]string{r.resources(), r.Environment} {
+		for k, v := range values {
+			if strings.ContainsAny(k, ",=") || strings.ContainsAny(v, ",=") {
+				return ErrInvalidSubmitValue
+			}
+		}
+	}
+
+	return nil
+}
+
+//resources gathers the hard resource requests, memory and runtime included
+func (r SubmitRequest) resources() map[string]string {
+	resources := make(map[string]string)
+	for k, v := range r.Resources {
+		resources[k] = v
+	}
+
+	if r.Memory != "" {
+		resources["h_vmem"] = r.Memory
+	}
+
+	if r.Runtime != "" {
+		resources["h_rt"] = r.Runtime
+	}
+
+	return resources
+}
+
+//SubmitResult identifies a submitted job
+type SubmitResult struct {
+	JobNumber int64 `json:"job_number"`
+	//Tasks is the task range of array jobs (eg: 1-10:1)
+	Tasks string `json:"tasks,omitempty"`
+}
+
+//Submit runs qsub for the request and returns the job number assigned. Requests which don't pass Validate are rejected before running qsub
+func (c *Client) Submit(ctx context.Context, request SubmitRequest) (SubmitResult, error) {
+	if err := request.Validate(); err != nil {
+		return SubmitResult{}, err
+	}
+
+	output, err := c.run(ctx, "qsub", request.Arguments()...)
+
+	if err != nil {
+		return SubmitResult{}, err
+	}
+
+	return parseTerseSubmission(output)
+}
+
+//Delete runs qdel for the provided job identifiers (job numbers or job.task)
+func (c *Client) Delete(ctx context.Context, ids []string) (string, error) {
+	return c.run(ctx, "qdel", strings.Join(ids, ","))
+}
+
+//DeleteByUser runs qdel for every job of the provided users
+func (c *Client) DeleteByUser(ctx context.Context, users []string) (string, error) {
+	return c.run(ctx, "qdel", "-u", strings.Join(users, ","))
+}
+
+//Hold runs qhold for the provided job identifiers
+func (c *Client) Hold(ctx context.Context, ids []string) (string, error) {
+	return c.run(ctx, "qhold", strings.Join(ids, ","))
+}
+
+//Release runs qrls for the provided job identifiers
+func (c *Client) Release(ctx context.Context, ids []string) (string, error) {
+	return c.run(ctx, "qrls", strings.Join(ids, ","))
+}
+
+func (c *Client) run(ctx context.Context, name string, args ...string) (string, error) {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	result, err := c.runner().Run(ctx, name, args...)
+
+	//Whatever happened, the cluster may have changed underneath any cached state
+	if cached, ok := c.Source.(*CachedDataSource); ok {
+		cached.Invalidate()
+	}
+
+	if err != nil {
+		return string(result.Stdout), &CommandError{Name: name, Result: result, Err: err}
+	}
+
+	return string(result.Stdout), nil
+}
+
+func (c *Client) runner() CommandRunner {
+	if c.Runner == nil {
+		return DefaultRunner
+	}
+
+	return c.Runner
+}
+
+//DataSource returns where the client reads qstat XML from, for watching the cell it acts upon
+func (c *Client) DataSource() XmlResourceGetter {
+	if c.Source == nil {
+		return &QstatDataSource{Runner: c.runner()}
+	}
+
+	return c.Source
+}
+
+type getResult struct {
+	content string
+	err     error
+}
+
+//getContext reads the source, bounded by the context
+func getContext(ctx context.Context, source XmlResourceGetter) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	if getter, ok := source.(XmlResourceContextGetter); ok {
+		return getter.GetContext(ctx)
+	}
+
+	result := make(chan getResult, 1)
+
+	go func() {
+		content, err := source.Get()
+		result <- getResult{
+			content: content,
+			err:     err,
+		}
+	}()
+
+	select {
+	case r := <-result:
+		return r.content, r.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+//parseTerseSubmission reads the job number (and task range of array jobs) from qsub -terse output
+func parseTerseSubmission(output string) (SubmitResult, error) {
+	pieces := strings.SplitN(strings.TrimSpace(output), ".", 2)
+
+	number, err := strconv.ParseInt(pieces[0], 10, 64)
+	if err != nil {
+		return SubmitResult{}, ErrUnexpectedSubmitOutput
+	}
+
+	result := SubmitResult{JobNumber: number}
+
+	if len(pieces) == 2 {
+		result.Tasks = pieces[1]
+	}
+
+	return result, nil
+}
+
+func joinSorted(values map[string]string) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+values[k])
+	}
+
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,142 @@
+package gogridengine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubmitRequestArguments(t *testing.T) {
+	tests := []struct {
+		name    string
+		request SubmitRequest
+		want    []string
+	}{
+		{
+			name:    "script only",
+			request: SubmitRequest{Script: "run.sh"},
+			want:    []string{"-terse", "run.sh"},
+		},
+		{
+			name: "everything",
+			request: SubmitRequest{
+				Script:           "Rscript",
+				Args:             []string{"model.R", "--fast"},
+				Binary:           true,
+				Name:             "model",
+				Queue:            "all.q",
+				Slots:            4,
+				Memory:           "4G",
+				Runtime:          "01:00:00",
+				Resources:        map[string]string{"gpu": "1"},
+				Tasks:            "1-10:1",
+				Priority:         -10,
+				Hold:             true,
+				WorkingDirectory: "/data",
+				Environment:      map[string]string{"B": "2", "A": "1"},
+			},
+			want: []string{
+				"-terse", "-N", "model", "-q", "all.q", "-pe", "smp", "4",
+				"-l", "gpu=1,h_rt=01:00:00,h_vmem=4G", "-t", "1-10:1", "-p", "-10", "-h",
+				"-wd", "/data", "-v", "A=1,B=2", "-b", "y", "Rscript", "model.R", "--fast",
+			},
+		},
+		{
+			name:    "parallel environment",
+			request: SubmitRequest{Script: "run.sh", Slots: 2, ParallelEnvironment: "mpi"},
+			want:    []string{"-terse", "-pe", "mpi", "2", "run.sh"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.request.Arguments())
+		})
+	}
+}
+
+func TestClientSubmit(t *testing.T) {
+	runner := &fakeRunner{
+		responses: map[string]CommandResult{
+			"qsub -terse run.sh":            {Stdout: []byte("1001\n")},
+			"qsub -terse -t 1-10:2 run.sh":  {Stdout: []byte("1002.1-10:2\n")},
+			"qsub -terse -N garbled run.sh": {Stdout: []byte("Your job has been submitted\n")},
+		},
+	}
+	client := NewClient(runner)
+
+	result, err := client.Submit(context.Background(), SubmitRequest{Script: "run.sh"})
+	assert.Nil(t, err)
+	assert.Equal(t, SubmitResult{JobNumber: 1001}, result)
+
+	result, err = client.Submit(context.Background(), SubmitRequest{Script: "run.sh", Tasks: "1-10:2"})
+	assert.Nil(t, err)
+	assert.Equal(t, SubmitResult{JobNumber: 1002, Tasks: "1-10:2"}, result)
+
+	_, err = client.Submit(context.Background(), SubmitRequest{Script: "run.sh", Name: "garbled"})
+	assert.Equal(t, ErrUnexpectedSubmitOutput, err)
+
+	for _, request := range []SubmitRequest{
+		{Script: "-b", Args: []string{"y", "/bin/sh", "-c", "id"}},
+		{Script: "run.sh", Environment: map[string]string{"A": "x,LD_PRELOAD=/tmp/x.so"}},
+		{Script: "run.sh", Environment: map[string]string{"A=B": "x"}},
+		{Script: "run.sh", Resources: map[string]string{"gpu": "1,h_vmem=1T"}},
+		{Script: "run.sh", Memory: "4G,gpu=8"},
+	} {
+		_, err = client.Submit(context.Background(), request)
+		assert.NotNil(t, err)
+	}
+
+	_, err = client.Submit(context.Background(), SubmitRequest{})
+	assert.Equal(t, ErrInvalidSubmission, err)
+
+	_, err = client.Submit(context.Background(), SubmitRequest{Script: "missing.sh"})
+	var commandErr *CommandError
+	assert.True(t, errors.As(err, &commandErr))
+	assert.Equal(t, "qsub", commandErr.Name)
+	assert.Equal(t, 1, commandErr.Result.ExitCode)
+}
+
+func TestClientActions(t *testing.T) {
+	runner := &fakeRunner{
+		responses: map[string]CommandResult{
+			"qdel 1,2":           {Stdout: []byte("darrellb has deleted job 1\ndarrellb has deleted job 2\n")},
+			"qdel -u a,b":        {Stdout: []byte("a has deleted job 3\n")},
+			"qhold 4":            {Stdout: []byte("modified hold of job 4\n")},
+			"qrls 4":             {Stdout: []byte("modified hold of job 4\n")},
+			"qstat -u * -F -xml": {Stdout: []byte(`<?xml version='1.0'?><job_info><queue_info></queue_info><job_info></job_info></job_info>`)},
+		},
+	}
+
+	cached := NewCachedDataSource(&QstatDataSource{Runner: runner}, time.Hour)
+	client := &Client{Runner: runner, Source: cached}
+
+	_, err := client.Jobs()
+	assert.Nil(t, err)
+
+	output, err := client.Delete(context.Background(), []string{"1", "2"})
+	assert.Nil(t, err)
+	assert.Equal(t, "darrellb has deleted job 1\ndarrellb has deleted job 2\n", output)
+
+	output, err = client.DeleteByUser(context.Background(), []string{"a", "b"})
+	assert.Nil(t, err)
+	assert.Equal(t, "a has deleted job 3\n", output)
+
+	output, err = client.Hold(context.Background(), []string{"4"})
+	assert.Nil(t, err)
+	assert.Equal(t, "modified hold of job 4\n", output)
+
+	_, err = client.Release(context.Background(), []string{"4"})
+	assert.Nil(t, err)
+
+	_, err = client.Hold(context.Background(), []string{"42"})
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "qhold failed")
+
+	//Every action invalidated the cache, so qstat runs again
+	_, err = client.Jobs()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2), cached.Stats().Misses)
+}
@@ -0,0 +1,179 @@
+package gogridengine
+
+import (
+	"strings"
+)
+
+const (
+	//HostStateNormal is the key used in ClusterSummary.HostStates for queue instances reporting no state flags
+	HostStateNormal string = "normal"
+)
+
+//ClusterSummary is a capacity and utilization report for the whole cluster computed from a single JobInfo
+type ClusterSummary struct {
+	QueueInstances int `json:"queue_instances"`
+	Hosts          int `json:"hosts"`
+	//HostStates counts queue instances by their queue state code (eg: auo). Instances without any flags are counted under HostStateNormal
+	HostStates    map[string]int `json:"host_states"`
+	SlotsTotal    int64          `json:"slots_total"`
+	SlotsUsed     int64          `json:"slots_used"`
+	SlotsReserved int64          `json:"slots_reserved"`
+	//SlotsFree only counts queue instances which are able to accept work
+	SlotsFree int64 `json:"slots_free"`
+	//SlotsUnavailable counts the slots of queue instances which are alarmed, disabled, unknown or otherwise unable to accept work
+	SlotsUnavailable int64 `json:"slots_unavailable"`
+	//Memory values are in bytes and are counted once per physical host, regardless of how many queues it serves
+	MemoryTotal int64 `json:"memory_total"`
+	MemoryFree  int64 `json:"memory_free"`
+	MemoryUsed  int64 `json:"memory_used"`
+	//AverageNPLoad is the mean np_load_avg of hosts reporting one
+	AverageNPLoad float64 `json:"average_np_load"`
+	RunningJobs   int     `json:"running_jobs"`
+	PendingJobs   int     `json:"pending_jobs"`
+	//PendingSlots is the slot demand of all jobs waiting to be scheduled
+	PendingSlots int64 `json:"pending_slots"`
+}
+
+//QueueSummary aggregates the queue instances of a cluster queue
+type QueueSummary struct {
+	Name           string `json:"name"`
+	QueueInstances int    `json:"queue_instances"`
+	//Available counts the queue instances able to accept work
+	Available     int   `json:"available"`
+	SlotsUsed     int64 `json:"slots_used"`
+	SlotsReserved int64 `json:"slots_reserved"`
+	SlotsTotal    int64 `json:"slots_total"`
+	RunningJobs   int   `json:"running_jobs"`
+}
+
+//IsHostAvailable evaluates whether the queue state of the host allows new work to be scheduled onto it. Any of the alarm (a/A), unknown (u), disabled (d/D), error (E), suspended (s/S/C) or orphaned (o) flags make it unavailable.
+func IsHostAvailable(host Host) bool {
+	return !strings.ContainsAny(host.State, "aAudDEsSCo")
+}
+
+//NewClusterSummary computes the capacity and utilization of the cluster described by the JobInfo
+func NewClusterSummary(ji JobInfo) ClusterSummary {
+	summary := ClusterSummary{
+		HostStates: make(map[string]int),
+	}
+
+	seenHosts := make(map[string]bool)
+	var loadTotal float64
+	var loadCount int
+
+	for _, h := range ji.QueueInfo.Queues {
+		summary.QueueInstances++
+
+		state := h.State
+		if state == "" {
+			state = HostStateNormal
+		}
+		summary.HostStates[state]++
+
+		summary.SlotsTotal += int64(h.SlotsTotal)
+		summary.SlotsUsed += int64(h.SlotsUsed)
+		summary.SlotsReserved += int64(h.SlotsReserved)
+
+		if IsHostAvailable(h) {
+			free := int64(h.SlotsTotal - h.SlotsUsed - h.SlotsReserved)
+			if free > 0 {
+				summary.SlotsFree += free
+			}
+		} else {
+			summary.SlotsUnavailable += int64(h.SlotsTotal)
+		}
+
+		for _, j := range h.JobList {
+			if JobPhase(j) == PhaseRunning {
+				summary.RunningJobs++
+			}
+		}
+
+		//Hosts of different federated clusters are told apart even when they share a name
+		_, hostname := SplitQueueInstance(h.Name)
+		key := h.Cluster + "@" + hostname
+		if seenHosts[key] {
+			//Host level resources have already been counted through another queue instance
+			continue
+		}
+		seenHosts[key] = true
+		summary.Hosts++
+
+		if total, err := h.Resources.TotalMemory(); err == nil {
+			summary.MemoryTotal += total.Bytes
+		}
+
+		if free, err := h.Resources.FreeMemory(); err == nil {
+			summary.MemoryFree += free.Bytes
+		}
+
+		if used, err := h.Resources.MemoryUsed(); err == nil {
+			summary.MemoryUsed += used.Bytes
+		}
+
+		if load, err := h.Resources.NPLoadAverage(); err == nil {
+			loadTotal += load
+			loadCount++
+		}
+	}
+
+	if loadCount > 0 {
+		summary.AverageNPLoad = loadTotal / float64(loadCount)
+	}
+
+	for _, j := range ji.PendingJobs.JobList {
+		if JobPhase(j) == PhasePending {
+			summary.PendingJobs++
+			summary.PendingSlots += int64(j.Slots)
+		}
+	}
+
+	return summary
+}
+
+//SummarizeQueues aggregates queue instances by their cluster queue, in the order the queues first appear
+func SummarizeQueues(ji JobInfo) []QueueSummary {
+	queues := []QueueSummary{}
+	index := make(map[string]int)
+
+	for _, host := range ji.QueueInfo.Queues {
+		name, _ := SplitQueueInstance(host.Name)
+
+		k, ok := index[name]
+		if !ok {
+			k = len(queues)
+			index[name] = k
+			queues = append(queues, QueueSummary{Name: name})
+		}
+
+		q := &queues[k]
+		q.QueueInstances++
+		q.SlotsUsed += int64(host.SlotsUsed)
+		q.SlotsReserved += int64(host.SlotsReserved)
+		q.SlotsTotal += int64(host.SlotsTotal)
+		q.RunningJobs += len(host.JobList)
+
+		if IsHostAvailable(host) {
+			q.Available++
+		}
+	}
+
+	return queues
+}
+
+//GetClusterSummary retrieves the current qstat output and computes its ClusterSummary
+func GetClusterSummary() (ClusterSummary, error) {
+	xml, err := GetQstatOutput(make(map[string]string))
+
+	if err != nil {
+		return ClusterSummary{}, err
+	}
+
+	ji, err := NewJobInfo(xml)
+
+	if err != nil {
+		return ClusterSummary{}, err
+	}
+
+	return NewClusterSummary(ji), nil
+}
@@ -0,0 +1,112 @@
+package gogridengine
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewClusterSummary(t *testing.T) {
+	content, err := ioutil.ReadFile("test_data/medium.xml")
+	assert.Nil(t, err)
+
+	ji, err := NewJobInfo(string(content))
+	assert.Nil(t, err)
+
+	summary := NewClusterSummary(ji)
+
+	assert.Equal(t, 28, summary.QueueInstances)
+	assert.Equal(t, 28, summary.Hosts)
+	assert.Equal(t, map[string]int{HostStateNormal: 26, "auo": 2}, summary.HostStates)
+	assert.Equal(t, int64(938), summary.SlotsTotal)
+	assert.Equal(t, int64(775), summary.SlotsUsed)
+	assert.Equal(t, int64(2), summary.SlotsUnavailable)
+	assert.Equal(t, int64(1773009744408), summary.MemoryTotal)
+	assert.Equal(t, int64(1602128028092), summary.MemoryFree)
+	assert.InDelta(t, 0.7994, summary.AverageNPLoad, 0.0001)
+	assert.Equal(t, 772, summary.RunningJobs)
+	assert.Equal(t, int64(0), summary.PendingSlots)
+}
+
+func TestSummarizeQueues(t *testing.T) {
+	ji := JobInfo{
+		QueueInfo: QueueInfo{
+			Queues: []Host{
+				{Name: "all.q@node1", SlotsUsed: 2, SlotsReserved: 1, SlotsTotal: 8, JobList: []Job{{JBJobNumber: 1}, {JBJobNumber: 2}}},
+				{Name: "gpu.q@node1", SlotsTotal: 4},
+				{Name: "all.q@node2", SlotsTotal: 8, State: "au"},
+			},
+		},
+	}
+
+	assert.Equal(t, []QueueSummary{
+		{Name: "all.q", QueueInstances: 2, Available: 1, SlotsUsed: 2, SlotsReserved: 1, SlotsTotal: 16, RunningJobs: 2},
+		{Name: "gpu.q", QueueInstances: 1, Available: 1, SlotsTotal: 4},
+	}, SummarizeQueues(ji))
+
+	//Never nil, so it encodes as an empty list
+	assert.Equal(t, []QueueSummary{}, SummarizeQueues(JobInfo{}))
+}
+
+func TestNewClusterSummaryWithPendingDemand(t *testing.T) {
+	ji := JobInfo{
+		QueueInfo: QueueInfo{
+			Queues: []Host{
+				{
+					Name:          "all.q@ip-10-0-1-80.ec2.internal",
+					SlotsUsed:     2,
+					SlotsReserved: 2,
+					SlotsTotal:    8,
+					Resources: ResourceList{
+						{Name: "mem_total", Value: "16.000G"},
+						{Name: "mem_free", Value: "12.000G"},
+						{Name: "np_load_avg", Value: "0.500000"},
+					},
+				},
+				{
+					//Same physical host through a second queue. Memory must not be counted twice
+					Name:       "gpu.q@ip-10-0-1-80.ec2.internal",
+					SlotsTotal: 2,
+					Resources: ResourceList{
+						{Name: "mem_total", Value: "16.000G"},
+						{Name: "mem_free", Value: "12.000G"},
+					},
+				},
+				{
+					Name:       "all.q@ip-10-0-1-113.ec2.internal",
+					SlotsTotal: 8,
+					State:      "d",
+				},
+			},
+		},
+		PendingJobs: PendingJob{
+			JobList: []Job{
+				{State: "qw", Slots: 4},
+				{State: "qw", Slots: 2},
+				{State: "Eqw", Slots: 16},
+			},
+		},
+	}
+
+	summary := NewClusterSummary(ji)
+
+	assert.Equal(t, 3, summary.QueueInstances)
+	assert.Equal(t, 2, summary.Hosts)
+	assert.Equal(t, 1, summary.HostStates["d"])
+	assert.Equal(t, int64(18), summary.SlotsTotal)
+	assert.Equal(t, int64(6), summary.SlotsFree)
+	assert.Equal(t, int64(8), summary.SlotsUnavailable)
+	assert.Equal(t, int64(17179869184), summary.MemoryTotal)
+	assert.Equal(t, int64(12884901888), summary.MemoryFree)
+	assert.Equal(t, 0.5, summary.AverageNPLoad)
+	assert.Equal(t, 2, summary.PendingJobs)
+	assert.Equal(t, int64(6), summary.PendingSlots)
+}
+
+func TestIsHostAvailable(t *testing.T) {
+	assert.True(t, IsHostAvailable(Host{}))
+	assert.False(t, IsHostAvailable(Host{State: "auo"}))
+	assert.False(t, IsHostAvailable(Host{State: "d"}))
+	assert.False(t, IsHostAvailable(Host{State: "E"}))
+}
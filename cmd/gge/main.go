@@ -0,0 +1,17 @@
+//Command gge lists, submits and acts upon grid engine jobs, writing tables, JSON, YAML or CSV
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	"github.com/metrumresearchgroup/gogridengine/internal/cli"
+)
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+
+	os.Exit(cli.Main(ctx, os.Args[1:], os.Stdout, os.Stderr))
+}
@@ -0,0 +1,139 @@
+//Command gridengine_api serves the state of the grid engine cluster the host can run qstat against (locally or on a submit host over SSH), and actions upon its jobs, as a JSON API, streaming changes as server-sent events from /events
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/metrumresearchgroup/gogridengine"
+	"github.com/metrumresearchgroup/gogridengine/api"
+	"github.com/metrumresearchgroup/gogridengine/stream"
+	log "github.com/sirupsen/logrus"
+)
+
+func main() {
+	home, _ := os.UserHomeDir()
+
+	listen := flag.String("listen", "127.0.0.1:8080", "Address to serve the API on. Only reachable from the host itself by default")
+	ttl := flag.Duration("cache-ttl", 5*time.Second, "How long qstat output is reused between requests. Job actions always invalidate it")
+	readOnly := flag.Bool("read-only", true, "Reject submitting, deleting, holding and releasing jobs. Enabling them requires -token-file or -tls-client-ca")
+	tokenFile := flag.String("token-file", "", "File holding the bearer token required to submit, delete, hold or release jobs")
+	tlsCert := flag.String("tls-cert", "", "Certificate to serve the API over TLS with")
+	tlsKey := flag.String("tls-key", "", "Private key of -tls-cert")
+	clientCA := flag.String("tls-client-ca", "", "Require clients to present a certificate signed by one of the CAs in this file (mutual TLS). Needs -tls-cert and -tls-key")
+	allowBinaries := flag.String("allow-binary", "", "Comma separated commands which may be submitted as binaries (qsub -b y). Nothing is allowed by default")
+	timeout := flag.Duration("timeout", 30*time.Second, "How long a grid engine command or qstat poll may run")
+	poll := flag.Duration("poll-interval", 10*time.Second, "How often qstat is polled for the changes streamed from /events")
+	file := flag.String("xml", "", "Read qstat -xml output from this file instead of running qstat. Implies -read-only")
+	sshAddress := flag.String("ssh", "", "Run the grid engine commands on this submit host (host or host:port) over SSH instead of locally")
+	sshUser := flag.String("ssh-user", os.Getenv("USER"), "User to connect to the submit host as")
+	sshKey := flag.String("ssh-key", filepath.Join(home, ".ssh", "id_rsa"), "Private key authenticating the user on the submit host")
+	knownHosts := flag.String("ssh-known-hosts", filepath.Join(home, ".ssh", "known_hosts"), "known_hosts file the submit host key is checked against")
+	sshEnv := flag.String("ssh-env", "", "Comma separated variables set for every command on the submit host (eg: SGE_ROOT=/opt/sge,SGE_CELL=default)")
+	flag.Parse()
+
+	opts := api.Options{ReadOnly: *readOnly}
+
+	if *tokenFile != "" {
+		token, err := ioutil.ReadFile(*tokenFile)
+		if err != nil {
+			log.Fatal("Unable to read the token: ", err)
+		}
+
+		opts.Token = strings.TrimSpace(string(token))
+		if opts.Token == "" {
+			log.Fatal("The token file is empty")
+		}
+	}
+
+	if *allowBinaries != "" {
+		opts.AllowedBinaries = strings.Split(*allowBinaries, ",")
+	}
+
+	server := &http.Server{Addr: *listen}
+
+	if *clientCA != "" {
+		if *tlsCert == "" || *tlsKey == "" {
+			log.Fatal("-tls-client-ca requires -tls-cert and -tls-key")
+		}
+
+		content, err := ioutil.ReadFile(*clientCA)
+		if err != nil {
+			log.Fatal("Unable to read the client CAs: ", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(content) {
+			log.Fatal("No certificates found in ", *clientCA)
+		}
+
+		server.TLSConfig = &tls.Config{
+			ClientCAs:  pool,
+			ClientAuth: tls.RequireAndVerifyClientCert,
+		}
+	}
+
+	if !opts.ReadOnly && opts.Token == "" && server.TLSConfig == nil {
+		log.Fatal("Job actions can run commands on the cluster: enabling them with -read-only=false requires -token-file or -tls-client-ca")
+	}
+
+	runner := gogridengine.DefaultRunner
+	qstat := &gogridengine.QstatDataSource{}
+	if *sshAddress != "" {
+		ssh, err := gogridengine.NewSSHRunner(*sshAddress, *sshUser, *sshKey, *knownHosts)
+		if err != nil {
+			log.Fatal("Unable to set up SSH to the submit host: ", err)
+		}
+
+		if *sshEnv != "" {
+			ssh.Env = strings.Split(*sshEnv, ",")
+		}
+		ssh.Timeout = *timeout
+		defer ssh.Close()
+
+		runner = ssh
+		qstat.Runner = ssh
+	}
+
+	var source gogridengine.XmlResourceGetter = qstat
+	if *file != "" {
+		source = &gogridengine.FileDataSource{Path: *file}
+		opts.ReadOnly = true
+	}
+
+	if *ttl > 0 {
+		source = gogridengine.NewCachedDataSource(source, *ttl)
+	}
+
+	client := &gogridengine.Client{
+		Runner:  runner,
+		Source:  source,
+		Timeout: *timeout,
+	}
+
+	events := stream.NewHandler(stream.Options{Retry: *poll})
+	go events.Run(context.Background(), gogridengine.Watcher{
+		Source:   source,
+		Interval: *poll,
+		Timeout:  *timeout,
+	})
+
+	http.Handle("/events", events)
+	http.Handle("/", api.NewHandler(client, opts))
+
+	log.Info("Serving the grid engine API on ", *listen)
+
+	if *tlsCert != "" {
+		log.Fatal(server.ListenAndServeTLS(*tlsCert, *tlsKey))
+	}
+
+	log.Fatal(server.ListenAndServe())
+}
@@ -0,0 +1,39 @@
+//Command gridengine_exporter serves Prometheus metrics about the grid engine cluster the host can run qstat against
+package main
+
+import (
+	"flag"
+	"net/http"
+	"time"
+
+	"github.com/metrumresearchgroup/gogridengine"
+	"github.com/metrumresearchgroup/gogridengine/exporter"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+)
+
+func main() {
+	listen := flag.String("listen", ":9710", "Address to serve metrics on")
+	path := flag.String("path", "/metrics", "Path metrics are served under")
+	namespace := flag.String("namespace", "gridengine", "Prefix of every metric name")
+	ttl := flag.Duration("cache-ttl", 15*time.Second, "How long qstat output is reused between scrapes")
+	file := flag.String("xml", "", "Read qstat -xml output from this file instead of running qstat")
+	flag.Parse()
+
+	var source gogridengine.XmlResourceGetter = &gogridengine.QstatDataSource{}
+	if *file != "" {
+		source = &gogridengine.FileDataSource{Path: *file}
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(exporter.NewCollector(source, exporter.Options{
+		Namespace: *namespace,
+		CacheTTL:  *ttl,
+	}))
+
+	http.Handle(*path, promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+	log.Info("Serving grid engine metrics on ", *listen, *path)
+	log.Fatal(http.ListenAndServe(*listen, nil))
+}
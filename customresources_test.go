@@ -24,7 +24,7 @@ func TestResourceListFreeMemory(t *testing.T) {
 			want: StorageValue{
 				Size:  2,
 				Scale: "G",
-				Bytes: 2000000000,
+				Bytes: 2147483648,
 			},
 			wantErr: false,
 		},
@@ -78,7 +78,7 @@ func TestResourceListFreeSwap(t *testing.T) {
 			want: StorageValue{
 				Size:  2,
 				Scale: "G",
-				Bytes: 2000000000,
+				Bytes: 2147483648,
 			},
 			wantErr: false,
 		},
@@ -132,7 +132,7 @@ func TestResourceListFreeVirtualMemory(t *testing.T) {
 			want: StorageValue{
 				Size:  1,
 				Scale: "G",
-				Bytes: 1000000000,
+				Bytes: 1073741824,
 			},
 			wantErr: false,
 		},
@@ -186,7 +186,7 @@ func TestResourceListTotalMemory(t *testing.T) {
 			want: StorageValue{
 				Size:  22,
 				Scale: "G",
-				Bytes: 22000000000,
+				Bytes: 23622320128,
 			},
 			wantErr: false,
 		},
@@ -240,7 +240,7 @@ func TestResourceListTotalSwap(t *testing.T) {
 			want: StorageValue{
 				Size:  432,
 				Scale: "G",
-				Bytes: 432000000000,
+				Bytes: 463856467968,
 			},
 			wantErr: false,
 		},
@@ -294,7 +294,7 @@ func TestResourceListTotalVirtual(t *testing.T) {
 			want: StorageValue{
 				Size:  92,
 				Scale: "G",
-				Bytes: 92000000000,
+				Bytes: 98784247808,
 			},
 			wantErr: false,
 		},
@@ -348,7 +348,7 @@ func TestResourceListMemoryUsed(t *testing.T) {
 			want: StorageValue{
 				Size:  29,
 				Scale: "G",
-				Bytes: 29000000000,
+				Bytes: 31138512896,
 			},
 			wantErr: false,
 		},
@@ -402,7 +402,7 @@ func TestResourceListSwapUsed(t *testing.T) {
 			want: StorageValue{
 				Size:  140,
 				Scale: "G",
-				Bytes: 140000000000,
+				Bytes: 150323855360,
 			},
 			wantErr: false,
 		},
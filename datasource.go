@@ -0,0 +1,102 @@
+package gogridengine
+
+import (
+	"embed"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//fixtures are the qstat outputs shipped in test_data, available without network or filesystem access
+//
+//go:embed test_data/*.xml
+var fixtures embed.FS
+
+//ErrUnknownFixture is returned when requesting an embedded fixture that doesn't exist
+const ErrUnknownFixture = Error("The requested fixture is not embedded in the library")
+
+//FileDataSource reads qstat XML from a file on local disk
+type FileDataSource struct {
+	Path string
+}
+
+//Get returns the content of the file
+func (d *FileDataSource) Get() (string, error) {
+	content, err := ioutil.ReadFile(d.Path)
+
+	if err != nil {
+		return "", err
+	}
+
+	return string(content), nil
+}
+
+//EmbeddedDataSource serves one of the qstat XML fixtures embedded in the library (small.xml, medium.xml or pending.xml)
+type EmbeddedDataSource struct {
+	Name string
+}
+
+//Get returns the content of the embedded fixture
+func (d *EmbeddedDataSource) Get() (string, error) {
+	content, err := fixtures.ReadFile("test_data/" + d.Name)
+
+	if err != nil {
+		return "", ErrUnknownFixture
+	}
+
+	return string(content), nil
+}
+
+//ReaderDataSource serves qstat XML from an io.Reader. The reader is consumed on the first Get and its content is returned for every Get after that.
+type ReaderDataSource struct {
+	Reader io.Reader
+
+	once    sync.Once
+	content string
+	err     error
+}
+
+//Get returns the content of the reader
+func (d *ReaderDataSource) Get() (string, error) {
+	d.once.Do(func() {
+		content, err := ioutil.ReadAll(d.Reader)
+		d.content = string(content)
+		d.err = err
+	})
+
+	return d.content, d.err
+}
+
+//SyntheticDataSource serves qstat XML produced by the seeded generator
+type SyntheticDataSource struct {
+	Options GeneratorOptions
+}
+
+//Get generates the qstat XML for the options. The same options (and seed) always produce the same output
+func (d *SyntheticDataSource) Get() (string, error) {
+	return GenerateQstatXML(d.Options)
+}
+
+//testDataSource picks the data source used in test mode from GOGRIDENGINE_TEST_SOURCE.
+//URLs are fetched over HTTP, "synthetic" (or "synthetic:<seed>") uses the generator, "embedded:<name>" uses an embedded fixture and anything else is read as a file path.
+//Without a value, the embedded medium.xml fixture is used so test mode works offline.
+func testDataSource(source string) XmlResourceGetter {
+	switch {
+	case source == "":
+		return &EmbeddedDataSource{Name: "medium.xml"}
+	case strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://"):
+		return &XMLDataSource{location: source}
+	case strings.HasPrefix(source, "embedded:"):
+		return &EmbeddedDataSource{Name: strings.TrimPrefix(source, "embedded:")}
+	case source == "synthetic" || strings.HasPrefix(source, "synthetic:"):
+		options := DefaultGeneratorOptions()
+		if seed, err := strconv.ParseInt(strings.TrimPrefix(source, "synthetic:"), 10, 64); err == nil {
+			options.Seed = seed
+		}
+		return &SyntheticDataSource{Options: options}
+	}
+
+	return &FileDataSource{Path: source}
+}
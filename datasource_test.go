@@ -0,0 +1,104 @@
+package gogridengine
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileDataSource(t *testing.T) {
+	content, err := (&FileDataSource{Path: "test_data/small.xml"}).Get()
+	assert.Nil(t, err)
+	assert.Contains(t, content, "<job_info")
+
+	_, err = (&FileDataSource{Path: "test_data/missing.xml"}).Get()
+	assert.NotNil(t, err)
+}
+
+func TestEmbeddedDataSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture string
+		wantErr error
+	}{
+		{
+			name:    "small",
+			fixture: "small.xml",
+		},
+		{
+			name:    "medium",
+			fixture: "medium.xml",
+		},
+		{
+			name:    "unknown",
+			fixture: "large.xml",
+			wantErr: ErrUnknownFixture,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content, err := (&EmbeddedDataSource{Name: tt.fixture}).Get()
+			assert.Equal(t, tt.wantErr, err)
+
+			if tt.wantErr == nil {
+				onDisk, _ := (&FileDataSource{Path: "test_data/" + tt.fixture}).Get()
+				assert.Equal(t, onDisk, content)
+			}
+		})
+	}
+}
+
+func TestReaderDataSource(t *testing.T) {
+	source := &ReaderDataSource{Reader: strings.NewReader("<job_info></job_info>")}
+
+	first, err := source.Get()
+	assert.Nil(t, err)
+	second, err := source.Get()
+	assert.Nil(t, err)
+
+	assert.Equal(t, "<job_info></job_info>", first)
+	assert.Equal(t, first, second)
+}
+
+func TestTestDataSource(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   XmlResourceGetter
+	}{
+		{
+			name:   "default",
+			source: "",
+			want:   &EmbeddedDataSource{Name: "medium.xml"},
+		},
+		{
+			name:   "url",
+			source: "https://example.com/qstat.xml",
+			want:   &XMLDataSource{location: "https://example.com/qstat.xml"},
+		},
+		{
+			name:   "embedded",
+			source: "embedded:small.xml",
+			want:   &EmbeddedDataSource{Name: "small.xml"},
+		},
+		{
+			name:   "synthetic",
+			source: "synthetic",
+			want:   &SyntheticDataSource{Options: DefaultGeneratorOptions()},
+		},
+		{
+			name:   "file",
+			source: "test_data/small.xml",
+			want:   &FileDataSource{Path: "test_data/small.xml"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, testDataSource(tt.source))
+		})
+	}
+
+	seeded := testDataSource("synthetic:42").(*SyntheticDataSource)
+	assert.Equal(t, int64(42), seeded.Options.Seed)
+}
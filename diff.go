@@ -0,0 +1,213 @@
+package gogridengine
+
+import (
+	"strconv"
+)
+
+//EventType identifies the kind of change a diff Event describes
+type EventType string
+
+const (
+	//JobSubmitted is emitted for jobs (or array tasks) present in the next snapshot but not the previous one
+	JobSubmitted EventType = "job_submitted"
+	//JobStarted is emitted when a job is first seen running, along with the queue instance it is running on
+	JobStarted EventType = "job_started"
+	//JobStateChanged is emitted whenever the state code of a job changes between snapshots
+	JobStateChanged EventType = "job_state_changed"
+	//JobEnteredError is emitted when a job moves into an error state
+	JobEnteredError EventType = "job_entered_error"
+	//JobDisappeared is emitted for jobs no longer reported by qstat, whether they finished or were deleted
+	JobDisappeared EventType = "job_disappeared"
+	//HostAdded is emitted for queue instances present in the next snapshot but not the previous one
+	HostAdded EventType = "host_added"
+	//HostRemoved is emitted for queue instances no longer reported by qstat
+	HostRemoved EventType = "host_removed"
+	//HostStateChanged is emitted whenever the queue state of a queue instance changes
+	HostStateChanged EventType = "host_state_changed"
+)
+
+//JobKey identifies a single job or array task across snapshots
+type JobKey struct {
+	JobNumber int64 `json:"job_number"`
+	TaskID    int64 `json:"task_id"`
+}
+
+//KeyForJob returns the JobKey identifying the provided job
+func KeyForJob(j Job) JobKey {
+	return JobKey{
+		JobNumber: j.JBJobNumber,
+		TaskID:    j.Tasks.TaskID,
+	}
+}
+
+//String renders the key the way SGE does, as either the job number or job.task for array tasks
+func (k JobKey) String() string {
+	if k.TaskID == 0 {
+		return strconv.FormatInt(k.JobNumber, 10)
+	}
+
+	return strconv.FormatInt(k.JobNumber, 10) + "." + strconv.FormatInt(k.TaskID, 10)
+}
+
+//Event is a single change observed between two JobInfo snapshots. Job events carry the Key and the job as seen in the snapshot it was observed in, host events carry the queue instance name.
+type Event struct {
+	Type          EventType `json:"type"`
+	Key           JobKey    `json:"key"`
+	Job           *Job      `json:"job,omitempty"`
+	Previous      *Job      `json:"previous,omitempty"`
+	Host          string    `json:"host,omitempty"`
+	State         string    `json:"state,omitempty"`
+	PreviousState string    `json:"previous_state,omitempty"`
+}
+
+//Diff compares two snapshots and returns the typed events describing what changed between them. Array tasks are tracked individually by job number and task ID.
+func Diff(prev, next JobInfo) []Event {
+	var events []Event
+
+	prevHosts := hostsByName(prev)
+	nextHosts := hostsByName(next)
+
+	for _, h := range next.QueueInfo.Queues {
+		p, ok := prevHosts[h.Name]
+
+		if !ok {
+			events = append(events, Event{
+				Type:  HostAdded,
+				Host:  h.Name,
+				State: h.State,
+			})
+			continue
+		}
+
+		if p.State != h.State {
+			events = append(events, Event{
+				Type:          HostStateChanged,
+				Host:          h.Name,
+				State:         h.State,
+				PreviousState: p.State,
+			})
+		}
+	}
+
+	prevJobs, _ := jobsByKey(prev)
+	nextJobs, nextOrder := jobsByKey(next)
+
+	for _, key := range nextOrder {
+		n := nextJobs[key]
+		p, ok := prevJobs[key]
+
+		if !ok {
+			events = append(events, Event{
+				Type:  JobSubmitted,
+				Key:   key,
+				Job:   &n,
+				Host:  n.QueueName,
+				State: n.State,
+			})
+		} else if p.State != n.State {
+			events = append(events, Event{
+				Type:          JobStateChanged,
+				Key:           key,
+				Job:           &n,
+				Previous:      &p,
+				Host:          n.QueueName,
+				State:         n.State,
+				PreviousState: p.State,
+			})
+		}
+
+		//p is the zero Job for newly submitted jobs, so anything already running or errored is reported too
+		if JobPhase(n) == PhaseRunning && (!ok || JobPhase(p) != PhaseRunning) {
+			events = append(events, Event{
+				Type:  JobStarted,
+				Key:   key,
+				Job:   &n,
+				Host:  n.QueueName,
+				State: n.State,
+			})
+		}
+
+		if JobPhase(n) == PhaseError && (!ok || JobPhase(p) != PhaseError) {
+			events = append(events, Event{
+				Type:          JobEnteredError,
+				Key:           key,
+				Job:           &n,
+				Host:          n.QueueName,
+				State:         n.State,
+				PreviousState: p.State,
+			})
+		}
+	}
+
+	_, prevOrder := jobsByKey(prev)
+
+	for _, key := range prevOrder {
+		if _, ok := nextJobs[key]; ok {
+			continue
+		}
+
+		p := prevJobs[key]
+		events = append(events, Event{
+			Type:          JobDisappeared,
+			Key:           key,
+			Previous:      &p,
+			Host:          p.QueueName,
+			PreviousState: p.State,
+		})
+	}
+
+	for _, h := range prev.QueueInfo.Queues {
+		if _, ok := nextHosts[h.Name]; !ok {
+			events = append(events, Event{
+				Type:          HostRemoved,
+				Host:          h.Name,
+				PreviousState: h.State,
+			})
+		}
+	}
+
+	return events
+}
+
+func hostsByName(ji JobInfo) map[string]Host {
+	hosts := make(map[string]Host)
+
+	for _, h := range ji.QueueInfo.Queues {
+		hosts[h.Name] = h
+	}
+
+	return hosts
+}
+
+//jobsByKey indexes the jobs of a snapshot, returning the keys in snapshot order. Parallel jobs spanning several queue instances are only indexed once (first seen).
+func jobsByKey(ji JobInfo) (map[JobKey]Job, []JobKey) {
+	jobs := make(map[JobKey]Job)
+	var order []JobKey
+
+	add := func(j Job) {
+		key := KeyForJob(j)
+
+		if _, ok := jobs[key]; ok {
+			return
+		}
+
+		jobs[key] = j
+		order = append(order, key)
+	}
+
+	for _, h := range ji.QueueInfo.Queues {
+		for _, j := range h.JobList {
+			//Snapshots that didn't come through NewJobInfo won't have the queue instance recorded yet
+			if j.QueueName == "" {
+				j.QueueName = h.Name
+			}
+			add(j)
+		}
+	}
+
+	for _, j := range ji.PendingJobs.JobList {
+		add(j)
+	}
+
+	return jobs, order
+}
@@ -0,0 +1,134 @@
+package gogridengine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func eventTypes(events []Event) []EventType {
+	var types []EventType
+
+	for _, e := range events {
+		types = append(types, e.Type)
+	}
+
+	return types
+}
+
+func TestJobKey_String(t *testing.T) {
+	assert.Equal(t, "4282", JobKey{JobNumber: 4282}.String())
+	assert.Equal(t, "1006.5", JobKey{JobNumber: 1006, TaskID: 5}.String())
+}
+
+func TestDiffFromEmpty(t *testing.T) {
+	next := JobInfo{
+		QueueInfo: QueueInfo{
+			Queues: []Host{
+				{
+					Name: "all.q@ip-10-0-1-80.ec2.internal",
+					JobList: []Job{
+						{JBJobNumber: 1, State: "r"},
+					},
+				},
+			},
+		},
+		PendingJobs: PendingJob{
+			JobList: []Job{
+				{JBJobNumber: 2, State: "qw"},
+			},
+		},
+	}
+
+	events := Diff(JobInfo{}, next)
+
+	assert.Equal(t, []EventType{HostAdded, JobSubmitted, JobStarted, JobSubmitted}, eventTypes(events))
+	assert.Equal(t, "all.q@ip-10-0-1-80.ec2.internal", events[2].Host)
+	assert.Equal(t, JobKey{JobNumber: 1}, events[2].Key)
+}
+
+func TestDiffLifecycle(t *testing.T) {
+	prev := JobInfo{
+		QueueInfo: QueueInfo{
+			Queues: []Host{
+				{
+					Name: "all.q@ip-10-0-1-80.ec2.internal",
+					JobList: []Job{
+						{JBJobNumber: 1, State: "r"},
+					},
+				},
+				{
+					Name: "all.q@ip-10-0-1-113.ec2.internal",
+				},
+			},
+		},
+		PendingJobs: PendingJob{
+			JobList: []Job{
+				{JBJobNumber: 1006, State: "qw", Tasks: Task{TaskID: 1}},
+				{JBJobNumber: 1006, State: "qw", Tasks: Task{TaskID: 2}},
+				{JBJobNumber: 1007, State: "qw"},
+			},
+		},
+	}
+
+	next := JobInfo{
+		QueueInfo: QueueInfo{
+			Queues: []Host{
+				{
+					Name:  "all.q@ip-10-0-1-80.ec2.internal",
+					State: "d",
+					JobList: []Job{
+						{JBJobNumber: 1006, State: "r", Tasks: Task{TaskID: 1}},
+					},
+				},
+				{
+					Name: "all.q@ip-10-0-1-203.ec2.internal",
+				},
+			},
+		},
+		PendingJobs: PendingJob{
+			JobList: []Job{
+				{JBJobNumber: 1006, State: "qw", Tasks: Task{TaskID: 2}},
+				{JBJobNumber: 1007, State: "Eqw"},
+			},
+		},
+	}
+
+	events := Diff(prev, next)
+
+	assert.Equal(t, []EventType{
+		HostStateChanged,
+		HostAdded,
+		JobStateChanged,
+		JobStarted,
+		JobStateChanged,
+		JobEnteredError,
+		JobDisappeared,
+		HostRemoved,
+	}, eventTypes(events))
+
+	assert.Equal(t, "d", events[0].State)
+	assert.Equal(t, JobKey{JobNumber: 1006, TaskID: 1}, events[3].Key)
+	assert.Equal(t, "all.q@ip-10-0-1-80.ec2.internal", events[3].Host)
+	assert.Equal(t, "qw", events[5].PreviousState)
+	assert.Equal(t, JobKey{JobNumber: 1}, events[6].Key)
+	assert.Equal(t, "r", events[6].Previous.State)
+	assert.Equal(t, "all.q@ip-10-0-1-113.ec2.internal", events[7].Host)
+}
+
+func TestDiffIdenticalSnapshots(t *testing.T) {
+	ji := JobInfo{
+		QueueInfo: QueueInfo{
+			Queues: []Host{
+				{
+					Name: "all.q@ip-10-0-1-80.ec2.internal",
+					JobList: []Job{
+						{JBJobNumber: 1, State: "r"},
+					},
+				},
+			},
+		},
+	}
+
+	assert.Empty(t, Diff(ji, ji))
+}
@@ -0,0 +1,357 @@
+package gogridengine
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+//Export formats
+const (
+	//ExportCSV writes a header row followed by one row per record
+	ExportCSV = "csv"
+	//ExportJSONLines writes one JSON object per line, keyed by column name in column order
+	ExportJSONLines = "jsonl"
+)
+
+//ErrUnknownExportFormat is returned when exporting in a format other than ExportCSV or ExportJSONLines
+const ErrUnknownExportFormat = Error("Export format must be csv or jsonl")
+
+//ErrUnknownColumn is returned when exporting a column the view doesn't have
+const ErrUnknownColumn = Error("Unknown export column")
+
+//JobColumns are the columns of an exported JobList, in their default order
+var JobColumns = []string{
+	"job_number",
+	"task_id",
+	"tasks",
+	"priority",
+	"name",
+	"owner",
+	"state",
+	"phase",
+	"submitted",
+	"started",
+	"queue_instance",
+	"slots",
+	"requested_memory",
+}
+
+//HostColumns are the columns of an exported host list, in their default order. Memory is in bytes
+var HostColumns = []string{
+	"queue_instance",
+	"queue",
+	"host",
+	"qtype",
+	"state",
+	"available",
+	"slots_used",
+	"slots_reserved",
+	"slots_total",
+	"load_average",
+	"np_load_avg",
+	"mem_total",
+	"mem_used",
+	"mem_free",
+	"jobs",
+}
+
+//JobHostColumns are the columns of the job with host view: the job columns followed by those of the host the job runs on, whose state and jobs are renamed host_state and host_jobs
+var JobHostColumns = jobHostColumns()
+
+//Exporter writes jobs and hosts in a tabular form suited to analysis tools. Columns without a value, such as the start time of a pending job, are left empty in CSV and null in JSON Lines
+type Exporter struct {
+	//Format is ExportCSV or ExportJSONLines. Defaults to ExportCSV
+	Format string
+	//Columns selects and orders the exported columns. Defaults to every column of the view
+	Columns []string
+	//Location is the time zone qstat reported times in. Defaults to the local time zone
+	Location *time.Location
+}
+
+//exportRow is a record of any view
+type exportRow struct {
+	job  *Job
+	host *Host
+}
+
+type exportColumn func(e Exporter, r exportRow) interface{}
+
+var jobExportColumns = map[string]exportColumn{
+	"job_number": func(e Exporter, r exportRow) interface{} { return r.job.JBJobNumber },
+	"task_id": func(e Exporter, r exportRow) interface{} {
+		if r.job.Tasks.TaskID == 0 {
+			return nil
+		}
+		return r.job.Tasks.TaskID
+	},
+	"tasks":          func(e Exporter, r exportRow) interface{} { return optional(r.job.Tasks.Source) },
+	"priority":       func(e Exporter, r exportRow) interface{} { return r.job.JATPriority },
+	"name":           func(e Exporter, r exportRow) interface{} { return r.job.JobName },
+	"owner":          func(e Exporter, r exportRow) interface{} { return r.job.JobOwner },
+	"state":          func(e Exporter, r exportRow) interface{} { return r.job.State },
+	"phase":          func(e Exporter, r exportRow) interface{} { return JobPhase(*r.job) },
+	"submitted":      func(e Exporter, r exportRow) interface{} { return e.timestamp(r.job.SubmittedTime) },
+	"started":        func(e Exporter, r exportRow) interface{} { return e.timestamp(r.job.StartTime) },
+	"queue_instance": func(e Exporter, r exportRow) interface{} { return optional(r.job.QueueName) },
+	"slots":          func(e Exporter, r exportRow) interface{} { return r.job.Slots },
+	"requested_memory": func(e Exporter, r exportRow) interface{} {
+		memory, err := r.job.RequestedMemory()
+		if err != nil {
+			return nil
+		}
+		return memory.Bytes
+	},
+}
+
+var hostExportColumns = map[string]exportColumn{
+	"queue_instance": func(e Exporter, r exportRow) interface{} { return r.host.Name },
+	"queue": func(e Exporter, r exportRow) interface{} {
+		queue, _ := SplitQueueInstance(r.host.Name)
+		return queue
+	},
+	"host": func(e Exporter, r exportRow) interface{} {
+		_, host := SplitQueueInstance(r.host.Name)
+		return host
+	},
+	"qtype":          func(e Exporter, r exportRow) interface{} { return r.host.QType },
+	"state":          func(e Exporter, r exportRow) interface{} { return r.host.State },
+	"available":      func(e Exporter, r exportRow) interface{} { return IsHostAvailable(*r.host) },
+	"slots_used":     func(e Exporter, r exportRow) interface{} { return r.host.SlotsUsed },
+	"slots_reserved": func(e Exporter, r exportRow) interface{} { return r.host.SlotsReserved },
+	"slots_total":    func(e Exporter, r exportRow) interface{} { return r.host.SlotsTotal },
+	"load_average":   func(e Exporter, r exportRow) interface{} { return r.host.LoadAverage },
+	"np_load_avg": func(e Exporter, r exportRow) interface{} {
+		load, err := r.host.Resources.NPLoadAverage()
+		if err != nil {
+			return nil
+		}
+		return load
+	},
+	"mem_total": func(e Exporter, r exportRow) interface{} { return storageBytes(r.host.Resources.TotalMemory()) },
+	"mem_used":  func(e Exporter, r exportRow) interface{} { return storageBytes(r.host.Resources.MemoryUsed()) },
+	"mem_free":  func(e Exporter, r exportRow) interface{} { return storageBytes(r.host.Resources.FreeMemory()) },
+	"jobs":      func(e Exporter, r exportRow) interface{} { return len(r.host.JobList) },
+}
+
+//hostRenames are the host columns renamed in the job with host view to tell them apart from the job's own
+var hostRenames = map[string]string{
+	"state": "host_state",
+	"jobs":  "host_jobs",
+}
+
+var jobHostExportColumns = func() map[string]exportColumn {
+	columns := make(map[string]exportColumn)
+
+	for name, column := range jobExportColumns {
+		columns[name] = column
+	}
+
+	for name, column := range hostExportColumns {
+		if _, ok := columns[name]; ok && hostRenames[name] == "" {
+			//Shared with the job, such as the queue instance
+			continue
+		}
+
+		if renamed, ok := hostRenames[name]; ok {
+			name = renamed
+		}
+
+		//Pending jobs have no host
+		column := column
+		columns[name] = func(e Exporter, r exportRow) interface{} {
+			if r.host == nil {
+				return nil
+			}
+			return column(e, r)
+		}
+	}
+
+	return columns
+}()
+
+func jobHostColumns() []string {
+	columns := append([]string{}, JobColumns...)
+
+	for _, name := range HostColumns {
+		if name == "queue_instance" {
+			continue
+		}
+
+		if renamed, ok := hostRenames[name]; ok {
+			name = renamed
+		}
+
+		columns = append(columns, name)
+	}
+
+	return columns
+}
+
+//WriteJobs exports one record per job
+func (e Exporter) WriteJobs(w io.Writer, jobs JobList) error {
+	rows := make([]exportRow, len(jobs))
+	for k := range jobs {
+		rows[k] = exportRow{job: &jobs[k]}
+	}
+
+	return e.write(w, jobExportColumns, JobColumns, rows)
+}
+
+//WriteHosts exports one record per queue instance
+func (e Exporter) WriteHosts(w io.Writer, hosts []Host) error {
+	rows := make([]exportRow, len(hosts))
+	for k := range hosts {
+		rows[k] = exportRow{host: &hosts[k]}
+	}
+
+	return e.write(w, hostExportColumns, HostColumns, rows)
+}
+
+//WriteJobHosts exports one record per job of the JobInfo, running jobs joined with the queue instance they run on. Pending jobs leave the host columns empty
+func (e Exporter) WriteJobHosts(w io.Writer, ji JobInfo) error {
+	var rows []exportRow
+
+	for k := range ji.QueueInfo.Queues {
+		host := &ji.QueueInfo.Queues[k]
+		for i := range host.JobList {
+			rows = append(rows, exportRow{job: &host.JobList[i], host: host})
+		}
+	}
+
+	for k := range ji.PendingJobs.JobList {
+		rows = append(rows, exportRow{job: &ji.PendingJobs.JobList[k]})
+	}
+
+	return e.write(w, jobHostExportColumns, JobHostColumns, rows)
+}
+
+func (e Exporter) write(w io.Writer, available map[string]exportColumn, defaults []string, rows []exportRow) error {
+	names := e.Columns
+	if len(names) == 0 {
+		names = defaults
+	}
+
+	columns := make([]exportColumn, len(names))
+	for k, name := range names {
+		column, ok := available[name]
+		if !ok {
+			return fmt.Errorf("%w: %q", ErrUnknownColumn, name)
+		}
+		columns[k] = column
+	}
+
+	switch e.Format {
+	case "", ExportCSV:
+		return e.writeCSV(w, names, columns, rows)
+	case ExportJSONLines:
+		return e.writeJSONLines(w, names, columns, rows)
+	default:
+		return fmt.Errorf("%w, got %q", ErrUnknownExportFormat, e.Format)
+	}
+}
+
+func (e Exporter) writeCSV(w io.Writer, names []string, columns []exportColumn, rows []exportRow) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write(names); err != nil {
+		return err
+	}
+
+	record := make([]string, len(columns))
+	for _, r := range rows {
+		for k, column := range columns {
+			record[k] = csvValue(column(e, r))
+		}
+
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+//writeJSONLines writes each record as an object whose keys follow the column order, which encoding a map wouldn't keep
+func (e Exporter) writeJSONLines(w io.Writer, names []string, columns []exportColumn, rows []exportRow) error {
+	var line bytes.Buffer
+
+	for _, r := range rows {
+		line.Reset()
+		line.WriteByte('{')
+
+		for k, column := range columns {
+			if k > 0 {
+				line.WriteByte(',')
+			}
+
+			key, _ := json.Marshal(names[k])
+			value, err := json.Marshal(column(e, r))
+			if err != nil {
+				return err
+			}
+
+			line.Write(key)
+			line.WriteByte(':')
+			line.Write(value)
+		}
+
+		line.WriteString("}\n")
+
+		if _, err := w.Write(line.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//timestamp converts a qstat time to RFC 3339, or nil when it is missing
+func (e Exporter) timestamp(value string) interface{} {
+	location := e.Location
+	if location == nil {
+		location = time.Local
+	}
+
+	t, err := time.ParseInLocation(ISO8601FMT, value, location)
+	if err != nil {
+		return nil
+	}
+
+	return t.Format(time.RFC3339)
+}
+
+func optional(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+
+	return value
+}
+
+func storageBytes(value StorageValue, err error) interface{} {
+	if err != nil {
+		return nil
+	}
+
+	return value.Bytes
+}
+
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
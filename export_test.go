@@ -0,0 +1,177 @@
+package gogridengine
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func exportJobInfo() JobInfo {
+	return JobInfo{
+		QueueInfo: QueueInfo{
+			Queues: []Host{
+				{
+					Name:        "all.q@node1",
+					QType:       "BIP",
+					SlotsUsed:   2,
+					SlotsTotal:  4,
+					LoadAverage: 1.5,
+					Resources: ResourceList{
+						{Name: "mem_total", Value: "16.000G"},
+						{Name: "mem_used", Value: "4.000G"},
+						{Name: "mem_free", Value: "12.000G"},
+						{Name: "np_load_avg", Value: "0.375000"},
+					},
+					JobList: []Job{
+						{
+							JBJobNumber:  1,
+							JATPriority:  0.55,
+							JobName:      "Run, with comma",
+							JobOwner:     "darrellb",
+							State:        "r",
+							StartTime:    "2019-12-18T14:05:00",
+							Slots:        2,
+							HardRequests: []ResourceRequest{{Name: "h_vmem", Value: "4G"}},
+							QueueName:    "all.q@node1",
+						},
+					},
+				},
+				{
+					Name:       "all.q@node2",
+					QType:      "BIP",
+					SlotsTotal: 4,
+					State:      "d",
+				},
+			},
+		},
+		PendingJobs: PendingJob{
+			JobList: []Job{
+				{
+					JBJobNumber:   2,
+					JobName:       "Array",
+					JobOwner:      "devinp",
+					State:         "qw",
+					SubmittedTime: "2019-12-18T14:00:00",
+					Slots:         1,
+					Tasks:         Task{Source: "1-3:1"},
+				},
+			},
+		},
+	}
+}
+
+func TestExporterWriteJobs(t *testing.T) {
+	ji := exportJobInfo()
+
+	tests := []struct {
+		name     string
+		exporter Exporter
+		want     string
+	}{
+		{
+			name:     "csv",
+			exporter: Exporter{Location: time.UTC},
+			want: `job_number,task_id,tasks,priority,name,owner,state,phase,submitted,started,queue_instance,slots,requested_memory
+1,,,0.55,"Run, with comma",darrellb,r,running,,2019-12-18T14:05:00Z,all.q@node1,2,4294967296
+2,,1-3:1,0,Array,devinp,qw,pending,2019-12-18T14:00:00Z,,,1,
+`,
+		},
+		{
+			name:     "selected columns",
+			exporter: Exporter{Columns: []string{"owner", "job_number", "started"}, Location: time.FixedZone("EST", -5*3600)},
+			want: `owner,job_number,started
+darrellb,1,2019-12-18T14:05:00-05:00
+devinp,2,
+`,
+		},
+		{
+			name:     "json lines",
+			exporter: Exporter{Format: ExportJSONLines, Columns: []string{"job_number", "tasks", "priority", "name", "started", "slots"}, Location: time.UTC},
+			want: `{"job_number":1,"tasks":null,"priority":0.55,"name":"Run, with comma","started":"2019-12-18T14:05:00Z","slots":2}
+{"job_number":2,"tasks":"1-3:1","priority":0,"name":"Array","started":null,"slots":1}
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			assert.Nil(t, tt.exporter.WriteJobs(&b, ji.Jobs()))
+			assert.Equal(t, tt.want, b.String())
+		})
+	}
+}
+
+func TestExporterWriteHosts(t *testing.T) {
+	ji := exportJobInfo()
+
+	var b bytes.Buffer
+	assert.Nil(t, Exporter{}.WriteHosts(&b, ji.QueueInfo.Queues))
+	assert.Equal(t, `queue_instance,queue,host,qtype,state,available,slots_used,slots_reserved,slots_total,load_average,np_load_avg,mem_total,mem_used,mem_free,jobs
+all.q@node1,all.q,node1,BIP,,true,2,0,4,1.5,0.375,17179869184,4294967296,12884901888,1
+all.q@node2,all.q,node2,BIP,d,false,0,0,4,0,,,,,0
+`, b.String())
+
+	b.Reset()
+	assert.Nil(t, Exporter{Format: ExportJSONLines, Columns: []string{"host", "available", "mem_free"}}.WriteHosts(&b, ji.QueueInfo.Queues))
+	assert.Equal(t, `{"host":"node1","available":true,"mem_free":12884901888}
+{"host":"node2","available":false,"mem_free":null}
+`, b.String())
+}
+
+func TestExporterWriteJobHosts(t *testing.T) {
+	ji := exportJobInfo()
+
+	var b bytes.Buffer
+	e := Exporter{Columns: []string{"job_number", "state", "queue_instance", "host", "host_state", "np_load_avg", "host_jobs"}}
+	assert.Nil(t, e.WriteJobHosts(&b, ji))
+	assert.Equal(t, `job_number,state,queue_instance,host,host_state,np_load_avg,host_jobs
+1,r,all.q@node1,node1,,0.375,1
+2,qw,,,,,
+`, b.String())
+
+	//Every default column has a value
+	b.Reset()
+	assert.Nil(t, Exporter{}.WriteJobHosts(&b, ji))
+	header := strings.SplitN(b.String(), "\n", 2)[0]
+	assert.Equal(t, strings.Join(JobHostColumns, ","), header)
+	assert.Len(t, JobHostColumns, len(JobColumns)+len(HostColumns)-1)
+}
+
+func TestExporterStableHeader(t *testing.T) {
+	content, err := ioutil.ReadFile("test_data/medium.xml")
+	assert.Nil(t, err)
+
+	ji, err := NewJobInfo(string(content))
+	assert.Nil(t, err)
+
+	//Empty results still write the header, so appended exports line up
+	var empty, full bytes.Buffer
+	assert.Nil(t, Exporter{}.WriteJobs(&empty, JobList{}))
+	assert.Nil(t, Exporter{}.WriteJobs(&full, ji.Jobs()))
+
+	assert.Equal(t, strings.Join(JobColumns, ",")+"\n", empty.String())
+	assert.True(t, strings.HasPrefix(full.String(), empty.String()))
+	assert.Equal(t, len(ji.Jobs())+1, strings.Count(full.String(), "\n"))
+}
+
+func TestExporterErrors(t *testing.T) {
+	var b bytes.Buffer
+
+	err := Exporter{Columns: []string{"job_number", "colour"}}.WriteJobs(&b, JobList{})
+	assert.True(t, errors.Is(err, ErrUnknownColumn))
+	assert.Contains(t, err.Error(), `"colour"`)
+
+	//Host columns of the job with host view aren't columns of a plain JobList
+	err = Exporter{Columns: []string{"host_state"}}.WriteJobs(&b, JobList{})
+	assert.True(t, errors.Is(err, ErrUnknownColumn))
+
+	err = Exporter{Format: "parquet"}.WriteHosts(&b, nil)
+	assert.True(t, errors.Is(err, ErrUnknownExportFormat))
+
+	assert.Empty(t, b.String())
+}
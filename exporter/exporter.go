@@ -0,0 +1,261 @@
+//Package exporter is a Prometheus collector exposing queue instance, host and job metrics computed from a single qstat call per scrape.
+package exporter
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/metrumresearchgroup/gogridengine"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+//Options tune the collector
+type Options struct {
+	//Namespace prefixes every metric name. Defaults to gridengine
+	Namespace string
+	//CacheTTL caches the qstat output between scrapes, so several Prometheus servers scraping the exporter don't multiply the load on qmaster. Zero disables the cache
+	CacheTTL time.Duration
+	//Location is the time zone qstat reports times in. Defaults to the local time zone
+	Location *time.Location
+}
+
+//Collector implements prometheus.Collector for a grid engine cluster
+type Collector struct {
+	source   gogridengine.XmlResourceGetter
+	location *time.Location
+	now      func() time.Time
+
+	//scrapeMutex serialises scrapes so concurrent ones share the source's cached content rather than racing it
+	scrapeMutex sync.Mutex
+
+	up               *prometheus.Desc
+	scrapeDuration   *prometheus.Desc
+	scrapeErrors     prometheus.Counter
+	slotsUsed        *prometheus.Desc
+	slotsReserved    *prometheus.Desc
+	slotsTotal       *prometheus.Desc
+	instanceUp       *prometheus.Desc
+	loadAverage      *prometheus.Desc
+	npLoadAverage    *prometheus.Desc
+	memoryTotal      *prometheus.Desc
+	memoryFree       *prometheus.Desc
+	memoryUsed       *prometheus.Desc
+	jobs             *prometheus.Desc
+	jobSlots         *prometheus.Desc
+	pendingSlots     *prometheus.Desc
+	oldestPendingAge *prometheus.Desc
+}
+
+//NewCollector creates a collector reading qstat XML from the provided source
+func NewCollector(source gogridengine.XmlResourceGetter, opts Options) *Collector {
+	if opts.Namespace == "" {
+		opts.Namespace = "gridengine"
+	}
+
+	if opts.Location == nil {
+		opts.Location = time.Local
+	}
+
+	if opts.CacheTTL > 0 {
+		source = gogridengine.NewCachedDataSource(source, opts.CacheTTL)
+	}
+
+	desc := func(name string, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(opts.Namespace, "", name), help, labels, nil)
+	}
+
+	instance := []string{"queue", "host"}
+
+	return &Collector{
+		source:         source,
+		location:       opts.Location,
+		now:            time.Now,
+		up:             desc("up", "Whether the last qstat call succeeded."),
+		scrapeDuration: desc("scrape_duration_seconds", "Time taken by the qstat call of the scrape."),
+		scrapeErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: opts.Namespace,
+			Name:      "scrape_errors_total",
+			Help:      "Number of scrapes where qstat failed or returned unparsable output.",
+		}),
+		slotsUsed:        desc("queue_slots_used", "Slots in use on the queue instance.", instance...),
+		slotsReserved:    desc("queue_slots_reserved", "Slots reserved on the queue instance.", instance...),
+		slotsTotal:       desc("queue_slots_total", "Slots configured on the queue instance.", instance...),
+		instanceUp:       desc("queue_available", "Whether the queue instance state allows work to be scheduled onto it.", instance...),
+		loadAverage:      desc("host_load_average", "load_avg reported by the host.", instance...),
+		npLoadAverage:    desc("host_np_load_average", "np_load_avg (load average per processor) reported by the host.", instance...),
+		memoryTotal:      desc("host_memory_total_bytes", "mem_total reported by the host.", instance...),
+		memoryFree:       desc("host_memory_free_bytes", "mem_free reported by the host.", instance...),
+		memoryUsed:       desc("host_memory_used_bytes", "mem_used reported by the host.", instance...),
+		jobs:             desc("jobs", "Jobs (and array tasks) by owner, state and queue. Pending jobs have an empty queue.", "owner", "state", "queue"),
+		jobSlots:         desc("job_slots", "Slots of jobs (and array tasks) by owner, state and queue. Pending jobs have an empty queue.", "owner", "state", "queue"),
+		pendingSlots:     desc("pending_slots", "Slot demand of every job waiting to be scheduled."),
+		oldestPendingAge: desc("oldest_pending_job_age_seconds", "Time since the oldest pending job was submitted. Zero when nothing is pending."),
+	}
+}
+
+//Describe sends the descriptors of every metric the collector produces
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{
+		c.up, c.scrapeDuration,
+		c.slotsUsed, c.slotsReserved, c.slotsTotal, c.instanceUp,
+		c.loadAverage, c.npLoadAverage, c.memoryTotal, c.memoryFree, c.memoryUsed,
+		c.jobs, c.jobSlots, c.pendingSlots, c.oldestPendingAge,
+	} {
+		ch <- d
+	}
+
+	c.scrapeErrors.Describe(ch)
+}
+
+//Collect makes a single qstat call and sends the metrics derived from it
+func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.scrapeMutex.Lock()
+	defer c.scrapeMutex.Unlock()
+
+	started := time.Now()
+	ji, err := c.scrape()
+	ch <- prometheus.MustNewConstMetric(c.scrapeDuration, prometheus.GaugeValue, time.Since(started).Seconds())
+
+	if err != nil {
+		log.Error("Scraping qstat for metrics failed: ", err)
+		c.scrapeErrors.Inc()
+		ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 0)
+		c.scrapeErrors.Collect(ch)
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.up, prometheus.GaugeValue, 1)
+	c.scrapeErrors.Collect(ch)
+
+	for _, h := range ji.QueueInfo.Queues {
+		c.collectQueueInstance(ch, h)
+	}
+
+	c.collectJobs(ch, ji)
+}
+
+func (c *Collector) scrape() (gogridengine.JobInfo, error) {
+	content, err := c.source.Get()
+
+	if err != nil {
+		return gogridengine.JobInfo{}, err
+	}
+
+	return gogridengine.NewJobInfo(content)
+}
+
+func (c *Collector) collectQueueInstance(ch chan<- prometheus.Metric, h gogridengine.Host) {
+	queue, host := gogridengine.SplitQueueInstance(h.Name)
+
+	ch <- prometheus.MustNewConstMetric(c.slotsUsed, prometheus.GaugeValue, float64(h.SlotsUsed), queue, host)
+	ch <- prometheus.MustNewConstMetric(c.slotsReserved, prometheus.GaugeValue, float64(h.SlotsReserved), queue, host)
+	ch <- prometheus.MustNewConstMetric(c.slotsTotal, prometheus.GaugeValue, float64(h.SlotsTotal), queue, host)
+	ch <- prometheus.MustNewConstMetric(c.instanceUp, prometheus.GaugeValue, boolValue(gogridengine.IsHostAvailable(h)), queue, host)
+
+	//Unreachable hosts don't report load or memory, so those series are left out rather than reported as zero
+	if load, err := h.Resources.Load("avg"); err == nil {
+		ch <- prometheus.MustNewConstMetric(c.loadAverage, prometheus.GaugeValue, load, queue, host)
+	}
+
+	if load, err := h.Resources.NPLoadAverage(); err == nil {
+		ch <- prometheus.MustNewConstMetric(c.npLoadAverage, prometheus.GaugeValue, load, queue, host)
+	}
+
+	if memory, err := h.Resources.TotalMemory(); err == nil {
+		ch <- prometheus.MustNewConstMetric(c.memoryTotal, prometheus.GaugeValue, float64(memory.Bytes), queue, host)
+	}
+
+	if memory, err := h.Resources.FreeMemory(); err == nil {
+		ch <- prometheus.MustNewConstMetric(c.memoryFree, prometheus.GaugeValue, float64(memory.Bytes), queue, host)
+	}
+
+	if memory, err := h.Resources.MemoryUsed(); err == nil {
+		ch <- prometheus.MustNewConstMetric(c.memoryUsed, prometheus.GaugeValue, float64(memory.Bytes), queue, host)
+	}
+}
+
+//jobGroup is the label set jobs are counted under
+type jobGroup struct {
+	owner string
+	state string
+	queue string
+}
+
+func (c *Collector) collectJobs(ch chan<- prometheus.Metric, ji gogridengine.JobInfo) {
+	counts := make(map[jobGroup]int)
+	slots := make(map[jobGroup]int64)
+	var pendingSlots int64
+	var oldest time.Time
+	//Parallel jobs are listed under every queue instance they span, but only count once
+	seen := make(map[gogridengine.JobKey]bool)
+
+	for _, j := range ji.Jobs() {
+		key := gogridengine.KeyForJob(j)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		queue := ""
+		if j.QueueName != "" {
+			queue, _ = gogridengine.SplitQueueInstance(j.QueueName)
+		}
+
+		group := jobGroup{owner: j.JobOwner, state: j.State, queue: queue}
+		counts[group]++
+		slots[group] += int64(j.Slots)
+
+		if gogridengine.JobPhase(j) != gogridengine.PhasePending {
+			continue
+		}
+
+		pendingSlots += int64(j.Slots)
+
+		submitted, err := time.ParseInLocation(gogridengine.ISO8601FMT, j.SubmittedTime, c.location)
+		if err == nil && (oldest.IsZero() || submitted.Before(oldest)) {
+			oldest = submitted
+		}
+	}
+
+	//Sorted so the exposition is stable between scrapes
+	groups := make([]jobGroup, 0, len(counts))
+	for g := range counts {
+		groups = append(groups, g)
+	}
+
+	sort.Slice(groups, func(a, b int) bool {
+		if groups[a].owner != groups[b].owner {
+			return groups[a].owner < groups[b].owner
+		}
+
+		if groups[a].state != groups[b].state {
+			return groups[a].state < groups[b].state
+		}
+
+		return groups[a].queue < groups[b].queue
+	})
+
+	for _, g := range groups {
+		ch <- prometheus.MustNewConstMetric(c.jobs, prometheus.GaugeValue, float64(counts[g]), g.owner, g.state, g.queue)
+		ch <- prometheus.MustNewConstMetric(c.jobSlots, prometheus.GaugeValue, float64(slots[g]), g.owner, g.state, g.queue)
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.pendingSlots, prometheus.GaugeValue, float64(pendingSlots))
+
+	age := 0.0
+	if !oldest.IsZero() {
+		age = c.now().Sub(oldest).Seconds()
+	}
+
+	ch <- prometheus.MustNewConstMetric(c.oldestPendingAge, prometheus.GaugeValue, age)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,285 @@
+package exporter
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/metrumresearchgroup/gogridengine"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+const cluster = `<?xml version='1.0'?>
+<job_info>
+  <queue_info>
+    <Queue-List>
+      <name>all.q@node1</name>
+      <qtype>BIP</qtype>
+      <slots_used>3</slots_used>
+      <slots_resv>1</slots_resv>
+      <slots_total>8</slots_total>
+      <load_avg>2.50000</load_avg>
+      <resource name="load_avg" type="hl">2.500000</resource>
+      <resource name="np_load_avg" type="hl">0.312500</resource>
+      <resource name="mem_total" type="hl">16.000G</resource>
+      <resource name="mem_free" type="hl">12.000G</resource>
+      <resource name="mem_used" type="hl">4.000G</resource>
+      <job_list state="running">
+        <JB_job_number>1</JB_job_number>
+        <JAT_prio>0.50500</JAT_prio>
+        <JB_name>Run1</JB_name>
+        <JB_owner>darrellb</JB_owner>
+        <state>r</state>
+        <JAT_start_time>2019-12-18T14:00:00</JAT_start_time>
+        <slots>2</slots>
+      </job_list>
+      <job_list state="running">
+        <JB_job_number>2</JB_job_number>
+        <JAT_prio>0.50500</JAT_prio>
+        <JB_name>Run2</JB_name>
+        <JB_owner>darrellb</JB_owner>
+        <state>r</state>
+        <JAT_start_time>2019-12-18T14:00:00</JAT_start_time>
+        <slots>1</slots>
+      </job_list>
+    </Queue-List>
+    <Queue-List>
+      <name>all.q@node2</name>
+      <qtype>BIP</qtype>
+      <slots_used>0</slots_used>
+      <slots_resv>0</slots_resv>
+      <slots_total>8</slots_total>
+      <state>au</state>
+      <resource name="mem_total" type="hl">16.000G</resource>
+    </Queue-List>
+  </queue_info>
+  <job_info>
+    <job_list state="pending">
+      <JB_job_number>3</JB_job_number>
+      <JAT_prio>0.50500</JAT_prio>
+      <JB_name>Array</JB_name>
+      <JB_owner>devinp</JB_owner>
+      <state>qw</state>
+      <JB_submission_time>2019-12-18T14:30:00</JB_submission_time>
+      <slots>4</slots>
+      <tasks>1-2:1</tasks>
+    </job_list>
+    <job_list state="pending">
+      <JB_job_number>4</JB_job_number>
+      <JAT_prio>0.50500</JAT_prio>
+      <JB_name>Broken</JB_name>
+      <JB_owner>devinp</JB_owner>
+      <state>Eqw</state>
+      <JB_submission_time>2019-12-18T13:00:00</JB_submission_time>
+      <slots>1</slots>
+    </job_list>
+  </job_info>
+</job_info>`
+
+//source returns its content (or error) and counts how often it was asked
+type source struct {
+	content string
+	err     error
+	calls   int32
+}
+
+func (s *source) Get() (string, error) {
+	atomic.AddInt32(&s.calls, 1)
+	return s.content, s.err
+}
+
+func newTestCollector(s gogridengine.XmlResourceGetter, opts Options) *Collector {
+	opts.Location = time.UTC
+	c := NewCollector(s, opts)
+	c.now = func() time.Time {
+		return time.Date(2019, 12, 18, 15, 0, 0, 0, time.UTC)
+	}
+
+	return c
+}
+
+func TestCollect(t *testing.T) {
+	c := newTestCollector(&source{content: cluster}, Options{})
+
+	expected := `
+# HELP gridengine_up Whether the last qstat call succeeded.
+# TYPE gridengine_up gauge
+gridengine_up 1
+# HELP gridengine_scrape_errors_total Number of scrapes where qstat failed or returned unparsable output.
+# TYPE gridengine_scrape_errors_total counter
+gridengine_scrape_errors_total 0
+# HELP gridengine_queue_slots_used Slots in use on the queue instance.
+# TYPE gridengine_queue_slots_used gauge
+gridengine_queue_slots_used{host="node1",queue="all.q"} 3
+gridengine_queue_slots_used{host="node2",queue="all.q"} 0
+# HELP gridengine_queue_slots_reserved Slots reserved on the queue instance.
+# TYPE gridengine_queue_slots_reserved gauge
+gridengine_queue_slots_reserved{host="node1",queue="all.q"} 1
+gridengine_queue_slots_reserved{host="node2",queue="all.q"} 0
+# HELP gridengine_queue_available Whether the queue instance state allows work to be scheduled onto it.
+# TYPE gridengine_queue_available gauge
+gridengine_queue_available{host="node1",queue="all.q"} 1
+gridengine_queue_available{host="node2",queue="all.q"} 0
+# HELP gridengine_host_load_average load_avg reported by the host.
+# TYPE gridengine_host_load_average gauge
+gridengine_host_load_average{host="node1",queue="all.q"} 2.5
+# HELP gridengine_host_np_load_average np_load_avg (load average per processor) reported by the host.
+# TYPE gridengine_host_np_load_average gauge
+gridengine_host_np_load_average{host="node1",queue="all.q"} 0.3125
+# HELP gridengine_host_memory_total_bytes mem_total reported by the host.
+# TYPE gridengine_host_memory_total_bytes gauge
+gridengine_host_memory_total_bytes{host="node1",queue="all.q"} 1.7179869184e+10
+gridengine_host_memory_total_bytes{host="node2",queue="all.q"} 1.7179869184e+10
+# HELP gridengine_host_memory_free_bytes mem_free reported by the host.
+# TYPE gridengine_host_memory_free_bytes gauge
+gridengine_host_memory_free_bytes{host="node1",queue="all.q"} 1.2884901888e+10
+# HELP gridengine_jobs Jobs (and array tasks) by owner, state and queue. Pending jobs have an empty queue.
+# TYPE gridengine_jobs gauge
+gridengine_jobs{owner="darrellb",queue="all.q",state="r"} 2
+gridengine_jobs{owner="devinp",queue="",state="Eqw"} 1
+gridengine_jobs{owner="devinp",queue="",state="qw"} 2
+# HELP gridengine_job_slots Slots of jobs (and array tasks) by owner, state and queue. Pending jobs have an empty queue.
+# TYPE gridengine_job_slots gauge
+gridengine_job_slots{owner="darrellb",queue="all.q",state="r"} 3
+gridengine_job_slots{owner="devinp",queue="",state="Eqw"} 1
+gridengine_job_slots{owner="devinp",queue="",state="qw"} 8
+# HELP gridengine_pending_slots Slot demand of every job waiting to be scheduled.
+# TYPE gridengine_pending_slots gauge
+gridengine_pending_slots 8
+# HELP gridengine_oldest_pending_job_age_seconds Time since the oldest pending job was submitted. Zero when nothing is pending.
+# TYPE gridengine_oldest_pending_job_age_seconds gauge
+gridengine_oldest_pending_job_age_seconds 1800
+`
+
+	err := testutil.CollectAndCompare(c, strings.NewReader(expected),
+		"gridengine_up",
+		"gridengine_scrape_errors_total",
+		"gridengine_queue_slots_used",
+		"gridengine_queue_slots_reserved",
+		"gridengine_queue_available",
+		"gridengine_host_load_average",
+		"gridengine_host_np_load_average",
+		"gridengine_host_memory_total_bytes",
+		"gridengine_host_memory_free_bytes",
+		"gridengine_jobs",
+		"gridengine_job_slots",
+		"gridengine_pending_slots",
+		"gridengine_oldest_pending_job_age_seconds",
+	)
+	assert.Nil(t, err)
+}
+
+func TestCollectFailures(t *testing.T) {
+	tests := []struct {
+		name   string
+		source *source
+	}{
+		{
+			name:   "qstat failure",
+			source: &source{err: errors.New("qmaster unreachable")},
+		},
+		{
+			name:   "unparsable output",
+			source: &source{content: "<job_info>"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestCollector(tt.source, Options{Namespace: "sge"})
+
+			testutil.CollectAndCount(c)
+			testutil.CollectAndCount(c)
+
+			expected := `
+# HELP sge_up Whether the last qstat call succeeded.
+# TYPE sge_up gauge
+sge_up 0
+# HELP sge_scrape_errors_total Number of scrapes where qstat failed or returned unparsable output.
+# TYPE sge_scrape_errors_total counter
+sge_scrape_errors_total 3
+`
+			assert.Nil(t, testutil.CollectAndCompare(c, strings.NewReader(expected), "sge_up", "sge_scrape_errors_total"))
+			//Nothing is reported about the cluster itself when qstat fails
+			assert.Equal(t, 0, testutil.CollectAndCount(c, "sge_queue_slots_used", "sge_jobs"))
+		})
+	}
+}
+
+func TestCollectUsesSingleCachedQstatCall(t *testing.T) {
+	s := &source{content: cluster}
+	c := newTestCollector(s, Options{CacheTTL: time.Minute})
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+
+	server := httptest.NewServer(promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+	defer server.Close()
+
+	for i := 0; i < 3; i++ {
+		response, err := http.Get(server.URL)
+		assert.Nil(t, err)
+
+		body, _ := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+
+		assert.Contains(t, string(body), "gridengine_up 1")
+		assert.Contains(t, string(body), `gridengine_jobs{owner="darrellb",queue="all.q",state="r"} 2`)
+	}
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(&s.calls))
+}
+
+func TestCollectParallelJob(t *testing.T) {
+	instance := func(host string) string {
+		return `<Queue-List>
+      <name>all.q@` + host + `</name>
+      <slots_used>4</slots_used>
+      <slots_total>8</slots_total>
+      <job_list state="running">
+        <JB_job_number>5</JB_job_number>
+        <JB_name>MPI</JB_name>
+        <JB_owner>darrellb</JB_owner>
+        <state>r</state>
+        <JAT_start_time>2019-12-18T14:00:00</JAT_start_time>
+        <slots>8</slots>
+      </job_list>
+    </Queue-List>`
+	}
+
+	content := `<?xml version='1.0'?><job_info><queue_info>` + instance("node1") + instance("node2") + `</queue_info><job_info></job_info></job_info>`
+	c := newTestCollector(&source{content: content}, Options{})
+
+	//The job spans both instances but is a single job
+	expected := `
+# HELP gridengine_jobs Jobs (and array tasks) by owner, state and queue. Pending jobs have an empty queue.
+# TYPE gridengine_jobs gauge
+gridengine_jobs{owner="darrellb",queue="all.q",state="r"} 1
+# HELP gridengine_job_slots Slots of jobs (and array tasks) by owner, state and queue. Pending jobs have an empty queue.
+# TYPE gridengine_job_slots gauge
+gridengine_job_slots{owner="darrellb",queue="all.q",state="r"} 8
+`
+	assert.Nil(t, testutil.CollectAndCompare(c, strings.NewReader(expected), "gridengine_jobs", "gridengine_job_slots"))
+}
+
+func TestCollectWithoutPendingJobs(t *testing.T) {
+	empty := `<?xml version='1.0'?><job_info><queue_info></queue_info><job_info></job_info></job_info>`
+	c := newTestCollector(&source{content: empty}, Options{})
+
+	expected := `
+# HELP gridengine_pending_slots Slot demand of every job waiting to be scheduled.
+# TYPE gridengine_pending_slots gauge
+gridengine_pending_slots 0
+# HELP gridengine_oldest_pending_job_age_seconds Time since the oldest pending job was submitted. Zero when nothing is pending.
+# TYPE gridengine_oldest_pending_job_age_seconds gauge
+gridengine_oldest_pending_job_age_seconds 0
+`
+	assert.Nil(t, testutil.CollectAndCompare(c, strings.NewReader(expected), "gridengine_pending_slots", "gridengine_oldest_pending_job_age_seconds"))
+}
@@ -0,0 +1,282 @@
+package gogridengine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+//ErrNoClusters is returned when querying a federation without any cluster
+const ErrNoClusters = Error("The federation has no clusters to query")
+
+//ErrUnknownCluster is returned when looking up a cluster the federation doesn't hold
+const ErrUnknownCluster = Error("The cluster is not part of the federation")
+
+//ErrClusterTimeout is recorded for a cluster which didn't answer within the federation's Timeout
+const ErrClusterTimeout = Error("The cluster didn't answer in time")
+
+//FederationError records the clusters of a federation which couldn't be queried, by cluster name
+type FederationError struct {
+	Errors map[string]error
+}
+
+func (e *FederationError) Error() string {
+	names := make([]string, 0, len(e.Errors))
+	for name := range e.Errors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	details := make([]string, len(names))
+	for k, name := range names {
+		details[k] = fmt.Sprintf("%s: %s", name, e.Errors[name])
+	}
+
+	return fmt.Sprintf("%d of the federated clusters failed: %s", len(names), strings.Join(details, "; "))
+}
+
+//Federation queries several grid engine cells (each with its own SGE_ROOT / SGE_CELL, possibly on another host) as one
+type Federation struct {
+	//Clusters are the clients of every cell, by cluster name
+	Clusters map[string]*Client
+	//Timeout bounds how long a single cluster may take to answer. Zero waits for as long as the context allows
+	Timeout time.Duration
+}
+
+//NewFederation creates an empty federation. Add clusters to it before querying
+func NewFederation() *Federation {
+	return &Federation{
+		Clusters: make(map[string]*Client),
+	}
+}
+
+//Add registers the client of a cell under the cluster name, replacing any client already registered under it
+func (f *Federation) Add(name string, client *Client) {
+	if f.Clusters == nil {
+		f.Clusters = make(map[string]*Client)
+	}
+
+	f.Clusters[name] = client
+}
+
+//Client returns the client of the named cluster, to act upon its jobs
+func (f *Federation) Client(name string) (*Client, error) {
+	client, ok := f.Clusters[name]
+
+	if !ok {
+		return nil, ErrUnknownCluster
+	}
+
+	return client, nil
+}
+
+//Names lists the clusters of the federation in alphabetical order
+func (f *Federation) Names() []string {
+	names := make([]string, 0, len(f.Clusters))
+	for name := range f.Clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+//FederatedJobInfo is the state of every cluster of a federation which answered, along with the errors of those which didn't
+type FederatedJobInfo struct {
+	//Clusters holds the JobInfo of every cluster which answered. Their hosts and jobs carry the cluster name
+	Clusters map[string]JobInfo
+	//Errors holds the reason every other cluster couldn't be queried
+	Errors map[string]error
+}
+
+type clusterResult struct {
+	name string
+	ji   JobInfo
+	err  error
+}
+
+//JobInfo queries every cluster concurrently. Clusters which fail or time out are recorded in the Errors of the result rather than failing the whole query,
+//an error is only returned when none of the clusters answered (as a *FederationError) or the federation is empty.
+func (f *Federation) JobInfo(ctx context.Context) (FederatedJobInfo, error) {
+	if len(f.Clusters) == 0 {
+		return FederatedJobInfo{}, ErrNoClusters
+	}
+
+	results := make(chan clusterResult, len(f.Clusters))
+
+	for name, client := range f.Clusters {
+		go func(name string, client *Client) {
+			ji, err := f.query(ctx, client)
+			results <- clusterResult{
+				name: name,
+				ji:   ji,
+				err:  err,
+			}
+		}(name, client)
+	}
+
+	federated := FederatedJobInfo{
+		Clusters: make(map[string]JobInfo),
+		Errors:   make(map[string]error),
+	}
+
+	for range f.Clusters {
+		result := <-results
+
+		if result.err != nil {
+			federated.Errors[result.name] = result.err
+			continue
+		}
+
+		federated.Clusters[result.name] = result.ji.tagCluster(result.name)
+	}
+
+	if len(federated.Clusters) == 0 {
+		return federated, federated.Err()
+	}
+
+	return federated, nil
+}
+
+//query reads the state of a single cluster, bounded by the federation's Timeout so a cluster which hangs is given up on and its qstat stopped
+func (f *Federation) query(ctx context.Context, client *Client) (JobInfo, error) {
+	queryCtx := ctx
+
+	if f.Timeout > 0 {
+		var cancel context.CancelFunc
+		queryCtx, cancel = context.WithTimeout(ctx, f.Timeout)
+		defer cancel()
+	}
+
+	ji, err := client.JobInfoContext(queryCtx)
+
+	if err != nil && ctx.Err() != nil {
+		return JobInfo{}, ctx.Err()
+	}
+
+	if err != nil && queryCtx.Err() != nil {
+		return JobInfo{}, ErrClusterTimeout
+	}
+
+	return ji, err
+}
+
+//Summary queries every cluster and summarizes the federation. Clusters which couldn't be queried are named in the summary
+func (f *Federation) Summary(ctx context.Context) (FederatedSummary, error) {
+	federated, err := f.JobInfo(ctx)
+
+	if err != nil {
+		return FederatedSummary{}, err
+	}
+
+	return federated.Summary(), nil
+}
+
+//tagCluster returns a copy of the JobInfo where every host and job carries the cluster name
+func (q JobInfo) tagCluster(name string) JobInfo {
+	tagged := q
+	tagged.QueueInfo.Queues = make([]Host, len(q.QueueInfo.Queues))
+
+	for k, h := range q.QueueInfo.Queues {
+		h.Cluster = name
+		h.JobList = tagJobs(h.JobList, name)
+		tagged.QueueInfo.Queues[k] = h
+	}
+
+	tagged.PendingJobs.JobList = tagJobs(q.PendingJobs.JobList, name)
+
+	return tagged
+}
+
+func tagJobs(jobs []Job, name string) []Job {
+	if jobs == nil {
+		return nil
+	}
+
+	tagged := make([]Job, len(jobs))
+
+	for k, j := range jobs {
+		j.Cluster = name
+		tagged[k] = j
+	}
+
+	return tagged
+}
+
+//Err returns a *FederationError naming the clusters which couldn't be queried, or nil when every cluster answered
+func (f FederatedJobInfo) Err() error {
+	if len(f.Errors) == 0 {
+		return nil
+	}
+
+	return &FederationError{Errors: f.Errors}
+}
+
+//names lists the clusters which answered in alphabetical order
+func (f FederatedJobInfo) names() []string {
+	names := make([]string, 0, len(f.Clusters))
+	for name := range f.Clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+//Merged combines the clusters which answered into a single JobInfo, cluster by cluster in alphabetical order. Queue instances of different clusters may share a name, tell them apart by their Cluster
+func (f FederatedJobInfo) Merged() JobInfo {
+	var merged JobInfo
+
+	for _, name := range f.names() {
+		ji := f.Clusters[name]
+		merged.QueueInfo.Queues = append(merged.QueueInfo.Queues, ji.QueueInfo.Queues...)
+		merged.PendingJobs.JobList = append(merged.PendingJobs.JobList, ji.PendingJobs.JobList...)
+	}
+
+	return merged
+}
+
+//Jobs flattens the running jobs and then the pending jobs of every cluster which answered down into a single JobList
+func (f FederatedJobInfo) Jobs() JobList {
+	return f.Merged().Jobs()
+}
+
+//Hosts lists the queue instances of every cluster which answered
+func (f FederatedJobInfo) Hosts() []Host {
+	return f.Merged().QueueInfo.Queues
+}
+
+//FederatedSummary is the capacity, utilization and job breakdown of a federation, both across all clusters and per cluster
+type FederatedSummary struct {
+	//Total is computed across the hosts and jobs of every cluster which answered
+	Total    ClusterSummary            `json:"total"`
+	Clusters map[string]ClusterSummary `json:"clusters"`
+	//Jobs groups the jobs of every cluster which answered, including by cluster
+	Jobs JobSummary `json:"jobs"`
+	//Unreachable names the clusters which couldn't be queried and are missing from the figures
+	Unreachable []string `json:"unreachable,omitempty"`
+}
+
+//Summary computes the FederatedSummary of the clusters which answered
+func (f FederatedJobInfo) Summary() FederatedSummary {
+	merged := f.Merged()
+
+	summary := FederatedSummary{
+		Total:    NewClusterSummary(merged),
+		Clusters: make(map[string]ClusterSummary),
+		Jobs:     merged.Jobs().Summarize(),
+	}
+
+	for name, ji := range f.Clusters {
+		summary.Clusters[name] = NewClusterSummary(ji)
+	}
+
+	for name := range f.Errors {
+		summary.Unreachable = append(summary.Unreachable, name)
+	}
+	sort.Strings(summary.Unreachable)
+
+	return summary
+}
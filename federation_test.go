@@ -0,0 +1,270 @@
+package gogridengine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//testFederation joins two cells serving the same small.xml, so their hosts share names, a cell serving pending.xml and a cell whose qmaster is down
+func testFederation() *Federation {
+	f := NewFederation()
+	f.Add("east", &Client{Source: &EmbeddedDataSource{Name: "small.xml"}})
+	f.Add("west", &Client{Source: &EmbeddedDataSource{Name: "small.xml"}})
+	f.Add("gpu", &Client{Source: &EmbeddedDataSource{Name: "pending.xml"}})
+	f.Add("down", &Client{Source: &scriptedSource{responses: []pollResult{{err: errors.New("qmaster unreachable")}}}})
+
+	return f
+}
+
+func fixtureJobInfo(t *testing.T, name string) JobInfo {
+	content, err := (&EmbeddedDataSource{Name: name}).Get()
+	assert.Nil(t, err)
+
+	ji, err := NewJobInfo(content)
+	assert.Nil(t, err)
+
+	return ji
+}
+
+func TestFederationJobInfo(t *testing.T) {
+	federated, err := testFederation().JobInfo(context.Background())
+	assert.Nil(t, err)
+
+	assert.Equal(t, []string{"east", "gpu", "west"}, federated.names())
+	assert.Len(t, federated.Errors, 1)
+	assert.EqualError(t, federated.Errors["down"], "qmaster unreachable")
+
+	var fedErr *FederationError
+	assert.True(t, errors.As(federated.Err(), &fedErr))
+	assert.Equal(t, "1 of the federated clusters failed: down: qmaster unreachable", fedErr.Error())
+
+	small := fixtureJobInfo(t, "small.xml")
+	pending := fixtureJobInfo(t, "pending.xml")
+
+	assert.Len(t, federated.Hosts(), 2*len(small.QueueInfo.Queues)+len(pending.QueueInfo.Queues))
+	assert.Len(t, federated.Jobs(), 2*len(small.Jobs())+len(pending.Jobs()))
+
+	//Every host and job is tagged, clusters in alphabetical order
+	assert.Equal(t, "east", federated.Hosts()[0].Cluster)
+	assert.Equal(t, "west", federated.Hosts()[len(federated.Hosts())-1].Cluster)
+	for _, h := range federated.Hosts() {
+		assert.NotEmpty(t, h.Cluster)
+		for _, j := range h.JobList {
+			assert.Equal(t, h.Cluster, j.Cluster)
+		}
+	}
+	for _, j := range federated.Jobs() {
+		assert.NotEmpty(t, j.Cluster)
+	}
+
+	//Tagging doesn't reach the qstat XML
+	xml, err := federated.Clusters["east"].GetXML()
+	assert.Nil(t, err)
+	assert.NotContains(t, xml, "east")
+
+	//The cluster is part of the JSON of hosts and jobs
+	content, err := json.Marshal(federated.Jobs()[0])
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), `"cluster":"east"`)
+}
+
+func TestFederationJobInfoFailures(t *testing.T) {
+	tests := []struct {
+		name       string
+		federation *Federation
+		ctx        func() (context.Context, context.CancelFunc)
+		wantErr    error
+		failed     []string
+	}{
+		{
+			name:       "no clusters",
+			federation: NewFederation(),
+			wantErr:    ErrNoClusters,
+		},
+		{
+			name: "every cluster failed",
+			federation: &Federation{Clusters: map[string]*Client{
+				"east": {Source: &scriptedSource{responses: []pollResult{{err: errors.New("qmaster unreachable")}}}},
+				"west": {Source: &scriptedSource{responses: []pollResult{{content: "<job_info"}}}},
+			}},
+			failed: []string{"east", "west"},
+		},
+		{
+			name: "timed out",
+			federation: &Federation{
+				Clusters: map[string]*Client{
+					"east": {Source: &EmbeddedDataSource{Name: "small.xml"}},
+					"slow": {Source: &scriptedSource{responses: []pollResult{{content: "<job_info/>"}}, delay: time.Second}},
+				},
+				Timeout: 50 * time.Millisecond,
+			},
+			failed: []string{"slow"},
+		},
+		{
+			name: "cancelled",
+			federation: &Federation{Clusters: map[string]*Client{
+				"slow": {Source: &scriptedSource{responses: []pollResult{{content: "<job_info/>"}}, delay: time.Second}},
+			}},
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 50*time.Millisecond)
+			},
+			failed: []string{"slow"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.Background(), context.CancelFunc(func() {})
+			if tt.ctx != nil {
+				ctx, cancel = tt.ctx()
+			}
+			defer cancel()
+
+			start := time.Now()
+			federated, err := tt.federation.JobInfo(ctx)
+			assert.True(t, time.Since(start) < 500*time.Millisecond)
+
+			if tt.wantErr != nil {
+				assert.Equal(t, tt.wantErr, err)
+				return
+			}
+
+			failed := make([]string, 0)
+			for name := range federated.Errors {
+				failed = append(failed, name)
+			}
+			assert.ElementsMatch(t, tt.failed, failed)
+
+			//Only a federation where nothing answered fails as a whole
+			if len(federated.Clusters) == 0 {
+				var fedErr *FederationError
+				assert.True(t, errors.As(err, &fedErr))
+				assert.Len(t, fedErr.Errors, len(tt.failed))
+			} else {
+				assert.Nil(t, err)
+			}
+		})
+	}
+
+	federated, _ := (&Federation{
+		Clusters: map[string]*Client{"slow": {Source: &scriptedSource{responses: []pollResult{{}}, delay: time.Second}}},
+		Timeout:  10 * time.Millisecond,
+	}).JobInfo(context.Background())
+	assert.Equal(t, ErrClusterTimeout, federated.Errors["slow"])
+}
+
+//hangingRunner never answers, recording every command it had to stop when the context was done
+type hangingRunner struct {
+	stopped chan string
+}
+
+func (r *hangingRunner) Run(ctx context.Context, name string, args ...string) (CommandResult, error) {
+	<-ctx.Done()
+	r.stopped <- name
+
+	return CommandResult{ExitCode: -1}, ctx.Err()
+}
+
+func TestFederationStopsHungClusters(t *testing.T) {
+	runner := &hangingRunner{stopped: make(chan string, 2)}
+
+	f := &Federation{
+		Clusters: map[string]*Client{
+			"east": {Source: &EmbeddedDataSource{Name: "small.xml"}},
+			"hung": NewClient(runner),
+		},
+		Timeout: 50 * time.Millisecond,
+	}
+
+	federated, err := f.JobInfo(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, ErrClusterTimeout, federated.Errors["hung"])
+
+	select {
+	case name := <-runner.stopped:
+		assert.Equal(t, "qstat", name)
+	case <-time.After(time.Second):
+		assert.Fail(t, "the qstat of the hung cluster was never stopped")
+	}
+
+	//Cancelling the query stops the command as well
+	ctx, cancel := context.WithCancel(context.Background())
+	f.Timeout = 0
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		cancel()
+	}()
+
+	federated, _ = f.JobInfo(ctx)
+	assert.Equal(t, context.Canceled, federated.Errors["hung"])
+
+	select {
+	case <-runner.stopped:
+	case <-time.After(time.Second):
+		assert.Fail(t, "the qstat of the hung cluster was never stopped")
+	}
+}
+
+func TestClientJobInfoContext(t *testing.T) {
+	client := &Client{Source: &scriptedSource{responses: []pollResult{{content: "<job_info/>"}}, delay: time.Second}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := client.JobInfoContext(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) < 500*time.Millisecond)
+
+	ji, err := (&Client{Source: &EmbeddedDataSource{Name: "small.xml"}}).JobInfoContext(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, fixtureJobInfo(t, "small.xml"), ji)
+}
+
+func TestFederationSummary(t *testing.T) {
+	summary, err := testFederation().Summary(context.Background())
+	assert.Nil(t, err)
+
+	small := NewClusterSummary(fixtureJobInfo(t, "small.xml"))
+	pending := NewClusterSummary(fixtureJobInfo(t, "pending.xml"))
+
+	assert.Equal(t, []string{"down"}, summary.Unreachable)
+	assert.Equal(t, map[string]ClusterSummary{"east": small, "west": small, "gpu": pending}, summary.Clusters)
+
+	//Hosts sharing a name in different clusters are counted once per cluster
+	assert.Equal(t, 2*small.Hosts+pending.Hosts, summary.Total.Hosts)
+	assert.Equal(t, 2*small.MemoryTotal+pending.MemoryTotal, summary.Total.MemoryTotal)
+	assert.Equal(t, 2*small.SlotsTotal+pending.SlotsTotal, summary.Total.SlotsTotal)
+	assert.Equal(t, 2*small.RunningJobs+pending.RunningJobs, summary.Total.RunningJobs)
+	assert.Equal(t, 2*small.PendingJobs+pending.PendingJobs, summary.Total.PendingJobs)
+
+	assert.Equal(t, 2*small.RunningJobs+2*small.PendingJobs+pending.RunningJobs+pending.PendingJobs, summary.Jobs.Total.Running+summary.Jobs.Total.Pending)
+	assert.Len(t, summary.Jobs.ByCluster, 3)
+	assert.Equal(t, summary.Jobs.ByCluster["east"].Jobs, summary.Jobs.ByCluster["west"].Jobs)
+	assert.Equal(t, "west", summary.Jobs.ByCluster["west"].LongestRunning.Cluster)
+	assert.Equal(t, pending.RunningJobs, summary.Jobs.ByCluster["gpu"].Running)
+
+	_, err = NewFederation().Summary(context.Background())
+	assert.Equal(t, ErrNoClusters, err)
+}
+
+func TestFederationClient(t *testing.T) {
+	f := testFederation()
+
+	client, err := f.Client("east")
+	assert.Nil(t, err)
+	assert.Equal(t, f.Clusters["east"], client)
+
+	_, err = f.Client("north")
+	assert.Equal(t, ErrUnknownCluster, err)
+
+	assert.Equal(t, []string{"down", "east", "gpu", "west"}, f.Names())
+
+	var empty Federation
+	empty.Add("east", client)
+	assert.Equal(t, []string{"east"}, empty.Names())
+}
@@ -7,8 +7,8 @@ import (
 )
 
 const (
-	//ISO8601FMT is a constant format used for parsing ISO 8601 compliant datetimes
-	ISO8601FMT string = "2006-01-02T15:04:05"
+	//ISO8601FMT is a constant format used for parsing ISO 8601 compliant datetimes. It is the layout of the gogridengine package
+	ISO8601FMT string = gogridengine.ISO8601FMT
 )
 
 //NewUsernameFilter returns a filter function for specifying an owner to filter a JobList Down
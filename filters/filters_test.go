@@ -1,6 +1,7 @@
 package filters
 
 import (
+	"regexp"
 	"testing"
 	"time"
 
@@ -126,7 +127,7 @@ func TestNewBeforeSubmitTimeFilter(t *testing.T) {
 
 	//Two days in the future
 	target := "2019-09-17T15:26:36"
-	targetTime, _ := time.Parse(ISO8601FMT, target)
+	targetTime, _ := time.ParseInLocation(ISO8601FMT, target, time.Local)
 
 	//Show me jobs with a submit time earlier than the targetTime.
 	jl = jl.Filter(NewBeforeSubmitTimeFilter(targetTime))
@@ -163,7 +164,7 @@ func TestNewAfterSubmitTimeFilter(t *testing.T) {
 
 	//Two days in the future
 	target := "2019-09-17T15:26:36"
-	targetTime, _ := time.Parse(ISO8601FMT, target)
+	targetTime, _ := time.ParseInLocation(ISO8601FMT, target, time.Local)
 
 	//Show me jobs with a submit time earlier than the targetTime.
 	jl = jl.Filter(NewAfterSubmitTimeFilter(targetTime))
@@ -199,10 +200,10 @@ func TestNewSubmitTimeBetweenFilter(t *testing.T) {
 
 	//Two days in the future
 	start := "2019-09-21T15:26:35"
-	startTime, _ := time.Parse(ISO8601FMT, start)
+	startTime, _ := time.ParseInLocation(ISO8601FMT, start, time.Local)
 
 	end := "2019-09-21T15:26:37"
-	endTime, _ := time.Parse(ISO8601FMT, end)
+	endTime, _ := time.ParseInLocation(ISO8601FMT, end, time.Local)
 
 	//Show me jobs with a submit time earlier than the targetTime.
 	jl = jl.Filter(NewBetweenSubmitTimeFilter(startTime, endTime))
@@ -231,7 +232,7 @@ func TestNewBeforeStartTimeFilter(t *testing.T) {
 
 	//Two days in the future
 	target := "2019-09-17T15:26:36"
-	targetTime, _ := time.Parse(ISO8601FMT, target)
+	targetTime, _ := time.ParseInLocation(ISO8601FMT, target, time.Local)
 
 	//Show me jobs with a submit time earlier than the targetTime.
 	jl = jl.Filter(NewBeforeStartTimeFilter(targetTime))
@@ -268,7 +269,7 @@ func TestNewAfterStartTimeFilter(t *testing.T) {
 
 	//Two days in the future
 	target := "2019-09-17T15:26:36"
-	targetTime, _ := time.Parse(ISO8601FMT, target)
+	targetTime, _ := time.ParseInLocation(ISO8601FMT, target, time.Local)
 
 	//Show me jobs with a submit time earlier than the targetTime.
 	jl = jl.Filter(NewAfterStartTimeFilter(targetTime))
@@ -304,10 +305,10 @@ func TestNewStartTimeBetweenFilter(t *testing.T) {
 
 	//Two days in the future
 	start := "2019-09-21T15:26:35"
-	startTime, _ := time.Parse(ISO8601FMT, start)
+	startTime, _ := time.ParseInLocation(ISO8601FMT, start, time.Local)
 
 	end := "2019-09-21T15:26:37"
-	endTime, _ := time.Parse(ISO8601FMT, end)
+	endTime, _ := time.ParseInLocation(ISO8601FMT, end, time.Local)
 
 	//Show me jobs with a submit time earlier than the targetTime.
 	jl = jl.Filter(NewBetweenStartTimeFilter(startTime, endTime))
@@ -317,3 +318,186 @@ func TestNewStartTimeBetweenFilter(t *testing.T) {
 	assert.Equal(t, "TheRightOne", jl[0].JobName)
 
 }
+
+func TestTimeFiltersIn(t *testing.T) {
+	//qstat on a cluster in New York reports its wall clock time
+	newYork := time.FixedZone("EST", -5*60*60)
+	jl := gogridengine.JobList{
+		{
+			JobName:       "Morning",
+			SubmittedTime: "2019-12-18T09:00:00",
+			StartTime:     "2019-12-18T09:30:00",
+		},
+		{
+			JobName:       "Afternoon",
+			SubmittedTime: "2019-12-18T13:00:00",
+			StartTime:     "2019-12-18T13:30:00",
+		},
+	}
+
+	//Noon in New York
+	noon := time.Date(2019, 12, 18, 17, 0, 0, 0, time.UTC)
+
+	names := func(jobs gogridengine.JobList) []string {
+		result := make([]string, 0)
+		for _, j := range jobs {
+			result = append(result, j.JobName)
+		}
+		return result
+	}
+
+	assert.Equal(t, []string{"Morning"}, names(jl.Filter(NewBeforeSubmitTimeFilterIn(noon, newYork))))
+	assert.Equal(t, []string{"Afternoon"}, names(jl.Filter(NewAfterSubmitTimeFilterIn(noon, newYork))))
+	assert.Equal(t, []string{"Morning"}, names(jl.Filter(NewBeforeStartTimeFilterIn(noon, newYork))))
+	assert.Equal(t, []string{"Afternoon"}, names(jl.Filter(NewAfterStartTimeFilterIn(noon, newYork))))
+	assert.Equal(t, []string{"Afternoon"}, names(jl.Filter(NewBetweenSubmitTimeFilterIn(noon, noon.Add(2*time.Hour), newYork))))
+	assert.Equal(t, []string{"Morning"}, names(jl.Filter(NewBetweenStartTimeFilterIn(noon.Add(-3*time.Hour), noon, newYork))))
+
+	//Read as UTC, both jobs would have been submitted before noon in New York
+	assert.Len(t, jl.Filter(NewBeforeSubmitTimeFilterIn(noon, time.UTC)), 2)
+}
+
+func TestNewJobNameGlobFilter(t *testing.T) {
+	jl := gogridengine.JobList{
+		{
+			JobName: "Run478",
+		},
+		{
+			JobName: "Run487",
+		},
+		{
+			JobName: "task_array.sh",
+		},
+	}
+
+	r1 := jl.Filter(NewJobNameGlobFilter("Run4*"))
+	assert.Len(t, r1, 2)
+
+	r2 := jl.Filter(NewJobNameGlobFilter("*.sh"))
+	assert.Len(t, r2, 1)
+	assert.Equal(t, "task_array.sh", r2[0].JobName)
+
+	//Malformed patterns should discard everything
+	r3 := jl.Filter(NewJobNameGlobFilter("Run[4"))
+	assert.Empty(t, r3)
+}
+
+func TestNewJobNameRegexFilter(t *testing.T) {
+	jl := gogridengine.JobList{
+		{
+			JobName: "Run478",
+		},
+		{
+			JobName: "Run487",
+		},
+		{
+			JobName: "task_array.sh",
+		},
+	}
+
+	r1 := jl.Filter(NewJobNameRegexFilter(regexp.MustCompile(`^Run4[0-9]7$`)))
+	assert.Len(t, r1, 1)
+	assert.Equal(t, "Run487", r1[0].JobName)
+}
+
+func TestPriorityFilters(t *testing.T) {
+	jl := gogridengine.JobList{
+		{
+			JobName:     "Low",
+			JATPriority: 0.25,
+		},
+		{
+			JobName:     "Medium",
+			JATPriority: 0.505,
+		},
+		{
+			JobName:     "High",
+			JATPriority: 0.75,
+		},
+	}
+
+	r1 := jl.Filter(NewAbovePriorityFilter(0.505))
+	assert.Len(t, r1, 2)
+
+	r2 := jl.Filter(NewBelowPriorityFilter(0.505))
+	assert.Len(t, r2, 2)
+
+	r3 := jl.Filter(NewBetweenPriorityFilter(0.3, 0.6))
+	assert.Len(t, r3, 1)
+	assert.Equal(t, "Medium", r3[0].JobName)
+}
+
+func TestSlotsFilters(t *testing.T) {
+	jl := gogridengine.JobList{
+		{
+			Slots: 1,
+		},
+		{
+			Slots: 4,
+		},
+		{
+			Slots: 16,
+		},
+	}
+
+	assert.Len(t, jl.Filter(NewMinimumSlotsFilter(4)), 2)
+	assert.Len(t, jl.Filter(NewMaximumSlotsFilter(4)), 2)
+
+	r1 := jl.Filter(NewBetweenSlotsFilter(2, 8))
+	assert.Len(t, r1, 1)
+	assert.Equal(t, int32(4), r1[0].Slots)
+}
+
+func TestQueueAndHostFilters(t *testing.T) {
+	jl := gogridengine.JobList{
+		{
+			JobName:   "First",
+			QueueName: "all.q@ip-10-0-1-80.ec2.internal",
+		},
+		{
+			JobName:   "Second",
+			QueueName: "all.q@ip-10-0-1-113.ec2.internal",
+		},
+		{
+			JobName:   "Third",
+			QueueName: "gpu.q@ip-10-0-1-113.ec2.internal",
+		},
+		{
+			JobName: "Pending",
+		},
+	}
+
+	assert.Len(t, jl.Filter(NewQueueFilter("all.q")), 2)
+	assert.Len(t, jl.Filter(NewQueueFilter("gpu.q@ip-10-0-1-113.ec2.internal")), 1)
+	assert.Empty(t, jl.Filter(NewQueueFilter("")))
+
+	r1 := jl.Filter(NewHostFilter("ip-10-0-1-113.ec2.internal"))
+	assert.Len(t, r1, 2)
+	assert.Equal(t, "Second", r1[0].JobName)
+
+	//Chained to locate a specific queue instance
+	r2 := jl.
+		Filter(NewQueueFilter("gpu.q")).
+		Filter(NewHostFilter("ip-10-0-1-113.ec2.internal"))
+	assert.Len(t, r2, 1)
+	assert.Equal(t, "Third", r2[0].JobName)
+}
+
+func TestNewTaskIDFilter(t *testing.T) {
+	jl := gogridengine.JobList{}
+
+	for i := int64(1); i <= 10; i++ {
+		jl = append(jl, gogridengine.Job{
+			JBJobNumber: 1006,
+			Tasks: gogridengine.Task{
+				TaskID: i,
+			},
+		})
+	}
+
+	r1 := jl.Filter(NewTaskIDFilter(2, 4, 6, 42))
+	assert.Len(t, r1, 3)
+	assert.Equal(t, int64(6), r1[2].Tasks.TaskID)
+
+	assert.Empty(t, jl.Filter(NewTaskIDFilter()))
+}
@@ -0,0 +1,30 @@
+package filters
+
+import (
+	"path"
+	"regexp"
+
+	"github.com/metrumresearchgroup/gogridengine"
+	log "github.com/sirupsen/logrus"
+)
+
+//NewJobNameGlobFilter returns only jobs whose name matches the provided shell glob pattern (eg: Run4*)
+func NewJobNameGlobFilter(pattern string) func(job gogridengine.Job) bool {
+	return func(job gogridengine.Job) bool {
+		matched, err := path.Match(pattern, job.JobName)
+		if err != nil {
+			//A malformed pattern can't match anything
+			log.Error("Failed matching the job name against the glob pattern: ", err)
+			return false
+		}
+
+		return matched
+	}
+}
+
+//NewJobNameRegexFilter returns only jobs whose name matches the provided regular expression
+func NewJobNameRegexFilter(expression *regexp.Regexp) func(job gogridengine.Job) bool {
+	return func(job gogridengine.Job) bool {
+		return expression.MatchString(job.JobName)
+	}
+}
@@ -0,0 +1,26 @@
+package filters
+
+import (
+	"github.com/metrumresearchgroup/gogridengine"
+)
+
+//NewAbovePriorityFilter returns only jobs whose JAT priority is greater than or equal to the provided threshold
+func NewAbovePriorityFilter(threshold float64) func(job gogridengine.Job) bool {
+	return func(job gogridengine.Job) bool {
+		return job.JATPriority >= threshold
+	}
+}
+
+//NewBelowPriorityFilter returns only jobs whose JAT priority is less than or equal to the provided threshold
+func NewBelowPriorityFilter(threshold float64) func(job gogridengine.Job) bool {
+	return func(job gogridengine.Job) bool {
+		return job.JATPriority <= threshold
+	}
+}
+
+//NewBetweenPriorityFilter returns only jobs whose JAT priority falls within the provided (inclusive) range
+func NewBetweenPriorityFilter(low float64, high float64) func(job gogridengine.Job) bool {
+	return func(job gogridengine.Job) bool {
+		return job.JATPriority >= low && job.JATPriority <= high
+	}
+}
@@ -0,0 +1,244 @@
+package filters
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/metrumresearchgroup/gogridengine"
+)
+
+//JobQuery is the filtering and sorting described by URL query parameters, as used by the /jobs endpoint of the api package and the query flags of the command line tools.
+//Repeated parameters match any of their values, distinct parameters must all match.
+//
+//	owner              job owner
+//	state              loose state code match (r matches Rr)
+//	state_exact        exact state code
+//	phase              running, pending, error or other
+//	name               job name glob (Run*)
+//	name_regex         job name regular expression
+//	queue              cluster queue or queue instance of running jobs
+//	host               host of running jobs
+//	task               array task id
+//	min_priority       lowest priority, inclusive
+//	max_priority       highest priority, inclusive
+//	min_slots          fewest slots, inclusive
+//	max_slots          most slots, inclusive
+//	submitted_after    RFC 3339 time
+//	submitted_before   RFC 3339 time
+//	started_after      RFC 3339 time
+//	started_before     RFC 3339 time
+//
+//qstat reports times without a time zone, they are read in the time zone of the cluster (see ParseJobQueryIn).
+//
+//	sort               comma separated keys, prefixed with - for descending order: job_number, task_id, priority, submit_time, start_time, owner, state
+type JobQuery struct {
+	Filters []func(j gogridengine.Job) bool
+	Sorters []gogridengine.JobSorter
+}
+
+//sortKeys maps the sort parameter onto the library's sorters
+var sortKeys = map[string]func(direction gogridengine.SortDirection) gogridengine.JobSorter{
+	"job_number":  gogridengine.ByJobNumber,
+	"task_id":     gogridengine.ByTaskID,
+	"priority":    gogridengine.ByPriority,
+	"submit_time": gogridengine.BySubmitTime,
+	"start_time":  gogridengine.ByStartTime,
+	"owner":       gogridengine.ByOwner,
+	"state":       gogridengine.ByState,
+}
+
+//ParseJobQuery builds the filters and sorters described by the query parameters. Unknown parameters are ignored, malformed values are an error.
+//Job times are read in the local time zone
+func ParseJobQuery(values url.Values) (JobQuery, error) {
+	return ParseJobQueryIn(values, time.Local)
+}
+
+//ParseJobQueryIn builds the filters and sorters described by the query parameters, reading job times in the time zone qstat reports them in
+func ParseJobQueryIn(values url.Values, location *time.Location) (JobQuery, error) {
+	var query JobQuery
+
+	//Every filter parameter maps each of its values to a filter, the values of one parameter being ORed together
+	builders := []struct {
+		name  string
+		build func(value string) (func(j gogridengine.Job) bool, error)
+	}{
+		{"owner", wrap(NewUsernameFilter)},
+		{"state", wrap(NewLooseStateFilter)},
+		{"state_exact", wrap(NewStrictStateFilter)},
+		{"phase", phaseFilter},
+		{"name", globFilter},
+		{"name_regex", regexFilter},
+		{"queue", wrap(NewQueueFilter)},
+		{"host", wrap(NewHostFilter)},
+		{"task", taskFilter},
+		{"min_priority", floatFilter(NewAbovePriorityFilter)},
+		{"max_priority", floatFilter(NewBelowPriorityFilter)},
+		{"min_slots", slotsFilter(NewMinimumSlotsFilter)},
+		{"max_slots", slotsFilter(NewMaximumSlotsFilter)},
+		{"submitted_after", timeFilter(NewAfterSubmitTimeFilterIn, location)},
+		{"submitted_before", timeFilter(NewBeforeSubmitTimeFilterIn, location)},
+		{"started_after", timeFilter(NewAfterStartTimeFilterIn, location)},
+		{"started_before", timeFilter(NewBeforeStartTimeFilterIn, location)},
+	}
+
+	for _, b := range builders {
+		var alternatives []func(j gogridengine.Job) bool
+
+		for _, value := range values[b.name] {
+			filter, err := b.build(value)
+			if err != nil {
+				return JobQuery{}, fmt.Errorf("invalid %s %q: %w", b.name, value, err)
+			}
+
+			alternatives = append(alternatives, filter)
+		}
+
+		if len(alternatives) > 0 {
+			query.Filters = append(query.Filters, anyOf(alternatives))
+		}
+	}
+
+	for _, sort := range values["sort"] {
+		for _, key := range strings.Split(sort, ",") {
+			direction := gogridengine.Ascending
+
+			if strings.HasPrefix(key, "-") {
+				direction = gogridengine.Descending
+				key = key[1:]
+			}
+
+			sorter, ok := sortKeys[key]
+			if !ok {
+				return JobQuery{}, fmt.Errorf("invalid sort key %q", key)
+			}
+
+			query.Sorters = append(query.Sorters, sorter(direction))
+		}
+	}
+
+	return query, nil
+}
+
+//Apply filters and sorts the jobs. The result is never nil so it encodes as an empty list
+func (q JobQuery) Apply(jobs gogridengine.JobList) gogridengine.JobList {
+	result := gogridengine.JobList{}
+
+	for _, j := range jobs {
+		if q.Matches(j) {
+			result = append(result, j)
+		}
+	}
+
+	if len(q.Sorters) > 0 {
+		result.SortBy(q.Sorters...)
+	}
+
+	return result
+}
+
+//Matches returns whether the job passes every filter of the query
+func (q JobQuery) Matches(j gogridengine.Job) bool {
+	for _, f := range q.Filters {
+		if !f(j) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func wrap(build func(value string) func(j gogridengine.Job) bool) func(value string) (func(j gogridengine.Job) bool, error) {
+	return func(value string) (func(j gogridengine.Job) bool, error) {
+		return build(value), nil
+	}
+}
+
+func phaseFilter(value string) (func(j gogridengine.Job) bool, error) {
+	switch value {
+	case gogridengine.PhaseRunning, gogridengine.PhasePending, gogridengine.PhaseError, gogridengine.PhaseOther:
+	default:
+		return nil, fmt.Errorf("expected one of running, pending, error or other")
+	}
+
+	return func(j gogridengine.Job) bool {
+		return gogridengine.JobPhase(j) == value
+	}, nil
+}
+
+func globFilter(value string) (func(j gogridengine.Job) bool, error) {
+	//Matching against an empty name checks the whole pattern, a malformed pattern would otherwise match nothing
+	if _, err := path.Match(value, ""); err != nil {
+		return nil, err
+	}
+
+	return NewJobNameGlobFilter(value), nil
+}
+
+func regexFilter(value string) (func(j gogridengine.Job) bool, error) {
+	expression, err := regexp.Compile(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewJobNameRegexFilter(expression), nil
+}
+
+func taskFilter(value string) (func(j gogridengine.Job) bool, error) {
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTaskIDFilter(id), nil
+}
+
+func floatFilter(build func(v float64) func(j gogridengine.Job) bool) func(value string) (func(j gogridengine.Job) bool, error) {
+	return func(value string) (func(j gogridengine.Job) bool, error) {
+		v, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, err
+		}
+
+		return build(v), nil
+	}
+}
+
+func slotsFilter(build func(slots int32) func(j gogridengine.Job) bool) func(value string) (func(j gogridengine.Job) bool, error) {
+	return func(value string) (func(j gogridengine.Job) bool, error) {
+		v, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+
+		return build(int32(v)), nil
+	}
+}
+
+func timeFilter(build func(t time.Time, location *time.Location) func(j gogridengine.Job) bool, location *time.Location) func(value string) (func(j gogridengine.Job) bool, error) {
+	return func(value string) (func(j gogridengine.Job) bool, error) {
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, err
+		}
+
+		return build(t, location), nil
+	}
+}
+
+//anyOf matches jobs matching any of the alternatives
+func anyOf(alternatives []func(j gogridengine.Job) bool) func(j gogridengine.Job) bool {
+	return func(j gogridengine.Job) bool {
+		for _, f := range alternatives {
+			if f(j) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
@@ -0,0 +1,109 @@
+package filters
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/metrumresearchgroup/gogridengine"
+	"github.com/stretchr/testify/assert"
+)
+
+func queryJobs() gogridengine.JobList {
+	return gogridengine.JobList{
+		{JBJobNumber: 1, JobName: "Run1", JobOwner: "darrellb", State: "r", JATPriority: 0.5, Slots: 1, SubmittedTime: "2019-12-18T10:00:00"},
+		{JBJobNumber: 2, JobName: "Run2", JobOwner: "devinp", State: "qw", JATPriority: 0.6, Slots: 4, SubmittedTime: "2019-12-18T11:00:00"},
+		{JBJobNumber: 3, JobName: "Model", JobOwner: "devinp", State: "Eqw", JATPriority: 0.4, Slots: 2, SubmittedTime: "2019-12-18T12:00:00"},
+	}
+}
+
+func TestParseJobQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []int64
+	}{
+		{name: "nothing", query: "", want: []int64{1, 2, 3}},
+		{name: "owner", query: "owner=devinp", want: []int64{2, 3}},
+		{name: "loose state", query: "state=q", want: []int64{2, 3}},
+		{name: "exact state", query: "state_exact=qw", want: []int64{2}},
+		{name: "phase", query: "phase=error", want: []int64{3}},
+		{name: "name glob", query: "name=Run*", want: []int64{1, 2}},
+		{name: "name regex", query: "name_regex=^M", want: []int64{3}},
+		{name: "priority range", query: "min_priority=0.45&max_priority=0.55", want: []int64{1}},
+		{name: "min priority inclusive", query: "min_priority=0.5", want: []int64{1, 2}},
+		{name: "max priority inclusive", query: "max_priority=0.5", want: []int64{1, 3}},
+		{name: "priority bounds equal", query: "min_priority=0.5&max_priority=0.5", want: []int64{1}},
+		{name: "slots range", query: "min_slots=2&max_slots=3", want: []int64{3}},
+		{name: "submitted after", query: "submitted_after=2019-12-18T10:30:00Z", want: []int64{2, 3}},
+		{name: "submitted before", query: "submitted_before=2019-12-18T10:30:00Z", want: []int64{1}},
+		{name: "alternatives", query: "name=Model&name=Run1", want: []int64{1, 3}},
+		{name: "sorted", query: "sort=-priority", want: []int64{2, 1, 3}},
+		{name: "sorted by several keys", query: "sort=owner,-job_number", want: []int64{1, 3, 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			assert.Nil(t, err)
+
+			query, err := ParseJobQueryIn(values, time.UTC)
+			assert.Nil(t, err)
+
+			numbers := []int64{}
+			for _, j := range query.Apply(queryJobs()) {
+				numbers = append(numbers, j.JBJobNumber)
+			}
+			assert.Equal(t, tt.want, numbers)
+		})
+	}
+}
+
+func TestParseJobQueryIn(t *testing.T) {
+	values := url.Values{"submitted_after": {"2019-12-18T15:30:00Z"}}
+
+	//The cluster reports its wall clock time five hours behind UTC, so 10:30 there is 15:30 UTC
+	query, err := ParseJobQueryIn(values, time.FixedZone("EST", -5*60*60))
+	assert.Nil(t, err)
+
+	numbers := []int64{}
+	for _, j := range query.Apply(queryJobs()) {
+		numbers = append(numbers, j.JBJobNumber)
+	}
+	assert.Equal(t, []int64{2, 3}, numbers)
+
+	query, err = ParseJobQueryIn(values, time.UTC)
+	assert.Nil(t, err)
+	assert.Empty(t, query.Apply(queryJobs()))
+}
+
+func TestParseJobQueryErrors(t *testing.T) {
+	tests := []string{
+		"phase=sleeping",
+		"name=[",
+		"name=Run*[",
+		"name_regex=(",
+		"task=first",
+		"min_priority=high",
+		"max_slots=many",
+		"started_after=yesterday",
+		"sort=colour",
+		"sort=-",
+	}
+	for _, tt := range tests {
+		t.Run(tt, func(t *testing.T) {
+			values, err := url.ParseQuery(tt)
+			assert.Nil(t, err)
+
+			_, err = ParseJobQuery(values)
+			assert.NotNil(t, err)
+		})
+	}
+}
+
+func TestApplyNeverNil(t *testing.T) {
+	query, err := ParseJobQuery(url.Values{"owner": {"nobody"}})
+	assert.Nil(t, err)
+
+	assert.NotNil(t, query.Apply(queryJobs()))
+	assert.NotNil(t, query.Apply(nil))
+}
@@ -0,0 +1,32 @@
+package filters
+
+import (
+	"github.com/metrumresearchgroup/gogridengine"
+)
+
+//NewQueueFilter returns only running jobs on the provided queue. Accepts either a cluster queue (all.q) or a full queue instance (all.q@hostname)
+func NewQueueFilter(queue string) func(job gogridengine.Job) bool {
+	return func(job gogridengine.Job) bool {
+		if job.QueueName == "" {
+			//Pending jobs aren't attached to a queue yet
+			return false
+		}
+
+		if job.QueueName == queue {
+			return true
+		}
+
+		clusterQueue, _ := gogridengine.SplitQueueInstance(job.QueueName)
+
+		return clusterQueue == queue
+	}
+}
+
+//NewHostFilter returns only running jobs on the provided host
+func NewHostFilter(host string) func(job gogridengine.Job) bool {
+	return func(job gogridengine.Job) bool {
+		_, jobHost := gogridengine.SplitQueueInstance(job.QueueName)
+
+		return jobHost != "" && jobHost == host
+	}
+}
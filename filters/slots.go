@@ -0,0 +1,26 @@
+package filters
+
+import (
+	"github.com/metrumresearchgroup/gogridengine"
+)
+
+//NewMinimumSlotsFilter returns only jobs requesting at least the provided number of slots
+func NewMinimumSlotsFilter(slots int32) func(job gogridengine.Job) bool {
+	return func(job gogridengine.Job) bool {
+		return job.Slots >= slots
+	}
+}
+
+//NewMaximumSlotsFilter returns only jobs requesting at most the provided number of slots
+func NewMaximumSlotsFilter(slots int32) func(job gogridengine.Job) bool {
+	return func(job gogridengine.Job) bool {
+		return job.Slots <= slots
+	}
+}
+
+//NewBetweenSlotsFilter returns only jobs whose slot count falls within the provided (inclusive) range
+func NewBetweenSlotsFilter(min int32, max int32) func(job gogridengine.Job) bool {
+	return func(job gogridengine.Job) bool {
+		return job.Slots >= min && job.Slots <= max
+	}
+}
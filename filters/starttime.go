@@ -7,10 +7,15 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-//NewBeforeStartTimeFilter returns only jobs whose start time occurs before the provided time.
+//NewBeforeStartTimeFilter returns only jobs whose start time occurs before the provided time. Job times are read in the local time zone
 func NewBeforeStartTimeFilter(t time.Time) func(job gogridengine.Job) bool {
+	return NewBeforeStartTimeFilterIn(t, time.Local)
+}
+
+//NewBeforeStartTimeFilterIn returns only jobs whose start time occurs before the provided time, reading job times in the time zone qstat reports them in
+func NewBeforeStartTimeFilterIn(t time.Time, location *time.Location) func(job gogridengine.Job) bool {
 	return func(job gogridengine.Job) bool {
-		jobTime, err := time.Parse(ISO8601FMT, job.StartTime)
+		jobTime, err := time.ParseInLocation(ISO8601FMT, job.StartTime, location)
 		if err != nil {
 			//If we can't parse the value, discard the job
 			log.Error("Failed parsing the time content: ", err)
@@ -21,10 +26,15 @@ func NewBeforeStartTimeFilter(t time.Time) func(job gogridengine.Job) bool {
 	}
 }
 
-//NewAfterStartTimeFilter returns only jobs whose start time occurs after the provided time.
+//NewAfterStartTimeFilter returns only jobs whose start time occurs after the provided time. Job times are read in the local time zone
 func NewAfterStartTimeFilter(t time.Time) func(job gogridengine.Job) bool {
+	return NewAfterStartTimeFilterIn(t, time.Local)
+}
+
+//NewAfterStartTimeFilterIn returns only jobs whose start time occurs after the provided time, reading job times in the time zone qstat reports them in
+func NewAfterStartTimeFilterIn(t time.Time, location *time.Location) func(job gogridengine.Job) bool {
 	return func(job gogridengine.Job) bool {
-		jobTime, err := time.Parse(ISO8601FMT, job.StartTime)
+		jobTime, err := time.ParseInLocation(ISO8601FMT, job.StartTime, location)
 		if err != nil {
 			//If we can't parse the value, discard the job
 			return false
@@ -34,10 +44,15 @@ func NewAfterStartTimeFilter(t time.Time) func(job gogridengine.Job) bool {
 	}
 }
 
-//NewBetweenStartTimeFilter allows you to provide a start and end time to return jobs whos start time falls within that range
+//NewBetweenStartTimeFilter allows you to provide a start and end time to return jobs whos start time falls within that range. Job times are read in the local time zone
 func NewBetweenStartTimeFilter(start time.Time, end time.Time) func(job gogridengine.Job) bool {
+	return NewBetweenStartTimeFilterIn(start, end, time.Local)
+}
+
+//NewBetweenStartTimeFilterIn returns jobs whose start time falls within the range, reading job times in the time zone qstat reports them in
+func NewBetweenStartTimeFilterIn(start time.Time, end time.Time, location *time.Location) func(job gogridengine.Job) bool {
 	return func(job gogridengine.Job) bool {
-		jobTime, err := time.Parse(ISO8601FMT, job.StartTime)
+		jobTime, err := time.ParseInLocation(ISO8601FMT, job.StartTime, location)
 		if err != nil {
 			//If we can't parse the value, discard the job
 			return false
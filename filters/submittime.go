@@ -7,10 +7,15 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-//NewBeforeSubmitTimeFilter returns only jobs whose submitted time occurs before the provided time.
+//NewBeforeSubmitTimeFilter returns only jobs whose submitted time occurs before the provided time. Job times are read in the local time zone
 func NewBeforeSubmitTimeFilter(t time.Time) func(job gogridengine.Job) bool {
+	return NewBeforeSubmitTimeFilterIn(t, time.Local)
+}
+
+//NewBeforeSubmitTimeFilterIn returns only jobs whose submitted time occurs before the provided time, reading job times in the time zone qstat reports them in
+func NewBeforeSubmitTimeFilterIn(t time.Time, location *time.Location) func(job gogridengine.Job) bool {
 	return func(job gogridengine.Job) bool {
-		jobTime, err := time.Parse(ISO8601FMT, job.SubmittedTime)
+		jobTime, err := time.ParseInLocation(ISO8601FMT, job.SubmittedTime, location)
 		if err != nil {
 			//If we can't parse the value, discard the job
 			log.Error("Failed parsing the time content: ", err)
@@ -21,10 +26,15 @@ func NewBeforeSubmitTimeFilter(t time.Time) func(job gogridengine.Job) bool {
 	}
 }
 
-//NewAfterSubmitTimeFilter returns only jobs whose submitted time occurs after the provided time.
+//NewAfterSubmitTimeFilter returns only jobs whose submitted time occurs after the provided time. Job times are read in the local time zone
 func NewAfterSubmitTimeFilter(t time.Time) func(job gogridengine.Job) bool {
+	return NewAfterSubmitTimeFilterIn(t, time.Local)
+}
+
+//NewAfterSubmitTimeFilterIn returns only jobs whose submitted time occurs after the provided time, reading job times in the time zone qstat reports them in
+func NewAfterSubmitTimeFilterIn(t time.Time, location *time.Location) func(job gogridengine.Job) bool {
 	return func(job gogridengine.Job) bool {
-		jobTime, err := time.Parse(ISO8601FMT, job.SubmittedTime)
+		jobTime, err := time.ParseInLocation(ISO8601FMT, job.SubmittedTime, location)
 		if err != nil {
 			//If we can't parse the value, discard the job
 			return false
@@ -34,10 +44,15 @@ func NewAfterSubmitTimeFilter(t time.Time) func(job gogridengine.Job) bool {
 	}
 }
 
-//NewBetweenSubmitTimeFilter allows you to provide a start and end time to return jobs whos submit time falls within that range
+//NewBetweenSubmitTimeFilter allows you to provide a start and end time to return jobs whos submit time falls within that range. Job times are read in the local time zone
 func NewBetweenSubmitTimeFilter(start time.Time, end time.Time) func(job gogridengine.Job) bool {
+	return NewBetweenSubmitTimeFilterIn(start, end, time.Local)
+}
+
+//NewBetweenSubmitTimeFilterIn returns jobs whose submit time falls within the range, reading job times in the time zone qstat reports them in
+func NewBetweenSubmitTimeFilterIn(start time.Time, end time.Time, location *time.Location) func(job gogridengine.Job) bool {
 	return func(job gogridengine.Job) bool {
-		jobTime, err := time.Parse(ISO8601FMT, job.SubmittedTime)
+		jobTime, err := time.ParseInLocation(ISO8601FMT, job.SubmittedTime, location)
 		if err != nil {
 			//If we can't parse the value, discard the job
 			return false
@@ -0,0 +1,18 @@
+package filters
+
+import (
+	"github.com/metrumresearchgroup/gogridengine"
+)
+
+//NewTaskIDFilter returns only jobs whose task ID is a member of the provided set of IDs
+func NewTaskIDFilter(ids ...int64) func(job gogridengine.Job) bool {
+	members := make(map[int64]bool)
+
+	for _, v := range ids {
+		members[v] = true
+	}
+
+	return func(job gogridengine.Job) bool {
+		return members[job.Tasks.TaskID]
+	}
+}
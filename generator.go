@@ -0,0 +1,296 @@
+package gogridengine
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+//GeneratorOptions describe the shape of a synthetic cluster produced by GenerateJobInfo
+type GeneratorOptions struct {
+	//Seed makes the output deterministic. The same options always produce the same cluster
+	Seed int64
+	//Queue is the cluster queue every host serves
+	Queue            string
+	Hosts            int
+	SlotsPerHost     int32
+	MemoryPerHost    int64
+	UnavailableHosts int
+	Owners           []string
+	//RunningJobs are placed onto free slots. Jobs that don't fit are left pending
+	RunningJobs int
+	PendingJobs int
+	//ArrayJobs each have TasksPerArray tasks. Tasks run while slots are free, the rest are listed as a pending task range
+	ArrayJobs     int
+	TasksPerArray int
+	ErrorJobs     int
+	HeldJobs      int
+	//Now is the time the snapshot is taken at. Start and submission times are generated before it
+	Now time.Time
+}
+
+//DefaultGeneratorOptions returns a small but varied cluster modelled on the EC2 clusters in test_data
+func DefaultGeneratorOptions() GeneratorOptions {
+	return GeneratorOptions{
+		Seed:             1,
+		Queue:            "all.q",
+		Hosts:            8,
+		SlotsPerHost:     8,
+		MemoryPerHost:    16 * 1024 * 1024 * 1024,
+		UnavailableHosts: 1,
+		Owners:           []string{"darrellb", "devinp", "ahmede", "user"},
+		RunningJobs:      40,
+		PendingJobs:      10,
+		ArrayJobs:        2,
+		TasksPerArray:    10,
+		ErrorJobs:        1,
+		HeldJobs:         1,
+		Now:              time.Date(2019, 12, 18, 15, 28, 27, 0, time.UTC),
+	}
+}
+
+//GenerateQstatXML renders a synthetic cluster as qstat -F -xml output
+func GenerateQstatXML(opts GeneratorOptions) (string, error) {
+	return newGenerator(opts).generate().GetXML()
+}
+
+//GenerateJobInfo produces a synthetic cluster the same way NewJobInfo would from its qstat output, with pending task ranges extrapolated
+func GenerateJobInfo(opts GeneratorOptions) (JobInfo, error) {
+	xml, err := GenerateQstatXML(opts)
+
+	if err != nil {
+		return JobInfo{}, err
+	}
+
+	return NewJobInfo(xml)
+}
+
+type generator struct {
+	opts      GeneratorOptions
+	random    *rand.Rand
+	nextJob   int64
+	hosts     []Host
+	pending   []Job
+	freeSlots []int32
+}
+
+func newGenerator(opts GeneratorOptions) *generator {
+	if opts.Queue == "" {
+		opts.Queue = "all.q"
+	}
+
+	if len(opts.Owners) == 0 {
+		opts.Owners = []string{"user"}
+	}
+
+	if opts.Now.IsZero() {
+		opts.Now = DefaultGeneratorOptions().Now
+	}
+
+	return &generator{
+		opts:    opts,
+		random:  rand.New(rand.NewSource(opts.Seed)),
+		nextJob: 1000,
+	}
+}
+
+func (g *generator) generate() JobInfo {
+	g.generateHosts()
+
+	for i := 0; i < g.opts.RunningJobs; i++ {
+		j := g.newJob("Run")
+		j.Slots = int32(1 + g.random.Intn(4))
+		g.place(j)
+	}
+
+	for i := 0; i < g.opts.ArrayJobs; i++ {
+		g.generateArrayJob()
+	}
+
+	for i := 0; i < g.opts.PendingJobs; i++ {
+		g.pending = append(g.pending, g.newPendingJob("qw"))
+	}
+
+	for i := 0; i < g.opts.HeldJobs; i++ {
+		g.pending = append(g.pending, g.newPendingJob("hqw"))
+	}
+
+	for i := 0; i < g.opts.ErrorJobs; i++ {
+		g.pending = append(g.pending, g.newPendingJob("Eqw"))
+	}
+
+	for k := range g.hosts {
+		g.hosts[k].SlotsUsed = g.hosts[k].SlotsTotal - g.freeSlots[k]
+		if g.hosts[k].State != "" {
+			g.hosts[k].SlotsUsed = 0
+		}
+	}
+
+	return JobInfo{
+		QueueInfo: QueueInfo{
+			Queues: g.hosts,
+		},
+		PendingJobs: PendingJob{
+			JobList: g.pending,
+		},
+	}
+}
+
+func (g *generator) generateHosts() {
+	for i := 0; i < g.opts.Hosts; i++ {
+		hostname := fmt.Sprintf("ip-10-0-%d-%d.ec2.internal", 1+i/250, 10+i%250)
+
+		host := Host{
+			Name:       g.opts.Queue + "@" + hostname,
+			QType:      "BIP",
+			SlotsTotal: g.opts.SlotsPerHost,
+		}
+
+		if i >= g.opts.Hosts-g.opts.UnavailableHosts {
+			//Unreachable hosts report no load values, just like qstat
+			host.State = "au"
+			host.Resources = ResourceList{
+				{Name: "num_proc", Type: "hl", Value: fmt.Sprintf("%d", g.opts.SlotsPerHost)},
+				{Name: "mem_total", Type: "hl", Value: formatGigabytes(g.opts.MemoryPerHost)},
+				{Name: "qname", Type: "qf", Value: g.opts.Queue},
+				{Name: "hostname", Type: "qf", Value: hostname},
+			}
+			g.freeSlots = append(g.freeSlots, 0)
+			g.hosts = append(g.hosts, host)
+			continue
+		}
+
+		load := g.random.Float64() * float64(g.opts.SlotsPerHost)
+		used := int64(g.random.Float64() * 0.5 * float64(g.opts.MemoryPerHost))
+
+		host.LoadAverage = roundTo(load, 5)
+		host.Resources = ResourceList{
+			{Name: "load_avg", Type: "hl", Value: fmt.Sprintf("%f", load)},
+			{Name: "num_proc", Type: "hl", Value: fmt.Sprintf("%d", g.opts.SlotsPerHost)},
+			{Name: "mem_free", Type: "hl", Value: formatGigabytes(g.opts.MemoryPerHost - used)},
+			{Name: "mem_total", Type: "hl", Value: formatGigabytes(g.opts.MemoryPerHost)},
+			{Name: "mem_used", Type: "hl", Value: formatGigabytes(used)},
+			{Name: "cpu", Type: "hl", Value: fmt.Sprintf("%f", g.random.Float64()*100)},
+			{Name: "np_load_avg", Type: "hl", Value: fmt.Sprintf("%f", load/float64(g.opts.SlotsPerHost))},
+			{Name: "qname", Type: "qf", Value: g.opts.Queue},
+			{Name: "hostname", Type: "qf", Value: hostname},
+		}
+
+		g.freeSlots = append(g.freeSlots, g.opts.SlotsPerHost)
+		g.hosts = append(g.hosts, host)
+	}
+}
+
+func (g *generator) generateArrayJob() {
+	template := g.newJob("task_array")
+	template.Slots = 1
+
+	task := int64(1)
+
+	//Run as many tasks as there are free slots for
+	for ; task <= int64(g.opts.TasksPerArray); task++ {
+		host := g.hostWithFreeSlots(1)
+		if host < 0 {
+			break
+		}
+
+		j := template
+		j.Tasks = Task{Source: fmt.Sprintf("%d", task), TaskID: task}
+		g.run(host, j)
+	}
+
+	if task > int64(g.opts.TasksPerArray) {
+		return
+	}
+
+	j := template
+	j.State = "qw"
+	j.StateAttribute = "pending"
+	j.StartTime = ""
+	j.SubmittedTime = g.timeBefore(2 * time.Hour)
+	j.Tasks = Task{Source: fmt.Sprintf("%d-%d:1", task, g.opts.TasksPerArray)}
+	g.pending = append(g.pending, j)
+}
+
+func (g *generator) newJob(prefix string) Job {
+	g.nextJob++
+
+	return Job{
+		StateAttribute: "running",
+		State:          "r",
+		JBJobNumber:    g.nextJob,
+		JATPriority:    roundTo(0.5+g.random.Float64()*0.1, 5),
+		JobName:        fmt.Sprintf("%s%d", prefix, g.nextJob),
+		JobOwner:       g.opts.Owners[g.random.Intn(len(g.opts.Owners))],
+		StartTime:      g.timeBefore(24 * time.Hour),
+	}
+}
+
+func (g *generator) newPendingJob(state string) Job {
+	j := g.newJob("Run")
+	j.StateAttribute = "pending"
+	j.State = state
+	j.StartTime = ""
+	j.SubmittedTime = g.timeBefore(2 * time.Hour)
+	j.Slots = int32(1 + g.random.Intn(4))
+
+	return j
+}
+
+//place runs the job on the first host with enough free slots, leaving it pending otherwise
+func (g *generator) place(j Job) {
+	host := g.hostWithFreeSlots(j.Slots)
+
+	if host < 0 {
+		j.StateAttribute = "pending"
+		j.State = "qw"
+		j.StartTime = ""
+		j.SubmittedTime = g.timeBefore(2 * time.Hour)
+		g.pending = append(g.pending, j)
+		return
+	}
+
+	g.run(host, j)
+}
+
+func (g *generator) run(host int, j Job) {
+	g.freeSlots[host] -= j.Slots
+	g.hosts[host].JobList = append(g.hosts[host].JobList, j)
+}
+
+//hostWithFreeSlots picks a random host able to fit the slots, or -1 if none can
+func (g *generator) hostWithFreeSlots(slots int32) int {
+	offset := 0
+	if len(g.hosts) > 0 {
+		offset = g.random.Intn(len(g.hosts))
+	}
+
+	for i := range g.hosts {
+		k := (i + offset) % len(g.hosts)
+		if g.hosts[k].State == "" && g.freeSlots[k] >= slots {
+			return k
+		}
+	}
+
+	return -1
+}
+
+func (g *generator) timeBefore(window time.Duration) string {
+	offset := time.Duration(g.random.Int63n(int64(window)))
+
+	return g.opts.Now.Add(-offset).Format(ISO8601FMT)
+}
+
+func formatGigabytes(bytes int64) string {
+	return fmt.Sprintf("%.3fG", float64(bytes)/(1024*1024*1024))
+}
+
+func roundTo(value float64, places int) float64 {
+	var scale float64 = 1
+
+	for i := 0; i < places; i++ {
+		scale *= 10
+	}
+
+	return float64(int64(value*scale)) / scale
+}
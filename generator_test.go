@@ -0,0 +1,80 @@
+package gogridengine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateQstatXMLIsDeterministic(t *testing.T) {
+	first, err := GenerateQstatXML(DefaultGeneratorOptions())
+	assert.Nil(t, err)
+
+	second, err := GenerateQstatXML(DefaultGeneratorOptions())
+	assert.Nil(t, err)
+
+	assert.Equal(t, first, second)
+
+	options := DefaultGeneratorOptions()
+	options.Seed = 2
+	other, err := GenerateQstatXML(options)
+	assert.Nil(t, err)
+	assert.NotEqual(t, first, other)
+}
+
+func TestGenerateJobInfo(t *testing.T) {
+	options := DefaultGeneratorOptions()
+
+	ji, err := GenerateJobInfo(options)
+	assert.Nil(t, err)
+	assert.Len(t, ji.QueueInfo.Queues, options.Hosts)
+
+	for _, h := range ji.QueueInfo.Queues {
+		assert.Equal(t, options.SlotsPerHost, h.SlotsTotal)
+		assert.True(t, h.SlotsUsed <= h.SlotsTotal)
+
+		if h.State != "" {
+			assert.Empty(t, h.JobList)
+			continue
+		}
+
+		memory, err := h.Resources.TotalMemory()
+		assert.Nil(t, err)
+		assert.Equal(t, options.MemoryPerHost, memory.Bytes)
+
+		for _, j := range h.JobList {
+			assert.Equal(t, "running", j.StateAttribute)
+			assert.Equal(t, h.Name, j.QueueName)
+		}
+	}
+
+	summary := ji.Jobs().Summarize()
+	assert.Equal(t, options.RunningJobs+options.PendingJobs+options.ArrayJobs*options.TasksPerArray+options.ErrorJobs+options.HeldJobs, summary.Total.Jobs)
+	assert.Equal(t, options.ErrorJobs, summary.Total.Error)
+
+	//Pending array tasks are extrapolated back out into individual jobs
+	for _, j := range ji.PendingJobs.JobList {
+		if DoesJobContainTaskRange(j) {
+			assert.NotZero(t, j.Tasks.TaskID)
+		}
+	}
+}
+
+func TestGenerateJobInfoOverflowsToPending(t *testing.T) {
+	options := DefaultGeneratorOptions()
+	options.Hosts = 1
+	options.UnavailableHosts = 0
+	options.SlotsPerHost = 4
+	options.RunningJobs = 20
+	options.ArrayJobs = 0
+	options.PendingJobs = 0
+	options.ErrorJobs = 0
+	options.HeldJobs = 0
+
+	ji, err := GenerateJobInfo(options)
+	assert.Nil(t, err)
+
+	running := len(ji.QueueInfo.Queues[0].JobList)
+	assert.True(t, running > 0)
+	assert.Equal(t, 20, running+len(ji.PendingJobs.JobList))
+}
@@ -1,11 +1,13 @@
 module github.com/metrumresearchgroup/gogridengine
 
-go 1.13
+go 1.16
 
 require (
-	github.com/kr/pretty v0.1.0 // indirect
+	github.com/gdamore/tcell/v2 v2.4.0
+	github.com/prometheus/client_golang v1.7.1
 	github.com/sirupsen/logrus v1.4.2
 	github.com/stretchr/testify v1.4.0
-	gopkg.in/check.v1 v1.0.0-20180628173108-788fd7840127 // indirect
-	gopkg.in/yaml.v2 v2.2.4 // indirect
+	go.etcd.io/bbolt v1.3.6
+	golang.org/x/crypto v0.9.0
+	gopkg.in/yaml.v2 v2.2.5
 )
@@ -0,0 +1,90 @@
+package history
+
+import (
+	"context"
+	"time"
+
+	"github.com/metrumresearchgroup/gogridengine"
+	log "github.com/sirupsen/logrus"
+)
+
+//Recorder periodically stores snapshots of a data source, pruning the store according to its retention policy
+type Recorder struct {
+	Store *Store
+	//Source provides the qstat XML. Defaults to a QstatDataSource
+	Source    gogridengine.XmlResourceGetter
+	Interval  time.Duration
+	Retention RetentionPolicy
+	//Filter limits the jobs recorded. Nil records everything
+	Filter func(j gogridengine.Job) bool
+}
+
+//Run records snapshots until the context is cancelled. Failed polls are logged and retried with the backoff of the underlying Watcher.
+func (r Recorder) Run(ctx context.Context) error {
+	w := gogridengine.Watcher{
+		Source:   r.Source,
+		Interval: r.Interval,
+		Filter:   r.Filter,
+	}
+
+	for update := range w.Watch(ctx) {
+		if update.Err != nil {
+			continue
+		}
+
+		if err := r.Store.Put(update.Time, update.Snapshot); err != nil {
+			log.Error("Unable to record the qstat snapshot: ", err)
+			return err
+		}
+
+		if _, err := r.Store.Prune(r.Retention, update.Time); err != nil {
+			log.Error("Unable to prune recorded qstat snapshots: ", err)
+			return err
+		}
+	}
+
+	return ctx.Err()
+}
+
+//TimelineEntry is a single change to a job observed between two consecutive snapshots
+type TimelineEntry struct {
+	Time  time.Time          `json:"time"`
+	Event gogridengine.Event `json:"event"`
+}
+
+//JobTimeline replays the recorded snapshots within the inclusive time range and returns every change observed for the job (or its array tasks), oldest first
+func (s *Store) JobTimeline(jobNumber int64, start, end time.Time) ([]TimelineEntry, error) {
+	var timeline []TimelineEntry
+
+	snapshots, err := s.Range(start, end)
+
+	if err != nil {
+		return nil, err
+	}
+
+	onlyJob := func(j gogridengine.Job) bool {
+		return j.JBJobNumber == jobNumber
+	}
+
+	var previous gogridengine.JobInfo
+
+	for _, snapshot := range snapshots {
+		current := snapshot.JobInfo.Filter(onlyJob)
+
+		for _, e := range gogridengine.Diff(previous, current) {
+			if e.Key.JobNumber != jobNumber {
+				//Host events aren't part of a job's timeline
+				continue
+			}
+
+			timeline = append(timeline, TimelineEntry{
+				Time:  snapshot.Time,
+				Event: e,
+			})
+		}
+
+		previous = current
+	}
+
+	return timeline, nil
+}
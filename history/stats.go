@@ -0,0 +1,276 @@
+package history
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/metrumresearchgroup/gogridengine"
+)
+
+//JobObservation is what the snapshot history tells us about a single job or array task
+type JobObservation struct {
+	Key   gogridengine.JobKey `json:"key"`
+	Owner string              `json:"owner"`
+	//Queue is the cluster queue the job ran on, empty if it was never seen running
+	Queue string `json:"queue,omitempty"`
+	Slots int32  `json:"slots"`
+	//Submitted is the JB_submission_time, only reported by qstat while a job is pending
+	Submitted    time.Time `json:"submitted"`
+	FirstSeen    time.Time `json:"first_seen"`
+	FirstRunning time.Time `json:"first_running,omitempty"`
+	LastRunning  time.Time `json:"last_running,omitempty"`
+	//Wait is the queue wait from submission to first being seen running. Only valid when HasWait is set
+	Wait    time.Duration `json:"wait"`
+	HasWait bool          `json:"has_wait"`
+	//Runtime is the observed runtime from the job's JAT_start_time (or first sighting running) to the last snapshot it was seen running in
+	Runtime    time.Duration `json:"runtime"`
+	HasRuntime bool          `json:"has_runtime"`
+	//Finished is set when the job disappeared from qstat within the window
+	Finished bool `json:"finished"`
+}
+
+//Percentiles summarizes a set of durations using the nearest-rank method
+type Percentiles struct {
+	Count int           `json:"count"`
+	Min   time.Duration `json:"min"`
+	P50   time.Duration `json:"p50"`
+	P90   time.Duration `json:"p90"`
+	P95   time.Duration `json:"p95"`
+	P99   time.Duration `json:"p99"`
+	Max   time.Duration `json:"max"`
+}
+
+//GroupStats holds the wait and runtime percentiles of a group of jobs
+type GroupStats struct {
+	Wait    Percentiles `json:"wait"`
+	Runtime Percentiles `json:"runtime"`
+}
+
+//JobStats is the wait and runtime analysis of a window of snapshots
+type JobStats struct {
+	Start   time.Time             `json:"start"`
+	End     time.Time             `json:"end"`
+	Jobs    []JobObservation      `json:"jobs"`
+	Overall GroupStats            `json:"overall"`
+	ByOwner map[string]GroupStats `json:"by_owner"`
+	ByQueue map[string]GroupStats `json:"by_queue"`
+	BySlots map[int32]GroupStats  `json:"by_slots"`
+}
+
+//Stats computes job statistics over the snapshots recorded within the inclusive time range. qstat times are interpreted in the provided location, nil meaning local time.
+func (s *Store) Stats(start, end time.Time, location *time.Location) (JobStats, error) {
+	snapshots, err := s.Range(start, end)
+
+	if err != nil {
+		return JobStats{}, err
+	}
+
+	stats := ComputeStats(snapshots, location)
+	stats.Start = start
+	stats.End = end
+
+	return stats, nil
+}
+
+//ComputeStats derives per job queue wait and observed runtime from a series of snapshots (oldest first), then reports percentiles overall and by owner, queue and slot count.
+//qstat times carry no zone, so they are interpreted in the provided location, nil meaning local time.
+func ComputeStats(snapshots []Snapshot, location *time.Location) JobStats {
+	if location == nil {
+		location = time.Local
+	}
+
+	observations := make(map[gogridengine.JobKey]*JobObservation)
+	var order []gogridengine.JobKey
+
+	stats := JobStats{
+		ByOwner: make(map[string]GroupStats),
+		ByQueue: make(map[string]GroupStats),
+		BySlots: make(map[int32]GroupStats),
+	}
+
+	if len(snapshots) > 0 {
+		stats.Start = snapshots[0].Time
+		stats.End = snapshots[len(snapshots)-1].Time
+	}
+
+	for _, snapshot := range snapshots {
+		present := make(map[gogridengine.JobKey]bool)
+
+		for _, j := range snapshot.JobInfo.Jobs() {
+			key := gogridengine.KeyForJob(j)
+
+			if present[key] {
+				//Parallel jobs are listed once per queue instance
+				continue
+			}
+			present[key] = true
+
+			o, ok := observations[key]
+			if !ok {
+				o = &JobObservation{
+					Key:       key,
+					Owner:     j.JobOwner,
+					Slots:     j.Slots,
+					FirstSeen: snapshot.Time,
+				}
+				observations[key] = o
+				order = append(order, key)
+			}
+
+			//A job seen again after disappearing is still running somewhere
+			o.Finished = false
+
+			if submitted, err := time.ParseInLocation(gogridengine.ISO8601FMT, j.SubmittedTime, location); err == nil && o.Submitted.IsZero() {
+				o.Submitted = submitted
+			}
+
+			if gogridengine.JobPhase(j) != gogridengine.PhaseRunning {
+				continue
+			}
+
+			if o.FirstRunning.IsZero() {
+				o.FirstRunning = snapshot.Time
+				o.Queue, _ = gogridengine.SplitQueueInstance(j.QueueName)
+
+				if started, err := time.ParseInLocation(gogridengine.ISO8601FMT, j.StartTime, location); err == nil {
+					//Prefer the scheduler's start time over when we first happened to look
+					o.FirstRunning = started
+				}
+			}
+
+			o.LastRunning = snapshot.Time
+		}
+
+		for key, o := range observations {
+			if !present[key] {
+				o.Finished = true
+			}
+		}
+	}
+
+	//Report observations ordered by job number and task
+	sort.Slice(order, func(i, j int) bool {
+		if order[i].JobNumber != order[j].JobNumber {
+			return order[i].JobNumber < order[j].JobNumber
+		}
+		return order[i].TaskID < order[j].TaskID
+	})
+
+	for _, key := range order {
+		o := observations[key]
+
+		if !o.Submitted.IsZero() && !o.FirstRunning.IsZero() {
+			o.Wait = o.FirstRunning.Sub(o.Submitted)
+			o.HasWait = true
+		}
+
+		if !o.FirstRunning.IsZero() {
+			o.Runtime = o.LastRunning.Sub(o.FirstRunning)
+			o.HasRuntime = true
+		}
+
+		stats.Jobs = append(stats.Jobs, *o)
+	}
+
+	overall := &groupDurations{}
+	byOwner := make(map[string]*groupDurations)
+	byQueue := make(map[string]*groupDurations)
+	bySlots := make(map[int32]*groupDurations)
+
+	for _, o := range stats.Jobs {
+		overall.add(o)
+		durationsFor(byOwner, o.Owner).add(o)
+
+		if o.Queue != "" {
+			durationsFor(byQueue, o.Queue).add(o)
+		}
+
+		if _, ok := bySlots[o.Slots]; !ok {
+			bySlots[o.Slots] = &groupDurations{}
+		}
+		bySlots[o.Slots].add(o)
+	}
+
+	stats.Overall = overall.stats()
+
+	for k, v := range byOwner {
+		stats.ByOwner[k] = v.stats()
+	}
+
+	for k, v := range byQueue {
+		stats.ByQueue[k] = v.stats()
+	}
+
+	for k, v := range bySlots {
+		stats.BySlots[k] = v.stats()
+	}
+
+	return stats
+}
+
+//NewPercentiles computes the nearest-rank percentiles of the provided durations
+func NewPercentiles(durations []time.Duration) Percentiles {
+	if len(durations) == 0 {
+		return Percentiles{}
+	}
+
+	sorted := append([]time.Duration{}, durations...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	rank := func(p float64) time.Duration {
+		index := int(math.Ceil(p*float64(len(sorted)))) - 1
+		if index < 0 {
+			index = 0
+		}
+		if index >= len(sorted) {
+			index = len(sorted) - 1
+		}
+		return sorted[index]
+	}
+
+	return Percentiles{
+		Count: len(sorted),
+		Min:   sorted[0],
+		P50:   rank(0.50),
+		P90:   rank(0.90),
+		P95:   rank(0.95),
+		P99:   rank(0.99),
+		Max:   sorted[len(sorted)-1],
+	}
+}
+
+type groupDurations struct {
+	waits    []time.Duration
+	runtimes []time.Duration
+}
+
+func durationsFor(groups map[string]*groupDurations, key string) *groupDurations {
+	group, ok := groups[key]
+
+	if !ok {
+		group = &groupDurations{}
+		groups[key] = group
+	}
+
+	return group
+}
+
+func (g *groupDurations) add(o JobObservation) {
+	if o.HasWait {
+		g.waits = append(g.waits, o.Wait)
+	}
+
+	if o.HasRuntime {
+		g.runtimes = append(g.runtimes, o.Runtime)
+	}
+}
+
+func (g *groupDurations) stats() GroupStats {
+	return GroupStats{
+		Wait:    NewPercentiles(g.waits),
+		Runtime: NewPercentiles(g.runtimes),
+	}
+}
@@ -0,0 +1,120 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/metrumresearchgroup/gogridengine"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPercentiles(t *testing.T) {
+	var durations []time.Duration
+	for i := 100; i >= 1; i-- {
+		durations = append(durations, time.Duration(i)*time.Second)
+	}
+
+	p := NewPercentiles(durations)
+
+	assert.Equal(t, 100, p.Count)
+	assert.Equal(t, time.Second, p.Min)
+	assert.Equal(t, 50*time.Second, p.P50)
+	assert.Equal(t, 90*time.Second, p.P90)
+	assert.Equal(t, 99*time.Second, p.P99)
+	assert.Equal(t, 100*time.Second, p.Max)
+
+	assert.Equal(t, Percentiles{}, NewPercentiles(nil))
+}
+
+func TestComputeStats(t *testing.T) {
+	submitted := base.Add(-10 * time.Minute).Format(gogridengine.ISO8601FMT)
+	started := base.Add(5 * time.Minute).Format(gogridengine.ISO8601FMT)
+
+	pending := func(number int64, owner string, slots int32) gogridengine.Job {
+		return gogridengine.Job{
+			JBJobNumber:   number,
+			JobOwner:      owner,
+			State:         "qw",
+			Slots:         slots,
+			SubmittedTime: submitted,
+		}
+	}
+
+	running := func(number int64, owner string, slots int32, start string) gogridengine.Job {
+		return gogridengine.Job{
+			JBJobNumber: number,
+			JobOwner:    owner,
+			State:       "r",
+			Slots:       slots,
+			StartTime:   start,
+		}
+	}
+
+	snapshots := []Snapshot{
+		{
+			Time:    base,
+			JobInfo: snapshotWith(pending(1, "alice", 1), pending(2, "bob", 4), running(3, "bob", 1, "")),
+		},
+		{
+			//Job 1 started with a recorded start time, job 2 without one
+			Time:    base.Add(10 * time.Minute),
+			JobInfo: snapshotWith(running(1, "alice", 1, started), running(2, "bob", 4, ""), running(3, "bob", 1, "")),
+		},
+		{
+			Time:    base.Add(20 * time.Minute),
+			JobInfo: snapshotWith(running(2, "bob", 4, ""), running(3, "bob", 1, "")),
+		},
+	}
+
+	stats := ComputeStats(snapshots, time.UTC)
+
+	assert.Len(t, stats.Jobs, 3)
+
+	alice := stats.Jobs[0]
+	assert.True(t, alice.HasWait)
+	assert.Equal(t, 15*time.Minute, alice.Wait)
+	assert.Equal(t, 5*time.Minute, alice.Runtime)
+	assert.True(t, alice.Finished)
+	assert.Equal(t, "all.q", alice.Queue)
+
+	bob := stats.Jobs[1]
+	assert.Equal(t, 20*time.Minute, bob.Wait)
+	assert.Equal(t, 10*time.Minute, bob.Runtime)
+	assert.False(t, bob.Finished)
+
+	//Never seen pending, so the wait is unknown
+	assert.False(t, stats.Jobs[2].HasWait)
+	assert.Equal(t, 20*time.Minute, stats.Jobs[2].Runtime)
+
+	assert.Equal(t, 2, stats.Overall.Wait.Count)
+	assert.Equal(t, 3, stats.Overall.Runtime.Count)
+	assert.Equal(t, 1, stats.ByOwner["alice"].Wait.Count)
+	assert.Equal(t, 20*time.Minute, stats.ByOwner["bob"].Wait.Max)
+	assert.Equal(t, 3, stats.ByQueue["all.q"].Runtime.Count)
+	assert.Equal(t, 20*time.Minute, stats.BySlots[4].Wait.P50)
+}
+
+func TestStoreStats(t *testing.T) {
+	store, cleanup := openStore(t)
+	defer cleanup()
+
+	job := gogridengine.Job{
+		JBJobNumber:   4282,
+		JobOwner:      "alice",
+		State:         "qw",
+		Slots:         1,
+		SubmittedTime: base.Format(gogridengine.ISO8601FMT),
+	}
+
+	assert.Nil(t, store.Put(base, snapshotWith(job)))
+
+	job.State = "r"
+	assert.Nil(t, store.Put(base.Add(time.Minute), snapshotWith(job)))
+	assert.Nil(t, store.Put(base.Add(3*time.Minute), snapshotWith(job)))
+
+	stats, err := store.Stats(base, base.Add(time.Hour), time.UTC)
+	assert.Nil(t, err)
+	assert.True(t, stats.End.Equal(base.Add(time.Hour)))
+	assert.Equal(t, time.Minute, stats.ByOwner["alice"].Wait.P50)
+	assert.Equal(t, 2*time.Minute, stats.ByOwner["alice"].Runtime.P50)
+}
@@ -0,0 +1,223 @@
+//Package history records qstat snapshots to an embedded bbolt database on local disk so the state of the cluster can be queried after the fact.
+package history
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"io/ioutil"
+	"time"
+
+	"github.com/metrumresearchgroup/gogridengine"
+	bolt "go.etcd.io/bbolt"
+)
+
+//ErrNoSnapshot is returned when no snapshot was recorded at or before the requested time
+const ErrNoSnapshot = gogridengine.Error("No snapshot was recorded at or before the requested time")
+
+var snapshotBucket = []byte("snapshots")
+
+//Snapshot is a JobInfo as it was observed at a point in time
+type Snapshot struct {
+	Time    time.Time            `json:"time"`
+	JobInfo gogridengine.JobInfo `json:"job_info"`
+}
+
+//RetentionPolicy limits how much history a Store keeps. Zero values disable the respective limit.
+type RetentionPolicy struct {
+	//MaxAge drops snapshots older than this
+	MaxAge time.Duration
+	//MaxSnapshots keeps only this many of the most recent snapshots
+	MaxSnapshots int
+}
+
+//Store persists gzip compressed snapshots keyed by the time they were taken
+type Store struct {
+	db *bolt.DB
+}
+
+//Open opens (or creates) the store at the provided path
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(snapshotBucket)
+		return err
+	})
+
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db}, nil
+}
+
+//Close releases the underlying database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+//Put records the JobInfo as observed at the provided time
+func (s *Store) Put(t time.Time, ji gogridengine.JobInfo) error {
+	encoded, err := encode(ji)
+
+	if err != nil {
+		return err
+	}
+
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(snapshotBucket).Put(timeKey(t), encoded)
+	})
+}
+
+//At returns the state of the cluster at the provided time, which is the most recent snapshot taken at or before it
+func (s *Store) At(t time.Time) (Snapshot, error) {
+	var snapshot Snapshot
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(snapshotBucket).Cursor()
+		target := timeKey(t)
+
+		k, v := c.Seek(target)
+
+		//Seek lands on the first key at or after the target, so the snapshot in effect is either an exact match or the one before it
+		if k == nil {
+			k, v = c.Last()
+		} else if !bytes.Equal(k, target) {
+			k, v = c.Prev()
+		}
+
+		if k == nil {
+			return ErrNoSnapshot
+		}
+
+		var err error
+		snapshot, err = decodeSnapshot(k, v)
+		return err
+	})
+
+	return snapshot, err
+}
+
+//Range returns every snapshot taken within the inclusive time range, oldest first
+func (s *Store) Range(start, end time.Time) ([]Snapshot, error) {
+	var snapshots []Snapshot
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(snapshotBucket).Cursor()
+		last := timeKey(end)
+
+		for k, v := c.Seek(timeKey(start)); k != nil && bytes.Compare(k, last) <= 0; k, v = c.Next() {
+			snapshot, err := decodeSnapshot(k, v)
+
+			if err != nil {
+				return err
+			}
+
+			snapshots = append(snapshots, snapshot)
+		}
+
+		return nil
+	})
+
+	return snapshots, err
+}
+
+//Count returns the number of snapshots held by the store
+func (s *Store) Count() (int, error) {
+	var count int
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		count = tx.Bucket(snapshotBucket).Stats().KeyN
+		return nil
+	})
+
+	return count, err
+}
+
+//Prune removes the snapshots falling outside of the retention policy, returning how many were removed
+func (s *Store) Prune(policy RetentionPolicy, now time.Time) (int, error) {
+	removed := 0
+
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(snapshotBucket)
+		var doomed [][]byte
+
+		total := b.Stats().KeyN
+		cutoff := timeKey(now.Add(-policy.MaxAge))
+
+		c := b.Cursor()
+		for k, _ := c.First(); k != nil; k, _ = c.Next() {
+			expired := policy.MaxAge > 0 && bytes.Compare(k, cutoff) < 0
+			excess := policy.MaxSnapshots > 0 && total-len(doomed) > policy.MaxSnapshots
+
+			if !expired && !excess {
+				//Keys are ordered by time, so everything after this is newer and within policy
+				break
+			}
+
+			doomed = append(doomed, append([]byte{}, k...))
+		}
+
+		for _, k := range doomed {
+			if err := b.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		removed = len(doomed)
+		return nil
+	})
+
+	return removed, err
+}
+
+//timeKey encodes times as big endian nanoseconds so keys sort chronologically
+func timeKey(t time.Time) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	return key
+}
+
+func encode(ji gogridengine.JobInfo) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	writer := gzip.NewWriter(buffer)
+
+	if err := json.NewEncoder(writer).Encode(ji); err != nil {
+		return nil, err
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func decodeSnapshot(key []byte, value []byte) (Snapshot, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(value))
+
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	content, err := ioutil.ReadAll(reader)
+
+	if err != nil {
+		return Snapshot{}, err
+	}
+
+	snapshot := Snapshot{
+		Time: time.Unix(0, int64(binary.BigEndian.Uint64(key))),
+	}
+
+	err = json.Unmarshal(content, &snapshot.JobInfo)
+
+	return snapshot, err
+}
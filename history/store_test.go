@@ -0,0 +1,180 @@
+package history
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/metrumresearchgroup/gogridengine"
+	"github.com/stretchr/testify/assert"
+)
+
+func openStore(t *testing.T) (*Store, func()) {
+	dir, err := ioutil.TempDir("", "gogridengine-history")
+	assert.Nil(t, err)
+
+	store, err := Open(filepath.Join(dir, "history.db"))
+	assert.Nil(t, err)
+
+	return store, func() {
+		store.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func snapshotWith(jobs ...gogridengine.Job) gogridengine.JobInfo {
+	ji := gogridengine.JobInfo{
+		QueueInfo: gogridengine.QueueInfo{
+			Queues: []gogridengine.Host{
+				{
+					Name:       "all.q@ip-10-0-1-80.ec2.internal",
+					SlotsTotal: 8,
+				},
+			},
+		},
+	}
+
+	for _, j := range jobs {
+		if j.State == "r" {
+			j.QueueName = ji.QueueInfo.Queues[0].Name
+			ji.QueueInfo.Queues[0].JobList = append(ji.QueueInfo.Queues[0].JobList, j)
+			continue
+		}
+		ji.PendingJobs.JobList = append(ji.PendingJobs.JobList, j)
+	}
+
+	return ji
+}
+
+var base = time.Date(2019, 9, 15, 15, 0, 0, 0, time.UTC)
+
+func TestStoreAt(t *testing.T) {
+	store, cleanup := openStore(t)
+	defer cleanup()
+
+	for i := 0; i < 3; i++ {
+		ji := snapshotWith(gogridengine.Job{JBJobNumber: int64(4280 + i), State: "r", Slots: 1})
+		assert.Nil(t, store.Put(base.Add(time.Duration(i)*time.Minute), ji))
+	}
+
+	_, err := store.At(base.Add(-time.Second))
+	assert.Equal(t, ErrNoSnapshot, err)
+
+	snapshot, err := store.At(base)
+	assert.Nil(t, err)
+	assert.True(t, snapshot.Time.Equal(base))
+	assert.Equal(t, int64(4280), snapshot.JobInfo.QueueInfo.Queues[0].JobList[0].JBJobNumber)
+
+	snapshot, err = store.At(base.Add(90 * time.Second))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4281), snapshot.JobInfo.QueueInfo.Queues[0].JobList[0].JBJobNumber)
+
+	snapshot, err = store.At(base.Add(time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4282), snapshot.JobInfo.QueueInfo.Queues[0].JobList[0].JBJobNumber)
+	assert.Equal(t, "all.q@ip-10-0-1-80.ec2.internal", snapshot.JobInfo.QueueInfo.Queues[0].JobList[0].QueueName)
+
+	snapshots, err := store.Range(base.Add(time.Minute), base.Add(time.Hour))
+	assert.Nil(t, err)
+	assert.Len(t, snapshots, 2)
+}
+
+func TestStorePrune(t *testing.T) {
+	store, cleanup := openStore(t)
+	defer cleanup()
+
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, store.Put(base.Add(time.Duration(i)*time.Minute), snapshotWith()))
+	}
+
+	removed, err := store.Prune(RetentionPolicy{MaxAge: 5 * time.Minute}, base.Add(9*time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, 4, removed)
+
+	removed, err = store.Prune(RetentionPolicy{MaxSnapshots: 2}, base.Add(9*time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, 4, removed)
+
+	count, err := store.Count()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, count)
+
+	snapshot, err := store.At(base.Add(time.Hour))
+	assert.Nil(t, err)
+	assert.True(t, snapshot.Time.Equal(base.Add(9*time.Minute)))
+}
+
+func TestStoreJobTimeline(t *testing.T) {
+	store, cleanup := openStore(t)
+	defer cleanup()
+
+	snapshots := []gogridengine.JobInfo{
+		snapshotWith(gogridengine.Job{JBJobNumber: 4282, State: "qw", Slots: 1}),
+		snapshotWith(gogridengine.Job{JBJobNumber: 4282, State: "qw", Slots: 1}, gogridengine.Job{JBJobNumber: 1, State: "r"}),
+		snapshotWith(gogridengine.Job{JBJobNumber: 4282, State: "r", Slots: 1}),
+		snapshotWith(),
+	}
+
+	for k, ji := range snapshots {
+		assert.Nil(t, store.Put(base.Add(time.Duration(k)*time.Minute), ji))
+	}
+
+	timeline, err := store.JobTimeline(4282, base, base.Add(time.Hour))
+	assert.Nil(t, err)
+
+	var types []gogridengine.EventType
+	for _, e := range timeline {
+		types = append(types, e.Event.Type)
+	}
+
+	assert.Equal(t, []gogridengine.EventType{
+		gogridengine.JobSubmitted,
+		gogridengine.JobStateChanged,
+		gogridengine.JobStarted,
+		gogridengine.JobDisappeared,
+	}, types)
+
+	assert.True(t, timeline[2].Time.Equal(base.Add(2*time.Minute)))
+	assert.Equal(t, "all.q@ip-10-0-1-80.ec2.internal", timeline[2].Event.Host)
+}
+
+//staticSource always returns the same content
+type staticSource struct {
+	content string
+}
+
+func (s staticSource) Get() (string, error) {
+	return s.content, nil
+}
+
+func TestRecorderRun(t *testing.T) {
+	store, cleanup := openStore(t)
+	defer cleanup()
+
+	content, err := ioutil.ReadFile("../test_data/small.xml")
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	r := Recorder{
+		Store:     store,
+		Source:    staticSource{content: string(content)},
+		Interval:  5 * time.Millisecond,
+		Retention: RetentionPolicy{MaxSnapshots: 3},
+	}
+
+	err = r.Run(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	count, err := store.Count()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, count)
+
+	snapshot, err := store.At(time.Now())
+	assert.Nil(t, err)
+	assert.Len(t, snapshot.JobInfo.Jobs(), 9)
+}
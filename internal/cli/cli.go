@@ -0,0 +1,205 @@
+//Package cli implements gge, a friendlier qstat built on the library. It lives outside cmd so it can be tested against the simulator.
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/metrumresearchgroup/gogridengine"
+)
+
+//ErrUsage is returned for invalid command lines, after the usage has been printed
+const ErrUsage = gogridengine.Error("Invalid usage")
+
+//App runs gge commands
+type App struct {
+	//Client is used for every command. When nil one is created running the grid engine binaries, reading qstat output from -xml when provided
+	Client *gogridengine.Client
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+//command is a gge subcommand. run receives the arguments following the subcommand name
+type command struct {
+	summary string
+	run     func(a *App, ctx context.Context, args []string) error
+}
+
+var commands = map[string]command{
+	"jobs":    {summary: "List running and pending jobs", run: (*App).jobs},
+	"hosts":   {summary: "List queue instances", run: (*App).hosts},
+	"queues":  {summary: "Summarize slots per cluster queue", run: (*App).queues},
+	"summary": {summary: "Report cluster capacity and job counts", run: (*App).summary},
+	"submit":  {summary: "Submit a job through qsub", run: (*App).submit},
+	"delete":  {summary: "Delete jobs through qdel", run: (*App).delete},
+	"hold":    {summary: "Hold jobs through qhold", run: (*App).hold},
+	"release": {summary: "Release held jobs through qrls", run: (*App).release},
+	"watch":   {summary: "Stream the changes between qstat polls", run: (*App).watch},
+	"top":     {summary: "Monitor hosts and jobs interactively", run: (*App).top},
+}
+
+//Main runs the command line, returning the process exit code: 0 on success, 1 when the command failed and 2 for invalid usage
+func Main(ctx context.Context, args []string, stdout io.Writer, stderr io.Writer) int {
+	a := &App{
+		Stdout: stdout,
+		Stderr: stderr,
+	}
+
+	return a.Run(ctx, args)
+}
+
+//Run runs the command line (without the program name), returning the process exit code
+func (a *App) Run(ctx context.Context, args []string) int {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		a.usage()
+		return 2
+	}
+
+	c, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(a.Stderr, "gge: unknown command %q\n\n", args[0])
+		a.usage()
+		return 2
+	}
+
+	err := c.run(a, ctx, args[1:])
+
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, ErrUsage), errors.Is(err, flag.ErrHelp):
+		return 2
+	default:
+		fmt.Fprintln(a.Stderr, "gge:", err)
+		return 1
+	}
+}
+
+func (a *App) usage() {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(a.Stderr, "Usage: gge <command> [flags]")
+	fmt.Fprintln(a.Stderr)
+	fmt.Fprintln(a.Stderr, "Commands:")
+	for _, name := range names {
+		fmt.Fprintf(a.Stderr, "  %-8s  %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintln(a.Stderr)
+	fmt.Fprintln(a.Stderr, "Run gge <command> -h for the flags of a command")
+}
+
+//options are the flags shared by every command
+type options struct {
+	format  string
+	xml     string
+	timeout time.Duration
+}
+
+//flags creates the flag set of a command, registering the shared flags
+func (a *App) flags(name string, usage string) (*flag.FlagSet, *options) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.Stderr)
+
+	opts := &options{}
+	fs.StringVar(&opts.format, "o", FormatTable, "Output format: table, json, yaml or csv")
+	fs.StringVar(&opts.xml, "xml", "", "Read qstat -xml output from this file instead of running qstat")
+	fs.DurationVar(&opts.timeout, "timeout", 30*time.Second, "How long a grid engine command may run")
+
+	fs.Usage = func() {
+		fmt.Fprintf(a.Stderr, "Usage: gge %s %s\n\n", name, usage)
+		fs.PrintDefaults()
+	}
+
+	return fs, opts
+}
+
+//parse parses the command's flags and validates the shared ones
+func (a *App) parse(fs *flag.FlagSet, opts *options, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return fmt.Errorf("%w: %s", ErrUsage, err)
+	}
+
+	if err := validFormat(opts.format); err != nil {
+		fmt.Fprintln(a.Stderr, err)
+		fs.Usage()
+		return fmt.Errorf("%w: %s", ErrUsage, err)
+	}
+
+	return nil
+}
+
+//usageError prints the command usage along with the problem found with the command line
+func (a *App) usageError(fs *flag.FlagSet, problem string) error {
+	fmt.Fprintln(a.Stderr, problem)
+	fs.Usage()
+
+	return fmt.Errorf("%w: %s", ErrUsage, problem)
+}
+
+func (a *App) client(opts *options) *gogridengine.Client {
+	if a.Client != nil {
+		return a.Client
+	}
+
+	client := gogridengine.NewClient(gogridengine.DefaultRunner)
+	client.Timeout = opts.timeout
+
+	if opts.xml != "" {
+		client.Source = &gogridengine.FileDataSource{Path: opts.xml}
+	}
+
+	return client
+}
+
+//stringsFlag collects every value of a repeatable flag. Values may also be comma separated
+type stringsFlag []string
+
+func (s *stringsFlag) String() string {
+	return strings.Join(*s, ",")
+}
+
+func (s *stringsFlag) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v != "" {
+			*s = append(*s, v)
+		}
+	}
+
+	return nil
+}
+
+//keyValueFlag collects key=value pairs of a repeatable flag. Values may also be comma separated
+type keyValueFlag map[string]string
+
+func (kv keyValueFlag) String() string {
+	pairs := make([]string, 0, len(kv))
+	for k, v := range kv {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, ",")
+}
+
+func (kv keyValueFlag) Set(value string) error {
+	for _, pair := range strings.Split(value, ",") {
+		pieces := strings.SplitN(pair, "=", 2)
+		if len(pieces) != 2 || pieces[0] == "" {
+			return fmt.Errorf("expected key=value, got %q", pair)
+		}
+
+		kv[pieces[0]] = pieces[1]
+	}
+
+	return nil
+}
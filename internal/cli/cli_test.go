@@ -0,0 +1,98 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/metrumresearchgroup/gogridengine"
+	"github.com/metrumresearchgroup/gogridengine/simulator"
+	"github.com/stretchr/testify/assert"
+)
+
+const gigabyte = 1024 * 1024 * 1024
+
+//newTestApp runs commands against a simulated cluster with a running job, a running array task, two pending array tasks and a held job
+func newTestApp(t *testing.T) (*App, *simulator.Cluster, *bytes.Buffer, *bytes.Buffer) {
+	cluster := simulator.New(simulator.Options{
+		Hosts: []simulator.HostSpec{
+			{Name: "node1", Slots: 4, Memory: 16 * gigabyte},
+			{Name: "node2", Slots: 4, Memory: 16 * gigabyte, State: "d"},
+		},
+		Start: time.Date(2019, 12, 18, 14, 0, 0, 0, time.UTC),
+		User:  "darrellb",
+	})
+
+	for _, spec := range []simulator.JobSpec{
+		{Name: "Run1", Owner: "darrellb", Slots: 2},
+		{Name: "Array", Owner: "devinp", Slots: 2, FirstTask: 1, LastTask: 3, TaskStep: 1},
+		{Name: "Held", Owner: "devinp", Hold: true},
+	} {
+		_, err := cluster.Submit(spec)
+		assert.Nil(t, err)
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	return &App{
+		Client: gogridengine.NewClient(cluster),
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}, cluster, &stdout, &stderr
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		code   int
+		stderr string
+	}{
+		{name: "no command", args: nil, code: 2, stderr: "Usage: gge <command>"},
+		{name: "help", args: []string{"help"}, code: 2, stderr: "watch"},
+		{name: "unknown command", args: []string{"qmod"}, code: 2, stderr: `unknown command "qmod"`},
+		{name: "unknown flag", args: []string{"jobs", "-x"}, code: 2, stderr: "Usage: gge jobs"},
+		{name: "command help", args: []string{"hosts", "-h"}, code: 2, stderr: "-available"},
+		{name: "unknown format", args: []string{"jobs", "-o", "xml"}, code: 2, stderr: "Output format must be"},
+		{name: "invalid filter", args: []string{"jobs", "-f", "phase=sleeping"}, code: 2, stderr: "invalid phase"},
+		{name: "unexpected arguments", args: []string{"queues", "all.q"}, code: 2, stderr: "queues takes no arguments"},
+		{name: "top arguments", args: []string{"top", "all.q"}, code: 2, stderr: "top takes no arguments"},
+		{name: "missing jobs", args: []string{"hold"}, code: 2, stderr: "hold requires jobs"},
+		{name: "failed command", args: []string{"hold", "42"}, code: 1, stderr: "qhold failed"},
+		{name: "success", args: []string{"queues"}, code: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, _, _, stderr := newTestApp(t)
+
+			assert.Equal(t, tt.code, app.Run(context.Background(), tt.args))
+
+			if tt.stderr == "" {
+				assert.Empty(t, stderr.String())
+			} else {
+				assert.Contains(t, stderr.String(), tt.stderr)
+			}
+		})
+	}
+}
+
+func TestFlagValues(t *testing.T) {
+	var s stringsFlag
+	assert.Nil(t, s.Set("a,b"))
+	assert.Nil(t, s.Set("c"))
+	assert.Equal(t, stringsFlag{"a", "b", "c"}, s)
+	assert.Equal(t, "a,b,c", s.String())
+
+	kv := keyValueFlag{}
+	assert.Nil(t, kv.Set("b=2,a=1"))
+	assert.Equal(t, "a=1,b=2", kv.String())
+	assert.NotNil(t, kv.Set("novalue"))
+
+	q := queryFlag{}
+	assert.Nil(t, q.Set("name_regex=^a,b"))
+	assert.Equal(t, []string{"^a,b"}, q["name_regex"])
+	assert.True(t, strings.HasPrefix(q.String(), "name_regex="))
+	assert.NotNil(t, q.Set("=value"))
+}
@@ -0,0 +1,553 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/metrumresearchgroup/gogridengine"
+	"github.com/metrumresearchgroup/gogridengine/filters"
+	"github.com/metrumresearchgroup/gogridengine/tui"
+)
+
+//queryFlag collects repeatable key=value filter expressions using the vocabulary of filters.JobQuery
+type queryFlag url.Values
+
+func (q queryFlag) String() string {
+	return url.Values(q).Encode()
+}
+
+func (q queryFlag) Set(value string) error {
+	pieces := strings.SplitN(value, "=", 2)
+	if len(pieces) != 2 || pieces[0] == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+
+	url.Values(q).Add(pieces[0], pieces[1])
+
+	return nil
+}
+
+const filterUsage = "Filter expression key=value, repeatable. Keys: owner, state, state_exact, phase (running, pending, error, other), name (glob), name_regex, queue, host, task, min_priority, max_priority, min_slots, max_slots, submitted_after, submitted_before, started_after, started_before (RFC 3339)"
+
+func (a *App) jobs(ctx context.Context, args []string) error {
+	fs, opts := a.flags("jobs", "[flags]")
+
+	query := queryFlag{}
+	var owners, states stringsFlag
+	fs.Var(query, "f", filterUsage)
+	fs.Var(&owners, "u", "Only list jobs of these owners, repeatable")
+	fs.Var(&states, "s", "Only list jobs in these states (eg: r, qw, E), repeatable")
+	sortKeys := fs.String("sort", "", "Comma separated sort keys, prefixed with - for descending order: job_number, task_id, priority, submit_time, start_time, owner, state")
+	collapse := fs.Bool("collapse", false, "List pending array tasks as their task range, as qstat does, rather than one row per task")
+
+	if err := a.parse(fs, opts, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 0 {
+		return a.usageError(fs, "jobs takes no arguments")
+	}
+
+	values := url.Values(query)
+	values["owner"] = append(values["owner"], owners...)
+	values["state"] = append(values["state"], states...)
+	if *sortKeys != "" {
+		values.Set("sort", *sortKeys)
+	}
+
+	q, err := filters.ParseJobQuery(values)
+	if err != nil {
+		return a.usageError(fs, err.Error())
+	}
+
+	jobs, err := a.client(opts).Jobs()
+	if err != nil {
+		return err
+	}
+
+	jobs = q.Apply(jobs)
+
+	if *collapse {
+		jobs = jobs.CollapseTasks()
+	}
+
+	return write(a.Stdout, opts.format, jobs, jobTable(jobs))
+}
+
+func jobTable(jobs gogridengine.JobList) table {
+	t := table{header: []string{"JOB", "TASK", "PRIORITY", "NAME", "OWNER", "STATE", "SUBMITTED", "STARTED", "QUEUE", "SLOTS"}}
+
+	for _, j := range jobs {
+		task := j.Tasks.Source
+		if j.Tasks.TaskID != 0 {
+			task = strconv.FormatInt(j.Tasks.TaskID, 10)
+		}
+
+		t.add(
+			strconv.FormatInt(j.JBJobNumber, 10),
+			task,
+			strconv.FormatFloat(j.JATPriority, 'f', 5, 64),
+			j.JobName,
+			j.JobOwner,
+			j.State,
+			j.SubmittedTime,
+			j.StartTime,
+			j.QueueName,
+			strconv.Itoa(int(j.Slots)),
+		)
+	}
+
+	return t
+}
+
+func (a *App) hosts(ctx context.Context, args []string) error {
+	fs, opts := a.flags("hosts", "[flags]")
+
+	var queues stringsFlag
+	fs.Var(&queues, "queue", "Only list queue instances of these cluster queues, repeatable")
+	available := fs.Bool("available", false, "Only list queue instances able to accept work")
+	unavailable := fs.Bool("unavailable", false, "Only list queue instances unable to accept work")
+
+	if err := a.parse(fs, opts, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 0 {
+		return a.usageError(fs, "hosts takes no arguments")
+	}
+
+	if *available && *unavailable {
+		return a.usageError(fs, "-available and -unavailable are exclusive")
+	}
+
+	ji, err := a.client(opts).JobInfo()
+	if err != nil {
+		return err
+	}
+
+	hosts := []gogridengine.Host{}
+
+	for _, h := range ji.QueueInfo.Queues {
+		queue, _ := gogridengine.SplitQueueInstance(h.Name)
+		if len(queues) > 0 && !contains(queues, queue) {
+			continue
+		}
+
+		if (*available && !gogridengine.IsHostAvailable(h)) || (*unavailable && gogridengine.IsHostAvailable(h)) {
+			continue
+		}
+
+		hosts = append(hosts, h)
+	}
+
+	return write(a.Stdout, opts.format, hosts, hostTable(hosts))
+}
+
+func hostTable(hosts []gogridengine.Host) table {
+	t := table{header: []string{"QUEUE", "HOST", "STATE", "USED", "RESERVED", "TOTAL", "LOAD", "MEMORY_TOTAL", "MEMORY_FREE", "JOBS"}}
+
+	for _, h := range hosts {
+		queue, hostname := gogridengine.SplitQueueInstance(h.Name)
+
+		t.add(
+			queue,
+			hostname,
+			h.State,
+			strconv.Itoa(int(h.SlotsUsed)),
+			strconv.Itoa(int(h.SlotsReserved)),
+			strconv.Itoa(int(h.SlotsTotal)),
+			strconv.FormatFloat(h.LoadAverage, 'f', 2, 64),
+			storage(h.Resources.TotalMemory()),
+			storage(h.Resources.FreeMemory()),
+			strconv.Itoa(len(h.JobList)),
+		)
+	}
+
+	return t
+}
+
+//storage renders a storage value the way the grid engine reports it, or nothing when the host doesn't report it
+func storage(sv gogridengine.StorageValue, err error) string {
+	if err != nil {
+		return ""
+	}
+
+	return strconv.FormatFloat(sv.Size, 'f', -1, 64) + sv.Scale
+}
+
+func (a *App) queues(ctx context.Context, args []string) error {
+	fs, opts := a.flags("queues", "[flags]")
+
+	if err := a.parse(fs, opts, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 0 {
+		return a.usageError(fs, "queues takes no arguments")
+	}
+
+	ji, err := a.client(opts).JobInfo()
+	if err != nil {
+		return err
+	}
+
+	queues := gogridengine.SummarizeQueues(ji)
+
+	t := table{header: []string{"QUEUE", "INSTANCES", "AVAILABLE", "USED", "RESERVED", "TOTAL", "RUNNING"}}
+	for _, q := range queues {
+		t.add(
+			q.Name,
+			strconv.Itoa(q.QueueInstances),
+			strconv.Itoa(q.Available),
+			strconv.FormatInt(q.SlotsUsed, 10),
+			strconv.FormatInt(q.SlotsReserved, 10),
+			strconv.FormatInt(q.SlotsTotal, 10),
+			strconv.Itoa(q.RunningJobs),
+		)
+	}
+
+	return write(a.Stdout, opts.format, queues, t)
+}
+
+//summaryOutput is what summary writes, matching the /summary response of the api package
+type summaryOutput struct {
+	Cluster gogridengine.ClusterSummary `json:"cluster"`
+	Jobs    gogridengine.JobSummary     `json:"jobs"`
+}
+
+func (a *App) summary(ctx context.Context, args []string) error {
+	fs, opts := a.flags("summary", "[flags]")
+
+	if err := a.parse(fs, opts, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 0 {
+		return a.usageError(fs, "summary takes no arguments")
+	}
+
+	ji, err := a.client(opts).JobInfo()
+	if err != nil {
+		return err
+	}
+
+	summary := summaryOutput{
+		Cluster: gogridengine.NewClusterSummary(ji),
+		Jobs:    ji.Jobs().Summarize(),
+	}
+
+	//The capacity of the cluster heads the table of job groups
+	if opts.format == FormatTable {
+		c := summary.Cluster
+		fmt.Fprintf(a.Stdout, "Hosts: %d (%d queue instances)\n", c.Hosts, c.QueueInstances)
+		fmt.Fprintf(a.Stdout, "Slots: %d used, %d reserved, %d free, %d unavailable of %d\n", c.SlotsUsed, c.SlotsReserved, c.SlotsFree, c.SlotsUnavailable, c.SlotsTotal)
+		fmt.Fprintf(a.Stdout, "Load: %.2f\n\n", c.AverageNPLoad)
+	}
+
+	t := table{header: []string{"GROUP", "NAME", "JOBS", "RUNNING", "PENDING", "ERROR", "SLOTS"}}
+	addGroup := func(group string, name string, g *gogridengine.GroupSummary) {
+		t.add(group, name, strconv.Itoa(g.Jobs), strconv.Itoa(g.Running), strconv.Itoa(g.Pending), strconv.Itoa(g.Error), strconv.FormatInt(g.Slots, 10))
+	}
+
+	addGroup("total", "", &summary.Jobs.Total)
+	for _, name := range sortedKeys(summary.Jobs.ByOwner) {
+		addGroup("owner", name, summary.Jobs.ByOwner[name])
+	}
+	for _, name := range sortedKeys(summary.Jobs.ByQueue) {
+		addGroup("queue", name, summary.Jobs.ByQueue[name])
+	}
+
+	return write(a.Stdout, opts.format, summary, t)
+}
+
+func (a *App) submit(ctx context.Context, args []string) error {
+	fs, opts := a.flags("submit", "[flags] script [args...]")
+
+	resources := keyValueFlag{}
+	environment := keyValueFlag{}
+	var request gogridengine.SubmitRequest
+	fs.StringVar(&request.Name, "N", "", "Job name")
+	fs.StringVar(&request.Queue, "q", "", "Queue to submit to")
+	slots := fs.Int("slots", 0, "Slots to request through the parallel environment")
+	fs.StringVar(&request.ParallelEnvironment, "pe", "", "Parallel environment the slots are requested through. Defaults to smp")
+	fs.StringVar(&request.Memory, "mem", "", "Memory to request (h_vmem, eg: 4G)")
+	fs.StringVar(&request.Runtime, "rt", "", "Runtime limit (h_rt, in seconds or hh:mm:ss)")
+	fs.Var(resources, "l", "Other resource request as key=value, repeatable")
+	fs.StringVar(&request.Tasks, "t", "", "Task range of an array job (eg: 1-10:1)")
+	fs.IntVar(&request.Priority, "p", 0, "Priority, from -1023 to 1024")
+	fs.BoolVar(&request.Hold, "hold", false, "Submit the job held")
+	fs.StringVar(&request.WorkingDirectory, "wd", "", "Working directory of the job")
+	fs.Var(environment, "v", "Environment variable as key=value, repeatable")
+	fs.BoolVar(&request.Binary, "b", false, "Run the script as a command rather than a job script")
+
+	if err := a.parse(fs, opts, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		return a.usageError(fs, "submit requires a script")
+	}
+
+	request.Script = fs.Arg(0)
+	request.Args = fs.Args()[1:]
+	request.Slots = int32(*slots)
+	request.Resources = resources
+	request.Environment = environment
+
+	result, err := a.client(opts).Submit(ctx, request)
+	if err != nil {
+		return err
+	}
+
+	if opts.format == FormatTable {
+		if result.Tasks != "" {
+			fmt.Fprintf(a.Stdout, "Submitted job-array %d.%s\n", result.JobNumber, result.Tasks)
+		} else {
+			fmt.Fprintf(a.Stdout, "Submitted job %d\n", result.JobNumber)
+		}
+
+		return nil
+	}
+
+	t := table{header: []string{"JOB_NUMBER", "TASKS"}}
+	t.add(strconv.FormatInt(result.JobNumber, 10), result.Tasks)
+
+	return write(a.Stdout, opts.format, result, t)
+}
+
+func (a *App) delete(ctx context.Context, args []string) error {
+	fs, opts := a.flags("delete", "[flags] job...")
+
+	var users stringsFlag
+	fs.Var(&users, "u", "Delete every job of these users rather than the jobs listed, repeatable")
+
+	if err := a.parse(fs, opts, args); err != nil {
+		return err
+	}
+
+	if len(users) > 0 {
+		if fs.NArg() > 0 {
+			return a.usageError(fs, "jobs can't be listed along with -u")
+		}
+
+		output, err := a.client(opts).DeleteByUser(ctx, users)
+		return a.action(opts, output, err)
+	}
+
+	if fs.NArg() == 0 {
+		return a.usageError(fs, "delete requires jobs or -u")
+	}
+
+	output, err := a.client(opts).Delete(ctx, fs.Args())
+	return a.action(opts, output, err)
+}
+
+func (a *App) hold(ctx context.Context, args []string) error {
+	return a.jobAction(ctx, "hold", args, func(client *gogridengine.Client) func(ctx context.Context, ids []string) (string, error) {
+		return client.Hold
+	})
+}
+
+func (a *App) release(ctx context.Context, args []string) error {
+	return a.jobAction(ctx, "release", args, func(client *gogridengine.Client) func(ctx context.Context, ids []string) (string, error) {
+		return client.Release
+	})
+}
+
+//jobAction runs a command taking nothing but job identifiers
+func (a *App) jobAction(ctx context.Context, name string, args []string, action func(client *gogridengine.Client) func(ctx context.Context, ids []string) (string, error)) error {
+	fs, opts := a.flags(name, "[flags] job...")
+
+	if err := a.parse(fs, opts, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() == 0 {
+		return a.usageError(fs, name+" requires jobs")
+	}
+
+	output, err := action(a.client(opts))(ctx, fs.Args())
+	return a.action(opts, output, err)
+}
+
+//actionOutput is what delete, hold and release write, matching the responses of the api package
+type actionOutput struct {
+	Output string `json:"output"`
+}
+
+//action writes the output of a grid engine command. The table format passes it through untouched
+func (a *App) action(opts *options, output string, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if opts.format == FormatTable {
+		fmt.Fprint(a.Stdout, output)
+		return nil
+	}
+
+	t := table{header: []string{"OUTPUT"}}
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		t.add(line)
+	}
+
+	return write(a.Stdout, opts.format, actionOutput{Output: output}, t)
+}
+
+//watchEvent is an event written by watch, along with when it was seen
+type watchEvent struct {
+	Time time.Time `json:"time"`
+	gogridengine.Event
+}
+
+func (a *App) watch(ctx context.Context, args []string) error {
+	fs, opts := a.flags("watch", "[flags]")
+
+	query := queryFlag{}
+	fs.Var(query, "f", filterUsage)
+	interval := fs.Duration("interval", 10*time.Second, "Delay between qstat polls")
+	count := fs.Int("count", 0, "Stop after this many successful polls. Zero watches until interrupted")
+
+	if err := a.parse(fs, opts, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 0 {
+		return a.usageError(fs, "watch takes no arguments")
+	}
+
+	q, err := filters.ParseJobQuery(url.Values(query))
+	if err != nil {
+		return a.usageError(fs, err.Error())
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w := gogridengine.Watcher{
+		Source:   a.client(opts).DataSource(),
+		Interval: *interval,
+		Timeout:  opts.timeout,
+		Filter:   q.Matches,
+	}
+
+	header := []string{"TIME", "TYPE", "JOB", "STATE", "HOST"}
+	if opts.format == FormatTable {
+		fmt.Fprintf(a.Stdout, "%-20s  %-18s  %-10s  %-10s  %s\n", header[0], header[1], header[2], header[3], header[4])
+	}
+	if opts.format == FormatCSV {
+		if err := writeCSV(a.Stdout, table{header: header}); err != nil {
+			return err
+		}
+	}
+
+	polls := 0
+
+	for update := range w.Watch(ctx) {
+		if update.Err != nil {
+			fmt.Fprintln(a.Stderr, "gge: polling qstat failed:", update.Err)
+			continue
+		}
+
+		for _, e := range update.Events {
+			if err := a.writeEvent(opts.format, watchEvent{Time: update.Time, Event: e}); err != nil {
+				return err
+			}
+		}
+
+		polls++
+		if *count > 0 && polls >= *count {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+func (a *App) top(ctx context.Context, args []string) error {
+	fs, opts := a.flags("top", "[flags]")
+
+	interval := fs.Duration("interval", 10*time.Second, "Delay between qstat polls")
+	filter := fs.String("f", "", "Initial job filter, as typed at the / prompt: key=value terms using the keys of jobs -f, or words matched against job id, name, owner, state and queue")
+
+	if err := a.parse(fs, opts, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() > 0 {
+		return a.usageError(fs, "top takes no arguments")
+	}
+
+	screen, err := tcell.NewScreen()
+	if err != nil {
+		return err
+	}
+
+	if err := screen.Init(); err != nil {
+		return err
+	}
+	defer screen.Fini()
+
+	return tui.New(screen, a.client(opts), tui.Options{
+		Interval: *interval,
+		Timeout:  opts.timeout,
+		Filter:   *filter,
+	}).Run(ctx)
+}
+
+//writeEvent writes a single event as soon as it is seen: a line of the table, a JSON line, a YAML document or a CSV row
+func (a *App) writeEvent(format string, e watchEvent) error {
+	job := ""
+	if e.Key.JobNumber != 0 {
+		job = e.Key.String()
+	}
+
+	state := e.State
+	if e.PreviousState != "" {
+		state = e.PreviousState + "->" + e.State
+	}
+
+	timestamp := e.Time.Format(time.RFC3339)
+
+	switch format {
+	case FormatJSON:
+		return json.NewEncoder(a.Stdout).Encode(e)
+	case FormatYAML:
+		fmt.Fprintln(a.Stdout, "---")
+		return writeYAML(a.Stdout, e)
+	case FormatCSV:
+		return writeCSV(a.Stdout, table{rows: [][]string{{timestamp, string(e.Type), job, state, e.Host}}})
+	default:
+		line := fmt.Sprintf("%-20s  %-18s  %-10s  %-10s  %s", timestamp, e.Type, job, state, e.Host)
+		_, err := fmt.Fprintln(a.Stdout, strings.TrimRight(line, " "))
+		return err
+	}
+}
+
+func contains(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func sortedKeys(groups map[string]*gogridengine.GroupSummary) []string {
+	keys := make([]string, 0, len(groups))
+	for k := range groups {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,225 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/metrumresearchgroup/gogridengine"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobs(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "table",
+			args: []string{"jobs"},
+			want: `JOB  TASK  PRIORITY  NAME   OWNER     STATE  SUBMITTED            STARTED              QUEUE        SLOTS
+1          0.49976   Run1   darrellb  r                           2019-12-18T14:00:00  all.q@node1  2
+2    1     0.49976   Array  devinp    r                           2019-12-18T14:00:00  all.q@node1  2
+2    2     0.49976   Array  devinp    qw     2019-12-18T14:00:00                                    2
+2    3     0.49976   Array  devinp    qw     2019-12-18T14:00:00                                    2
+3          0.49976   Held   devinp    hqw    2019-12-18T14:00:00                                    1
+`,
+		},
+		{
+			name: "collapsed task ranges",
+			args: []string{"jobs", "-collapse", "-f", "phase=pending", "-o", "csv"},
+			want: `job,task,priority,name,owner,state,submitted,started,queue,slots
+2,2-3:1,0.49976,Array,devinp,qw,2019-12-18T14:00:00,,,2
+3,,0.49976,Held,devinp,hqw,2019-12-18T14:00:00,,,1
+`,
+		},
+		{
+			name: "filtered and sorted",
+			args: []string{"jobs", "-u", "devinp", "-s", "qw", "-sort", "-job_number,-task_id", "-o", "csv"},
+			want: `job,task,priority,name,owner,state,submitted,started,queue,slots
+3,,0.49976,Held,devinp,hqw,2019-12-18T14:00:00,,,1
+2,3,0.49976,Array,devinp,qw,2019-12-18T14:00:00,,,2
+2,2,0.49976,Array,devinp,qw,2019-12-18T14:00:00,,,2
+`,
+		},
+		{
+			name: "nothing matching",
+			args: []string{"jobs", "-u", "nobody", "-o", "json"},
+			want: "[]\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, _, stdout, _ := newTestApp(t)
+
+			assert.Equal(t, 0, app.Run(context.Background(), tt.args))
+			assert.Equal(t, tt.want, stdout.String())
+		})
+	}
+}
+
+func TestJobsJSON(t *testing.T) {
+	app, _, stdout, _ := newTestApp(t)
+
+	assert.Equal(t, 0, app.Run(context.Background(), []string{"jobs", "-o", "json", "-f", "name=Run*"}))
+
+	var jobs gogridengine.JobList
+	assert.Nil(t, json.Unmarshal(stdout.Bytes(), &jobs))
+	assert.Len(t, jobs, 1)
+	assert.Equal(t, "all.q@node1", jobs[0].QueueName)
+}
+
+func TestHosts(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			name: "table",
+			args: []string{"hosts"},
+			want: `QUEUE  HOST   STATE  USED  RESERVED  TOTAL  LOAD  MEMORY_TOTAL  MEMORY_FREE  JOBS
+all.q  node1         4     0         4      4.00  16G           16G          2
+all.q  node2  d      0     0         4      0.00  16G           16G          0
+`,
+		},
+		{
+			name: "unavailable",
+			args: []string{"hosts", "-unavailable", "-o", "csv"},
+			want: `queue,host,state,used,reserved,total,load,memory_total,memory_free,jobs
+all.q,node2,d,0,0,4,0.00,16G,16G,0
+`,
+		},
+		{
+			name: "other queue",
+			args: []string{"hosts", "-queue", "gpu.q", "-o", "json"},
+			want: "[]\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, _, stdout, _ := newTestApp(t)
+
+			assert.Equal(t, 0, app.Run(context.Background(), tt.args))
+			assert.Equal(t, tt.want, stdout.String())
+		})
+	}
+}
+
+func TestQueuesAndSummary(t *testing.T) {
+	app, _, stdout, _ := newTestApp(t)
+
+	assert.Equal(t, 0, app.Run(context.Background(), []string{"queues"}))
+	assert.Equal(t, `QUEUE  INSTANCES  AVAILABLE  USED  RESERVED  TOTAL  RUNNING
+all.q  2          1          4     0         8      2
+`, stdout.String())
+
+	stdout.Reset()
+	assert.Equal(t, 0, app.Run(context.Background(), []string{"summary"}))
+	assert.Equal(t, `Hosts: 2 (2 queue instances)
+Slots: 4 used, 0 reserved, 0 free, 4 unavailable of 8
+Load: 0.50
+
+GROUP  NAME      JOBS  RUNNING  PENDING  ERROR  SLOTS
+total            5     2        3        0      9
+owner  darrellb  1     1        0        0      2
+owner  devinp    4     1        3        0      7
+queue  all.q     2     2        0        0      4
+`, stdout.String())
+
+	stdout.Reset()
+	assert.Equal(t, 0, app.Run(context.Background(), []string{"summary", "-o", "yaml"}))
+	assert.Contains(t, stdout.String(), "cluster:\n  average_np_load: 0.5\n")
+	assert.Contains(t, stdout.String(), "  slots_total: 8\n")
+}
+
+func TestSubmit(t *testing.T) {
+	app, cluster, stdout, _ := newTestApp(t)
+
+	assert.Equal(t, 0, app.Run(context.Background(), []string{"submit", "-N", "model", "-slots", "2", "-mem", "1G", "-v", "SIM_RUNTIME=60s", "run.sh", "--fast"}))
+	assert.Equal(t, "Submitted job 4\n", stdout.String())
+
+	stdout.Reset()
+	assert.Equal(t, 0, app.Run(context.Background(), []string{"submit", "-o", "json", "-t", "1-4:2", "-hold", "run.sh"}))
+	assert.JSONEq(t, `{"job_number": 5, "tasks": "1-4:2"}`, stdout.String())
+
+	stdout.Reset()
+	assert.Equal(t, 0, app.Run(context.Background(), []string{"submit", "-o", "csv", "run.sh"}))
+	assert.Equal(t, "job_number,tasks\n6,\n", stdout.String())
+
+	assert.Equal(t, []int64{1, 2, 3, 4, 5, 6}, cluster.Jobs())
+	assert.Equal(t, 2, app.Run(context.Background(), []string{"submit", "-N", "model"}))
+	assert.Equal(t, 1, app.Run(context.Background(), []string{"submit", "-mem", "lots", "run.sh"}))
+}
+
+func TestActions(t *testing.T) {
+	app, cluster, stdout, _ := newTestApp(t)
+
+	assert.Equal(t, 0, app.Run(context.Background(), []string{"release", "3"}))
+	assert.Equal(t, "modified hold of job 3\n", stdout.String())
+
+	stdout.Reset()
+	assert.Equal(t, 0, app.Run(context.Background(), []string{"hold", "-o", "yaml", "3"}))
+	assert.Equal(t, "output: |\n  modified hold of job 3\n", stdout.String())
+
+	stdout.Reset()
+	assert.Equal(t, 0, app.Run(context.Background(), []string{"delete", "-o", "json", "1"}))
+
+	var response actionOutput
+	assert.Nil(t, json.Unmarshal(stdout.Bytes(), &response))
+	assert.Equal(t, "darrellb has registered the job 1 for deletion\n", response.Output)
+
+	assert.Equal(t, 2, app.Run(context.Background(), []string{"delete"}))
+	assert.Equal(t, 2, app.Run(context.Background(), []string{"delete", "-u", "devinp", "2"}))
+
+	stdout.Reset()
+	assert.Equal(t, 0, app.Run(context.Background(), []string{"delete", "-u", "devinp", "-o", "csv"}))
+	assert.True(t, strings.HasPrefix(stdout.String(), "output\n"))
+	assert.Empty(t, cluster.Jobs())
+}
+
+func TestWatch(t *testing.T) {
+	app, _, stdout, _ := newTestApp(t)
+
+	assert.Equal(t, 0, app.Run(context.Background(), []string{"watch", "-count", "1", "-o", "json", "-f", "owner=devinp"}))
+
+	var types []string
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		var e watchEvent
+		assert.Nil(t, json.Unmarshal(scanner.Bytes(), &e))
+		assert.False(t, e.Time.IsZero())
+		types = append(types, string(e.Type)+" "+e.Key.String())
+	}
+
+	assert.Equal(t, []string{
+		"host_added 0",
+		"host_added 0",
+		"job_submitted 2.1",
+		"job_started 2.1",
+		"job_submitted 2.2",
+		"job_submitted 2.3",
+		"job_submitted 3",
+	}, types)
+
+	stdout.Reset()
+	assert.Equal(t, 0, app.Run(context.Background(), []string{"watch", "-count", "1", "-o", "csv", "-f", "owner=darrellb"}))
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	assert.Equal(t, "time,type,job,state,host", lines[0])
+	assert.Len(t, lines, 5)
+	assert.True(t, strings.HasSuffix(lines[3], ",job_submitted,1,r,all.q@node1"))
+}
+
+func TestWatchCancelled(t *testing.T) {
+	app, _, stdout, _ := newTestApp(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	assert.Equal(t, 0, app.Run(ctx, []string{"watch"}))
+	assert.True(t, strings.HasPrefix(stdout.String(), "TIME"))
+}
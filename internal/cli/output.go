@@ -0,0 +1,141 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/metrumresearchgroup/gogridengine"
+	"gopkg.in/yaml.v2"
+)
+
+//Output formats selected with -o
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatYAML  = "yaml"
+	FormatCSV   = "csv"
+)
+
+//ErrUnknownFormat is returned for -o values naming no output format
+const ErrUnknownFormat = gogridengine.Error("Output format must be one of table, json, yaml or csv")
+
+//table is the tabular rendering of a command's result, used for the table and csv formats
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+func (t *table) add(row ...string) {
+	t.rows = append(t.rows, row)
+}
+
+func validFormat(format string) error {
+	switch format {
+	case FormatTable, FormatJSON, FormatYAML, FormatCSV:
+		return nil
+	}
+
+	return ErrUnknownFormat
+}
+
+//write renders the result in the requested format. Structured formats encode the value itself, tabular ones the table
+func write(w io.Writer, format string, value interface{}, t table) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	case FormatYAML:
+		return writeYAML(w, value)
+	case FormatCSV:
+		return writeCSV(w, t)
+	default:
+		return writeTable(w, t)
+	}
+}
+
+func writeTable(w io.Writer, t table) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, strings.Join(t.header, "\t"))
+	for _, row := range t.rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+
+	return tw.Flush()
+}
+
+//writeCSV writes the table with a lower case header, as a machine readable header is expected. Tables without a header only write their rows
+func writeCSV(w io.Writer, t table) error {
+	cw := csv.NewWriter(w)
+
+	if t.header != nil {
+		header := make([]string, len(t.header))
+		for k, h := range t.header {
+			header[k] = strings.ToLower(h)
+		}
+
+		if err := cw.Write(header); err != nil {
+			return err
+		}
+	}
+
+	if err := cw.WriteAll(t.rows); err != nil {
+		return err
+	}
+
+	return cw.Error()
+}
+
+//writeYAML encodes the value as YAML using its JSON field names, so every format names fields the same way
+func writeYAML(w io.Writer, value interface{}) error {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	var generic interface{}
+	if err := decoder.Decode(&generic); err != nil {
+		return err
+	}
+
+	out, err := yaml.Marshal(numbers(generic))
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(out)
+
+	return err
+}
+
+//numbers replaces the json.Numbers of a decoded value with integers or floats, which would otherwise be quoted as strings in YAML
+func numbers(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = numbers(item)
+		}
+	case []interface{}:
+		for k, item := range v {
+			v[k] = numbers(item)
+		}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+
+		f, _ := v.Float64()
+		return f
+	}
+
+	return value
+}
@@ -0,0 +1,59 @@
+package cli
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWrite(t *testing.T) {
+	value := []struct {
+		Name  string  `json:"name"`
+		Slots int64   `json:"slots"`
+		Load  float64 `json:"load"`
+	}{
+		{Name: "node1", Slots: 16000000000, Load: 0.5},
+		{Name: "node,2", Slots: 4, Load: 1},
+	}
+
+	tab := table{header: []string{"NAME", "SLOTS"}}
+	tab.add("node1", "16000000000")
+	tab.add("node,2", "4")
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: FormatTable,
+			want:   "NAME    SLOTS\nnode1   16000000000\nnode,2  4\n",
+		},
+		{
+			format: FormatCSV,
+			want:   "name,slots\nnode1,16000000000\n\"node,2\",4\n",
+		},
+		{
+			format: FormatJSON,
+			want:   "[\n  {\n    \"name\": \"node1\",\n    \"slots\": 16000000000,\n    \"load\": 0.5\n  },\n  {\n    \"name\": \"node,2\",\n    \"slots\": 4,\n    \"load\": 1\n  }\n]\n",
+		},
+		{
+			//Numbers aren't quoted or turned into exponents, and fields keep their JSON names
+			format: FormatYAML,
+			want:   "- load: 0.5\n  name: node1\n  slots: 16000000000\n- load: 1\n  name: node,2\n  slots: 4\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			assert.Nil(t, write(&buf, tt.format, value, tab))
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestValidFormat(t *testing.T) {
+	assert.Nil(t, validFormat(FormatYAML))
+	assert.Equal(t, ErrUnknownFormat, validFormat("xml"))
+}
@@ -0,0 +1,12 @@
+//Command fake-qdel is a stand-in for the grid engine qdel binary, backed by the simulator state shared with the other fake binaries. Install it as qdel, see internal/fakegrid for the environment it honours.
+package main
+
+import (
+	"os"
+
+	"github.com/metrumresearchgroup/gogridengine/internal/fakegrid"
+)
+
+func main() {
+	os.Exit(fakegrid.Main("qdel", os.Args[1:], os.Stdout, os.Stderr))
+}
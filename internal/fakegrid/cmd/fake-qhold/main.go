@@ -0,0 +1,12 @@
+//Command fake-qhold is a stand-in for the grid engine qhold binary, backed by the simulator state shared with the other fake binaries. Install it as qhold, see internal/fakegrid for the environment it honours.
+package main
+
+import (
+	"os"
+
+	"github.com/metrumresearchgroup/gogridengine/internal/fakegrid"
+)
+
+func main() {
+	os.Exit(fakegrid.Main("qhold", os.Args[1:], os.Stdout, os.Stderr))
+}
@@ -0,0 +1,12 @@
+//Command fake-qrls is a stand-in for the grid engine qrls binary, backed by the simulator state shared with the other fake binaries. Install it as qrls, see internal/fakegrid for the environment it honours.
+package main
+
+import (
+	"os"
+
+	"github.com/metrumresearchgroup/gogridengine/internal/fakegrid"
+)
+
+func main() {
+	os.Exit(fakegrid.Main("qrls", os.Args[1:], os.Stdout, os.Stderr))
+}
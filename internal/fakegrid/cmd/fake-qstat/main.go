@@ -0,0 +1,12 @@
+//Command fake-qstat is a stand-in for the grid engine qstat binary, backed by the simulator state shared with the other fake binaries. Install it as qstat, see internal/fakegrid for the environment it honours.
+package main
+
+import (
+	"os"
+
+	"github.com/metrumresearchgroup/gogridengine/internal/fakegrid"
+)
+
+func main() {
+	os.Exit(fakegrid.Main("qstat", os.Args[1:], os.Stdout, os.Stderr))
+}
@@ -0,0 +1,240 @@
+//Package fakegrid backs the stand-in grid engine binaries in cmd/, named fake-qstat and so on so installing the module never shadows the real binaries. Every invocation loads the simulated cluster from a state file, runs the command against it and saves it back,
+//so a set of binaries on the PATH behaves like a single cluster across processes.
+package fakegrid
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/metrumresearchgroup/gogridengine"
+	"github.com/metrumresearchgroup/gogridengine/simulator"
+)
+
+const (
+	//StateVariable is the path of the state file shared by the binaries. Defaults to gogridengine-sim.json in the temporary directory
+	StateVariable string = "GOGRIDENGINE_SIM_STATE"
+	//XMLVariable points qstat at a file of qstat -xml output to print instead of the simulated cluster
+	XMLVariable string = "GOGRIDENGINE_SIM_XML"
+	//HostsVariable describes the hosts of a new cluster as count:slots:memory. Defaults to 4:8:16G
+	HostsVariable string = "GOGRIDENGINE_SIM_HOSTS"
+	//UserVariable is the owner of submitted jobs. Defaults to USER
+	UserVariable string = "GOGRIDENGINE_SIM_USER"
+	//ClockVariable is either wall (simulated time follows the wall clock, the default) or frozen (time only moves when explicitly advanced)
+	ClockVariable string = "GOGRIDENGINE_SIM_CLOCK"
+)
+
+//ErrStateLocked is returned when the state file stays locked by another invocation for too long
+const ErrStateLocked = gogridengine.Error("Timed out waiting for the simulator state lock")
+
+//lockTimeout bounds how long an invocation waits for concurrent ones to finish
+var lockTimeout = 10 * time.Second
+
+//staleLockAge is how old a lock file must be to have been left behind by an invocation which was killed, as invocations only hold it for a few milliseconds
+var staleLockAge = 5 * time.Second
+
+//Main runs the named grid engine command with its arguments, writing output like the real binary would and returning the exit code
+func Main(name string, args []string, stdout io.Writer, stderr io.Writer) int {
+	if name == "qstat" && os.Getenv(XMLVariable) != "" {
+		return staticQstat(os.Getenv(XMLVariable), stdout, stderr)
+	}
+
+	path := StatePath()
+
+	unlock, err := lock(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %s\n", err)
+		return 1
+	}
+	defer unlock()
+
+	cluster, err := loadCluster(path)
+	if err != nil {
+		fmt.Fprintf(stderr, "error: unable to load the simulator state from %s: %s\n", path, err)
+		return 1
+	}
+
+	if os.Getenv(ClockVariable) != "frozen" {
+		cluster.AdvanceTo(time.Now())
+	}
+
+	result, _ := cluster.Run(context.Background(), name, args...)
+
+	stdout.Write(result.Stdout)
+	stderr.Write(result.Stderr)
+
+	if err := saveCluster(path, cluster); err != nil {
+		fmt.Fprintf(stderr, "error: unable to save the simulator state to %s: %s\n", path, err)
+		return 1
+	}
+
+	return result.ExitCode
+}
+
+//StatePath returns the state file used by the binaries
+func StatePath() string {
+	if path := os.Getenv(StateVariable); path != "" {
+		return path
+	}
+
+	return filepath.Join(os.TempDir(), "gogridengine-sim.json")
+}
+
+//NewCluster creates the cluster used when no state file exists yet, shaped by the environment
+func NewCluster() (*simulator.Cluster, error) {
+	hosts := os.Getenv(HostsVariable)
+	if hosts == "" {
+		hosts = "4:8:16G"
+	}
+
+	pieces := strings.Split(hosts, ":")
+	if len(pieces) != 3 {
+		return nil, fmt.Errorf("%s must be in the form count:slots:memory, got %s", HostsVariable, hosts)
+	}
+
+	count, err := strconv.Atoi(pieces[0])
+	if err != nil {
+		return nil, err
+	}
+
+	slots, err := strconv.ParseInt(pieces[1], 10, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	memory, err := gogridengine.ParseStorageValue(pieces[2])
+	if err != nil {
+		return nil, err
+	}
+
+	user := os.Getenv(UserVariable)
+	if user == "" {
+		user = os.Getenv("USER")
+	}
+
+	opts := simulator.Options{
+		User: user,
+	}
+
+	for i := 0; i < count; i++ {
+		opts.Hosts = append(opts.Hosts, simulator.HostSpec{
+			Name:   fmt.Sprintf("node%d", i+1),
+			Slots:  int32(slots),
+			Memory: memory.Bytes,
+		})
+	}
+
+	return simulator.New(opts), nil
+}
+
+func staticQstat(path string, stdout io.Writer, stderr io.Writer) int {
+	content, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		fmt.Fprintf(stderr, "error: %s\n", err)
+		return 1
+	}
+
+	stdout.Write(content)
+
+	return 0
+}
+
+func loadCluster(path string) (*simulator.Cluster, error) {
+	file, err := os.Open(path)
+
+	if os.IsNotExist(err) {
+		return NewCluster()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return simulator.Load(file)
+}
+
+//saveCluster writes to a temporary file first so a failed write never leaves a truncated state behind
+func saveCluster(path string, cluster *simulator.Cluster) error {
+	temporary := path + ".tmp"
+
+	file, err := os.Create(temporary)
+	if err != nil {
+		return err
+	}
+
+	if err := cluster.Save(file); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(temporary, path)
+}
+
+//lock takes an exclusive lock file beside the state so concurrent invocations don't lose each other's changes
+func lock(path string) (func(), error) {
+	lockPath := path + ".lock"
+	deadline := time.Now().Add(lockTimeout)
+
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if removeStaleLock(lockPath) {
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, ErrStateLocked
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+//removeStaleLock removes the lock file when it is older than staleLockAge, reporting whether it did.
+//The lock is moved aside before being removed, so a waiter which found it stale at the same time can't remove the lock just taken by another.
+func removeStaleLock(lockPath string) bool {
+	info, err := os.Stat(lockPath)
+
+	if err != nil || time.Since(info.ModTime()) < staleLockAge {
+		return false
+	}
+
+	aside := fmt.Sprintf("%s.%d", lockPath, os.Getpid())
+
+	if err := os.Rename(lockPath, aside); err != nil {
+		return false
+	}
+	defer os.Remove(aside)
+
+	moved, err := os.Stat(aside)
+
+	if err == nil && os.SameFile(info, moved) {
+		return true
+	}
+
+	//Another waiter replaced the stale lock in the meantime, give it back
+	os.Link(aside, lockPath)
+
+	return false
+}
@@ -0,0 +1,245 @@
+package fakegrid
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/metrumresearchgroup/gogridengine"
+	"github.com/stretchr/testify/assert"
+)
+
+//useState points the binaries at a fresh state file with a frozen clock, returning a function restoring the environment
+func useState(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "fakegrid")
+	assert.Nil(t, err)
+
+	path := filepath.Join(dir, "state.json")
+
+	os.Setenv(StateVariable, path)
+	os.Setenv(ClockVariable, "frozen")
+	os.Setenv(HostsVariable, "2:4:8G")
+	os.Setenv(UserVariable, "darrellb")
+
+	return path, func() {
+		for _, v := range []string{StateVariable, ClockVariable, HostsVariable, UserVariable, XMLVariable} {
+			os.Unsetenv(v)
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func invoke(name string, args ...string) (string, string, int) {
+	var stdout, stderr bytes.Buffer
+	code := Main(name, args, &stdout, &stderr)
+
+	return stdout.String(), stderr.String(), code
+}
+
+func TestMainSharesStateAcrossInvocations(t *testing.T) {
+	path, restore := useState(t)
+	defer restore()
+
+	out, _, code := invoke("qsub", "-N", "model", "-pe", "smp", "4", "run.sh")
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "Your job 1 (\"model\") has been submitted\n", out)
+
+	out, _, _ = invoke("qsub", "-terse", "-t", "1-6", "run.sh")
+	assert.Equal(t, "2.1-6:1\n", out)
+
+	_, err := os.Stat(path)
+	assert.Nil(t, err)
+
+	out, _, code = invoke("qstat", "-u", "*", "-F", "-xml")
+	assert.Equal(t, 0, code)
+
+	ji, err := gogridengine.NewJobInfo(out)
+	assert.Nil(t, err)
+	assert.Len(t, ji.QueueInfo.Queues, 2)
+	assert.Equal(t, "all.q@node1", ji.QueueInfo.Queues[0].Name)
+	assert.Len(t, ji.Jobs(), 7)
+	assert.Len(t, ji.PendingJobs.JobList, 2)
+
+	out, _, _ = invoke("qhold", "2")
+	assert.Equal(t, "modified hold of job 2\n", out)
+
+	out, _, _ = invoke("qdel", "1")
+	assert.Equal(t, "darrellb has registered the job 1 for deletion\n", out)
+
+	_, stderr, code := invoke("qdel", "42")
+	assert.Equal(t, 1, code)
+	assert.Equal(t, "denied: job \"42\" does not exist\n", stderr)
+
+	out, _, code = invoke("qacct", "-j", "1")
+	assert.Equal(t, 0, code)
+	records, err := gogridengine.ParseAccounting(out)
+	assert.Nil(t, err)
+	assert.Equal(t, 137, records[0].ExitStatus)
+}
+
+func TestStaticQstat(t *testing.T) {
+	_, restore := useState(t)
+	defer restore()
+
+	os.Setenv(XMLVariable, "../../test_data/small.xml")
+	out, _, code := invoke("qstat", "-u", "*", "-F", "-xml")
+	assert.Equal(t, 0, code)
+
+	expected, _ := ioutil.ReadFile("../../test_data/small.xml")
+	assert.Equal(t, string(expected), out)
+
+	os.Setenv(XMLVariable, "missing.xml")
+	_, stderr, code := invoke("qstat", "-xml")
+	assert.Equal(t, 1, code)
+	assert.NotEmpty(t, stderr)
+}
+
+func TestNewCluster(t *testing.T) {
+	tests := []struct {
+		name    string
+		hosts   string
+		wantErr bool
+	}{
+		{
+			name:  "default",
+			hosts: "",
+		},
+		{
+			name:  "custom",
+			hosts: "3:2:4G",
+		},
+		{
+			name:    "malformed",
+			hosts:   "3:2",
+			wantErr: true,
+		},
+		{
+			name:    "bad memory",
+			hosts:   "3:2:lots",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv(HostsVariable, tt.hosts)
+			defer os.Unsetenv(HostsVariable)
+
+			c, err := NewCluster()
+			assert.Equal(t, tt.wantErr, err != nil)
+
+			if err == nil {
+				ji, err := c.Snapshot()
+				assert.Nil(t, err)
+				assert.NotEmpty(t, ji.QueueInfo.Queues)
+			}
+		})
+	}
+}
+
+func TestLockTimesOut(t *testing.T) {
+	path, restore := useState(t)
+	defer restore()
+
+	original := lockTimeout
+	lockTimeout = 50 * time.Millisecond
+	defer func() { lockTimeout = original }()
+
+	unlock, err := lock(path)
+	assert.Nil(t, err)
+
+	_, _, code := invoke("qstat", "-xml")
+	assert.Equal(t, 1, code)
+
+	_, err = lock(path)
+	assert.Equal(t, ErrStateLocked, err)
+
+	unlock()
+	_, _, code = invoke("qstat", "-xml")
+	assert.Equal(t, 0, code)
+}
+
+func TestStaleLock(t *testing.T) {
+	path, restore := useState(t)
+	defer restore()
+
+	original := lockTimeout
+	lockTimeout = 50 * time.Millisecond
+	defer func() { lockTimeout = original }()
+
+	//A lock left behind by a killed invocation
+	file, err := os.Create(path + ".lock")
+	assert.Nil(t, err)
+	file.Close()
+
+	_, err = lock(path)
+	assert.Equal(t, ErrStateLocked, err)
+
+	old := time.Now().Add(-2 * staleLockAge)
+	assert.Nil(t, os.Chtimes(path+".lock", old, old))
+
+	_, _, code := invoke("qstat", "-xml")
+	assert.Equal(t, 0, code)
+
+	_, err = os.Stat(path + ".lock")
+	assert.True(t, os.IsNotExist(err))
+
+	leftovers, err := filepath.Glob(path + ".lock.*")
+	assert.Nil(t, err)
+	assert.Empty(t, leftovers)
+}
+
+//TestExecPath builds the binaries and drives them through the library's real exec path
+func TestExecPath(t *testing.T) {
+	if testing.Short() {
+		t.Skip("building the stand-in binaries is skipped in short mode")
+	}
+
+	goBinary, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("the go toolchain is required to build the stand-in binaries")
+	}
+
+	_, restore := useState(t)
+	defer restore()
+
+	bin := filepath.Dir(os.Getenv(StateVariable))
+	for _, name := range []string{"qstat", "qsub", "qdel"} {
+		build := exec.Command(goBinary, "build", "-o", filepath.Join(bin, name), "./cmd/fake-"+name)
+		output, err := build.CombinedOutput()
+		assert.Nil(t, err, string(output))
+	}
+
+	originalPath := os.Getenv("PATH")
+	os.Setenv("PATH", bin+string(os.PathListSeparator)+originalPath)
+	defer os.Setenv("PATH", originalPath)
+
+	originalTest := os.Getenv("GOGRIDENGINE_TEST")
+	os.Unsetenv("GOGRIDENGINE_TEST")
+	defer os.Setenv("GOGRIDENGINE_TEST", originalTest)
+
+	submit := exec.Command("qsub", "-N", "integration", "-t", "1-3", "run.sh")
+	output, err := submit.Output()
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(string(output), "Your job-array 1.1-3:1"))
+
+	jobs, err := gogridengine.GetJobs()
+	assert.Nil(t, err)
+	assert.Len(t, jobs, 3)
+	assert.Equal(t, "integration", jobs[0].JobName)
+
+	output2, err := gogridengine.DeleteQueuedJobByID([]string{"1"})
+	assert.Nil(t, err)
+	assert.Equal(t, "darrellb has registered the job 1 for deletion\n", output2)
+
+	_, err = gogridengine.DeleteQueuedJobByID([]string{"42"})
+	assert.NotNil(t, err)
+
+	jobs, err = gogridengine.GetJobs()
+	assert.Nil(t, err)
+	assert.Empty(t, jobs)
+}
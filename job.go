@@ -2,6 +2,7 @@ package gogridengine
 
 import (
 	"encoding/xml"
+	"fmt"
 	"sort"
 	"strconv"
 	"strings"
@@ -15,6 +16,8 @@ type Error string
 func (e Error) Error() string { return string(e) }
 
 const (
+	//ISO8601FMT is the layout qstat uses for JAT_start_time and JB_submission_time
+	ISO8601FMT string = "2006-01-02T15:04:05"
 	//TASKRANGEIDENTIFIERREGEX is a regex string used for identifying whether <tasks> objects indicate a range of tasks (normally only expressed on pending tasks)
 	TASKRANGEIDENTIFIERREGEX string = `[0-9]{1,}-[0-9]{1,}:[0-9]`
 )
@@ -22,6 +25,9 @@ const (
 //ErrInvalidTaskRangeIdentifier is an error that identifies jobs with a non-range conformant task attribute. Basically means you're trying to extrapolate jobs from a task range that isn't really a task range.
 const ErrInvalidTaskRangeIdentifier = Error("The provided job does not actually indicate a range or group of tasks")
 
+//ErrNoMemoryRequest is returned when a job didn't request memory as part of its hard resource requests
+const ErrNoMemoryRequest = Error("The job does not have a memory hard request")
+
 //Task is an element used for handling task arrays from the grid engine. Here we'll store the raw value (Source) and the TaskID if an individual identifier.
 type Task struct {
 	//Mixed type. Can be either a string representation of an int64 OR a string range identifier, eg: 40-55:1 (Jobs 40-55 incremented by 1)
@@ -38,7 +44,7 @@ func (t *Task) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 
 	t.Source = v
 
-	if !strings.Contains(t.Source, ":") {
+	if t.Source != "" && !strings.Contains(t.Source, ":") && !strings.Contains(t.Source, ",") {
 		//Only process TaskIDs when not presented with a ":"
 		parsed, err := strconv.ParseInt(t.Source, 10, 64)
 
@@ -53,19 +59,16 @@ func (t *Task) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
 	return nil
 }
 
-//MarshalXML renders the value back down to the XML structure
+//MarshalXML renders the value back down to the XML structure: the task when known (including one extrapolated from a range), otherwise the source as read, such as a task range or task 0. Jobs without tasks have no element at all
 func (t *Task) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
-	if strings.Contains(t.Source, ":") {
-		e.EncodeElement(string(t.Source), start)
-	} else {
-		if t.TaskID == 0 {
-			e.EncodeElement(nil, start)
-			return nil
-		}
-		e.EncodeElement(strconv.Itoa(int(t.TaskID)), start)
+	switch {
+	case t.TaskID != 0:
+		return e.EncodeElement(strconv.FormatInt(t.TaskID, 10), start)
+	case t.Source != "":
+		return e.EncodeElement(t.Source, start)
+	default:
+		return nil
 	}
-
-	return nil
 }
 
 //JobList is a slice of Jobs that is filterable and otherwise actionable via receiver.
@@ -74,17 +77,57 @@ type JobList []Job
 //Job is the Sun Grid Engine XML Definition for a job running on a specific host, its details and current status
 type Job struct {
 	//Because this is a node, we still need the XMLName identifier
-	XMLName        xml.Name `xml:"job_list" json:"-"`
-	StateAttribute string   `xml:"state,attr" json:"state_attribute_text"`
-	State          string   `xml:"state" json:"state"`
-	JBJobNumber    int64    `xml:"JB_job_number" json:"jb_job_number"`
-	JATPriority    float64  `xml:"JAT_prio" json:"jat_prio"`
-	JobName        string   `xml:"JB_name" json:"jb_name"`
-	JobOwner       string   `xml:"JB_owner" json:"jb_owner"`
-	StartTime      string   `xml:"JAT_start_time,omitempty" json:"start_time"`
-	SubmittedTime  string   `xml:"JB_submission_time,omitempty" json:"submitted_time"`
-	Slots          int32    `xml:"slots" json:"slots"`
-	Tasks          Task     `xml:"tasks,omitempty" json:"tasks,omitempty"`
+	XMLName        xml.Name      `xml:"job_list" json:"-"`
+	StateAttribute string        `xml:"state,attr" json:"state_attribute_text"`
+	UnknownAttrs   []UnknownAttr `xml:",any,attr" json:"-"`
+	State          string        `xml:"state" json:"state"`
+	JBJobNumber    int64         `xml:"JB_job_number" json:"jb_job_number"`
+	JATPriority    float64       `xml:"JAT_prio" json:"jat_prio"`
+	JobName        string        `xml:"JB_name" json:"jb_name"`
+	JobOwner       string        `xml:"JB_owner" json:"jb_owner"`
+	StartTime      string        `xml:"JAT_start_time,omitempty" json:"start_time"`
+	SubmittedTime  string        `xml:"JB_submission_time,omitempty" json:"submitted_time"`
+	//UnknownElements are the elements not modelled by the library, such as queue_name or granted_pe. They are written back after the times, where qstat places queue_name
+	UnknownElements []UnknownElement `xml:",any" json:"-"`
+	Slots           int32            `xml:"slots" json:"slots"`
+	Tasks           Task             `xml:"tasks,omitempty" json:"tasks,omitempty"`
+	//HardRequests are only present when qstat is asked for full resource requests (-r)
+	HardRequests []ResourceRequest `xml:"hard_request,omitempty" json:"hard_requests,omitempty"`
+	//QueueName is the queue instance (eg: all.q@hostname) the job is running on. Not part of the qstat output, populated by NewJobInfo
+	QueueName string `xml:"-" json:"queue_name,omitempty"`
+	//Cluster names the cell the job belongs to. Not part of the qstat output, populated by Federation
+	Cluster string `xml:"-" json:"cluster,omitempty"`
+}
+
+//ResourceRequest is a hard resource request made at submission time (qsub -l h_vmem=4G)
+type ResourceRequest struct {
+	Name         string        `xml:"name,attr" json:"name"`
+	UnknownAttrs []UnknownAttr `xml:",any,attr" json:"-"`
+	Value        string        `xml:",chardata" json:"value"`
+}
+
+//RequestedMemory returns the memory the job asked for through its h_vmem, mem_free or virtual_free hard requests (in that order of preference)
+func (j Job) RequestedMemory() (StorageValue, error) {
+	for _, name := range []string{"h_vmem", "mem_free", "virtual_free"} {
+		for _, r := range j.HardRequests {
+			if r.Name == name {
+				return newStorageValue(r.Value)
+			}
+		}
+	}
+
+	return StorageValue{}, ErrNoMemoryRequest
+}
+
+//SplitQueueInstance breaks a queue instance identifier (all.q@hostname) down into its cluster queue and host components
+func SplitQueueInstance(instance string) (string, string) {
+	pieces := strings.SplitN(instance, "@", 2)
+
+	if len(pieces) == 1 {
+		return pieces[0], ""
+	}
+
+	return pieces[0], pieces[1]
 }
 
 //IsJobRunning returns a int (1 - running) (0 - not)
@@ -127,9 +170,12 @@ func IsJobInErrorState(job Job) int {
 
 //GetJobs returns a slice of only jobs from both scheduled and unscheduled queues
 func GetJobs() (JobList, error) {
-	var jobs []Job
+	return GetJobsFromSource(&QstatDataSource{})
+}
 
-	xml, err := GetQstatOutput(make(map[string]string))
+//GetJobsFromSource returns the jobs from both scheduled and unscheduled queues of the provided data source (a CachedDataSource shared between callers, for instance)
+func GetJobsFromSource(source XmlResourceGetter) (JobList, error) {
+	xml, err := source.Get()
 
 	if err != nil {
 		return JobList{}, err
@@ -141,15 +187,8 @@ func GetJobs() (JobList, error) {
 		return []Job{}, err
 	}
 
-	//Add running jobs to the slice first
-	for _, q := range ji.QueueInfo.Queues {
-		jobs = append(jobs, q.JobList...)
-	}
-
-	//Add pending jobs
-	jobs = append(jobs, ji.PendingJobs.JobList...)
-
-	return jobs, nil
+	//Running jobs come first, then pending. NewJobInfo has already recorded the owning queue instance on each running job
+	return ji.Jobs(), nil
 }
 
 //GetJobsWithFilter allows you to specify a filter at the time of retrieving the JobList
@@ -263,3 +302,79 @@ func ExtrapolateTasksToJobs(original Job) (JobList, error) {
 	return jl, nil
 
 }
+
+//CollapseTasks reverses ExtrapolateTasksToJobs: consecutive tasks extrapolated from the same range or group of a job are replaced by a single entry for it, as qstat lists them.
+//When only part of the range is left (once filtered, for instance) the remaining tasks are listed as the smaller ranges they form, or on their own.
+func (jl JobList) CollapseTasks() JobList {
+	collapsed := JobList{}
+
+	for k := 0; k < len(jl); {
+		if !isExtrapolatedTask(jl[k]) {
+			collapsed = append(collapsed, jl[k])
+			k++
+			continue
+		}
+
+		end := k + 1
+		for end < len(jl) && isExtrapolatedTask(jl[end]) && jl[end].JBJobNumber == jl[k].JBJobNumber && jl[end].Tasks.Source == jl[k].Tasks.Source && jl[end].State == jl[k].State {
+			end++
+		}
+
+		collapsed = append(collapsed, collapseTaskRun(jl[k:end])...)
+		k = end
+	}
+
+	return collapsed
+}
+
+func isExtrapolatedTask(j Job) bool {
+	return j.Tasks.TaskID != 0 && (DoesJobContainTaskRange(j) || DoesJobContainTaskGroup(j))
+}
+
+//collapseTaskRun collapses tasks extrapolated from the same source
+func collapseTaskRun(tasks JobList) JobList {
+	all, err := ExtrapolateTasksToJobs(tasks[0])
+
+	if err == nil && len(all) == len(tasks) {
+		complete := true
+		for k := range all {
+			if all[k].Tasks.TaskID != tasks[k].Tasks.TaskID {
+				complete = false
+				break
+			}
+		}
+
+		if complete {
+			j := tasks[0]
+			j.Tasks.TaskID = 0
+			return JobList{j}
+		}
+	}
+
+	step := int64(1)
+	if DoesJobContainTaskRange(tasks[0]) {
+		identifier := TaskRangeRegex.FindString(tasks[0].Tasks.Source)
+		step, _ = strconv.ParseInt(strings.Split(identifier, ":")[1], 10, 64)
+	}
+
+	var collapsed JobList
+
+	for k := 0; k < len(tasks); {
+		end := k + 1
+		for end < len(tasks) && tasks[end].Tasks.TaskID == tasks[end-1].Tasks.TaskID+step {
+			end++
+		}
+
+		j := tasks[k]
+		if end-k == 1 {
+			j.Tasks = Task{Source: strconv.FormatInt(j.Tasks.TaskID, 10), TaskID: j.Tasks.TaskID}
+		} else {
+			j.Tasks = Task{Source: fmt.Sprintf("%d-%d:%d", tasks[k].Tasks.TaskID, tasks[end-1].Tasks.TaskID, step)}
+		}
+
+		collapsed = append(collapsed, j)
+		k = end
+	}
+
+	return collapsed
+}
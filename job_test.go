@@ -3,6 +3,7 @@ package gogridengine
 import (
 	"encoding/xml"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -527,7 +528,7 @@ func TestTaskRangeSerialization(t *testing.T) {
 	assert.NotEmpty(t, x)
 	assert.Contains(t, string(x), "<tasks>10</tasks>")
 
-	//0 Task --> Make sure the XML Representation doesn't have the empty, serialized Task content.
+	//0 Task --> Task 0 is still a task and is written back
 	ji.QueueInfo.Queues[0].JobList[0].Tasks.Source = "0"
 	ji.QueueInfo.Queues[0].JobList[0].Tasks.TaskID = 0
 
@@ -535,7 +536,16 @@ func TestTaskRangeSerialization(t *testing.T) {
 
 	assert.Nil(t, err)
 
-	assert.NotContains(t, string(x), "<tasks>0</tasks>")
+	assert.Contains(t, string(x), "<tasks>0</tasks>")
+
+	//No task --> Make sure the XML Representation doesn't have an empty tasks element
+	ji.QueueInfo.Queues[0].JobList[0].Tasks = Task{}
+
+	x, err = xml.Marshal(ji)
+
+	assert.Nil(t, err)
+
+	assert.NotContains(t, string(x), "<tasks")
 
 	//Pending Job
 	ji.QueueInfo.Queues[0].JobList[0].Tasks.Source = "50-125:5"
@@ -646,7 +656,7 @@ func TestIsJobInErrorState(t *testing.T) {
 		{
 			name: "Not errored",
 			args: args{
-				job: Job {
+				job: Job{
 					StateAttribute: "running",
 					State:          "r",
 					JBJobNumber:    1,
@@ -664,7 +674,7 @@ func TestIsJobInErrorState(t *testing.T) {
 		{
 			name: "dt State should error",
 			args: args{
-				job: Job {
+				job: Job{
 					StateAttribute: "running",
 					State:          "dt",
 					JBJobNumber:    1,
@@ -682,7 +692,7 @@ func TestIsJobInErrorState(t *testing.T) {
 		{
 			name: "auo State should error",
 			args: args{
-				job: Job {
+				job: Job{
 					StateAttribute: "running",
 					State:          "auo",
 					JBJobNumber:    1,
@@ -700,7 +710,7 @@ func TestIsJobInErrorState(t *testing.T) {
 		{
 			name: "Host error code should return error",
 			args: args{
-				job: Job {
+				job: Job{
 					StateAttribute: "running",
 					State:          "Ew",
 					JBJobNumber:    1,
@@ -718,7 +728,7 @@ func TestIsJobInErrorState(t *testing.T) {
 		{
 			name: "any individual e code should error",
 			args: args{
-				job: Job {
+				job: Job{
 					StateAttribute: "running",
 					State:          "et",
 					JBJobNumber:    1,
@@ -741,4 +751,144 @@ func TestIsJobInErrorState(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestSplitQueueInstance(t *testing.T) {
+	queue, host := SplitQueueInstance("all.q@ip-10-0-1-80.ec2.internal")
+	assert.Equal(t, "all.q", queue)
+	assert.Equal(t, "ip-10-0-1-80.ec2.internal", host)
+
+	queue, host = SplitQueueInstance("all.q")
+	assert.Equal(t, "all.q", queue)
+	assert.Empty(t, host)
+}
+
+func TestJobRequestedMemory(t *testing.T) {
+	source := `<job_list state="pending">
+	<JB_job_number>4291</JB_job_number>
+	<JAT_prio>0.50500</JAT_prio>
+	<JB_name>Run487</JB_name>
+	<JB_owner>ahmede</JB_owner>
+	<state>qw</state>
+	<JB_submission_time>2019-09-15T15:26:36</JB_submission_time>
+	<slots>1</slots>
+	<hard_request name="mem_free" resource_contribution="0.000000">2G</hard_request>
+	<hard_request name="h_vmem" resource_contribution="0.000000">4G</hard_request>
+</job_list>`
+
+	var j Job
+	err := xml.Unmarshal([]byte(source), &j)
+	assert.Nil(t, err)
+	assert.Len(t, j.HardRequests, 2)
+
+	//h_vmem is preferred over mem_free
+	mem, err := j.RequestedMemory()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4294967296), mem.Bytes)
+
+	//qsub accepts lowercase units and plain byte counts
+	for value, bytes := range map[string]int64{"4g": 4000000000, "512m": 512000000, "1048576": 1048576} {
+		mem, err = Job{HardRequests: []ResourceRequest{{Name: "h_vmem", Value: value}}}.RequestedMemory()
+		assert.Nil(t, err)
+		assert.Equal(t, bytes, mem.Bytes, value)
+	}
+
+	_, err = Job{HardRequests: []ResourceRequest{{Name: "h_vmem", Value: "lots"}}}.RequestedMemory()
+	assert.NotNil(t, err)
+
+	_, err = Job{}.RequestedMemory()
+	assert.Equal(t, ErrNoMemoryRequest, err)
+}
+
+func TestJobList_CollapseTasks(t *testing.T) {
+	extrapolate := func(source string, state string) JobList {
+		jl, err := ExtrapolateTasksToJobs(Job{JBJobNumber: 7, State: state, Tasks: Task{Source: source}})
+		assert.Nil(t, err)
+		return jl
+	}
+
+	tasks := func(jl JobList) []string {
+		var sources []string
+		for _, j := range jl {
+			sources = append(sources, j.Tasks.Source)
+		}
+		return sources
+	}
+
+	without := func(jl JobList, ids ...int64) JobList {
+		return jl.Filter(func(j Job) bool {
+			for _, id := range ids {
+				if j.Tasks.TaskID == id {
+					return false
+				}
+			}
+			return true
+		})
+	}
+
+	tests := []struct {
+		name string
+		jobs JobList
+		want []string
+	}{
+		{
+			name: "whole range",
+			jobs: extrapolate("1-10:1", "qw"),
+			want: []string{"1-10:1"},
+		},
+		{
+			name: "range with a step",
+			jobs: extrapolate("5-25:5", "qw"),
+			want: []string{"5-25:5"},
+		},
+		{
+			name: "range ending between steps",
+			jobs: extrapolate("1-10:4", "qw"),
+			want: []string{"1-10:4"},
+		},
+		{
+			name: "group",
+			jobs: extrapolate("1,4,9", "qw"),
+			want: []string{"1,4,9"},
+		},
+		{
+			name: "part of a range",
+			jobs: without(extrapolate("1-10:1", "qw"), 1, 5, 6),
+			want: []string{"2-4:1", "7-10:1"},
+		},
+		{
+			name: "single task left",
+			jobs: without(extrapolate("5-25:5", "qw"), 5, 10, 20, 25),
+			want: []string{"15"},
+		},
+		{
+			name: "other jobs in between",
+			jobs: append(append(extrapolate("1-2:1", "qw"), Job{JBJobNumber: 8}), extrapolate("3-4:1", "qw")...),
+			want: []string{"1-2:1", "", "3-4:1"},
+		},
+		{
+			name: "states differ",
+			jobs: append(extrapolate("1-2:1", "qw"), extrapolate("1-2:1", "hqw")...),
+			want: []string{"1-2:1", "1-2:1"},
+		},
+		{
+			name: "not extrapolated",
+			jobs: JobList{{JBJobNumber: 1}, {JBJobNumber: 2, Tasks: Task{Source: "3-6:1"}}, {JBJobNumber: 3, Tasks: Task{Source: "4", TaskID: 4}}},
+			want: []string{"", "3-6:1", "4"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collapsed := tt.jobs.CollapseTasks()
+			assert.Equal(t, tt.want, tasks(collapsed))
+
+			for _, j := range collapsed {
+				if strings.ContainsAny(j.Tasks.Source, ":,") {
+					assert.Equal(t, int64(0), j.Tasks.TaskID)
+				}
+			}
+		})
+	}
+
+	assert.Equal(t, JobList{}, JobList(nil).CollapseTasks())
+}
@@ -3,7 +3,6 @@ package gogridengine
 import (
 	"encoding/xml"
 	"regexp"
-	"sort"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -21,13 +20,20 @@ var TaskRangeRegex *regexp.Regexp = regexp.MustCompile(TASKRANGEIDENTIFIERREGEX)
 
 //JobInfo is the top level object for the SGE Qstat output
 type JobInfo struct {
-	XMLName     xml.Name   `xml:"job_info" json:"-"`
-	QueueInfo   QueueInfo  `xml:"queue_info" json:"queue_info"`
-	PendingJobs PendingJob `xml:"job_info,omitempty" json:"pending_jobs"`
+	XMLName      xml.Name      `xml:"job_info" json:"-"`
+	UnknownAttrs []UnknownAttr `xml:",any,attr" json:"-"`
+	QueueInfo    QueueInfo     `xml:"queue_info" json:"queue_info"`
+	PendingJobs  PendingJob    `xml:"job_info,omitempty" json:"pending_jobs"`
+	//UnknownElements are the elements not modelled by the library, preserved for GetXML
+	UnknownElements []UnknownElement `xml:",any" json:"-"`
 }
 
-//GetXML renders down the XML with UTF-8 opening tags to ensure feasability for testing of output
+//GetXML renders down the XML with UTF-8 opening tags to ensure feasability for testing of output.
+//Pending tasks extrapolated by NewJobInfo are collapsed back into their task ranges, so the output lists jobs the way qstat did. Elements and attributes the library doesn't model are written back as they were read.
+//Pending jobs are written in the order of the JobInfo, which NewJobInfo sorts by job number when it extrapolated task ranges.
 func (q JobInfo) GetXML() (string, error) {
+	q.PendingJobs.JobList = JobList(q.PendingJobs.JobList).CollapseTasks()
+
 	output, err := xml.Marshal(q)
 
 	if err != nil {
@@ -39,6 +45,36 @@ func (q JobInfo) GetXML() (string, error) {
 	return formatted, nil
 }
 
+//Jobs flattens the running jobs of every queue instance and the pending jobs down into a single JobList
+func (q JobInfo) Jobs() JobList {
+	var jobs JobList
+
+	//Add running jobs to the slice first
+	for _, h := range q.QueueInfo.Queues {
+		jobs = append(jobs, h.JobList...)
+	}
+
+	//Add pending jobs
+	jobs = append(jobs, q.PendingJobs.JobList...)
+
+	return jobs
+}
+
+//Filter returns a copy of the JobInfo where both running and pending jobs have been limited by the provided filter. Queue instances are always kept.
+func (q JobInfo) Filter(filter func(j Job) bool) JobInfo {
+	filtered := q
+	filtered.QueueInfo.Queues = make([]Host, len(q.QueueInfo.Queues))
+
+	for k, h := range q.QueueInfo.Queues {
+		h.JobList = JobList(h.JobList).Filter(filter)
+		filtered.QueueInfo.Queues[k] = h
+	}
+
+	filtered.PendingJobs.JobList = JobList(q.PendingJobs.JobList).Filter(filter)
+
+	return filtered
+}
+
 //NewJobInfo returns the go struct of the qstat output
 func NewJobInfo(input string) (JobInfo, error) {
 	var ji JobInfo
@@ -48,41 +84,42 @@ func NewJobInfo(input string) (JobInfo, error) {
 		return JobInfo{}, err
 	}
 
-	deleteTargets := make(map[int]Job)
-
-	//Handle extrapolation of pending tasks
-
-	for k, p := range ji.PendingJobs.JobList {
-		if DoesJobContainTaskRange(p) {
-			//Mark for deletion and substitution
-			deleteTargets[k] = p
+	//Record the owning queue instance on each running job so it survives flattening into a JobList
+	for k, q := range ji.QueueInfo.Queues {
+		for i := range q.JobList {
+			ji.QueueInfo.Queues[k].JobList[i].QueueName = q.Name
 		}
 	}
 
-	//If anything comes up as an extrapolatable task list, we need to extrapolate to multiple job entries and remove the original listing.
-	if len(deleteTargets) > 0 {
-		//Reiterate over collected jobs to cleanup and reconstruct
-		for k, p := range deleteTargets {
+	var pending []Job
+	extrapolated := false
 
-			jobs, err := ExtrapolateTasksToJobs(p)
-
-			if err != nil {
-				//We can't do anything with this entry. Just continue along
-				log.Error("An error occurred trying to extrapolate Task range into JobList", err)
-				continue
-			}
+	//Handle extrapolation of pending tasks. The list is rebuilt rather than spliced so indexes don't shift underneath us when several ranges are present.
+	for _, p := range ji.PendingJobs.JobList {
+		if !DoesJobContainTaskRange(p) {
+			pending = append(pending, p)
+			continue
+		}
 
-			//Remove the target Job
-			ji.PendingJobs.JobList = append(ji.PendingJobs.JobList[:k], ji.PendingJobs.JobList[k+1:]...)
+		jobs, err := ExtrapolateTasksToJobs(p)
 
-			//Append Extrapolated Jobs
-			ji.PendingJobs.JobList = append(ji.PendingJobs.JobList, jobs...)
+		if err != nil {
+			//We can't do anything with this entry. Keep it as is and continue along
+			log.Error("An error occurred trying to extrapolate Task range into JobList", err)
+			pending = append(pending, p)
+			continue
 		}
 
-		//Sort the slice after all the shuffling By Job Number and Task IDß
-		sort.Slice(ji.PendingJobs.JobList, func(i, j int) bool {
-			return ji.PendingJobs.JobList[i].JBJobNumber < ji.PendingJobs.JobList[j].JBJobNumber && ji.PendingJobs.JobList[i].Tasks.TaskID < ji.PendingJobs.JobList[j].Tasks.TaskID
-		})
+		pending = append(pending, jobs...)
+		extrapolated = true
+	}
+
+	//If anything came up as an extrapolatable task list, the original listing has been replaced with multiple job entries
+	if extrapolated {
+		ji.PendingJobs.JobList = pending
+
+		//Sort the slice after all the shuffling By Job Number and Task ID
+		JobList(ji.PendingJobs.JobList).SortBy(ByJobNumber(Ascending), ByTaskID(Ascending))
 	}
 
 	return ji, nil
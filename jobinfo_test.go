@@ -2,7 +2,13 @@ package gogridengine
 
 import (
 	"encoding/xml"
+	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -268,7 +274,7 @@ func TestJobInfo_GetXML(t *testing.T) {
 					},
 				},
 			},
-			want:    `<?xml version='1.0'?><job_info><queue_info><Queue-List><name>testing.local</name><qtype></qtype><slots_used>1</slots_used><slots_rsv>3</slots_rsv><slots_total>4</slots_total><load_avg>2.04</load_avg><resource name="free_mem" type="hl">1.4G</resource><job_list state="running"><state>r</state><JB_job_number>13</JB_job_number><JAT_prio>1.04</JAT_prio><JB_name>Initial Test</JB_name><JB_owner>You</JB_owner><slots>3</slots></job_list></Queue-List></queue_info><job_info></job_info></job_info>`,
+			want:    `<?xml version='1.0'?><job_info><queue_info><Queue-List><name>testing.local</name><qtype></qtype><slots_used>1</slots_used><slots_resv>3</slots_resv><slots_total>4</slots_total><load_avg>2.04</load_avg><resource name="free_mem" type="hl">1.4G</resource><job_list state="running"><state>r</state><JB_job_number>13</JB_job_number><JAT_prio>1.04</JAT_prio><JB_name>Initial Test</JB_name><JB_owner>You</JB_owner><slots>3</slots></job_list></Queue-List></queue_info><job_info></job_info></job_info>`,
 			wantErr: false,
 		},
 	}
@@ -1262,3 +1268,148 @@ func TestNewJobInfo(t *testing.T) {
 	assert.Equal(t, int64(41), ji.PendingJobs.JobList[0].Tasks.TaskID)
 	assert.Equal(t, int64(150), ji.PendingJobs.JobList[len(ji.PendingJobs.JobList)-1].Tasks.TaskID)
 }
+
+func TestNewJobInfoRecordsQueueName(t *testing.T) {
+	content, err := ioutil.ReadFile("test_data/small.xml")
+	assert.Nil(t, err)
+
+	ji, err := NewJobInfo(string(content))
+	assert.Nil(t, err)
+
+	for _, q := range ji.QueueInfo.Queues {
+		assert.NotEmpty(t, q.JobList)
+		for _, j := range q.JobList {
+			assert.Equal(t, q.Name, j.QueueName)
+		}
+	}
+
+	//Should not be serialized back into the qstat XML
+	output, err := ji.GetXML()
+	assert.Nil(t, err)
+	assert.NotContains(t, output, "QueueName")
+}
+
+//xmlNode is a parsed element, compared structurally by the round trip tests
+type xmlNode struct {
+	Name     string
+	Attrs    []string
+	Text     string
+	Children []xmlNode
+}
+
+//xmlTree parses the document into a form where serialisation details don't matter: attributes are sorted, numbers are compared by value,
+//and children are ordered by name. Children sharing a name (the job_list entries, for instance) keep their order.
+func xmlTree(t *testing.T, content string) xmlNode {
+	decoder := xml.NewDecoder(strings.NewReader(content))
+	stack := []*xmlNode{{}}
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		assert.Nil(t, err)
+
+		current := stack[len(stack)-1]
+
+		switch tok := token.(type) {
+		case xml.StartElement:
+			node := xmlNode{Name: tok.Name.Local}
+			for _, a := range tok.Attr {
+				node.Attrs = append(node.Attrs, a.Name.Space+":"+a.Name.Local+"="+a.Value)
+			}
+			sort.Strings(node.Attrs)
+			stack = append(stack, &node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+
+			current.Text = strings.TrimSpace(current.Text)
+			if f, err := strconv.ParseFloat(current.Text, 64); err == nil {
+				current.Text = strconv.FormatFloat(f, 'g', -1, 64)
+			}
+			sort.SliceStable(current.Children, func(i, j int) bool { return current.Children[i].Name < current.Children[j].Name })
+
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, *current)
+		case xml.CharData:
+			current.Text += string(tok)
+		}
+	}
+
+	return stack[0].Children[0]
+}
+
+func TestJobInfoGetXMLRoundTrip(t *testing.T) {
+	for _, name := range []string{"small.xml", "medium.xml", "pending.xml"} {
+		t.Run(name, func(t *testing.T) {
+			content, err := ioutil.ReadFile(filepath.Join("test_data", name))
+			assert.Nil(t, err)
+
+			ji, err := NewJobInfo(string(content))
+			assert.Nil(t, err)
+
+			output, err := ji.GetXML()
+			assert.Nil(t, err)
+
+			//Nothing read from qstat is lost or added
+			assert.Equal(t, xmlTree(t, string(content)), xmlTree(t, output))
+
+			//And reading the output back gives the same JobInfo
+			again, err := NewJobInfo(output)
+			assert.Nil(t, err)
+			assert.Equal(t, ji, again)
+		})
+	}
+}
+
+func TestJobInfoGetXMLPreservesUnknownContent(t *testing.T) {
+	content, err := ioutil.ReadFile("test_data/pending.xml")
+	assert.Nil(t, err)
+
+	ji, err := NewJobInfo(string(content))
+	assert.Nil(t, err)
+
+	//Ranges are extrapolated into a job per task
+	assert.Len(t, ji.PendingJobs.JobList, 8+5+1+1)
+
+	host := ji.QueueInfo.Queues[0]
+	assert.Equal(t, int32(2), host.SlotsReserved)
+	assert.Equal(t, "arch", host.UnknownElements[0].XMLName.Local)
+	assert.Equal(t, "lx-amd64", host.UnknownElements[0].Content)
+
+	job := host.JobList[0]
+	assert.Equal(t, []string{"full_job_name", "requested_pe", "granted_pe", "hard_req_queue", "binding"}, elementNames(job.UnknownElements))
+	assert.Equal(t, "resource_contribution", job.HardRequests[0].UnknownAttrs[0].Name.Local)
+
+	output, err := ji.GetXML()
+	assert.Nil(t, err)
+
+	assert.True(t, strings.HasPrefix(output, `<?xml version='1.0'?><job_info xmlns:xsd="http://arc.liv.ac.uk/repos/darcs/sge/source/dist/util/resources/schemas/qstat/qstat.xsd"><queue_info>`))
+	assert.Contains(t, output, `<load_avg>4.21</load_avg><arch>lx-amd64</arch><resource name="load_avg" type="hl">4.210000</resource>`)
+	assert.Contains(t, output, `<granted_pe name="smp">4</granted_pe>`)
+	assert.Contains(t, output, `<hard_request name="h_vmem" resource_contribution="0.000000">4G</hard_request>`)
+	assert.Contains(t, output, `<resource name="gpu" type="hc" consumable="true">2</resource>`)
+	assert.Contains(t, output, `<slots_resv>2</slots_resv>`)
+	assert.Contains(t, output, `<tasks>3-10:1</tasks>`)
+	assert.Contains(t, output, `<tasks>5-25:5</tasks>`)
+	assert.Contains(t, output, `<tasks>0</tasks>`)
+	assert.Equal(t, 4, strings.Count(output, `<job_list state="pending">`))
+
+	//Once filtered, what remains of a range is listed as the ranges it forms
+	filtered := ji.Filter(func(j Job) bool { return j.Tasks.TaskID != 6 && j.Tasks.TaskID != 15 })
+	output, err = filtered.GetXML()
+	assert.Nil(t, err)
+	assert.Contains(t, output, `<tasks>3-5:1</tasks>`)
+	assert.Contains(t, output, `<tasks>7-10:1</tasks>`)
+	assert.Contains(t, output, `<tasks>5-10:5</tasks>`)
+	assert.Contains(t, output, `<tasks>20-25:5</tasks>`)
+}
+
+func elementNames(elements []UnknownElement) []string {
+	var names []string
+	for _, e := range elements {
+		names = append(names, e.XMLName.Local)
+	}
+
+	return names
+}
@@ -0,0 +1,140 @@
+package gogridengine
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+//SortDirection identifies whether a JobSorter orders its key in ascending or descending fashion
+type SortDirection int
+
+const (
+	//Ascending orders the smallest values first
+	Ascending SortDirection = iota
+	//Descending orders the largest values first
+	Descending
+)
+
+//JobSorter compares a single key of two jobs. It returns a negative number when a sorts before b, a positive number when a sorts after b and 0 when they are equivalent for that key.
+type JobSorter func(a, b Job) int
+
+//SortBy stably sorts the JobList by the provided sorters in order of precedence. Later sorters are only consulted when all earlier ones consider two jobs equivalent.
+func (jl JobList) SortBy(sorters ...JobSorter) JobList {
+	combined := ComposeSorters(sorters...)
+
+	sort.SliceStable(jl, func(i, j int) bool {
+		return combined(jl[i], jl[j]) < 0
+	})
+
+	return jl
+}
+
+//ComposeSorters builds a single multi-key JobSorter from the provided sorters in order of precedence
+func ComposeSorters(sorters ...JobSorter) JobSorter {
+	return func(a, b Job) int {
+		for _, s := range sorters {
+			if result := s(a, b); result != 0 {
+				return result
+			}
+		}
+
+		return 0
+	}
+}
+
+//ByJobNumber orders jobs by their JB_job_number
+func ByJobNumber(direction SortDirection) JobSorter {
+	return func(a, b Job) int {
+		return directed(direction, compareInt64(a.JBJobNumber, b.JBJobNumber))
+	}
+}
+
+//ByTaskID orders jobs by the TaskID of their array task
+func ByTaskID(direction SortDirection) JobSorter {
+	return func(a, b Job) int {
+		return directed(direction, compareInt64(a.Tasks.TaskID, b.Tasks.TaskID))
+	}
+}
+
+//ByPriority orders jobs by their JAT priority
+func ByPriority(direction SortDirection) JobSorter {
+	return func(a, b Job) int {
+		switch {
+		case a.JATPriority < b.JATPriority:
+			return directed(direction, -1)
+		case a.JATPriority > b.JATPriority:
+			return directed(direction, 1)
+		}
+
+		return 0
+	}
+}
+
+//BySubmitTime orders jobs by their submission time. Jobs without a parsable submission time (running jobs, for instance) always sort last.
+func BySubmitTime(direction SortDirection) JobSorter {
+	return func(a, b Job) int {
+		return compareTimes(direction, a.SubmittedTime, b.SubmittedTime)
+	}
+}
+
+//ByStartTime orders jobs by their start time. Jobs without a parsable start time (pending jobs, for instance) always sort last.
+func ByStartTime(direction SortDirection) JobSorter {
+	return func(a, b Job) int {
+		return compareTimes(direction, a.StartTime, b.StartTime)
+	}
+}
+
+//ByOwner orders jobs alphabetically by their owner
+func ByOwner(direction SortDirection) JobSorter {
+	return func(a, b Job) int {
+		return directed(direction, strings.Compare(a.JobOwner, b.JobOwner))
+	}
+}
+
+//ByState orders jobs alphabetically by their state code
+func ByState(direction SortDirection) JobSorter {
+	return func(a, b Job) int {
+		return directed(direction, strings.Compare(a.State, b.State))
+	}
+}
+
+func directed(direction SortDirection, result int) int {
+	if direction == Descending {
+		return -result
+	}
+
+	return result
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+func compareTimes(direction SortDirection, a, b string) int {
+	at, aErr := time.Parse(ISO8601FMT, a)
+	bt, bErr := time.Parse(ISO8601FMT, b)
+
+	//Unparsable times are pushed to the end regardless of direction
+	switch {
+	case aErr != nil && bErr != nil:
+		return 0
+	case aErr != nil:
+		return 1
+	case bErr != nil:
+		return -1
+	case at.Before(bt):
+		return directed(direction, -1)
+	case at.After(bt):
+		return directed(direction, 1)
+	}
+
+	return 0
+}
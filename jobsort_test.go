@@ -0,0 +1,178 @@
+package gogridengine
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobList_SortBy(t *testing.T) {
+	jl := JobList{
+		{
+			JBJobNumber: 2,
+			Tasks:       Task{TaskID: 1},
+		},
+		{
+			JBJobNumber: 1,
+			Tasks:       Task{TaskID: 3},
+		},
+		{
+			JBJobNumber: 1,
+			Tasks:       Task{TaskID: 2},
+		},
+		{
+			JBJobNumber: 2,
+			Tasks:       Task{TaskID: 0},
+		},
+	}
+
+	jl.SortBy(ByJobNumber(Ascending), ByTaskID(Ascending))
+
+	assert.Equal(t, int64(1), jl[0].JBJobNumber)
+	assert.Equal(t, int64(2), jl[0].Tasks.TaskID)
+	assert.Equal(t, int64(3), jl[1].Tasks.TaskID)
+	assert.Equal(t, int64(2), jl[2].JBJobNumber)
+	assert.Equal(t, int64(0), jl[2].Tasks.TaskID)
+	assert.Equal(t, int64(1), jl[3].Tasks.TaskID)
+
+	jl.SortBy(ByJobNumber(Descending), ByTaskID(Descending))
+
+	assert.Equal(t, int64(2), jl[0].JBJobNumber)
+	assert.Equal(t, int64(1), jl[0].Tasks.TaskID)
+	assert.Equal(t, int64(1), jl[3].JBJobNumber)
+	assert.Equal(t, int64(2), jl[3].Tasks.TaskID)
+}
+
+func TestJobList_SortByIsStable(t *testing.T) {
+	jl := JobList{
+		{
+			JobName:  "First",
+			JobOwner: "bob",
+		},
+		{
+			JobName:  "Second",
+			JobOwner: "alice",
+		},
+		{
+			JobName:  "Third",
+			JobOwner: "bob",
+		},
+		{
+			JobName:  "Fourth",
+			JobOwner: "alice",
+		},
+	}
+
+	jl.SortBy(ByOwner(Ascending))
+
+	assert.Equal(t, []string{"Second", "Fourth", "First", "Third"}, []string{jl[0].JobName, jl[1].JobName, jl[2].JobName, jl[3].JobName})
+}
+
+func TestSortByPriorityAndState(t *testing.T) {
+	jl := JobList{
+		{
+			JobName:     "Low",
+			State:       "qw",
+			JATPriority: 0.25,
+		},
+		{
+			JobName:     "High",
+			State:       "r",
+			JATPriority: 0.75,
+		},
+		{
+			JobName:     "HighPending",
+			State:       "qw",
+			JATPriority: 0.75,
+		},
+	}
+
+	jl.SortBy(ByPriority(Descending), ByState(Ascending))
+
+	assert.Equal(t, "HighPending", jl[0].JobName)
+	assert.Equal(t, "High", jl[1].JobName)
+	assert.Equal(t, "Low", jl[2].JobName)
+}
+
+func TestSortByTimes(t *testing.T) {
+	jl := JobList{
+		{
+			JobName:       "Running",
+			StartTime:     "2019-09-15T15:26:36",
+			SubmittedTime: "",
+		},
+		{
+			JobName:       "Newer",
+			SubmittedTime: "2019-09-21T15:26:36",
+		},
+		{
+			JobName:       "Older",
+			SubmittedTime: "2019-09-15T15:26:36",
+		},
+	}
+
+	jl.SortBy(BySubmitTime(Ascending))
+	assert.Equal(t, "Older", jl[0].JobName)
+	assert.Equal(t, "Newer", jl[1].JobName)
+	assert.Equal(t, "Running", jl[2].JobName)
+
+	//Missing times stay at the end even when descending
+	jl.SortBy(BySubmitTime(Descending))
+	assert.Equal(t, "Newer", jl[0].JobName)
+	assert.Equal(t, "Older", jl[1].JobName)
+	assert.Equal(t, "Running", jl[2].JobName)
+
+	jl.SortBy(ByStartTime(Ascending))
+	assert.Equal(t, "Running", jl[0].JobName)
+}
+
+func TestNewJobInfoOrdersPendingJobs(t *testing.T) {
+	input := `<?xml version='1.0'?>
+<job_info>
+  <queue_info>
+  </queue_info>
+  <job_info>
+    <job_list state="pending">
+      <JB_job_number>1007</JB_job_number>
+      <JAT_prio>0.55500</JAT_prio>
+      <JB_name>task_array.sh</JB_name>
+      <JB_owner>darrellb</JB_owner>
+      <state>qw</state>
+      <JB_submission_time>2019-11-15T11:31:41</JB_submission_time>
+      <slots>1</slots>
+      <tasks>1-3:1</tasks>
+    </job_list>
+    <job_list state="pending">
+      <JB_job_number>1006</JB_job_number>
+      <JAT_prio>0.55500</JAT_prio>
+      <JB_name>task_array.sh</JB_name>
+      <JB_owner>darrellb</JB_owner>
+      <state>qw</state>
+      <JB_submission_time>2019-11-15T11:31:41</JB_submission_time>
+      <slots>1</slots>
+      <tasks>4-6:1</tasks>
+    </job_list>
+  </job_info>
+</job_info>`
+
+	ji, err := NewJobInfo(input)
+	assert.Nil(t, err)
+	assert.Len(t, ji.PendingJobs.JobList, 6)
+
+	expected := []struct {
+		job  int64
+		task int64
+	}{
+		{1006, 4},
+		{1006, 5},
+		{1006, 6},
+		{1007, 1},
+		{1007, 2},
+		{1007, 3},
+	}
+
+	for k, v := range expected {
+		assert.Equal(t, v.job, ji.PendingJobs.JobList[k].JBJobNumber)
+		assert.Equal(t, v.task, ji.PendingJobs.JobList[k].Tasks.TaskID)
+	}
+}
@@ -6,6 +6,8 @@ import (
 
 //PendingJob is a sub tag of job_info (also labeled job_info) which details jobs not yet executing.
 type PendingJob struct {
-	XMLName xml.Name `xml:"job_info" json:"-"`
-	JobList []Job    `xml:"job_list" json:"job_list"`
+	XMLName         xml.Name         `xml:"job_info" json:"-"`
+	UnknownAttrs    []UnknownAttr    `xml:",any,attr" json:"-"`
+	JobList         []Job            `xml:"job_list" json:"job_list"`
+	UnknownElements []UnknownElement `xml:",any" json:"-"`
 }
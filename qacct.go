@@ -0,0 +1,111 @@
+package gogridengine
+
+import (
+	"bufio"
+	"context"
+	"strconv"
+	"strings"
+)
+
+//AccountingRecord is a single job (or array task) entry from the qacct -j output
+type AccountingRecord struct {
+	JobNumber  int64  `json:"jobnumber"`
+	TaskID     int64  `json:"taskid"`
+	JobName    string `json:"jobname"`
+	Owner      string `json:"owner"`
+	QueueName  string `json:"qname"`
+	Hostname   string `json:"hostname"`
+	Failed     string `json:"failed"`
+	ExitStatus int    `json:"exit_status"`
+	//Fields holds every key / value pair of the record, including the ones typed above
+	Fields map[string]string `json:"fields"`
+}
+
+//accountingSeparator is the line of = signs qacct prints ahead of every record
+const accountingSeparator = "====="
+
+//ParseAccounting breaks the plain text output of qacct -j down into one record per job or task
+func ParseAccounting(output string) ([]AccountingRecord, error) {
+	var records []AccountingRecord
+	var current *AccountingRecord
+
+	scanner := bufio.NewScanner(strings.NewReader(output))
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		if strings.HasPrefix(line, accountingSeparator) {
+			records = append(records, AccountingRecord{
+				Fields: make(map[string]string),
+			})
+			current = &records[len(records)-1]
+			continue
+		}
+
+		if current == nil || line == "" {
+			continue
+		}
+
+		pieces := strings.SplitN(line, " ", 2)
+		key := pieces[0]
+		value := ""
+
+		if len(pieces) > 1 {
+			value = strings.TrimSpace(pieces[1])
+		}
+
+		current.Fields[key] = value
+
+		if err := current.set(key, value); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func (r *AccountingRecord) set(key string, value string) error {
+	var err error
+
+	switch key {
+	case "jobnumber":
+		r.JobNumber, err = strconv.ParseInt(value, 10, 64)
+	case "taskid":
+		//Non array jobs report their task as undefined
+		if value != "undefined" {
+			r.TaskID, err = strconv.ParseInt(value, 10, 64)
+		}
+	case "jobname":
+		r.JobName = value
+	case "owner":
+		r.Owner = value
+	case "qname":
+		r.QueueName = value
+	case "hostname":
+		r.Hostname = value
+	case "failed":
+		r.Failed = value
+	case "exit_status":
+		//Can be followed by a description of the signal, eg: 137 (Killed)
+		if fields := strings.Fields(value); len(fields) > 0 {
+			r.ExitStatus, err = strconv.Atoi(fields[0])
+		}
+	}
+
+	return err
+}
+
+//GetAccounting runs qacct -j for the provided job number through the runner and parses the records returned
+func GetAccounting(ctx context.Context, runner CommandRunner, jobNumber int64) ([]AccountingRecord, error) {
+	result, err := runner.Run(ctx, "qacct", "-j", strconv.FormatInt(jobNumber, 10))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseAccounting(string(result.Stdout))
+}
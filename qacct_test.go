@@ -0,0 +1,100 @@
+package gogridengine
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const accountingOutput = `==============================================================
+qname        all.q
+hostname     ip-10-0-1-80.ec2.internal
+group        users
+owner        darrellb
+project      NONE
+department   defaultdepartment
+jobname      task_array.sh
+jobnumber    1006
+taskid       5
+account      sge
+priority     0
+qsub_time    Fri Nov 15 11:31:41 2019
+start_time   Fri Nov 15 11:31:47 2019
+end_time     Fri Nov 15 11:32:47 2019
+granted_pe   NONE
+slots        1
+failed       0
+exit_status  0
+ru_wallclock 60s
+==============================================================
+qname        all.q
+hostname     ip-10-0-1-113.ec2.internal
+group        users
+owner        darrellb
+project      NONE
+department   defaultdepartment
+jobname      task_array.sh
+jobnumber    1006
+taskid       10
+account      sge
+failed       100 : assumedly after job
+exit_status  137 (Killed)
+`
+
+//fakeRunner answers commands from a map keyed by the full command line
+type fakeRunner struct {
+	responses map[string]CommandResult
+	calls     []string
+}
+
+func (f *fakeRunner) Run(ctx context.Context, name string, args ...string) (CommandResult, error) {
+	command := strings.Join(append([]string{name}, args...), " ")
+	f.calls = append(f.calls, command)
+
+	result, ok := f.responses[command]
+	if !ok {
+		return CommandResult{ExitCode: 1, Stderr: []byte("error: job id not found")}, errors.New("exit status 1")
+	}
+
+	return result, nil
+}
+
+func TestParseAccounting(t *testing.T) {
+	records, err := ParseAccounting(accountingOutput)
+	assert.Nil(t, err)
+	assert.Len(t, records, 2)
+
+	assert.Equal(t, int64(1006), records[0].JobNumber)
+	assert.Equal(t, int64(5), records[0].TaskID)
+	assert.Equal(t, "ip-10-0-1-80.ec2.internal", records[0].Hostname)
+	assert.Equal(t, 0, records[0].ExitStatus)
+	assert.Equal(t, "Fri Nov 15 11:32:47 2019", records[0].Fields["end_time"])
+
+	assert.Equal(t, 137, records[1].ExitStatus)
+	assert.Equal(t, "100 : assumedly after job", records[1].Failed)
+
+	records, err = ParseAccounting(strings.Replace(accountingOutput, "taskid       5", "taskid       undefined", 1))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), records[0].TaskID)
+
+	_, err = ParseAccounting(strings.Replace(accountingOutput, "jobnumber    1006", "jobnumber    cat", 1))
+	assert.NotNil(t, err)
+}
+
+func TestGetAccounting(t *testing.T) {
+	runner := &fakeRunner{
+		responses: map[string]CommandResult{
+			"qacct -j 1006": {Stdout: []byte(accountingOutput)},
+		},
+	}
+
+	records, err := GetAccounting(context.Background(), runner, 1006)
+	assert.Nil(t, err)
+	assert.Len(t, records, 2)
+
+	_, err = GetAccounting(context.Background(), runner, 42)
+	assert.NotNil(t, err)
+}
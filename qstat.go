@@ -1,15 +1,13 @@
 package gogridengine
 
 import (
-	"bytes"
 	"context"
-	"errors"
 	"fmt"
 	"io/ioutil"
 	"math/rand"
 	"net/http"
 	"os"
-	"os/exec"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -50,10 +48,47 @@ type XmlResourceGetter interface {
 	Get() (string, error)
 }
 
+//XmlResourceContextGetter is implemented by sources which can abandon a fetch (and the command behind it) when the context is done
+type XmlResourceContextGetter interface {
+	GetContext(ctx context.Context) (string, error)
+}
+
 type XmlResourceReader interface {
 	Read() (string, error)
 }
 
+//QstatDataSource is an XmlResourceGetter backed by GetQstatOutput, honoring test mode like the rest of the library
+type QstatDataSource struct {
+	//Filters are passed along to qstat as switches. See buildQstatArgumentList
+	Filters map[string]string
+	//Runner runs qstat instead of DefaultRunner. When set, test mode is bypassed
+	Runner CommandRunner
+}
+
+//Get returns the current qstat XML output
+func (d *QstatDataSource) Get() (string, error) {
+	return d.GetContext(context.Background())
+}
+
+//GetContext returns the current qstat XML output, stopping qstat when the context is done
+func (d *QstatDataSource) GetContext(ctx context.Context) (string, error) {
+	filters := d.Filters
+
+	if filters == nil {
+		filters = make(map[string]string)
+	}
+
+	if d.Runner != nil {
+		return qStatFromRunner(ctx, d.Runner, filters)
+	}
+
+	if os.Getenv(environmentPrefix+"TEST") != "true" {
+		return qStatFromRunner(ctx, DefaultRunner, filters)
+	}
+
+	return generatedQstatOputput()
+}
+
 // GetQstatOutput is used to pull in XML content from either the QSTAT command or generated data for testing purpoes
 func GetQstatOutput(filters map[string]string) (string, error) {
 
@@ -82,31 +117,19 @@ func DeleteQueuedJobByID(targets []string) (string, error) {
 	s := strings.Join(targets, ",")
 	s = strings.TrimSpace(s)
 
-	//Locate the binary in existing path
-	binary, err := exec.LookPath("qdel")
-
-	if err != nil {
-		log.Error("Couldn't locate binary", err)
-		return "", errors.New("Couldn't locate the binary")
-	}
-
 	ctx := context.Background()
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	//Cowardly cancel on any other exit mode
 	defer cancel()
 
 	log.Info("Requesting qdel with a list of IDs: ", s)
-	command := exec.CommandContext(ctx, binary, s)
-	command.Env = os.Environ()
-	output := &bytes.Buffer{}
-	command.Stdout = output
-	err = command.Run()
+	result, err := DefaultRunner.Run(ctx, "qdel", s)
 	if err != nil {
-		log.Error(output.String())
-		return output.String(), err
+		log.Error(string(result.Stdout))
+		return string(result.Stdout), err
 	}
 
-	return output.String(), nil
+	return string(result.Stdout), nil
 }
 
 // DeleteQueuedJobByUsernames is used to delete (1 or many) jobs by concatenating usernames together and feeding them to qdel
@@ -128,70 +151,59 @@ func DeleteQueuedJobByUsernames(targets []string) (string, error) {
 	s := strings.Join(targets, ",")
 	s = strings.TrimSpace(s)
 
-	//Locate the binary in existing path
-	binary, err := exec.LookPath("qdel")
-
-	if err != nil {
-		log.Error("Couldn't locate binary", err)
-		return "", errors.New("Couldn't locate the binary")
-	}
-
 	ctx := context.Background()
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	//Cowardly cancel on any other exit mode
 	defer cancel()
 
 	log.Info("Running qdel with the following user input ", s)
-	command := exec.CommandContext(ctx, binary, "-u", s)
-	command.Env = os.Environ()
-	output := &bytes.Buffer{}
-	command.Stdout = output
-	err = command.Run()
+	result, err := DefaultRunner.Run(ctx, "qdel", "-u", s)
 	if err != nil {
-		log.Error(output.String())
+		log.Error(string(result.Stdout))
 		log.Error(err)
-		return output.String(), err
+		return string(result.Stdout), err
 	}
 
-	return output.String(), nil
+	return string(result.Stdout), nil
 }
 
 // Filters are meant to be in the form of [key] being being a switch and the value to be the anything passed to the option
 func qStatFromExec(filters map[string]string) (string, error) {
+	return qStatFromRunner(context.Background(), DefaultRunner, filters)
+}
 
-	//Locate the binary in existing path
-	binary, err := exec.LookPath("qstat")
+func qStatFromRunner(ctx context.Context, runner CommandRunner, filters map[string]string) (string, error) {
 
-	if err != nil {
-		log.Error("Couldn't locate binary", err)
-		return "", errors.New("Couldn't locate the binary")
-	}
-
-	ctx := context.Background()
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	//Cowardly cancel on any other exit mode
 	defer cancel()
 
 	arguments := buildQstatArgumentList(filters)
 
-	command := exec.CommandContext(ctx, binary, arguments...)
-	command.Env = os.Environ()
-	outputBytes, err := command.CombinedOutput()
+	result, err := runner.Run(ctx, "qstat", arguments...)
 
 	if err != nil {
-		log.Errorf("An error occurred during execution of the the binary %s. Execution details are %s ", binary, string(outputBytes))
-		return "", fmt.Errorf("an error occurred during execution of the the binary %s. Execution details are %s. Error: %w", binary, string(outputBytes), err)
+		details := string(result.Stdout) + string(result.Stderr)
+		log.Errorf("An error occurred during execution of qstat. Execution details are %s ", details)
+		return "", fmt.Errorf("an error occurred during execution of qstat. Execution details are %s. Error: %w", details, err)
 	}
 
-	return string(outputBytes), nil
+	return string(result.Stdout), nil
 }
 
 func buildQstatArgumentList(filters map[string]string) []string {
 	var arguments []string
 	userFiltered := false
 
-	//Let's iterate over all the provided kvps
-	for k, v := range filters {
+	//Iterate over the provided kvps in a stable order so the command line is deterministic (and replayable)
+	keys := make([]string, 0, len(filters))
+	for k := range filters {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := filters[k]
 		//If a user has been provided, let's specify those users
 		if k == "-u" {
 			userFiltered = true
@@ -221,13 +233,7 @@ func buildQstatArgumentList(filters map[string]string) []string {
 }
 
 func generatedQstatOputput() (string, error) {
-	xmlLocation := XMLDataSource{location: "https://raw.githubusercontent.com/metrumresearchgroup/gogridengine/master/test_data/medium.xml"}
-
-	if os.Getenv("GOGRIDENGINE_TEST_SOURCE") != "" {
-		xmlLocation.location = os.Getenv("GOGRIDENGINE_TEST_SOURCE")
-	}
-
-	xmlResp := &XmlContentReader{resource: &xmlLocation}
+	xmlResp := &XmlContentReader{resource: testDataSource(os.Getenv("GOGRIDENGINE_TEST_SOURCE"))}
 
 	return xmlResp.Read()
 }
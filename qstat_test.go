@@ -2,11 +2,9 @@ package gogridengine
 
 import (
 	"encoding/xml"
-	"io/ioutil"
 	"os"
 	"testing"
 
-	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -66,18 +64,32 @@ func TestGeneratedOutputGenerateQState(t *testing.T) {
 	}
 }
 
+//useReplayRunner swaps the DefaultRunner for one serving the recordings in test_data/recordings, returning a function restoring the original
+func useReplayRunner(t *testing.T) func() {
+	replay, err := NewReplayRunner("test_data/recordings")
+	assert.Nil(t, err)
+
+	original := DefaultRunner
+	DefaultRunner = replay
+
+	return func() {
+		DefaultRunner = original
+	}
+}
+
 func TestDeleteQueuedJobByID(t *testing.T) {
-	fakeBinary("qdel")
-	updatePathWithCurrentDir()
+	defer useReplayRunner(t)()
 	originalValue := os.Getenv(environmentPrefix + "TEST")
 
 	type args struct {
 		jobs []string
 	}
 	tests := []struct {
-		name    string
-		args    args
-		wantErr bool
+		name     string
+		args     args
+		testMode bool
+		want     string
+		wantErr  bool
 	}{
 		{
 			name: "Execution",
@@ -87,6 +99,17 @@ func TestDeleteQueuedJobByID(t *testing.T) {
 					"2",
 				},
 			},
+			want:    "darrellb has registered the job 1 for deletion\ndarrellb has registered the job 2 for deletion\n",
+			wantErr: false,
+		},
+		{
+			name: "Execution of a missing job",
+			args: args{
+				jobs: []string{
+					"42",
+				},
+			},
+			want:    "",
 			wantErr: true,
 		},
 		{
@@ -97,55 +120,41 @@ func TestDeleteQueuedJobByID(t *testing.T) {
 					"2",
 				},
 			},
-			wantErr: false,
+			testMode: true,
+			want:     "username has deleted job 1\nusername has deleted job 2",
+			wantErr:  false,
 		},
 	}
-	for k, tt := range tests {
-		if (k+1)%2 == 0 {
+	for _, tt := range tests {
+		if tt.testMode {
 			os.Setenv(environmentPrefix+"TEST", "true")
 		} else {
 			os.Unsetenv(environmentPrefix + "TEST")
 		}
 		t.Run(tt.name, func(t *testing.T) {
-			if _, err := DeleteQueuedJobByID(tt.args.jobs); (err != nil) != tt.wantErr {
+			got, err := DeleteQueuedJobByID(tt.args.jobs)
+			if (err != nil) != tt.wantErr {
 				t.Errorf("DeleteQueuedJobByID() error = %v, wantErr %v", err, tt.wantErr)
 			}
+			assert.Equal(t, tt.want, got)
 		})
 	}
 
 	os.Setenv(environmentPrefix+"TEST", originalValue)
-	purgeBinary("qdel")
-}
-
-// Create an executable qstat file that will exit ok. Will also print the raw input just so we can verify it
-func fakeBinary(name string) {
-	contents := `#!/bin/bash
-	echo $0 $@
-	exit 0`
-
-	err := ioutil.WriteFile(name, []byte(contents), 0755)
-	if err != nil {
-		log.Error("Unable to create the file", err)
-	}
-}
-
-func purgeBinary(name string) {
-	err := os.Remove(name)
-
-	if err != nil {
-		log.Error("Unable to create the file", err)
-	}
 }
 
 func TestDeleteQueuedJobByUsernames(t *testing.T) {
-	fakeBinary("qdel")
+	defer useReplayRunner(t)()
+	originalValue := os.Getenv(environmentPrefix + "TEST")
+
 	type args struct {
 		usernames []string
 	}
 	tests := []struct {
-		name    string
-		args    args
-		wantErr bool
+		name     string
+		args     args
+		testMode bool
+		wantErr  bool
 	}{
 		{
 			name: "Successful activation",
@@ -155,6 +164,15 @@ func TestDeleteQueuedJobByUsernames(t *testing.T) {
 					"dbreeden",
 				},
 			},
+			wantErr: false,
+		},
+		{
+			name: "Unknown users",
+			args: args{
+				usernames: []string{
+					"nobody",
+				},
+			},
 			wantErr: true,
 		},
 		{
@@ -165,11 +183,12 @@ func TestDeleteQueuedJobByUsernames(t *testing.T) {
 					"dbreeden",
 				},
 			},
-			wantErr: false,
+			testMode: true,
+			wantErr:  false,
 		},
 	}
-	for k, tt := range tests {
-		if (k+1)%2 == 0 {
+	for _, tt := range tests {
+		if tt.testMode {
 			os.Setenv(environmentPrefix+"TEST", "true")
 		} else {
 			os.Unsetenv(environmentPrefix + "TEST")
@@ -180,29 +199,25 @@ func TestDeleteQueuedJobByUsernames(t *testing.T) {
 			}
 		})
 	}
-	purgeBinary("qdel")
-}
-
-func getCurrentPath() string {
-	return os.Getenv("PATH")
-}
 
-func updatePathWithCurrentDir() {
-	os.Setenv("PATH", getCurrentPath()+":.")
+	os.Setenv(environmentPrefix+"TEST", originalValue)
 }
 
-func TestQSTATWithFakeBinary(t *testing.T) {
-	fakeBinary("qstat")
-	updatePathWithCurrentDir()
+func TestQSTATWithReplayRunner(t *testing.T) {
+	defer useReplayRunner(t)()
+	originalValue := os.Getenv(environmentPrefix + "TEST")
+	os.Unsetenv(environmentPrefix + "TEST")
 
-	os.Unsetenv("TEST")
 	output, err := GetQstatOutput(make(map[string]string))
 
+	assert.Nil(t, err)
 	assert.NotEmpty(t, output)
+
+	ji, err := NewJobInfo(output)
 	assert.Nil(t, err)
+	assert.Equal(t, int64(612), ji.QueueInfo.Queues[0].JobList[0].JBJobNumber)
 
-	purgeBinary("qstat")
-	os.Setenv("TEST", "true")
+	os.Setenv(environmentPrefix+"TEST", originalValue)
 }
 
 func Test_generatedQstatOputput(t *testing.T) {
@@ -213,7 +228,8 @@ func Test_generatedQstatOputput(t *testing.T) {
 
 func Test_Select_Source_For_Test(t *testing.T) {
 	os.Setenv("GOGRIDENGINE_TEST", "true")
-	os.Setenv("GOGRIDENGINE_TEST_SOURCE", "https://gist.githubusercontent.com/shairozan/03f6f6123b11483bb17fd2c6ee95c338/raw/d65e6d603e7563b7307f9b045171ea7693c95f40/small_sge.xml")
+	os.Setenv("GOGRIDENGINE_TEST_SOURCE", "test_data/small.xml")
+	defer os.Unsetenv("GOGRIDENGINE_TEST_SOURCE")
 
 	out, _ := GetQstatOutput(make(map[string]string))
 	ji := JobInfo{}
@@ -0,0 +1,123 @@
+package gogridengine
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//qstatTimeFormat is the layout of the submit/start at column
+const qstatTimeFormat = "01/02/2006 15:04:05"
+
+const (
+	qstatHeader       = "job-ID  prior   name       user         state submit/start at     queue                          slots ja-task-ID "
+	qstatFullHeader   = "queuename                      qtype resv/used/tot. load_avg arch          states"
+	qstatPendingFence = "############################################################################"
+	qstatPendingTitle = " - PENDING JOBS - PENDING JOBS - PENDING JOBS - PENDING JOBS - PENDING JOBS"
+)
+
+//WriteQstat renders the jobs the way plain qstat lists them, one line per job in the order given. As with qstat, nothing is written when there are no jobs.
+//Pending tasks extrapolated by NewJobInfo are collapsed back into a single line per task range. Use JobInfo.Jobs to render a whole JobInfo, running jobs first.
+func WriteQstat(w io.Writer, jobs JobList) error {
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	jobs = jobs.CollapseTasks()
+
+	b := bufio.NewWriter(w)
+
+	fmt.Fprintln(b, qstatHeader)
+	fmt.Fprintln(b, strings.Repeat("-", len(qstatHeader)-1))
+
+	for _, j := range jobs {
+		fmt.Fprintf(b, "%s %-30.30s %5d %s\n", qstatJobColumns(j), j.QueueName, j.Slots, qstatTask(j))
+	}
+
+	return b.Flush()
+}
+
+//WriteQstatFull renders the JobInfo the way qstat -f does: a block per queue instance listing the jobs running on it, followed by the pending jobs with their task ranges collapsed.
+//Filter the JobInfo first to render a subset of its jobs.
+func WriteQstatFull(w io.Writer, ji JobInfo) error {
+	b := bufio.NewWriter(w)
+
+	fmt.Fprintln(b, qstatFullHeader)
+
+	for _, h := range ji.QueueInfo.Queues {
+		fmt.Fprintln(b, strings.Repeat("-", len(qstatFullHeader)))
+		fmt.Fprintf(b, "%-30.30s %-5.5s %-14.14s %-8.8s %-13.13s %s\n", h.Name, h.QType, fmt.Sprintf("%d/%d/%d", h.SlotsReserved, h.SlotsUsed, h.SlotsTotal), qstatLoad(h), qstatArch(h), h.State)
+
+		for _, j := range h.JobList {
+			writeQstatFullJob(b, j)
+		}
+	}
+
+	if pending := JobList(ji.PendingJobs.JobList).CollapseTasks(); len(pending) > 0 {
+		fmt.Fprintln(b)
+		fmt.Fprintln(b, qstatPendingFence)
+		fmt.Fprintln(b, qstatPendingTitle)
+		fmt.Fprintln(b, qstatPendingFence)
+
+		for _, j := range pending {
+			writeQstatFullJob(b, j)
+		}
+	}
+
+	return b.Flush()
+}
+
+//writeQstatFullJob writes a job line of qstat -f, which leaves out the queue column as the block already names it
+func writeQstatFullJob(w io.Writer, j Job) {
+	fmt.Fprintf(w, "%s %5d %s\n", qstatJobColumns(j), j.Slots, qstatTask(j))
+}
+
+//qstatJobColumns are the columns from job-ID to submit/start at, shared by both listings
+func qstatJobColumns(j Job) string {
+	return fmt.Sprintf("%7d %7.5f %-10.10s %-12.12s %-5.5s %s", j.JBJobNumber, j.JATPriority, j.JobName, j.JobOwner, j.State, qstatTime(j))
+}
+
+//qstatTime is the start time of running jobs and the submission time of pending ones
+func qstatTime(j Job) string {
+	value := j.StartTime
+	if value == "" {
+		value = j.SubmittedTime
+	}
+
+	t, err := time.Parse(ISO8601FMT, value)
+	if err != nil {
+		return strings.Repeat(" ", len(qstatTimeFormat))
+	}
+
+	return t.Format(qstatTimeFormat)
+}
+
+//qstatTask is the ja-task-ID column: the task of an array task, the task range of pending array jobs, or nothing for other jobs
+func qstatTask(j Job) string {
+	if j.Tasks.TaskID != 0 {
+		return strconv.FormatInt(j.Tasks.TaskID, 10)
+	}
+
+	return j.Tasks.Source
+}
+
+//qstatLoad is the load_avg column, which qstat doesn't know for hosts in an unknown state
+func qstatLoad(h Host) string {
+	if strings.Contains(h.State, "u") {
+		return "-NA-"
+	}
+
+	return strconv.FormatFloat(h.LoadAverage, 'f', 2, 64)
+}
+
+func qstatArch(h Host) string {
+	arch, err := h.Resources.locateKey("arch")
+	if err != nil {
+		return "-NA-"
+	}
+
+	return arch.Value
+}
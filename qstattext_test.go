@@ -0,0 +1,186 @@
+package gogridengine
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//qstatTextJobInfo adds a running array task on a long queue instance name and a host in an unknown state to the export fixture
+func qstatTextJobInfo() JobInfo {
+	ji := exportJobInfo()
+
+	ji.QueueInfo.Queues[0].Resources = append(ji.QueueInfo.Queues[0].Resources, Resource{Name: "arch", Value: "lx-amd64"})
+	ji.QueueInfo.Queues = append(ji.QueueInfo.Queues, Host{
+		Name:       "all.q@ip-172-16-2-102.ec2.internal",
+		QType:      "BIP",
+		SlotsUsed:  1,
+		SlotsTotal: 2,
+		State:      "au",
+		JobList: []Job{
+			{
+				JBJobNumber: 3,
+				JATPriority: 0.50500,
+				JobName:     "ArrayTaskWithLongName",
+				JobOwner:    "devinp",
+				State:       "r",
+				StartTime:   "2019-12-18T14:10:00",
+				Slots:       1,
+				Tasks:       Task{Source: "2", TaskID: 2},
+				QueueName:   "all.q@ip-172-16-2-102.ec2.internal",
+			},
+		},
+	})
+
+	return ji
+}
+
+//qstatLines joins the expected lines. qstat pads its columns, so some lines end in blanks
+func qstatLines(lines ...string) string {
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func TestWriteQstat(t *testing.T) {
+	ji := qstatTextJobInfo()
+
+	tests := []struct {
+		name string
+		jobs JobList
+		want string
+	}{
+		{
+			name: "every job",
+			jobs: ji.Jobs(),
+			want: qstatLines(
+				"job-ID  prior   name       user         state submit/start at     queue                          slots ja-task-ID ",
+				"-----------------------------------------------------------------------------------------------------------------",
+				"      1 0.55000 Run, with  darrellb     r     12/18/2019 14:05:00 all.q@node1                        2 ",
+				"      3 0.50500 ArrayTaskW devinp       r     12/18/2019 14:10:00 all.q@ip-172-16-2-102.ec2.inte     1 2",
+				"      2 0.00000 Array      devinp       qw    12/18/2019 14:00:00                                    1 1-3:1",
+			),
+		},
+		{
+			name: "filtered",
+			jobs: ji.Jobs().Filter(func(j Job) bool { return j.JobOwner == "darrellb" }),
+			want: qstatLines(
+				"job-ID  prior   name       user         state submit/start at     queue                          slots ja-task-ID ",
+				"-----------------------------------------------------------------------------------------------------------------",
+				"      1 0.55000 Run, with  darrellb     r     12/18/2019 14:05:00 all.q@node1                        2 ",
+			),
+		},
+		{
+			name: "no jobs",
+			jobs: JobList{},
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			assert.Nil(t, WriteQstat(&b, tt.jobs))
+			assert.Equal(t, tt.want, b.String())
+		})
+	}
+}
+
+func TestWriteQstatFull(t *testing.T) {
+	ji := qstatTextJobInfo()
+
+	var b bytes.Buffer
+	assert.Nil(t, WriteQstatFull(&b, ji))
+	assert.Equal(t, qstatLines(
+		"queuename                      qtype resv/used/tot. load_avg arch          states",
+		"---------------------------------------------------------------------------------",
+		"all.q@node1                    BIP   0/2/4          1.50     lx-amd64      ",
+		"      1 0.55000 Run, with  darrellb     r     12/18/2019 14:05:00     2 ",
+		"---------------------------------------------------------------------------------",
+		"all.q@node2                    BIP   0/0/4          0.00     -NA-          d",
+		"---------------------------------------------------------------------------------",
+		"all.q@ip-172-16-2-102.ec2.inte BIP   0/1/2          -NA-     -NA-          au",
+		"      3 0.50500 ArrayTaskW devinp       r     12/18/2019 14:10:00     1 2",
+		"",
+		"############################################################################",
+		" - PENDING JOBS - PENDING JOBS - PENDING JOBS - PENDING JOBS - PENDING JOBS",
+		"############################################################################",
+		"      2 0.00000 Array      devinp       qw    12/18/2019 14:00:00     1 1-3:1",
+	), b.String())
+
+	//Filtering keeps every queue instance block but drops the pending section once it is empty
+	b.Reset()
+	assert.Nil(t, WriteQstatFull(&b, ji.Filter(func(j Job) bool { return j.JBJobNumber == 3 })))
+	assert.NotContains(t, b.String(), "PENDING JOBS")
+	assert.NotContains(t, b.String(), "darrellb")
+	assert.Equal(t, 3, strings.Count(b.String(), "\n---"))
+	assert.Contains(t, b.String(), "      3 0.50500 ArrayTaskW devinp       r     12/18/2019 14:10:00     1 2\n")
+}
+
+func TestWriteQstatFromPendingXML(t *testing.T) {
+	ji := fixtureJobInfo(t, "pending.xml")
+
+	//NewJobInfo splits the pending task ranges into a job per task, qstat lists each range once
+	var b bytes.Buffer
+	assert.Nil(t, WriteQstat(&b, ji.Jobs()))
+	assert.Equal(t, qstatLines(
+		"job-ID  prior   name       user         state submit/start at     queue                          slots ja-task-ID ",
+		"-----------------------------------------------------------------------------------------------------------------",
+		"   1001 0.60500 bootstrap  darrellb     r     11/15/2019 11:02:11 all.q@ip-10-0-1-113.ec2.intern     4 ",
+		"   1002 0.55500 task_array devinp       r     11/15/2019 11:05:40 all.q@ip-10-0-1-113.ec2.intern     1 1",
+		"   1002 0.55500 task_array devinp       r     11/15/2019 11:05:40 all.q@ip-10-0-1-113.ec2.intern     1 2",
+		"   1002 0.55500 task_array devinp       qw    11/15/2019 11:04:59                                    1 3-10:1",
+		"   1003 0.50500 sweep      darrellb     hqw   11/15/2019 11:10:02                                    1 5-25:5",
+		"   1004 0.50500 retry      devinp       Eqw   11/15/2019 11:12:45                                    1 0",
+		"   1005 0.00000 gpu_model  darrellb     qw    11/15/2019 11:20:00                                    2 ",
+	), b.String())
+
+	b.Reset()
+	assert.Nil(t, WriteQstatFull(&b, ji))
+	assert.Equal(t, qstatLines(
+		"queuename                      qtype resv/used/tot. load_avg arch          states",
+		"---------------------------------------------------------------------------------",
+		"all.q@ip-10-0-1-113.ec2.intern BIP   2/6/8          4.21     lx-amd64      ",
+		"   1001 0.60500 bootstrap  darrellb     r     11/15/2019 11:02:11     4 ",
+		"   1002 0.55500 task_array devinp       r     11/15/2019 11:05:40     1 1",
+		"   1002 0.55500 task_array devinp       r     11/15/2019 11:05:40     1 2",
+		"---------------------------------------------------------------------------------",
+		"gpu.q@ip-10-0-1-80.ec2.interna BP    0/0/2          0.01     lx-amd64      d",
+		"",
+		"############################################################################",
+		" - PENDING JOBS - PENDING JOBS - PENDING JOBS - PENDING JOBS - PENDING JOBS",
+		"############################################################################",
+		"   1002 0.55500 task_array devinp       qw    11/15/2019 11:04:59     1 3-10:1",
+		"   1003 0.50500 sweep      darrellb     hqw   11/15/2019 11:10:02     1 5-25:5",
+		"   1004 0.50500 retry      devinp       Eqw   11/15/2019 11:12:45     1 0",
+		"   1005 0.00000 gpu_model  darrellb     qw    11/15/2019 11:20:00     2 ",
+	), b.String())
+
+	//A partial range, as left by a filter, is listed as what remains of it
+	b.Reset()
+	assert.Nil(t, WriteQstat(&b, ji.Jobs().Filter(func(j Job) bool { return j.JBJobNumber == 1002 && j.Tasks.TaskID >= 5 })))
+	assert.Contains(t, b.String(), "1 5-10:1\n")
+}
+
+func TestWriteQstatFullFromQstat(t *testing.T) {
+	content, err := ioutil.ReadFile("test_data/small.xml")
+	assert.Nil(t, err)
+
+	ji, err := NewJobInfo(string(content))
+	assert.Nil(t, err)
+
+	var b bytes.Buffer
+	assert.Nil(t, WriteQstatFull(&b, ji))
+
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	assert.Equal(t, 1+2*len(ji.QueueInfo.Queues)+len(ji.Jobs()), len(lines))
+
+	for _, line := range lines[1:] {
+		if strings.HasPrefix(line, "---") {
+			continue
+		}
+
+		//Every queue instance line has the arch column filled from its resources
+		assert.NotContains(t, line, "-NA-")
+	}
+}
@@ -6,6 +6,8 @@ import (
 
 //QueueInfo is the child object for qstat job output
 type QueueInfo struct {
-	XMLName xml.Name `xml:"queue_info" json:"-"`
-	Queues  []Host   `xml:"Queue-List" json:"queue_list"`
+	XMLName         xml.Name         `xml:"queue_info" json:"-"`
+	UnknownAttrs    []UnknownAttr    `xml:",any,attr" json:"-"`
+	Queues          []Host           `xml:"Queue-List" json:"queue_list"`
+	UnknownElements []UnknownElement `xml:",any" json:"-"`
 }
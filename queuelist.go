@@ -2,17 +2,58 @@ package gogridengine
 
 import (
 	"encoding/xml"
+	"strings"
 )
 
 //Host is the top-level object (per host) that includes all subsequent data including jobs, resources etc
 type Host struct {
-	XMLName       xml.Name     `xml:"Queue-List" json:"-"`
-	Name          string       `xml:"name" json:"name"`
-	QType         string       `xml:"qtype" json:"qtype"`
-	SlotsUsed     int32        `xml:"slots_used" json:"slots_used"`
-	SlotsReserved int32        `xml:"slots_rsv" json:"slots_reserved"`
-	SlotsTotal    int32        `xml:"slots_total" json:"slots_total"`
-	LoadAverage   float64      `xml:"load_avg" json:"load_average"`
-	Resources     ResourceList `xml:"resource" json:"resources"`
-	JobList       []Job        `xml:"job_list" json:"job_list"`
+	XMLName       xml.Name      `xml:"Queue-List" json:"-"`
+	UnknownAttrs  []UnknownAttr `xml:",any,attr" json:"-"`
+	Name          string        `xml:"name" json:"name"`
+	QType         string        `xml:"qtype" json:"qtype"`
+	SlotsUsed     int32         `xml:"slots_used" json:"slots_used"`
+	SlotsReserved int32         `xml:"slots_resv" json:"slots_reserved"`
+	SlotsTotal    int32         `xml:"slots_total" json:"slots_total"`
+	LoadAverage   float64       `xml:"load_avg" json:"load_average"`
+	//UnknownElements are the elements not modelled by the library, such as arch. They are written back where qstat places them, after load_avg
+	UnknownElements []UnknownElement `xml:",any" json:"-"`
+	State           string           `xml:"state,omitempty" json:"state,omitempty"`
+	Resources       ResourceList     `xml:"resource" json:"resources"`
+	JobList         []Job            `xml:"job_list" json:"job_list"`
+	//Cluster names the cell the queue instance belongs to. Not part of the qstat output, populated by Federation
+	Cluster string `xml:"-" json:"cluster,omitempty"`
+}
+
+//unreportedLoadHost has the fields of Host with load_avg left out when zero. Converting a Host to it stops compiling should the two drift apart
+type unreportedLoadHost struct {
+	XMLName         xml.Name         `xml:"Queue-List"`
+	UnknownAttrs    []UnknownAttr    `xml:",any,attr"`
+	Name            string           `xml:"name"`
+	QType           string           `xml:"qtype"`
+	SlotsUsed       int32            `xml:"slots_used"`
+	SlotsReserved   int32            `xml:"slots_resv"`
+	SlotsTotal      int32            `xml:"slots_total"`
+	LoadAverage     float64          `xml:"load_avg,omitempty"`
+	UnknownElements []UnknownElement `xml:",any"`
+	State           string           `xml:"state,omitempty"`
+	Resources       ResourceList     `xml:"resource"`
+	JobList         []Job            `xml:"job_list"`
+	Cluster         string           `xml:"-"`
+}
+
+//MarshalXML leaves load_avg out for hosts in an unknown state, as qstat has no load to report for them
+func (h Host) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	//Encoded on its own, the element would otherwise be named after the type
+	start.Name = xml.Name{Local: "Queue-List"}
+
+	if strings.Contains(h.State, "u") && h.LoadAverage == 0 {
+		unreported := unreportedLoadHost(h)
+		return e.EncodeElement(&unreported, start)
+	}
+
+	//Without its methods, so encoding doesn't come back here
+	type host Host
+	reported := host(h)
+
+	return e.EncodeElement(&reported, start)
 }
@@ -4,6 +4,8 @@ import (
 	"encoding/xml"
 	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestDeserializeQueueList(t *testing.T) {
@@ -405,3 +407,40 @@ func TestSerializeToXML(t *testing.T) {
 		t.Errorf("Does not contain one of the raw components")
 	}
 }
+
+func TestHostMarshalXML(t *testing.T) {
+	tests := []struct {
+		name string
+		host Host
+		want string
+	}{
+		{
+			name: "idle",
+			host: Host{Name: "all.q@node1", QType: "BIP", SlotsTotal: 4},
+			want: `<Queue-List><name>all.q@node1</name><qtype>BIP</qtype><slots_used>0</slots_used><slots_resv>0</slots_resv><slots_total>4</slots_total><load_avg>0</load_avg></Queue-List>`,
+		},
+		{
+			name: "unknown state",
+			host: Host{Name: "all.q@node2", QType: "BIP", SlotsTotal: 4, State: "au"},
+			want: `<Queue-List><name>all.q@node2</name><qtype>BIP</qtype><slots_used>0</slots_used><slots_resv>0</slots_resv><slots_total>4</slots_total><state>au</state></Queue-List>`,
+		},
+		{
+			name: "unknown elements",
+			host: Host{
+				Name:            "all.q@node3",
+				SlotsReserved:   2,
+				LoadAverage:     1.5,
+				UnknownAttrs:    []UnknownAttr{{Name: xml.Name{Local: "zone"}, Value: "a"}},
+				UnknownElements: []UnknownElement{{XMLName: xml.Name{Local: "arch"}, Content: "lx-amd64"}},
+			},
+			want: `<Queue-List zone="a"><name>all.q@node3</name><qtype></qtype><slots_used>0</slots_used><slots_resv>2</slots_resv><slots_total>0</slots_total><load_avg>1.5</load_avg><arch>lx-amd64</arch></Queue-List>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := xml.Marshal(tt.host)
+			assert.Nil(t, err)
+			assert.Equal(t, tt.want, string(output))
+		})
+	}
+}
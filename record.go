@@ -0,0 +1,213 @@
+package gogridengine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+//ErrNoRecording is returned by a ReplayRunner asked to run a command it has no recording of
+const ErrNoRecording = Error("No recording matches the requested command")
+
+//DefaultRecordedEnv is the subset of the environment captured alongside every recording when none is specified
+var DefaultRecordedEnv = []string{"SGE_ROOT", "SGE_CELL", "SGE_QMASTER_PORT", "SGE_EXECD_PORT", "SGE_CLUSTER_NAME"}
+
+//Recording is a single captured command invocation as stored in a fixture directory
+type Recording struct {
+	Name     string            `json:"name"`
+	Args     []string          `json:"args"`
+	Env      map[string]string `json:"env,omitempty"`
+	Stdout   string            `json:"stdout"`
+	Stderr   string            `json:"stderr"`
+	ExitCode int               `json:"exit_code"`
+	//Error is the message of the error returned by the runner, if any
+	Error   string        `json:"error,omitempty"`
+	Latency time.Duration `json:"latency"`
+}
+
+//RecordingRunner decorates another CommandRunner, writing every invocation to a fixture directory as a numbered JSON file
+type RecordingRunner struct {
+	Runner CommandRunner
+	Dir    string
+	//Env is the list of environment variable names captured with each recording. Defaults to DefaultRecordedEnv
+	Env []string
+
+	mu    sync.Mutex
+	count int
+}
+
+//NewRecordingRunner records every command run through the provided runner into dir, creating it if needed
+func NewRecordingRunner(runner CommandRunner, dir string) (*RecordingRunner, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	existing, err := filepath.Glob(filepath.Join(dir, "*.json"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &RecordingRunner{
+		Runner: runner,
+		Dir:    dir,
+		//Continue numbering after anything already recorded
+		count: len(existing),
+	}, nil
+}
+
+//Run executes the command through the wrapped runner and records the outcome before returning it
+func (r *RecordingRunner) Run(ctx context.Context, name string, args ...string) (CommandResult, error) {
+	started := time.Now()
+	result, err := r.Runner.Run(ctx, name, args...)
+
+	recording := Recording{
+		Name:     name,
+		Args:     args,
+		Env:      make(map[string]string),
+		Stdout:   string(result.Stdout),
+		Stderr:   string(result.Stderr),
+		ExitCode: result.ExitCode,
+		Latency:  time.Since(started),
+	}
+
+	if err != nil {
+		recording.Error = err.Error()
+	}
+
+	env := r.Env
+	if env == nil {
+		env = DefaultRecordedEnv
+	}
+
+	for _, v := range env {
+		if value, ok := os.LookupEnv(v); ok {
+			recording.Env[v] = value
+		}
+	}
+
+	content, marshalErr := json.MarshalIndent(recording, "", "  ")
+	if marshalErr != nil {
+		return result, marshalErr
+	}
+
+	r.mu.Lock()
+	r.count++
+	path := filepath.Join(r.Dir, fmt.Sprintf("%04d-%s.json", r.count, filepath.Base(name)))
+	r.mu.Unlock()
+
+	if writeErr := ioutil.WriteFile(path, content, 0644); writeErr != nil {
+		return result, writeErr
+	}
+
+	return result, err
+}
+
+//ReplayRunner serves recorded invocations deterministically. Identical commands are answered in the order they were recorded, repeating the last answer once exhausted.
+type ReplayRunner struct {
+	//SimulateLatency sleeps for the recorded latency before answering
+	SimulateLatency bool
+
+	mu         sync.Mutex
+	recordings map[string][]Recording
+	served     map[string]int
+}
+
+//NewReplayRunner loads every recording found in the fixture directory
+func NewReplayRunner(dir string) (*ReplayRunner, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	//Zero padded names keep the recorded order
+	sort.Strings(paths)
+
+	var recordings []Recording
+
+	for _, p := range paths {
+		content, err := ioutil.ReadFile(p)
+
+		if err != nil {
+			return nil, err
+		}
+
+		var recording Recording
+		if err := json.Unmarshal(content, &recording); err != nil {
+			return nil, fmt.Errorf("unable to load recording %s: %w", p, err)
+		}
+
+		recordings = append(recordings, recording)
+	}
+
+	return NewReplayRunnerFromRecordings(recordings), nil
+}
+
+//NewReplayRunnerFromRecordings serves the provided recordings, in order
+func NewReplayRunnerFromRecordings(recordings []Recording) *ReplayRunner {
+	r := &ReplayRunner{
+		recordings: make(map[string][]Recording),
+		served:     make(map[string]int),
+	}
+
+	for _, v := range recordings {
+		key := recordingKey(v.Name, v.Args)
+		r.recordings[key] = append(r.recordings[key], v)
+	}
+
+	return r
+}
+
+//Run answers the command from the matching recording
+func (r *ReplayRunner) Run(ctx context.Context, name string, args ...string) (CommandResult, error) {
+	key := recordingKey(name, args)
+
+	r.mu.Lock()
+	candidates := r.recordings[key]
+
+	if len(candidates) == 0 {
+		r.mu.Unlock()
+		return CommandResult{ExitCode: -1}, fmt.Errorf("%w: %s", ErrNoRecording, key)
+	}
+
+	index := r.served[key]
+	if index >= len(candidates) {
+		index = len(candidates) - 1
+	}
+	r.served[key]++
+	r.mu.Unlock()
+
+	recording := candidates[index]
+
+	if r.SimulateLatency && recording.Latency > 0 {
+		select {
+		case <-time.After(recording.Latency):
+		case <-ctx.Done():
+			return CommandResult{ExitCode: -1}, ctx.Err()
+		}
+	}
+
+	result := CommandResult{
+		Stdout:   []byte(recording.Stdout),
+		Stderr:   []byte(recording.Stderr),
+		ExitCode: recording.ExitCode,
+	}
+
+	if recording.Error != "" {
+		return result, Error(recording.Error)
+	}
+
+	return result, nil
+}
+
+func recordingKey(name string, args []string) string {
+	return strings.Join(append([]string{filepath.Base(name)}, args...), " ")
+}
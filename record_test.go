@@ -0,0 +1,76 @@
+package gogridengine
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gogridengine-recordings")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	os.Setenv("SGE_CELL", "testing")
+	defer os.Unsetenv("SGE_CELL")
+
+	underlying := &fakeRunner{
+		responses: map[string]CommandResult{
+			"qstat -u * -F -xml": {Stdout: []byte("<job_info></job_info>")},
+		},
+	}
+
+	recorder, err := NewRecordingRunner(underlying, dir)
+	assert.Nil(t, err)
+
+	result, err := recorder.Run(context.Background(), "qstat", "-u", "*", "-F", "-xml")
+	assert.Nil(t, err)
+	assert.Equal(t, "<job_info></job_info>", string(result.Stdout))
+
+	_, err = recorder.Run(context.Background(), "qdel", "42")
+	assert.NotNil(t, err)
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	assert.Nil(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "0001-qstat.json"), filepath.Join(dir, "0002-qdel.json")}, paths)
+
+	replay, err := NewReplayRunner(dir)
+	assert.Nil(t, err)
+
+	result, err = replay.Run(context.Background(), "qstat", "-u", "*", "-F", "-xml")
+	assert.Nil(t, err)
+	assert.Equal(t, "<job_info></job_info>", string(result.Stdout))
+
+	result, err = replay.Run(context.Background(), "qdel", "42")
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Equal(t, "error: job id not found", string(result.Stderr))
+
+	_, err = replay.Run(context.Background(), "qstat")
+	assert.True(t, errors.Is(err, ErrNoRecording))
+
+	content, err := ioutil.ReadFile(filepath.Join(dir, "0001-qstat.json"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(content), `"SGE_CELL": "testing"`)
+}
+
+func TestReplayRunnerServesInOrder(t *testing.T) {
+	replay := NewReplayRunnerFromRecordings([]Recording{
+		{Name: "qstat", Args: []string{"-xml"}, Stdout: "first"},
+		{Name: "qdel", Args: []string{"1"}, Stdout: "deleted"},
+		{Name: "qstat", Args: []string{"-xml"}, Stdout: "second", Latency: time.Millisecond},
+	})
+	replay.SimulateLatency = true
+
+	for _, want := range []string{"first", "second", "second"} {
+		result, err := replay.Run(context.Background(), "/opt/sge/bin/qstat", "-xml")
+		assert.Nil(t, err)
+		assert.Equal(t, want, string(result.Stdout))
+	}
+}
@@ -3,6 +3,7 @@ package gogridengine
 import (
 	"encoding/xml"
 	"strconv"
+	"strings"
 )
 
 //ResourceList is a slice of resources primarily used for sourcing internally and setup of receiver based functions
@@ -13,9 +14,14 @@ type Resource struct {
 	XMLName xml.Name `xml:"resource" json:"-"`
 	Name    string   `xml:"name,attr" json:"name"`
 	Type    string   `xml:"type,attr" json:"type"`
-	Value   string   `xml:",innerxml"`
+	//UnknownAttrs are attributes other than name and type, preserved for re-serialisation
+	UnknownAttrs []UnknownAttr `xml:",any,attr" json:"-"`
+	Value        string        `xml:",innerxml"`
 }
 
+//ErrEmptyStorageValue is returned when attempting to parse a storage value from an empty string
+const ErrEmptyStorageValue = Error("Cannot parse a storage value from an empty string")
+
 //StorageValue breaks down string metrics from a computer storage standpoint (ie 10.2G) so that it can be calculated to bytes
 type StorageValue struct {
 	Size  float64 `json:"size"`
@@ -23,25 +29,56 @@ type StorageValue struct {
 	Bytes int64   `json:"bytes"`
 }
 
+//ErrInvalidStorageScale is returned when a storage value ends in a unit other than k, m, g, t, K, M, G or T
+const ErrInvalidStorageScale = Error("Storage values must be a number of bytes, optionally followed by k, m, g, t, K, M, G or T")
+
+//storageScales are the bytes in each unit. As in the grid engine, lowercase units are multiples of 1000 and uppercase units multiples of 1024
+var storageScales = map[string]float64{
+	"k": 1000,
+	"m": 1000 * 1000,
+	"g": 1000 * 1000 * 1000,
+	"t": 1000 * 1000 * 1000 * 1000,
+	"K": 1024,
+	"M": 1024 * 1024,
+	"G": 1024 * 1024 * 1024,
+	"T": 1024 * 1024 * 1024 * 1024,
+}
+
+//ParseStorageValue parses a storage value as reported by the grid engine or requested through qsub (eg: 4G, 512.000M, 4g or a plain number of bytes)
+func ParseStorageValue(input string) (StorageValue, error) {
+	return newStorageValue(input)
+}
+
 func newStorageValue(input string) (StorageValue, error) {
 	var sv StorageValue
-	sv.Scale = string(input[len(input)-1])
-	remainingBytes := input[:(len(input) - 1)]
-	remainingSize, err := strconv.ParseFloat(string(remainingBytes), 64)
+
+	input = strings.TrimSpace(input)
+	if len(input) == 0 {
+		return StorageValue{}, ErrEmptyStorageValue
+	}
+
+	number := input
+	multiplier := 1.0
+
+	//Values without a unit are a number of bytes
+	if last := input[len(input)-1:]; !strings.ContainsAny(last, "0123456789.") {
+		scale, ok := storageScales[last]
+		if !ok {
+			return StorageValue{}, ErrInvalidStorageScale
+		}
+
+		sv.Scale = last
+		number = input[:len(input)-1]
+		multiplier = scale
+	}
+
+	size, err := strconv.ParseFloat(number, 64)
 	if err != nil {
 		return StorageValue{}, err
 	}
-	sv.Size = remainingSize
-
-	//Now the case statement
-	switch sv.Scale {
-	case "G":
-		sv.Bytes = int64(sv.Size * (1000 * 1000 * 1000))
-	case "M":
-		sv.Bytes = int64(sv.Size * (1000 * 1000))
-	case "T":
-		sv.Bytes = int64(sv.Size * (1000 * 1000 * 1000 * 1000))
-	}
+
+	sv.Size = size
+	sv.Bytes = int64(size * multiplier)
 
 	return sv, nil
 }
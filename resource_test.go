@@ -21,7 +21,7 @@ func TestNewStorageValue(t *testing.T) {
 				input: "57.00G",
 			},
 			want: StorageValue{
-				Bytes: 57000000000,
+				Bytes: 61203283968,
 				Scale: "G",
 				Size:  57.00,
 			},
@@ -32,7 +32,7 @@ func TestNewStorageValue(t *testing.T) {
 				input: "1.01M",
 			},
 			want: StorageValue{
-				Bytes: 1010000,
+				Bytes: 1059061,
 				Scale: "M",
 				Size:  1.01,
 			},
@@ -43,11 +43,102 @@ func TestNewStorageValue(t *testing.T) {
 				input: "4.76T",
 			},
 			want: StorageValue{
-				Bytes: 4760000000000,
+				Bytes: 5233675348213,
 				Scale: "T",
 				Size:  4.76,
 			},
 		},
+		{
+			name: "Gigabytes",
+			args: args{
+				input: "4G",
+			},
+			want: StorageValue{
+				Bytes: 4294967296,
+				Scale: "G",
+				Size:  4,
+			},
+		},
+		{
+			name: "Lowercase gigabytes",
+			args: args{
+				input: "4g",
+			},
+			want: StorageValue{
+				Bytes: 4000000000,
+				Scale: "g",
+				Size:  4,
+			},
+		},
+		{
+			name: "Lowercase megabytes",
+			args: args{
+				input: "512m",
+			},
+			want: StorageValue{
+				Bytes: 512000000,
+				Scale: "m",
+				Size:  512,
+			},
+		},
+		{
+			name: "Lowercase kilobytes",
+			args: args{
+				input: "2.5k",
+			},
+			want: StorageValue{
+				Bytes: 2500,
+				Scale: "k",
+				Size:  2.5,
+			},
+		},
+		{
+			name: "Kilobytes",
+			args: args{
+				input: "2.5K",
+			},
+			want: StorageValue{
+				Bytes: 2560,
+				Scale: "K",
+				Size:  2.5,
+			},
+		},
+		{
+			name: "Plain bytes",
+			args: args{
+				input: "4096",
+			},
+			want: StorageValue{
+				Bytes: 4096,
+				Scale: "",
+				Size:  4096,
+			},
+		},
+		{
+			name: "Unreported swap",
+			args: args{
+				input: "0.000",
+			},
+			want: StorageValue{
+				Bytes: 0,
+				Scale: "",
+				Size:  0,
+			},
+		},
+		{
+			name: "Unknown unit",
+			args: args{
+				input: "4P",
+			},
+			wantErr: true,
+		},
+		{
+			name: "Unlimited",
+			args: args{
+				input: "infinity",
+			},
+			wantErr: true,
+		},
 		{
 			name: "Invalid Storage Value",
 			args: args{
@@ -242,7 +333,7 @@ func TestResourceListGetStorageValueFromList(t *testing.T) {
 			want: StorageValue{
 				Size:  3.2,
 				Scale: "G",
-				Bytes: 3200000000,
+				Bytes: 3435973836,
 			},
 			wantErr: false,
 		},
@@ -0,0 +1,73 @@
+package gogridengine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//CommandResult is the captured outcome of running a grid engine binary
+type CommandResult struct {
+	Stdout   []byte
+	Stderr   []byte
+	ExitCode int
+}
+
+//CommandRunner executes grid engine binaries (qstat, qdel, qacct...) on behalf of the library.
+//Implementations return a non-nil error alongside the result when the command couldn't be run or exited non-zero.
+type CommandRunner interface {
+	Run(ctx context.Context, name string, args ...string) (CommandResult, error)
+}
+
+//ExecRunner runs binaries found on the PATH of the local machine
+type ExecRunner struct {
+	//Env is appended to the environment of the current process (eg: SGE_ROOT=/opt/sge)
+	Env []string
+}
+
+//DefaultRunner is the CommandRunner used by the package level functions
+var DefaultRunner CommandRunner = ExecRunner{}
+
+//Run locates the binary and executes it with the provided arguments
+func (r ExecRunner) Run(ctx context.Context, name string, args ...string) (CommandResult, error) {
+	//Locate the binary in existing path
+	binary, err := exec.LookPath(name)
+
+	if err != nil {
+		log.Error("Couldn't locate binary", err)
+		return CommandResult{ExitCode: -1}, errors.New("Couldn't locate the binary")
+	}
+
+	command := exec.CommandContext(ctx, binary, args...)
+	command.Env = append(os.Environ(), r.Env...)
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	command.Stdout = stdout
+	command.Stderr = stderr
+
+	err = command.Run()
+
+	result := CommandResult{
+		Stdout: stdout.Bytes(),
+		Stderr: stderr.Bytes(),
+	}
+
+	if err != nil {
+		result.ExitCode = -1
+
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			result.ExitCode = exitErr.ExitCode()
+		}
+
+		return result, fmt.Errorf("an error occurred during execution of the the binary %s: %w", binary, err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,27 @@
+package gogridengine
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecRunner_Run(t *testing.T) {
+	runner := ExecRunner{
+		Env: []string{"GOGRIDENGINE_RUNNER_TEST=meow"},
+	}
+
+	result, err := runner.Run(context.Background(), "sh", "-c", "echo $GOGRIDENGINE_RUNNER_TEST; echo woof 1>&2")
+	assert.Nil(t, err)
+	assert.Equal(t, "meow\n", string(result.Stdout))
+	assert.Equal(t, "woof\n", string(result.Stderr))
+	assert.Equal(t, 0, result.ExitCode)
+
+	result, err = runner.Run(context.Background(), "sh", "-c", "exit 3")
+	assert.NotNil(t, err)
+	assert.Equal(t, 3, result.ExitCode)
+
+	_, err = runner.Run(context.Background(), "not-a-grid-engine-binary")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,728 @@
+//Package simulator is an in-memory stand-in for a Sun Grid Engine cell. Jobs are scheduled onto hosts as simulated time advances and the cluster answers qstat, qsub, qdel, qhold, qrls and qacct through the CommandRunner interface.
+package simulator
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/metrumresearchgroup/gogridengine"
+)
+
+const (
+	//StatePending is a task waiting to be scheduled
+	StatePending string = "qw"
+	//StateHeld is a pending task that won't be scheduled until released
+	StateHeld string = "hqw"
+	//StateRunning is a task occupying slots on a host
+	StateRunning string = "r"
+	//StateError is a task that failed to start. It stays queued until deleted
+	StateError string = "Eqw"
+)
+
+//ErrUnknownJob is returned when acting on a job number the cluster doesn't know about
+const ErrUnknownJob = gogridengine.Error("The job does not exist")
+
+//ErrUnknownHost is returned when changing the state of a host the cluster doesn't have
+const ErrUnknownHost = gogridengine.Error("The host does not exist")
+
+//ErrInvalidJobSpec is returned when submitting a job that could never be scheduled
+const ErrInvalidJobSpec = gogridengine.Error("The job specification is invalid")
+
+//HostSpec describes an execution host of the simulated cluster
+type HostSpec struct {
+	Name   string `json:"name"`
+	Slots  int32  `json:"slots"`
+	Memory int64  `json:"memory"`
+	//State is the queue instance state (eg: au, d, E). Hosts with a state are not scheduled onto
+	State string `json:"state,omitempty"`
+}
+
+//Options configure a simulated cluster
+type Options struct {
+	//Queue is the cluster queue every host serves. Defaults to all.q
+	Queue string
+	Hosts []HostSpec
+	//Start is the simulated time the cluster starts at. Defaults to the current time
+	Start time.Time
+	//User owns jobs submitted through qsub and is named in qdel output. Defaults to user
+	User string
+	//DefaultRuntime is used for qsub submissions without a runtime. Defaults to an hour
+	DefaultRuntime time.Duration
+	//Backfill lets smaller jobs start ahead of a higher priority job that doesn't fit yet. Without it the scheduler is strictly priority then FIFO ordered
+	Backfill bool
+}
+
+//JobSpec describes a job submitted to the simulated cluster
+type JobSpec struct {
+	Name  string `json:"name"`
+	Owner string `json:"owner"`
+	//Slots requested per task. Defaults to 1
+	Slots int32 `json:"slots"`
+	//Memory in bytes requested per task (h_vmem)
+	Memory int64 `json:"memory"`
+	//Priority as passed to qsub -p, from -1023 to 1024
+	Priority int `json:"priority"`
+	//Runtime is how long each task runs once scheduled
+	Runtime time.Duration `json:"runtime"`
+	//FirstTask, LastTask and TaskStep describe an array job (qsub -t). Leave FirstTask at 0 for a regular job
+	FirstTask int64 `json:"first_task,omitempty"`
+	LastTask  int64 `json:"last_task,omitempty"`
+	TaskStep  int64 `json:"task_step,omitempty"`
+	//Hold submits the job in the held state (qsub -h)
+	Hold bool `json:"hold,omitempty"`
+	//Error makes tasks enter the Eqw state when they are dispatched instead of running
+	Error bool `json:"error,omitempty"`
+	//ExitStatus is reported by qacct once tasks finish
+	ExitStatus int `json:"exit_status,omitempty"`
+}
+
+//IsArray returns whether the spec describes an array job
+func (s JobSpec) IsArray() bool {
+	return s.FirstTask > 0
+}
+
+//Cluster is a simulated grid engine cell. It is safe for concurrent use
+type Cluster struct {
+	mutex    sync.Mutex
+	opts     Options
+	now      time.Time
+	nextJob  int64
+	hosts    []*host
+	jobs     []*job
+	finished []finishedTask
+}
+
+type host struct {
+	HostSpec
+	usedSlots  int32
+	usedMemory int64
+}
+
+type job struct {
+	number    int64
+	spec      JobSpec
+	submitted time.Time
+	tasks     []*task
+}
+
+type task struct {
+	id    int64
+	state string
+	host  *host
+	start time.Time
+}
+
+//finishedTask is kept around so qacct can report on it
+type finishedTask struct {
+	job        *job
+	taskID     int64
+	host       string
+	start      time.Time
+	end        time.Time
+	failed     int
+	exitStatus int
+}
+
+//New creates a simulated cluster with the provided hosts and no jobs
+func New(opts Options) *Cluster {
+	if opts.Queue == "" {
+		opts.Queue = "all.q"
+	}
+
+	if opts.Start.IsZero() {
+		opts.Start = time.Now()
+	}
+
+	if opts.User == "" {
+		opts.User = "user"
+	}
+
+	if opts.DefaultRuntime <= 0 {
+		opts.DefaultRuntime = time.Hour
+	}
+
+	c := &Cluster{
+		opts:    opts,
+		now:     opts.Start.Truncate(time.Second),
+		nextJob: 1,
+	}
+
+	for _, h := range opts.Hosts {
+		c.hosts = append(c.hosts, &host{HostSpec: h})
+	}
+
+	return c
+}
+
+//Now returns the current simulated time
+func (c *Cluster) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.now
+}
+
+//Submit queues a job and runs the scheduler, returning the job number assigned
+func (c *Cluster) Submit(spec JobSpec) (int64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if spec.Slots <= 0 {
+		spec.Slots = 1
+	}
+
+	if spec.Owner == "" {
+		spec.Owner = c.opts.User
+	}
+
+	if spec.Name == "" {
+		spec.Name = "STDIN"
+	}
+
+	if spec.Runtime <= 0 {
+		spec.Runtime = c.opts.DefaultRuntime
+	}
+
+	if spec.TaskStep <= 0 {
+		spec.TaskStep = 1
+	}
+
+	if spec.FirstTask < 0 || (spec.IsArray() && spec.LastTask < spec.FirstTask) {
+		return 0, ErrInvalidJobSpec
+	}
+
+	if spec.Priority < -1023 || spec.Priority > 1024 {
+		return 0, ErrInvalidJobSpec
+	}
+
+	j := &job{
+		number:    c.nextJob,
+		spec:      spec,
+		submitted: c.now,
+	}
+	c.nextJob++
+
+	state := StatePending
+	if spec.Hold {
+		state = StateHeld
+	}
+
+	if spec.IsArray() {
+		for id := spec.FirstTask; id <= spec.LastTask; id += spec.TaskStep {
+			j.tasks = append(j.tasks, &task{id: id, state: state})
+		}
+	} else {
+		j.tasks = append(j.tasks, &task{state: state})
+	}
+
+	c.jobs = append(c.jobs, j)
+	c.schedule()
+
+	return j.number, nil
+}
+
+//Delete removes every task of the job, freeing any slots it held. It returns whether any of the tasks were running
+func (c *Cluster) Delete(jobNumber int64) (bool, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.deleteTasks(jobNumber, 0)
+}
+
+//Hold places every pending task of the job on hold. Running tasks are unaffected
+func (c *Cluster) Hold(jobNumber int64) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.setPendingState(jobNumber, 0, StatePending, StateHeld)
+}
+
+//Release removes the hold from every held task of the job and runs the scheduler
+func (c *Cluster) Release(jobNumber int64) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.setPendingState(jobNumber, 0, StateHeld, StatePending)
+}
+
+//Jobs returns the numbers of the jobs currently known, optionally limited to the provided owners
+func (c *Cluster) Jobs(owners ...string) []int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	wanted := make(map[string]bool)
+	for _, o := range owners {
+		wanted[o] = true
+	}
+
+	var numbers []int64
+
+	for _, j := range c.jobs {
+		if len(wanted) > 0 && !wanted[j.spec.Owner] {
+			continue
+		}
+
+		numbers = append(numbers, j.number)
+	}
+
+	return numbers
+}
+
+//SetHostState changes the queue instance state of a host (eg: "d" to disable it, "" to enable it). Running tasks are left alone
+func (c *Cluster) SetHostState(name string, state string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, h := range c.hosts {
+		if h.Name == name {
+			h.State = state
+			c.schedule()
+			return nil
+		}
+	}
+
+	return ErrUnknownHost
+}
+
+//Advance moves simulated time forward, completing tasks and scheduling pending ones in the order they would have happened
+func (c *Cluster) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.advanceTo(c.now.Add(d))
+}
+
+//AdvanceTo moves simulated time forward to the provided time. Times in the past are ignored
+func (c *Cluster) AdvanceTo(t time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.advanceTo(t)
+}
+
+func (c *Cluster) advanceTo(target time.Time) {
+	for {
+		var next *task
+		var owner *job
+
+		//Find the next task to complete before the target
+		for _, j := range c.jobs {
+			for _, t := range j.tasks {
+				if t.state != StateRunning {
+					continue
+				}
+
+				if next == nil || t.start.Add(j.spec.Runtime).Before(next.start.Add(owner.spec.Runtime)) {
+					next = t
+					owner = j
+				}
+			}
+		}
+
+		if next == nil || next.start.Add(owner.spec.Runtime).After(target) {
+			break
+		}
+
+		c.now = next.start.Add(owner.spec.Runtime)
+		c.finish(owner, next, 0, owner.spec.ExitStatus)
+		c.removeFinishedJobs()
+		c.schedule()
+	}
+
+	if target.After(c.now) {
+		c.now = target
+	}
+}
+
+//finish records the task in the accounting and releases its resources. The task is removed from the job
+func (c *Cluster) finish(j *job, t *task, failed int, exitStatus int) {
+	c.finished = append(c.finished, finishedTask{
+		job:        j,
+		taskID:     t.id,
+		host:       t.host.Name,
+		start:      t.start,
+		end:        c.now,
+		failed:     failed,
+		exitStatus: exitStatus,
+	})
+
+	t.host.usedSlots -= j.spec.Slots
+	t.host.usedMemory -= j.spec.Memory
+
+	for k, candidate := range j.tasks {
+		if candidate == t {
+			j.tasks = append(j.tasks[:k], j.tasks[k+1:]...)
+			break
+		}
+	}
+}
+
+func (c *Cluster) removeFinishedJobs() {
+	remaining := c.jobs[:0]
+
+	for _, j := range c.jobs {
+		if len(j.tasks) > 0 {
+			remaining = append(remaining, j)
+		}
+	}
+
+	c.jobs = remaining
+}
+
+//schedule dispatches pending tasks by priority, then job number, then task id
+func (c *Cluster) schedule() {
+	type candidate struct {
+		job  *job
+		task *task
+	}
+
+	var pending []candidate
+
+	for _, j := range c.jobs {
+		for _, t := range j.tasks {
+			if t.state == StatePending {
+				pending = append(pending, candidate{job: j, task: t})
+			}
+		}
+	}
+
+	sort.SliceStable(pending, func(a, b int) bool {
+		if pending[a].job.spec.Priority != pending[b].job.spec.Priority {
+			return pending[a].job.spec.Priority > pending[b].job.spec.Priority
+		}
+
+		return pending[a].job.number < pending[b].job.number
+	})
+
+	for _, p := range pending {
+		h := c.hostFor(p.job.spec)
+
+		if h == nil {
+			if c.opts.Backfill {
+				continue
+			}
+
+			return
+		}
+
+		if p.job.spec.Error {
+			p.task.state = StateError
+			continue
+		}
+
+		p.task.state = StateRunning
+		p.task.host = h
+		p.task.start = c.now
+		h.usedSlots += p.job.spec.Slots
+		h.usedMemory += p.job.spec.Memory
+	}
+}
+
+//hostFor returns the first available host with enough free slots and memory for a task of the job
+func (c *Cluster) hostFor(spec JobSpec) *host {
+	for _, h := range c.hosts {
+		if h.State != "" {
+			continue
+		}
+
+		if h.Slots-h.usedSlots < spec.Slots {
+			continue
+		}
+
+		if h.Memory > 0 && h.Memory-h.usedMemory < spec.Memory {
+			continue
+		}
+
+		return h
+	}
+
+	return nil
+}
+
+//tasks returns the job and its tasks matching the task id, every task when the id is 0. Only array jobs can be addressed by task. The lock must be held
+func (c *Cluster) tasks(jobNumber int64, taskID int64) (*job, []*task, error) {
+	k := c.jobIndex(jobNumber)
+	if k < 0 {
+		return nil, nil, ErrUnknownJob
+	}
+
+	j := c.jobs[k]
+
+	if taskID == 0 {
+		return j, append([]*task(nil), j.tasks...), nil
+	}
+
+	if j.spec.IsArray() {
+		for _, t := range j.tasks {
+			if t.id == taskID {
+				return j, []*task{t}, nil
+			}
+		}
+	}
+
+	return nil, nil, ErrUnknownJob
+}
+
+//deleteTasks removes the tasks of the job matching the task id, freeing any slots they held, and the job once it has no task left.
+//It returns whether any of the tasks were running. The lock must be held
+func (c *Cluster) deleteTasks(jobNumber int64, taskID int64) (bool, error) {
+	j, tasks, err := c.tasks(jobNumber, taskID)
+	if err != nil {
+		return false, err
+	}
+
+	running := false
+
+	//tasks are a copy, so finishing (which removes the task from the job) doesn't disturb the loop
+	for _, t := range tasks {
+		if t.state == StateRunning {
+			//Deleted tasks are killed, which is how SGE accounts for them
+			c.finish(j, t, 100, 137)
+			running = true
+			continue
+		}
+
+		for k, candidate := range j.tasks {
+			if candidate == t {
+				j.tasks = append(j.tasks[:k], j.tasks[k+1:]...)
+				break
+			}
+		}
+	}
+
+	c.removeFinishedJobs()
+	c.schedule()
+
+	return running, nil
+}
+
+//setPendingState moves the tasks of the job matching the task id from one pending state to another and runs the scheduler. The lock must be held
+func (c *Cluster) setPendingState(jobNumber int64, taskID int64, from string, to string) error {
+	_, tasks, err := c.tasks(jobNumber, taskID)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tasks {
+		if t.state == from {
+			t.state = to
+		}
+	}
+
+	c.schedule()
+
+	return nil
+}
+
+func (c *Cluster) jobIndex(jobNumber int64) int {
+	for k, j := range c.jobs {
+		if j.number == jobNumber {
+			return k
+		}
+	}
+
+	return -1
+}
+
+//Snapshot returns the cluster state as NewJobInfo would parse it from qstat -F -xml, with pending task ranges extrapolated
+func (c *Cluster) Snapshot() (gogridengine.JobInfo, error) {
+	xml, err := c.qstatXML(nil, false)
+
+	if err != nil {
+		return gogridengine.JobInfo{}, err
+	}
+
+	return gogridengine.NewJobInfo(xml)
+}
+
+//jobInfo renders the cluster state the way qstat reports it: running tasks under their queue instance and pending tasks collapsed into ranges
+func (c *Cluster) jobInfo(owners map[string]bool, requests bool) gogridengine.JobInfo {
+	var ji gogridengine.JobInfo
+
+	instances := make(map[*host]int)
+
+	for _, h := range c.hosts {
+		instances[h] = len(ji.QueueInfo.Queues)
+		ji.QueueInfo.Queues = append(ji.QueueInfo.Queues, c.queueInstance(h))
+	}
+
+	for _, j := range c.jobs {
+		if len(owners) > 0 && !owners[j.spec.Owner] {
+			continue
+		}
+
+		for _, t := range j.tasks {
+			if t.state != StateRunning {
+				continue
+			}
+
+			entry := c.jobEntry(j, requests)
+			entry.StateAttribute = "running"
+			entry.State = StateRunning
+			entry.StartTime = t.start.Format(gogridengine.ISO8601FMT)
+			if j.spec.IsArray() {
+				entry.Tasks = gogridengine.Task{Source: strconv.FormatInt(t.id, 10), TaskID: t.id}
+			}
+
+			k := instances[t.host]
+			entry.QueueName = ji.QueueInfo.Queues[k].Name
+			ji.QueueInfo.Queues[k].JobList = append(ji.QueueInfo.Queues[k].JobList, entry)
+		}
+	}
+
+	for _, j := range c.pendingOrder() {
+		if len(owners) > 0 && !owners[j.spec.Owner] {
+			continue
+		}
+
+		ji.PendingJobs.JobList = append(ji.PendingJobs.JobList, c.pendingEntries(j, requests)...)
+	}
+
+	return ji
+}
+
+//pendingOrder lists jobs with queued tasks the way qstat does, highest priority first
+func (c *Cluster) pendingOrder() []*job {
+	var jobs []*job
+
+	for _, j := range c.jobs {
+		for _, t := range j.tasks {
+			if t.state != StateRunning {
+				jobs = append(jobs, j)
+				break
+			}
+		}
+	}
+
+	sort.SliceStable(jobs, func(a, b int) bool {
+		return jobs[a].spec.Priority > jobs[b].spec.Priority
+	})
+
+	return jobs
+}
+
+//pendingEntries collapses consecutive queued tasks sharing a state into task ranges (eg: 4-10:1)
+func (c *Cluster) pendingEntries(j *job, requests bool) []gogridengine.Job {
+	var entries []gogridengine.Job
+
+	var group []*task
+
+	flush := func() {
+		if len(group) == 0 {
+			return
+		}
+
+		entry := c.jobEntry(j, requests)
+		entry.StateAttribute = "pending"
+		entry.State = group[0].state
+		entry.SubmittedTime = j.submitted.Format(gogridengine.ISO8601FMT)
+
+		if j.spec.IsArray() {
+			first := group[0].id
+			last := group[len(group)-1].id
+
+			if len(group) == 1 {
+				entry.Tasks = gogridengine.Task{Source: strconv.FormatInt(first, 10), TaskID: first}
+			} else {
+				entry.Tasks = gogridengine.Task{Source: fmt.Sprintf("%d-%d:%d", first, last, j.spec.TaskStep)}
+			}
+		}
+
+		entries = append(entries, entry)
+		group = nil
+	}
+
+	for _, t := range j.tasks {
+		if t.state == StateRunning {
+			flush()
+			continue
+		}
+
+		if len(group) > 0 && (group[0].state != t.state || group[len(group)-1].id+j.spec.TaskStep != t.id) {
+			flush()
+		}
+
+		group = append(group, t)
+	}
+
+	flush()
+
+	return entries
+}
+
+func (c *Cluster) jobEntry(j *job, requests bool) gogridengine.Job {
+	entry := gogridengine.Job{
+		JBJobNumber: j.number,
+		JATPriority: normalizedPriority(j.spec.Priority),
+		JobName:     j.spec.Name,
+		JobOwner:    j.spec.Owner,
+		Slots:       j.spec.Slots,
+	}
+
+	if requests && j.spec.Memory > 0 {
+		entry.HardRequests = []gogridengine.ResourceRequest{
+			{Name: "h_vmem", Value: formatMemory(j.spec.Memory)},
+		}
+	}
+
+	return entry
+}
+
+func (c *Cluster) queueInstance(h *host) gogridengine.Host {
+	instance := gogridengine.Host{
+		Name:       c.opts.Queue + "@" + h.Name,
+		QType:      "BIP",
+		SlotsUsed:  h.usedSlots,
+		SlotsTotal: h.Slots,
+		State:      h.State,
+	}
+
+	//Unreachable hosts don't report load values
+	if h.State == "au" {
+		instance.Resources = gogridengine.ResourceList{
+			{Name: "num_proc", Type: "hl", Value: strconv.Itoa(int(h.Slots))},
+			{Name: "mem_total", Type: "hl", Value: formatMemory(h.Memory)},
+			{Name: "qname", Type: "qf", Value: c.opts.Queue},
+			{Name: "hostname", Type: "qf", Value: h.Name},
+		}
+
+		return instance
+	}
+
+	//Each running slot is assumed to keep a processor busy
+	load := float64(h.usedSlots)
+	instance.LoadAverage = load
+
+	instance.Resources = gogridengine.ResourceList{
+		{Name: "load_avg", Type: "hl", Value: fmt.Sprintf("%f", load)},
+		{Name: "num_proc", Type: "hl", Value: strconv.Itoa(int(h.Slots))},
+		{Name: "mem_free", Type: "hl", Value: formatMemory(h.Memory - h.usedMemory)},
+		{Name: "mem_total", Type: "hl", Value: formatMemory(h.Memory)},
+		{Name: "mem_used", Type: "hl", Value: formatMemory(h.usedMemory)},
+		{Name: "np_load_avg", Type: "hl", Value: fmt.Sprintf("%f", load/float64(maxInt32(h.Slots, 1)))},
+		{Name: "qname", Type: "qf", Value: c.opts.Queue},
+		{Name: "hostname", Type: "qf", Value: h.Name},
+	}
+
+	return instance
+}
+
+//normalizedPriority maps a qsub -p priority onto the 0 to 1 range qstat reports as JAT_prio
+func normalizedPriority(priority int) float64 {
+	return float64(priority+1023) / 2047
+}
+
+func formatMemory(bytes int64) string {
+	return fmt.Sprintf("%.3fG", float64(bytes)/(1024*1024*1024))
+}
+
+func maxInt32(a int32, b int32) int32 {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,280 @@
+package simulator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/metrumresearchgroup/gogridengine"
+	"github.com/stretchr/testify/assert"
+)
+
+const gigabyte = 1024 * 1024 * 1024
+
+var start = time.Date(2019, 12, 18, 15, 0, 0, 0, time.UTC)
+
+//newCluster builds a cluster of two 4 slot hosts with 16G of memory each
+func newCluster(backfill bool) *Cluster {
+	return New(Options{
+		Hosts: []HostSpec{
+			{Name: "node1", Slots: 4, Memory: 16 * gigabyte},
+			{Name: "node2", Slots: 4, Memory: 16 * gigabyte},
+		},
+		Start:    start,
+		User:     "darrellb",
+		Backfill: backfill,
+	})
+}
+
+func snapshot(t *testing.T, c *Cluster) gogridengine.JobInfo {
+	ji, err := c.Snapshot()
+	assert.Nil(t, err)
+
+	return ji
+}
+
+func TestSubmitSchedulesOntoFreeSlots(t *testing.T) {
+	c := newCluster(false)
+
+	first, err := c.Submit(JobSpec{Name: "first", Slots: 4})
+	assert.Nil(t, err)
+	second, err := c.Submit(JobSpec{Name: "second", Slots: 2})
+	assert.Nil(t, err)
+	third, err := c.Submit(JobSpec{Name: "third", Slots: 4})
+	assert.Nil(t, err)
+
+	assert.Equal(t, int64(1), first)
+	assert.Equal(t, int64(2), second)
+	assert.Equal(t, int64(3), third)
+
+	ji := snapshot(t, c)
+
+	assert.Equal(t, "all.q@node1", ji.QueueInfo.Queues[0].Name)
+	assert.Equal(t, int32(4), ji.QueueInfo.Queues[0].SlotsUsed)
+	assert.Equal(t, int32(2), ji.QueueInfo.Queues[1].SlotsUsed)
+	assert.Len(t, ji.QueueInfo.Queues[0].JobList, 1)
+	assert.Equal(t, "first", ji.QueueInfo.Queues[0].JobList[0].JobName)
+	assert.Equal(t, "all.q@node1", ji.QueueInfo.Queues[0].JobList[0].QueueName)
+	assert.Equal(t, "2019-12-18T15:00:00", ji.QueueInfo.Queues[0].JobList[0].StartTime)
+
+	assert.Len(t, ji.PendingJobs.JobList, 1)
+	assert.Equal(t, third, ji.PendingJobs.JobList[0].JBJobNumber)
+	assert.Equal(t, StatePending, ji.PendingJobs.JobList[0].State)
+	assert.Equal(t, "darrellb", ji.PendingJobs.JobList[0].JobOwner)
+}
+
+func TestSchedulerOrdering(t *testing.T) {
+	tests := []struct {
+		name     string
+		backfill bool
+		//running job names once the queue settles
+		running []string
+	}{
+		{
+			name:    "strict priority then FIFO",
+			running: []string{"blocker", "urgent"},
+		},
+		{
+			name:     "backfill",
+			backfill: true,
+			running:  []string{"blocker", "urgent", "small"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCluster(tt.backfill)
+
+			_, _ = c.Submit(JobSpec{Name: "blocker", Slots: 4})
+			_, _ = c.Submit(JobSpec{Name: "partial", Slots: 2})
+			//Hold everything while submitting so priorities decide the order
+			assert.Nil(t, c.SetHostState("node2", "d"))
+			_, _ = c.Submit(JobSpec{Name: "big", Slots: 4})
+			_, _ = c.Submit(JobSpec{Name: "small", Slots: 1})
+			_, _ = c.Submit(JobSpec{Name: "urgent", Slots: 3, Priority: 100})
+
+			//Free node2 of the partial job and re-enable it
+			_, err := c.Delete(2)
+			assert.Nil(t, err)
+			assert.Nil(t, c.SetHostState("node2", ""))
+
+			var running []string
+			for _, h := range snapshot(t, c).QueueInfo.Queues {
+				for _, j := range h.JobList {
+					running = append(running, j.JobName)
+				}
+			}
+
+			assert.ElementsMatch(t, tt.running, running)
+		})
+	}
+}
+
+func TestMemoryLimitsPlacement(t *testing.T) {
+	c := newCluster(false)
+
+	_, _ = c.Submit(JobSpec{Name: "hungry", Memory: 12 * gigabyte})
+	_, _ = c.Submit(JobSpec{Name: "hungry", Memory: 12 * gigabyte})
+	_, _ = c.Submit(JobSpec{Name: "hungry", Memory: 12 * gigabyte})
+
+	ji := snapshot(t, c)
+	assert.Len(t, ji.QueueInfo.Queues[0].JobList, 1)
+	assert.Len(t, ji.QueueInfo.Queues[1].JobList, 1)
+	assert.Len(t, ji.PendingJobs.JobList, 1)
+
+	free, err := ji.QueueInfo.Queues[0].Resources.FreeMemory()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4*gigabyte), free.Bytes)
+}
+
+func TestArrayJobs(t *testing.T) {
+	c := newCluster(false)
+
+	number, err := c.Submit(JobSpec{Name: "array", FirstTask: 1, LastTask: 12, Runtime: time.Minute})
+	assert.Nil(t, err)
+
+	ji := snapshot(t, c)
+
+	running := 0
+	for _, h := range ji.QueueInfo.Queues {
+		for _, j := range h.JobList {
+			assert.Equal(t, number, j.JBJobNumber)
+			assert.NotZero(t, j.Tasks.TaskID)
+			running++
+		}
+	}
+	assert.Equal(t, 8, running)
+
+	//Tasks 9-12 are reported as a range and extrapolated by NewJobInfo
+	assert.Len(t, ji.PendingJobs.JobList, 4)
+	assert.Equal(t, "9-12:1", ji.PendingJobs.JobList[0].Tasks.Source)
+	assert.Equal(t, int64(9), ji.PendingJobs.JobList[0].Tasks.TaskID)
+	assert.Equal(t, int64(12), ji.PendingJobs.JobList[3].Tasks.TaskID)
+
+	c.Advance(time.Minute)
+	ji = snapshot(t, c)
+	assert.Empty(t, ji.PendingJobs.JobList)
+	assert.Equal(t, "2019-12-18T15:01:00", ji.QueueInfo.Queues[0].JobList[0].StartTime)
+
+	c.Advance(time.Minute)
+	assert.Empty(t, c.Jobs())
+}
+
+func TestDeleteRunningArrayJob(t *testing.T) {
+	c := newCluster(false)
+
+	number, err := c.Submit(JobSpec{Name: "array", Slots: 2, FirstTask: 1, LastTask: 4})
+	assert.Nil(t, err)
+
+	ji := snapshot(t, c)
+	assert.Equal(t, int32(4), ji.QueueInfo.Queues[0].SlotsUsed)
+	assert.Equal(t, int32(4), ji.QueueInfo.Queues[1].SlotsUsed)
+
+	running, err := c.Delete(number)
+	assert.Nil(t, err)
+	assert.True(t, running)
+
+	//Every task is killed exactly once and gives its slots back
+	ji = snapshot(t, c)
+	for _, h := range ji.QueueInfo.Queues {
+		assert.Equal(t, int32(0), h.SlotsUsed, h.Name)
+		assert.Empty(t, h.JobList)
+	}
+
+	var killed []int64
+	for _, f := range c.finished {
+		assert.Equal(t, 137, f.exitStatus)
+		killed = append(killed, f.taskID)
+	}
+	assert.ElementsMatch(t, []int64{1, 2, 3, 4}, killed)
+	assert.Empty(t, c.Jobs())
+}
+
+func TestInvalidJobSpecs(t *testing.T) {
+	c := newCluster(false)
+
+	_, err := c.Submit(JobSpec{FirstTask: 5, LastTask: 2})
+	assert.Equal(t, ErrInvalidJobSpec, err)
+
+	_, err = c.Submit(JobSpec{Priority: 2000})
+	assert.Equal(t, ErrInvalidJobSpec, err)
+}
+
+func TestHoldAndRelease(t *testing.T) {
+	c := newCluster(false)
+
+	_, _ = c.Submit(JobSpec{Name: "filler", Slots: 4})
+	_, _ = c.Submit(JobSpec{Name: "filler", Slots: 4})
+	number, _ := c.Submit(JobSpec{Name: "array", FirstTask: 1, LastTask: 4})
+
+	assert.Nil(t, c.Hold(number))
+	ji := snapshot(t, c)
+	assert.Equal(t, StateHeld, ji.PendingJobs.JobList[0].State)
+
+	assert.Equal(t, ErrUnknownJob, c.Hold(99))
+	assert.Equal(t, ErrUnknownJob, c.Release(99))
+
+	assert.Nil(t, c.Release(number))
+	ji = snapshot(t, c)
+	assert.Equal(t, StatePending, ji.PendingJobs.JobList[0].State)
+
+	//Held jobs are skipped by the scheduler
+	held, _ := c.Submit(JobSpec{Name: "held", Hold: true})
+	_, err := c.Delete(1)
+	assert.Nil(t, err)
+
+	ji = snapshot(t, c)
+	for _, j := range ji.PendingJobs.JobList {
+		assert.NotEqual(t, number, j.JBJobNumber)
+	}
+	assert.Equal(t, held, ji.PendingJobs.JobList[0].JBJobNumber)
+	assert.Equal(t, StateHeld, ji.PendingJobs.JobList[0].State)
+}
+
+func TestErrorJobsStayQueued(t *testing.T) {
+	c := newCluster(false)
+
+	number, _ := c.Submit(JobSpec{Name: "broken", Error: true})
+
+	ji := snapshot(t, c)
+	assert.Len(t, ji.PendingJobs.JobList, 1)
+	assert.Equal(t, StateError, ji.PendingJobs.JobList[0].State)
+	assert.Equal(t, int32(0), ji.QueueInfo.Queues[0].SlotsUsed)
+
+	c.Advance(24 * time.Hour)
+	assert.Equal(t, []int64{number}, c.Jobs())
+}
+
+func TestAdvanceCompletesInOrder(t *testing.T) {
+	c := newCluster(false)
+
+	_, _ = c.Submit(JobSpec{Name: "long", Slots: 4, Runtime: time.Hour})
+	_, _ = c.Submit(JobSpec{Name: "short", Slots: 4, Runtime: 10 * time.Minute})
+	next, _ := c.Submit(JobSpec{Name: "next", Slots: 4, Runtime: time.Hour})
+
+	c.Advance(30 * time.Minute)
+	assert.Equal(t, start.Add(30*time.Minute), c.Now())
+
+	ji := snapshot(t, c)
+	assert.Empty(t, ji.PendingJobs.JobList)
+	assert.Equal(t, next, ji.QueueInfo.Queues[1].JobList[0].JBJobNumber)
+	assert.Equal(t, "2019-12-18T15:10:00", ji.QueueInfo.Queues[1].JobList[0].StartTime)
+
+	c.AdvanceTo(start)
+	assert.Equal(t, start.Add(30*time.Minute), c.Now())
+}
+
+func TestUnavailableHosts(t *testing.T) {
+	c := newCluster(false)
+
+	assert.Equal(t, ErrUnknownHost, c.SetHostState("node9", "d"))
+	assert.Nil(t, c.SetHostState("node1", "au"))
+	_, _ = c.Submit(JobSpec{Slots: 4})
+
+	ji := snapshot(t, c)
+	assert.Equal(t, "au", ji.QueueInfo.Queues[0].State)
+	assert.False(t, gogridengine.IsHostAvailable(ji.QueueInfo.Queues[0]))
+	assert.Empty(t, ji.QueueInfo.Queues[0].JobList)
+	assert.Len(t, ji.QueueInfo.Queues[1].JobList, 1)
+
+	_, err := ji.QueueInfo.Queues[0].Resources.Load("avg")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,551 @@
+package simulator
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/metrumresearchgroup/gogridengine"
+)
+
+//Variables understood by qsub -v to shape how a submitted job behaves in the simulation
+const (
+	//RuntimeVariable sets how long each task runs (eg: SIM_RUNTIME=90s or SIM_RUNTIME=90)
+	RuntimeVariable string = "SIM_RUNTIME"
+	//ExitStatusVariable sets the exit status qacct reports once tasks finish
+	ExitStatusVariable string = "SIM_EXIT_STATUS"
+	//ErrorVariable makes tasks enter Eqw instead of running when set to true
+	ErrorVariable string = "SIM_ERROR"
+)
+
+//Run answers grid engine commands against the simulated cluster, making the Cluster a gogridengine.CommandRunner.
+//Output, exit codes and error messages follow the formats of the real binaries.
+func (c *Cluster) Run(ctx context.Context, name string, args ...string) (gogridengine.CommandResult, error) {
+	if err := ctx.Err(); err != nil {
+		return gogridengine.CommandResult{ExitCode: -1}, err
+	}
+
+	var stdout, stderr string
+	var code int
+
+	switch name {
+	case "qstat":
+		stdout, stderr, code = c.qstat(args)
+	case "qsub":
+		stdout, stderr, code = c.qsub(args)
+	case "qdel":
+		stdout, stderr, code = c.qdel(args)
+	case "qhold":
+		stdout, stderr, code = c.modifyHold(args, StatePending, StateHeld)
+	case "qrls":
+		stdout, stderr, code = c.modifyHold(args, StateHeld, StatePending)
+	case "qacct":
+		stdout, stderr, code = c.qacct(args)
+	default:
+		return gogridengine.CommandResult{ExitCode: -1}, fmt.Errorf("the simulator doesn't provide %s", name)
+	}
+
+	result := gogridengine.CommandResult{
+		Stdout:   []byte(stdout),
+		Stderr:   []byte(stderr),
+		ExitCode: code,
+	}
+
+	if code != 0 {
+		return result, fmt.Errorf("exit status %d", code)
+	}
+
+	return result, nil
+}
+
+func (c *Cluster) qstat(args []string) (string, string, int) {
+	owners := make(map[string]bool)
+	requests := false
+	xmlOutput := false
+	full := false
+
+	for k := 0; k < len(args); k++ {
+		switch args[k] {
+		case "-xml":
+			xmlOutput = true
+		case "-r":
+			requests = true
+		case "-F", "-f":
+			//The XML always holds the full output with resources
+			full = true
+		case "-u":
+			if k+1 >= len(args) {
+				return "", "error: ERROR! -u option must have argument\n", 1
+			}
+			k++
+
+			for _, o := range strings.Split(args[k], ",") {
+				if o != "*" {
+					owners[o] = true
+				}
+			}
+		default:
+			return "", fmt.Sprintf("error: ERROR! invalid option argument \"%s\"\n", args[k]), 1
+		}
+	}
+
+	if xmlOutput {
+		content, err := c.qstatXML(owners, requests)
+
+		if err != nil {
+			return "", err.Error() + "\n", 1
+		}
+
+		return content + "\n", "", 0
+	}
+
+	c.mutex.Lock()
+	ji := c.jobInfo(owners, requests)
+	c.mutex.Unlock()
+
+	var content strings.Builder
+	var err error
+
+	if full {
+		err = gogridengine.WriteQstatFull(&content, ji)
+	} else {
+		err = gogridengine.WriteQstat(&content, ji.Jobs())
+	}
+
+	if err != nil {
+		return "", err.Error() + "\n", 1
+	}
+
+	return content.String(), "", 0
+}
+
+func (c *Cluster) qstatXML(owners map[string]bool, requests bool) (string, error) {
+	c.mutex.Lock()
+	ji := c.jobInfo(owners, requests)
+	c.mutex.Unlock()
+
+	return ji.GetXML()
+}
+
+func (c *Cluster) qsub(args []string) (string, string, int) {
+	spec := JobSpec{}
+	terse := false
+	script := ""
+
+	for k := 0; k < len(args); k++ {
+		arg := args[k]
+
+		//Every option we understand that takes a value
+		value := func() (string, bool) {
+			if k+1 >= len(args) {
+				return "", false
+			}
+			k++
+			return args[k], true
+		}
+
+		switch arg {
+		case "-terse":
+			terse = true
+		case "-h":
+			spec.Hold = true
+		case "-cwd", "-V":
+		case "-b", "-o", "-e", "-j", "-S", "-q", "-M", "-m", "-wd":
+			if _, ok := value(); !ok {
+				return "", fmt.Sprintf("qsub: ERROR! %s option must have argument\n", arg), 2
+			}
+		case "-N":
+			v, ok := value()
+			if !ok {
+				return "", "qsub: ERROR! -N option must have argument\n", 2
+			}
+			spec.Name = v
+		case "-p":
+			v, ok := value()
+			priority, err := strconv.Atoi(v)
+			if !ok || err != nil {
+				return "", fmt.Sprintf("qsub: ERROR! invalid priority \"%s\"\n", v), 2
+			}
+			spec.Priority = priority
+		case "-pe":
+			if _, ok := value(); !ok {
+				return "", "qsub: ERROR! -pe option must have argument\n", 2
+			}
+			v, ok := value()
+			slots, err := strconv.ParseInt(v, 10, 32)
+			if !ok || err != nil {
+				return "", fmt.Sprintf("qsub: ERROR! invalid slot range \"%s\"\n", v), 2
+			}
+			spec.Slots = int32(slots)
+		case "-l":
+			v, ok := value()
+			if !ok {
+				return "", "qsub: ERROR! -l option must have argument\n", 2
+			}
+			if err := applyResources(&spec, v); err != nil {
+				return "", fmt.Sprintf("qsub: ERROR! %s\n", err), 2
+			}
+		case "-t":
+			v, ok := value()
+			if !ok {
+				return "", "qsub: ERROR! -t option must have argument\n", 2
+			}
+			if err := applyTaskRange(&spec, v); err != nil {
+				return "", fmt.Sprintf("ERROR! -t option must have the format n[-m[:s]]: \"%s\"\n", v), 2
+			}
+		case "-v":
+			v, ok := value()
+			if !ok {
+				return "", "qsub: ERROR! -v option must have argument\n", 2
+			}
+			if err := applyVariables(&spec, v); err != nil {
+				return "", fmt.Sprintf("qsub: ERROR! %s\n", err), 2
+			}
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return "", fmt.Sprintf("qsub: ERROR! invalid option argument \"%s\"\n", arg), 2
+			}
+
+			//The script and its arguments end the options
+			script = arg
+			k = len(args)
+		}
+	}
+
+	if spec.Name == "" && script != "" {
+		spec.Name = filepath.Base(script)
+	}
+
+	number, err := c.Submit(spec)
+	if err != nil {
+		return "", fmt.Sprintf("Unable to run job: %s.\nExiting.\n", err), 1
+	}
+
+	c.mutex.Lock()
+	submitted := c.jobs[c.jobIndex(number)].spec
+	c.mutex.Unlock()
+
+	if submitted.IsArray() {
+		tasks := fmt.Sprintf("%d-%d:%d", submitted.FirstTask, submitted.LastTask, submitted.TaskStep)
+
+		if terse {
+			return fmt.Sprintf("%d.%s\n", number, tasks), "", 0
+		}
+
+		return fmt.Sprintf("Your job-array %d.%s (\"%s\") has been submitted\n", number, tasks, submitted.Name), "", 0
+	}
+
+	if terse {
+		return fmt.Sprintf("%d\n", number), "", 0
+	}
+
+	return fmt.Sprintf("Your job %d (\"%s\") has been submitted\n", number, submitted.Name), "", 0
+}
+
+//applyResources handles the -l resource list. Memory requests and h_rt are honoured, anything else is accepted and ignored
+func applyResources(spec *JobSpec, list string) error {
+	for _, pair := range strings.Split(list, ",") {
+		pieces := strings.SplitN(pair, "=", 2)
+
+		if len(pieces) != 2 {
+			return fmt.Errorf("invalid resource request \"%s\"", pair)
+		}
+
+		switch pieces[0] {
+		case "h_vmem", "mem_free", "virtual_free":
+			memory, err := gogridengine.ParseStorageValue(pieces[1])
+			if err != nil {
+				return fmt.Errorf("invalid memory request \"%s\"", pieces[1])
+			}
+			spec.Memory = memory.Bytes
+		case "h_rt":
+			runtime, err := parseRuntime(pieces[1])
+			if err != nil {
+				return fmt.Errorf("invalid runtime \"%s\"", pieces[1])
+			}
+			spec.Runtime = runtime
+		}
+	}
+
+	return nil
+}
+
+func applyTaskRange(spec *JobSpec, value string) error {
+	step := "1"
+	if pieces := strings.SplitN(value, ":", 2); len(pieces) == 2 {
+		value = pieces[0]
+		step = pieces[1]
+	}
+
+	bounds := strings.SplitN(value, "-", 2)
+	if len(bounds) == 1 {
+		bounds = append(bounds, bounds[0])
+	}
+
+	var err error
+
+	if spec.FirstTask, err = strconv.ParseInt(bounds[0], 10, 64); err != nil {
+		return err
+	}
+
+	if spec.LastTask, err = strconv.ParseInt(bounds[1], 10, 64); err != nil {
+		return err
+	}
+
+	if spec.TaskStep, err = strconv.ParseInt(step, 10, 64); err != nil {
+		return err
+	}
+
+	if spec.FirstTask <= 0 || spec.LastTask < spec.FirstTask || spec.TaskStep <= 0 {
+		return ErrInvalidJobSpec
+	}
+
+	return nil
+}
+
+//applyVariables picks the simulation variables out of a qsub -v list. Other variables are ignored
+func applyVariables(spec *JobSpec, list string) error {
+	for _, pair := range strings.Split(list, ",") {
+		pieces := strings.SplitN(pair, "=", 2)
+
+		if len(pieces) != 2 {
+			continue
+		}
+
+		var err error
+
+		switch pieces[0] {
+		case RuntimeVariable:
+			spec.Runtime, err = parseRuntime(pieces[1])
+		case ExitStatusVariable:
+			spec.ExitStatus, err = strconv.Atoi(pieces[1])
+		case ErrorVariable:
+			spec.Error, err = strconv.ParseBool(pieces[1])
+		}
+
+		if err != nil {
+			return fmt.Errorf("invalid value for %s: \"%s\"", pieces[0], pieces[1])
+		}
+	}
+
+	return nil
+}
+
+//parseRuntime accepts seconds, hh:mm:ss or a Go duration
+func parseRuntime(value string) (time.Duration, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+
+	if pieces := strings.Split(value, ":"); len(pieces) == 3 {
+		var total int64
+
+		for _, p := range pieces {
+			v, err := strconv.ParseInt(p, 10, 64)
+			if err != nil {
+				return 0, err
+			}
+			total = total*60 + v
+		}
+
+		return time.Duration(total) * time.Second, nil
+	}
+
+	return time.ParseDuration(value)
+}
+
+func (c *Cluster) qdel(args []string) (string, string, int) {
+	if len(args) == 0 {
+		return "", "error: no option argument provided to \"qdel\"\n", 1
+	}
+
+	//Looking the jobs up and deleting them is a single step, so concurrent commands can't change the cluster in between
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var targets []string
+
+	if args[0] == "-u" {
+		if len(args) < 2 {
+			return "", "error: ERROR! -u option must have argument\n", 1
+		}
+
+		owners := make(map[string]bool)
+		for _, o := range strings.Split(args[1], ",") {
+			owners[o] = true
+		}
+
+		for _, j := range c.jobs {
+			if owners[j.spec.Owner] {
+				targets = append(targets, strconv.FormatInt(j.number, 10))
+			}
+		}
+	} else {
+		targets = jobArguments(args)
+	}
+
+	var stdout, stderr strings.Builder
+	code := 0
+
+	for _, target := range targets {
+		number, taskID, err := parseTarget(target)
+
+		owner := ""
+		if err == nil {
+			if k := c.jobIndex(number); k >= 0 {
+				owner = c.jobs[k].spec.Owner
+			}
+		}
+
+		var running bool
+		if err == nil {
+			running, err = c.deleteTasks(number, taskID)
+		}
+
+		if err != nil {
+			stderr.WriteString(fmt.Sprintf("denied: job \"%s\" does not exist\n", target))
+			code = 1
+			continue
+		}
+
+		switch {
+		case taskID != 0 && running:
+			stdout.WriteString(fmt.Sprintf("%s has registered the job-array task %d.%d for deletion\n", owner, number, taskID))
+		case taskID != 0:
+			stdout.WriteString(fmt.Sprintf("%s has deleted job-array task %d.%d\n", owner, number, taskID))
+		case running:
+			stdout.WriteString(fmt.Sprintf("%s has registered the job %d for deletion\n", owner, number))
+		default:
+			stdout.WriteString(fmt.Sprintf("%s has deleted job %d\n", owner, number))
+		}
+	}
+
+	return stdout.String(), stderr.String(), code
+}
+
+func (c *Cluster) modifyHold(args []string, from string, to string) (string, string, int) {
+	targets := jobArguments(args)
+
+	if len(targets) == 0 {
+		return "", "error: no option argument provided\n", 1
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var stdout, stderr strings.Builder
+	code := 0
+
+	for _, target := range targets {
+		number, taskID, err := parseTarget(target)
+
+		if err == nil {
+			err = c.setPendingState(number, taskID, from, to)
+		}
+
+		if err != nil {
+			stderr.WriteString(fmt.Sprintf("denied: job \"%s\" does not exist\n", target))
+			code = 1
+			continue
+		}
+
+		if taskID != 0 {
+			stdout.WriteString(fmt.Sprintf("modified hold of job-array task %d.%d\n", number, taskID))
+		} else {
+			stdout.WriteString(fmt.Sprintf("modified hold of job %d\n", number))
+		}
+	}
+
+	return stdout.String(), stderr.String(), code
+}
+
+//parseTarget reads a job number, or a single array task addressed as job.task
+func parseTarget(target string) (int64, int64, error) {
+	pieces := strings.SplitN(target, ".", 2)
+
+	number, err := strconv.ParseInt(pieces[0], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if len(pieces) == 1 {
+		return number, 0, nil
+	}
+
+	taskID, err := strconv.ParseInt(pieces[1], 10, 64)
+	if err != nil || taskID <= 0 {
+		return 0, 0, ErrUnknownJob
+	}
+
+	return number, taskID, nil
+}
+
+//jobArguments splits job lists given either as separate arguments or comma separated
+func jobArguments(args []string) []string {
+	var targets []string
+
+	for _, a := range args {
+		for _, t := range strings.Split(a, ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				targets = append(targets, t)
+			}
+		}
+	}
+
+	return targets
+}
+
+func (c *Cluster) qacct(args []string) (string, string, int) {
+	if len(args) != 2 || args[0] != "-j" {
+		return "", "error: the simulator only supports qacct -j <job>\n", 1
+	}
+
+	number, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return "", fmt.Sprintf("error: job name %s not found\n", args[1]), 1
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	var out strings.Builder
+
+	for _, f := range c.finished {
+		if f.job.number != number {
+			continue
+		}
+
+		taskID := "undefined"
+		if f.job.spec.IsArray() {
+			taskID = strconv.FormatInt(f.taskID, 10)
+		}
+
+		out.WriteString("==============================================================\n")
+		for _, field := range [][2]string{
+			{"qname", c.opts.Queue},
+			{"hostname", f.host},
+			{"owner", f.job.spec.Owner},
+			{"jobname", f.job.spec.Name},
+			{"jobnumber", strconv.FormatInt(number, 10)},
+			{"taskid", taskID},
+			{"qsub_time", f.job.submitted.Format(time.ANSIC)},
+			{"start_time", f.start.Format(time.ANSIC)},
+			{"end_time", f.end.Format(time.ANSIC)},
+			{"slots", strconv.Itoa(int(f.job.spec.Slots))},
+			{"failed", strconv.Itoa(f.failed)},
+			{"exit_status", strconv.Itoa(f.exitStatus)},
+			{"ru_wallclock", fmt.Sprintf("%.0f", f.end.Sub(f.start).Seconds())},
+		} {
+			out.WriteString(fmt.Sprintf("%-13s%s\n", field[0], field[1]))
+		}
+	}
+
+	if out.Len() == 0 {
+		return "", fmt.Sprintf("error: job id %d not found\n", number), 1
+	}
+
+	return out.String(), "", 0
+}
@@ -0,0 +1,380 @@
+package simulator
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/metrumresearchgroup/gogridengine"
+	"github.com/stretchr/testify/assert"
+)
+
+func run(t *testing.T, c *Cluster, name string, args ...string) gogridengine.CommandResult {
+	result, _ := c.Run(context.Background(), name, args...)
+	return result
+}
+
+func TestQsub(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		want     string
+		wantCode int
+	}{
+		{
+			name: "script",
+			args: []string{"-cwd", "-V", "/home/darrellb/run.sh", "--flag"},
+			want: "Your job 1 (\"run.sh\") has been submitted\n",
+		},
+		{
+			name: "named",
+			args: []string{"-N", "model", "-pe", "smp", "2", "-l", "h_vmem=4G,h_rt=00:10:00", "run.sh"},
+			want: "Your job 1 (\"model\") has been submitted\n",
+		},
+		{
+			name: "array",
+			args: []string{"-t", "1-10:2", "run.sh"},
+			want: "Your job-array 1.1-10:2 (\"run.sh\") has been submitted\n",
+		},
+		{
+			name: "terse",
+			args: []string{"-terse", "run.sh"},
+			want: "1\n",
+		},
+		{
+			name: "terse array",
+			args: []string{"-terse", "-t", "5", "run.sh"},
+			want: "1.5-5:1\n",
+		},
+		{
+			name:     "invalid task range",
+			args:     []string{"-t", "10-1", "run.sh"},
+			wantCode: 2,
+		},
+		{
+			name:     "unknown option",
+			args:     []string{"-bogus", "run.sh"},
+			wantCode: 2,
+		},
+		{
+			name:     "invalid memory",
+			args:     []string{"-l", "h_vmem=lots", "run.sh"},
+			wantCode: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newCluster(false)
+			result, err := c.Run(context.Background(), "qsub", tt.args...)
+
+			assert.Equal(t, tt.wantCode, result.ExitCode)
+			assert.Equal(t, tt.wantCode != 0, err != nil)
+
+			if tt.wantCode == 0 {
+				assert.Equal(t, tt.want, string(result.Stdout))
+			} else {
+				assert.NotEmpty(t, result.Stderr)
+			}
+		})
+	}
+}
+
+func TestQsubResourceRequests(t *testing.T) {
+	c := newCluster(false)
+
+	run(t, c, "qsub", "-N", "model", "-p", "-100", "-pe", "smp", "2", "-l", "h_vmem=4G,h_rt=600", "run.sh")
+
+	result := run(t, c, "qstat", "-F", "-r", "-xml")
+	ji, err := gogridengine.NewJobInfo(string(result.Stdout))
+	assert.Nil(t, err)
+
+	j := ji.QueueInfo.Queues[0].JobList[0]
+	assert.Equal(t, "model", j.JobName)
+	assert.Equal(t, int32(2), j.Slots)
+	assert.InDelta(t, 0.4509, j.JATPriority, 0.0001)
+
+	memory, err := j.RequestedMemory()
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4*gigabyte), memory.Bytes)
+
+	c.Advance(10 * time.Minute)
+	assert.Empty(t, c.Jobs())
+}
+
+func TestQstat(t *testing.T) {
+	c := newCluster(false)
+	c.opts.User = "devinp"
+	run(t, c, "qsub", "run.sh")
+	c.opts.User = "darrellb"
+	run(t, c, "qsub", "-t", "1-20", "run.sh")
+
+	tests := []struct {
+		name     string
+		args     []string
+		wantJobs int
+		wantCode int
+	}{
+		{
+			name:     "everyone",
+			args:     []string{"-u", "*", "-F", "-xml"},
+			wantJobs: 21,
+		},
+		{
+			name:     "single user",
+			args:     []string{"-xml", "-u", "devinp"},
+			wantJobs: 1,
+		},
+		{
+			name:     "unknown option",
+			args:     []string{"-xml", "-bogus"},
+			wantCode: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := c.Run(context.Background(), "qstat", tt.args...)
+			assert.Equal(t, tt.wantCode, result.ExitCode)
+
+			if tt.wantCode != 0 {
+				assert.NotNil(t, err)
+				return
+			}
+
+			ji, err := gogridengine.NewJobInfo(string(result.Stdout))
+			assert.Nil(t, err)
+			assert.Len(t, ji.Jobs(), tt.wantJobs)
+			assert.Len(t, ji.QueueInfo.Queues, 2)
+		})
+	}
+}
+
+func TestQstatText(t *testing.T) {
+	c := newCluster(false)
+	c.opts.User = "devinp"
+	run(t, c, "qsub", "run.sh")
+	c.opts.User = "darrellb"
+	run(t, c, "qsub", "-t", "1-20", "run.sh")
+
+	content, err := c.Run(context.Background(), "qstat", "-xml")
+	assert.Nil(t, err)
+	ji, err := gogridengine.NewJobInfo(string(content.Stdout))
+	assert.Nil(t, err)
+
+	tests := []struct {
+		name  string
+		args  []string
+		write func(*strings.Builder) error
+	}{
+		{
+			name:  "plain",
+			args:  []string{"-u", "*"},
+			write: func(b *strings.Builder) error { return gogridengine.WriteQstat(b, ji.Jobs()) },
+		},
+		{
+			name:  "full",
+			args:  []string{"-f"},
+			write: func(b *strings.Builder) error { return gogridengine.WriteQstatFull(b, ji) },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := c.Run(context.Background(), "qstat", tt.args...)
+			assert.Nil(t, err)
+			assert.Equal(t, 0, result.ExitCode)
+
+			var want strings.Builder
+			assert.Nil(t, tt.write(&want))
+			assert.Equal(t, want.String(), string(result.Stdout))
+
+			//The pending tasks of the array are a single range, like qstat shows them
+			assert.Contains(t, string(result.Stdout), " 8-20:1\n")
+			assert.Equal(t, 1, strings.Count(string(result.Stdout), "qw"))
+		})
+	}
+
+	result := run(t, c, "qstat", "-u", "devinp")
+	assert.Equal(t, 1, strings.Count(string(result.Stdout), "devinp"))
+	assert.NotContains(t, string(result.Stdout), "darrellb")
+}
+
+func TestQdel(t *testing.T) {
+	c := newCluster(false)
+	run(t, c, "qsub", "-pe", "smp", "4", "run.sh")
+	run(t, c, "qsub", "-pe", "smp", "4", "run.sh")
+	run(t, c, "qsub", "-pe", "smp", "4", "run.sh")
+
+	//Job 3 would start as soon as job 1 is gone, so delete it first
+	result := run(t, c, "qdel", "3,1")
+	assert.Equal(t, "darrellb has deleted job 3\ndarrellb has registered the job 1 for deletion\n", string(result.Stdout))
+
+	result, err := c.Run(context.Background(), "qdel", "42")
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Equal(t, "denied: job \"42\" does not exist\n", string(result.Stderr))
+
+	result = run(t, c, "qdel", "-u", "darrellb")
+	assert.Equal(t, "darrellb has registered the job 2 for deletion\n", string(result.Stdout))
+	assert.Empty(t, c.Jobs())
+}
+
+func TestArrayTaskActions(t *testing.T) {
+	c := newCluster(false)
+	//Tasks 1 to 4 fill node1 and node2, 5 and 6 wait
+	run(t, c, "qsub", "-pe", "smp", "2", "-t", "1-6", "run.sh")
+
+	result := run(t, c, "qhold", "1.6")
+	assert.Equal(t, "modified hold of job-array task 1.6\n", string(result.Stdout))
+
+	ji, _ := c.Snapshot()
+	states := make(map[int64]string)
+	for _, j := range ji.Jobs() {
+		states[j.Tasks.TaskID] = j.State
+	}
+	assert.Equal(t, map[int64]string{1: "r", 2: "r", 3: "r", 4: "r", 5: "qw", 6: "hqw"}, states)
+
+	//Deleting a running task frees its slots for the next pending one
+	result = run(t, c, "qdel", "1.2")
+	assert.Equal(t, "darrellb has registered the job-array task 1.2 for deletion\n", string(result.Stdout))
+
+	ji, _ = c.Snapshot()
+	var tasks []int64
+	for _, j := range ji.Jobs() {
+		if j.State == "r" {
+			tasks = append(tasks, j.Tasks.TaskID)
+		}
+	}
+	assert.ElementsMatch(t, []int64{1, 3, 4, 5}, tasks)
+	for _, h := range ji.QueueInfo.Queues {
+		assert.Equal(t, int32(4), h.SlotsUsed)
+	}
+
+	result = run(t, c, "qrls", "1.6")
+	assert.Equal(t, "modified hold of job-array task 1.6\n", string(result.Stdout))
+
+	result = run(t, c, "qdel", "1.6", "1.2", "1.x", "2.1")
+	assert.Equal(t, "darrellb has deleted job-array task 1.6\n", string(result.Stdout))
+	assert.Equal(t, "denied: job \"1.2\" does not exist\ndenied: job \"1.x\" does not exist\ndenied: job \"2.1\" does not exist\n", string(result.Stderr))
+	assert.Equal(t, 1, result.ExitCode)
+
+	//The job goes away with its last task
+	run(t, c, "qdel", "1.1", "1.3", "1.4")
+	assert.Equal(t, []int64{1}, c.Jobs())
+	run(t, c, "qdel", "1.5")
+	assert.Empty(t, c.Jobs())
+
+	//Tasks of jobs which aren't arrays can't be addressed
+	run(t, c, "qsub", "run.sh")
+	result = run(t, c, "qhold", "2.1")
+	assert.Equal(t, 1, result.ExitCode)
+}
+
+func TestConcurrentCommands(t *testing.T) {
+	c := newCluster(false)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			run(t, c, "qsub", "-t", "1-4", "run.sh")
+		}()
+		go func() {
+			defer wg.Done()
+			run(t, c, "qdel", "-u", "darrellb")
+		}()
+	}
+	wg.Wait()
+
+	run(t, c, "qdel", "-u", "darrellb")
+	assert.Empty(t, c.Jobs())
+
+	ji, _ := c.Snapshot()
+	for _, h := range ji.QueueInfo.Queues {
+		assert.Equal(t, int32(0), h.SlotsUsed)
+	}
+}
+
+func TestQholdAndQrls(t *testing.T) {
+	c := newCluster(false)
+	run(t, c, "qsub", "-pe", "smp", "4", "run.sh")
+	run(t, c, "qsub", "-pe", "smp", "4", "run.sh")
+	run(t, c, "qsub", "run.sh")
+
+	result := run(t, c, "qhold", "3")
+	assert.Equal(t, "modified hold of job 3\n", string(result.Stdout))
+
+	ji, _ := c.Snapshot()
+	assert.Equal(t, StateHeld, ji.PendingJobs.JobList[0].State)
+
+	result = run(t, c, "qrls", "3", "7")
+	assert.Equal(t, "modified hold of job 3\n", string(result.Stdout))
+	assert.Equal(t, "denied: job \"7\" does not exist\n", string(result.Stderr))
+	assert.Equal(t, 1, result.ExitCode)
+
+	ji, _ = c.Snapshot()
+	assert.Equal(t, StatePending, ji.PendingJobs.JobList[0].State)
+}
+
+func TestQacct(t *testing.T) {
+	c := newCluster(false)
+	run(t, c, "qsub", "-v", "SIM_RUNTIME=90s,SIM_EXIT_STATUS=3,HOME=/home/darrellb", "-t", "1-2", "run.sh")
+
+	_, err := c.Run(context.Background(), "qacct", "-j", "1")
+	assert.NotNil(t, err)
+
+	c.Advance(90 * time.Second)
+
+	records, err := gogridengine.GetAccounting(context.Background(), c, 1)
+	assert.Nil(t, err)
+	assert.Len(t, records, 2)
+	assert.Equal(t, int64(1), records[0].TaskID)
+	assert.Equal(t, 3, records[0].ExitStatus)
+	assert.Equal(t, "node1", records[0].Hostname)
+	assert.Equal(t, "90", records[0].Fields["ru_wallclock"])
+}
+
+func TestWaitForJobsAgainstSimulator(t *testing.T) {
+	c := newCluster(false)
+	run(t, c, "qsub", "-v", "SIM_RUNTIME=60", "-t", "1-3", "run.sh")
+	run(t, c, "qsub", "-v", "SIM_ERROR=true", "run.sh")
+
+	//Simulated time moves forward in the background while waiting
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	go func() {
+		for ctx.Err() == nil {
+			c.Advance(30 * time.Second)
+			time.Sleep(5 * time.Millisecond)
+		}
+	}()
+
+	outcomes, err := gogridengine.WaitForJobs(ctx, []int64{1, 2}, gogridengine.WaitOptions{
+		Interval:   5 * time.Millisecond,
+		Source:     &gogridengine.QstatDataSource{Runner: c},
+		Accounting: true,
+		Runner:     c,
+	})
+	assert.Nil(t, err)
+	assert.Len(t, outcomes, 4)
+
+	for _, o := range outcomes[:3] {
+		assert.Equal(t, gogridengine.OutcomeFinished, o.Outcome)
+		assert.NotNil(t, o.ExitStatus)
+	}
+	assert.Equal(t, gogridengine.OutcomeError, outcomes[3].Outcome)
+}
+
+func TestUnknownCommand(t *testing.T) {
+	c := newCluster(false)
+
+	_, err := c.Run(context.Background(), "qconf", "-sql")
+	assert.NotNil(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = c.Run(ctx, "qstat", "-xml")
+	assert.Equal(t, context.Canceled, err)
+}
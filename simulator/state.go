@@ -0,0 +1,164 @@
+package simulator
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+//state is the serialised form of a Cluster, allowing a simulation to outlive the process running it
+type state struct {
+	Options  Options         `json:"options"`
+	Now      time.Time       `json:"now"`
+	NextJob  int64           `json:"next_job"`
+	Hosts    []hostState     `json:"hosts"`
+	Jobs     []jobState      `json:"jobs"`
+	Finished []finishedState `json:"finished"`
+}
+
+type hostState struct {
+	HostSpec
+	UsedSlots  int32 `json:"used_slots"`
+	UsedMemory int64 `json:"used_memory"`
+}
+
+type jobState struct {
+	Number    int64       `json:"number"`
+	Spec      JobSpec     `json:"spec"`
+	Submitted time.Time   `json:"submitted"`
+	Tasks     []taskState `json:"tasks"`
+}
+
+type taskState struct {
+	ID    int64     `json:"id"`
+	State string    `json:"state"`
+	Host  string    `json:"host,omitempty"`
+	Start time.Time `json:"start,omitempty"`
+}
+
+type finishedState struct {
+	Job        jobState  `json:"job"`
+	TaskID     int64     `json:"task_id"`
+	Host       string    `json:"host"`
+	Start      time.Time `json:"start"`
+	End        time.Time `json:"end"`
+	Failed     int       `json:"failed"`
+	ExitStatus int       `json:"exit_status"`
+}
+
+//Save writes the whole cluster state, including accounting of finished tasks, as JSON
+func (c *Cluster) Save(w io.Writer) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	s := state{
+		Options: c.opts,
+		Now:     c.now,
+		NextJob: c.nextJob,
+	}
+
+	for _, h := range c.hosts {
+		s.Hosts = append(s.Hosts, hostState{HostSpec: h.HostSpec, UsedSlots: h.usedSlots, UsedMemory: h.usedMemory})
+	}
+
+	for _, j := range c.jobs {
+		s.Jobs = append(s.Jobs, saveJob(j))
+	}
+
+	for _, f := range c.finished {
+		s.Finished = append(s.Finished, finishedState{
+			Job:        jobState{Number: f.job.number, Spec: f.job.spec, Submitted: f.job.submitted},
+			TaskID:     f.taskID,
+			Host:       f.host,
+			Start:      f.start,
+			End:        f.end,
+			Failed:     f.failed,
+			ExitStatus: f.exitStatus,
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(s)
+}
+
+//Load restores a cluster previously written by Save
+func Load(r io.Reader) (*Cluster, error) {
+	var s state
+
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return nil, err
+	}
+
+	c := &Cluster{
+		opts:    s.Options,
+		now:     s.Now,
+		nextJob: s.NextJob,
+	}
+
+	hosts := make(map[string]*host)
+
+	for _, h := range s.Hosts {
+		restored := &host{HostSpec: h.HostSpec, usedSlots: h.UsedSlots, usedMemory: h.UsedMemory}
+		hosts[h.Name] = restored
+		c.hosts = append(c.hosts, restored)
+	}
+
+	for _, js := range s.Jobs {
+		j := &job{number: js.Number, spec: js.Spec, submitted: js.Submitted}
+
+		for _, ts := range js.Tasks {
+			t := &task{id: ts.ID, state: ts.State, start: ts.Start}
+
+			if ts.State == StateRunning {
+				if t.host = hosts[ts.Host]; t.host == nil {
+					return nil, ErrUnknownHost
+				}
+			}
+
+			j.tasks = append(j.tasks, t)
+		}
+
+		c.jobs = append(c.jobs, j)
+	}
+
+	//Finished tasks of the same job share their job so qacct reports them together
+	finishedJobs := make(map[int64]*job)
+
+	for _, f := range s.Finished {
+		j, ok := finishedJobs[f.Job.Number]
+		if !ok {
+			j = &job{number: f.Job.Number, spec: f.Job.Spec, submitted: f.Job.Submitted}
+			finishedJobs[f.Job.Number] = j
+		}
+
+		c.finished = append(c.finished, finishedTask{
+			job:        j,
+			taskID:     f.TaskID,
+			host:       f.Host,
+			start:      f.Start,
+			end:        f.End,
+			failed:     f.Failed,
+			exitStatus: f.ExitStatus,
+		})
+	}
+
+	return c, nil
+}
+
+func saveJob(j *job) jobState {
+	js := jobState{Number: j.number, Spec: j.spec, Submitted: j.submitted}
+
+	for _, t := range j.tasks {
+		ts := taskState{ID: t.id, State: t.state, Start: t.start}
+
+		if t.host != nil {
+			ts.Host = t.host.Name
+		}
+
+		js.Tasks = append(js.Tasks, ts)
+	}
+
+	return js
+}
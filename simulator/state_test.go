@@ -0,0 +1,53 @@
+package simulator
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	c := newCluster(false)
+	run(t, c, "qsub", "-v", "SIM_RUNTIME=60,SIM_EXIT_STATUS=2", "-t", "1-10", "run.sh")
+	run(t, c, "qsub", "-h", "-N", "held", "run.sh")
+	c.Advance(time.Minute)
+
+	var saved bytes.Buffer
+	assert.Nil(t, c.Save(&saved))
+
+	restored, err := Load(bytes.NewReader(saved.Bytes()))
+	assert.Nil(t, err)
+
+	assert.Equal(t, c.Now(), restored.Now())
+	assert.Equal(t, c.Jobs(), restored.Jobs())
+
+	before, _ := c.Run(context.Background(), "qstat", "-xml")
+	after, _ := restored.Run(context.Background(), "qstat", "-xml")
+	assert.Equal(t, string(before.Stdout), string(after.Stdout))
+
+	before, _ = c.Run(context.Background(), "qacct", "-j", "1")
+	after, _ = restored.Run(context.Background(), "qacct", "-j", "1")
+	assert.Equal(t, string(before.Stdout), string(after.Stdout))
+
+	//Both continue the same way
+	c.Advance(time.Minute)
+	restored.Advance(time.Minute)
+	number, _ := restored.Submit(JobSpec{})
+	assert.Equal(t, int64(3), number)
+
+	snapshot, err := restored.Snapshot()
+	assert.Nil(t, err)
+	assert.Equal(t, int32(1), snapshot.QueueInfo.Queues[0].SlotsUsed)
+}
+
+func TestLoadInvalidState(t *testing.T) {
+	_, err := Load(strings.NewReader("{"))
+	assert.NotNil(t, err)
+
+	_, err = Load(strings.NewReader(`{"jobs":[{"number":1,"tasks":[{"state":"r","host":"missing"}]}]}`))
+	assert.Equal(t, ErrUnknownHost, err)
+}
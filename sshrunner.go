@@ -0,0 +1,405 @@
+package gogridengine
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+//ErrNoHostKeyCallback is returned when an SSHRunner has no way of verifying the host key of the submit host. Host keys are never accepted blindly
+const ErrNoHostKeyCallback = Error("A host key callback (or known_hosts file) is required to verify the submit host")
+
+//ErrInvalidSSHEnvironment is returned when an entry of SSHRunner.Env isn't NAME=value with a name a shell accepts
+const ErrInvalidSSHEnvironment = Error("SSH environment variables must be NAME=value, the name made of letters, digits and underscores")
+
+//ErrSSHCommandTimeout is returned when a command run over SSH takes longer than the runner's Timeout
+const ErrSSHCommandTimeout = Error("The command run over SSH timed out")
+
+//SSHRunner runs the grid engine binaries on a remote submit host over SSH, so the library can be used from outside the cluster.
+//A single connection is opened on first use and shared by every command, each running in its own session. A connection which has dropped, or stopped answering keepalives, is re-established on the next command.
+type SSHRunner struct {
+	//Address of the submit host as host or host:port. The port defaults to 22
+	Address string
+	User    string
+	//Auth are the methods used to authenticate the user, see SSHKeyAuth
+	Auth []ssh.AuthMethod
+	//HostKeyCallback verifies the submit host, see knownhosts.New. Required
+	HostKeyCallback ssh.HostKeyCallback
+	//Env is set on the command line of every command (eg: SGE_ROOT=/opt/sge), as sshd usually refuses to set the variables requested by clients
+	Env []string
+	//Timeout bounds every command, on top of any deadline of its context. Zero leaves it to the context
+	Timeout time.Duration
+	//DialTimeout bounds establishing the connection. Defaults to 10 seconds
+	DialTimeout time.Duration
+	//KeepAlive is how often the connection is checked while idle. A connection which doesn't answer within the interval is closed, and the next command connects again. Defaults to 30 seconds, a negative value disables it
+	KeepAlive time.Duration
+
+	mu     sync.Mutex
+	client *ssh.Client
+}
+
+//NewSSHRunner creates a runner authenticating with the private key in keyFile and verifying the submit host against the knownHostsFile (eg: ~/.ssh/known_hosts)
+func NewSSHRunner(address string, user string, keyFile string, knownHostsFile string) (*SSHRunner, error) {
+	auth, err := SSHKeyAuth(keyFile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	callback, err := knownhosts.New(knownHostsFile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &SSHRunner{
+		Address:         address,
+		User:            user,
+		Auth:            []ssh.AuthMethod{auth},
+		HostKeyCallback: callback,
+	}, nil
+}
+
+//SSHKeyAuth reads an unencrypted private key (RSA, ECDSA, ED25519...) for public key authentication
+func SSHKeyAuth(keyFile string) (ssh.AuthMethod, error) {
+	key, err := ioutil.ReadFile(keyFile)
+
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := ssh.ParsePrivateKey(key)
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse the private key %s: %w", keyFile, err)
+	}
+
+	return ssh.PublicKeys(signer), nil
+}
+
+//Run executes the binary on the submit host with the provided arguments, which are quoted for the remote shell
+func (r *SSHRunner) Run(ctx context.Context, name string, args ...string) (CommandResult, error) {
+	if r.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+		defer cancel()
+	}
+
+	command, err := r.commandLine(name, args)
+
+	if err != nil {
+		return CommandResult{ExitCode: -1}, err
+	}
+
+	session, err := r.session(ctx)
+
+	if err != nil {
+		return CommandResult{ExitCode: -1}, err
+	}
+	defer session.Close()
+
+	stdout := &bytes.Buffer{}
+	stderr := &bytes.Buffer{}
+	session.Stdout = stdout
+	session.Stderr = stderr
+
+	done := make(chan error, 1)
+	go func() {
+		done <- session.Run(command)
+	}()
+
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		//Servers honouring signals stop the command, closing the session stops waiting on it regardless. Its output is abandoned, as it may still be written to
+		session.Signal(ssh.SIGKILL)
+		session.Close()
+
+		err = ctx.Err()
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = ErrSSHCommandTimeout
+		}
+
+		return CommandResult{ExitCode: -1}, fmt.Errorf("an error occurred during execution of %s on %s: %w", name, r.Address, err)
+	}
+
+	result := CommandResult{
+		Stdout: stdout.Bytes(),
+		Stderr: stderr.Bytes(),
+	}
+
+	if err != nil {
+		result.ExitCode = -1
+
+		var exitErr *ssh.ExitError
+		if errors.As(err, &exitErr) {
+			result.ExitCode = exitErr.ExitStatus()
+		}
+
+		return result, fmt.Errorf("an error occurred during execution of %s on %s: %w", name, r.Address, err)
+	}
+
+	return result, nil
+}
+
+//Close closes the shared connection. The next command opens a new one
+func (r *SSHRunner) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.client == nil {
+		return nil
+	}
+
+	err := r.client.Close()
+	r.client = nil
+
+	return err
+}
+
+//session opens a session on the shared connection, connecting again once should the connection have dropped.
+//Only getting hold of the connection is done under the lock, so a connection which stopped answering doesn't hold up every other command past their context.
+func (r *SSHRunner) session(ctx context.Context) (*ssh.Session, error) {
+	client, err := r.connection(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := openSession(ctx, client)
+
+	if err == nil {
+		return session, nil
+	}
+
+	//Giving up on the session leaves the connection to the keepalive, as other commands may still be running over it
+	if ctx.Err() != nil {
+		return nil, err
+	}
+
+	r.drop(client)
+
+	client, err = r.connection(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	session, err = openSession(ctx, client)
+
+	if err != nil {
+		r.drop(client)
+		return nil, err
+	}
+
+	return session, nil
+}
+
+//connection returns the shared connection, dialing it when there is none
+func (r *SSHRunner) connection(ctx context.Context) (*ssh.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.client != nil {
+		return r.client, nil
+	}
+
+	client, err := r.dial(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	r.client = client
+
+	if r.KeepAlive >= 0 {
+		go r.keepAlive(client)
+	}
+
+	return client, nil
+}
+
+//drop closes the connection, forgetting it unless another one has replaced it already
+func (r *SSHRunner) drop(client *ssh.Client) {
+	r.mu.Lock()
+	if r.client == client {
+		r.client = nil
+	}
+	r.mu.Unlock()
+
+	client.Close()
+}
+
+type sessionResult struct {
+	session *ssh.Session
+	err     error
+}
+
+//openSession opens a session, giving up when the context is done as the server may never answer
+func openSession(ctx context.Context, client *ssh.Client) (*ssh.Session, error) {
+	result := make(chan sessionResult, 1)
+
+	go func() {
+		session, err := client.NewSession()
+		result <- sessionResult{
+			session: session,
+			err:     err,
+		}
+	}()
+
+	select {
+	case r := <-result:
+		return r.session, r.err
+	case <-ctx.Done():
+		go func() {
+			if r := <-result; r.session != nil {
+				r.session.Close()
+			}
+		}()
+
+		err := ctx.Err()
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = ErrSSHCommandTimeout
+		}
+
+		return nil, fmt.Errorf("unable to open a session on %s: %w", client.RemoteAddr(), err)
+	}
+}
+
+//keepAlive checks the connection every interval until it is closed, closing it when a check isn't answered in time
+func (r *SSHRunner) keepAlive(client *ssh.Client) {
+	interval := r.KeepAlive
+	if interval == 0 {
+		interval = 30 * time.Second
+	}
+
+	closed := make(chan struct{})
+	go func() {
+		client.Wait()
+		close(closed)
+	}()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+		}
+
+		answered := make(chan error, 1)
+		go func() {
+			_, _, err := client.SendRequest("keepalive@openssh.com", true, nil)
+			answered <- err
+		}()
+
+		timer := time.NewTimer(interval)
+
+		select {
+		case err := <-answered:
+			timer.Stop()
+			if err == nil {
+				continue
+			}
+		case <-closed:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		r.drop(client)
+		return
+	}
+}
+
+func (r *SSHRunner) dial(ctx context.Context) (*ssh.Client, error) {
+	if r.HostKeyCallback == nil {
+		return nil, ErrNoHostKeyCallback
+	}
+
+	address := r.Address
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		address = net.JoinHostPort(address, "22")
+	}
+
+	timeout := r.DialTimeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+
+	if err != nil {
+		return nil, err
+	}
+
+	//The handshake isn't bound by the context, the connection's deadline is
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	c, channels, requests, err := ssh.NewClientConn(conn, address, &ssh.ClientConfig{
+		User:            r.User,
+		Auth:            r.Auth,
+		HostKeyCallback: r.HostKeyCallback,
+	})
+
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("unable to connect to %s: %w", address, err)
+	}
+
+	conn.SetDeadline(time.Time{})
+
+	return ssh.NewClient(c, channels, requests), nil
+}
+
+//commandLine builds the remote command line, with the environment set before the command and every word quoted for a POSIX shell
+func (r *SSHRunner) commandLine(name string, args []string) (string, error) {
+	words := make([]string, 0, len(r.Env)+len(args)+1)
+
+	for _, variable := range r.Env {
+		pieces := strings.SplitN(variable, "=", 2)
+		if len(pieces) != 2 || !environmentName.MatchString(pieces[0]) {
+			return "", ErrInvalidSSHEnvironment
+		}
+
+		words = append(words, pieces[0]+"="+shellQuote(pieces[1]))
+	}
+
+	words = append(words, shellQuote(name))
+
+	for _, arg := range args {
+		words = append(words, shellQuote(arg))
+	}
+
+	return strings.Join(words, " "), nil
+}
+
+//environmentName matches the variable names a POSIX shell accepts in an assignment
+var environmentName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+//shellQuote quotes the word for a POSIX shell, leaving words which need no quoting as they are
+func shellQuote(word string) string {
+	if word != "" && strings.Trim(word, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_.,:/=@%+") == "" {
+		return word
+	}
+
+	return "'" + strings.ReplaceAll(word, "'", `'\''`) + "'"
+}
@@ -0,0 +1,474 @@
+package gogridengine
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+//sshTestServer is an in-process SSH server standing in for a submit host. It answers exec requests through its handler
+type sshTestServer struct {
+	listener net.Listener
+	config   *ssh.ServerConfig
+	hostKey  ssh.Signer
+	handler  func(command string) (stdout string, stderr string, status uint32)
+
+	mu          sync.Mutex
+	connections int
+	commands    []string
+	conns       []net.Conn
+	keepAlives  int
+	//stalled leaves new sessions and keepalives unanswered, as a half-open connection would
+	stalled bool
+}
+
+func newTestSigner(t *testing.T) (ssh.Signer, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+
+	der, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+
+	signer, err := ssh.NewSignerFromKey(key)
+	assert.Nil(t, err)
+
+	return signer, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+}
+
+//newSSHTestServer serves on a random local port, only letting in the user holding the authorized key
+func newSSHTestServer(t *testing.T, authorized ssh.PublicKey) *sshTestServer {
+	hostKey, _ := newTestSigner(t)
+
+	s := &sshTestServer{
+		hostKey: hostKey,
+		handler: func(command string) (string, string, uint32) {
+			return "", "", 0
+		},
+	}
+
+	s.config = &ssh.ServerConfig{
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if conn.User() == "sge" && string(key.Marshal()) == string(authorized.Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("unauthorized")
+		},
+	}
+	s.config.AddHostKey(hostKey)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	s.listener = listener
+
+	go s.serve()
+	t.Cleanup(s.close)
+
+	return s
+}
+
+func (s *sshTestServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns = append(s.conns, conn)
+		s.mu.Unlock()
+
+		go s.serveConn(conn)
+	}
+}
+
+func (s *sshTestServer) serveConn(conn net.Conn) {
+	_, channels, requests, err := ssh.NewServerConn(conn, s.config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	s.mu.Lock()
+	s.connections++
+	s.mu.Unlock()
+
+	go func() {
+		for request := range requests {
+			s.mu.Lock()
+			s.keepAlives++
+			stalled := s.stalled
+			s.mu.Unlock()
+
+			if request.WantReply && !stalled {
+				request.Reply(false, nil)
+			}
+		}
+	}()
+
+	for newChannel := range channels {
+		if s.isStalled() {
+			continue
+		}
+
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "sessions only")
+			continue
+		}
+
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			continue
+		}
+
+		go s.serveSession(channel, channelRequests)
+	}
+}
+
+func (s *sshTestServer) serveSession(channel ssh.Channel, requests <-chan *ssh.Request) {
+	for request := range requests {
+		if request.Type != "exec" {
+			request.Reply(false, nil)
+			continue
+		}
+
+		//The payload is the command line as an SSH string
+		length := binary.BigEndian.Uint32(request.Payload)
+		command := string(request.Payload[4 : 4+length])
+		request.Reply(true, nil)
+
+		s.mu.Lock()
+		s.commands = append(s.commands, command)
+		s.mu.Unlock()
+
+		go func() {
+			stdout, stderr, status := s.handler(command)
+			channel.Write([]byte(stdout))
+			channel.Stderr().Write([]byte(stderr))
+
+			status32 := make([]byte, 4)
+			binary.BigEndian.PutUint32(status32, status)
+			channel.SendRequest("exit-status", false, status32)
+			channel.Close()
+		}()
+	}
+}
+
+func (s *sshTestServer) isStalled() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stalled
+}
+
+func (s *sshTestServer) setStalled(stalled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stalled = stalled
+}
+
+//dropConnections closes every accepted connection, as a restarted submit host would
+func (s *sshTestServer) dropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+}
+
+func (s *sshTestServer) close() {
+	s.listener.Close()
+	s.dropConnections()
+}
+
+func (s *sshTestServer) stats() (int, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.connections, append([]string(nil), s.commands...)
+}
+
+//newTestSSHRunner writes the user key and a known_hosts file trusting the server, and creates a runner with them
+func newTestSSHRunner(t *testing.T) (*SSHRunner, *sshTestServer) {
+	dir, err := ioutil.TempDir("", "sshrunner")
+	assert.Nil(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	userKey, userPEM := newTestSigner(t)
+	server := newSSHTestServer(t, userKey.PublicKey())
+
+	keyFile := filepath.Join(dir, "id_ecdsa")
+	assert.Nil(t, ioutil.WriteFile(keyFile, userPEM, 0600))
+
+	knownHosts := filepath.Join(dir, "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(server.listener.Addr().String())}, server.hostKey.PublicKey())
+	assert.Nil(t, ioutil.WriteFile(knownHosts, []byte(line+"\n"), 0600))
+
+	runner, err := NewSSHRunner(server.listener.Addr().String(), "sge", keyFile, knownHosts)
+	assert.Nil(t, err)
+	t.Cleanup(func() { runner.Close() })
+
+	return runner, server
+}
+
+func TestSSHRunnerRun(t *testing.T) {
+	runner, server := newTestSSHRunner(t)
+	runner.Env = []string{"SGE_ROOT=/opt/sge", "SGE_CELL=my cell"}
+
+	server.handler = func(command string) (string, string, uint32) {
+		if strings.Contains(command, "qdel") {
+			return "", "denied: job \"99\" does not exist\n", 1
+		}
+		return "output of " + command, "", 0
+	}
+
+	result, err := runner.Run(context.Background(), "qstat", "-u", "*", "-xml")
+	assert.Nil(t, err)
+	assert.Equal(t, 0, result.ExitCode)
+	assert.Equal(t, "output of SGE_ROOT=/opt/sge SGE_CELL='my cell' qstat -u '*' -xml", string(result.Stdout))
+
+	result, err = runner.Run(context.Background(), "qdel", "99")
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, result.ExitCode)
+	assert.Equal(t, "denied: job \"99\" does not exist\n", string(result.Stderr))
+
+	var exitErr *ssh.ExitError
+	assert.True(t, errors.As(err, &exitErr))
+
+	//Both commands went over a single connection
+	connections, commands := server.stats()
+	assert.Equal(t, 1, connections)
+	assert.Len(t, commands, 2)
+}
+
+func TestSSHRunnerReconnects(t *testing.T) {
+	runner, server := newTestSSHRunner(t)
+
+	_, err := runner.Run(context.Background(), "qstat")
+	assert.Nil(t, err)
+
+	server.dropConnections()
+
+	//The dropped connection is noticed once its reader stops, after which the next command connects again
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		_, err = runner.Run(context.Background(), "qstat")
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.Nil(t, err)
+
+	connections, _ := server.stats()
+	assert.Equal(t, 2, connections)
+}
+
+func TestSSHRunnerTimeout(t *testing.T) {
+	runner, server := newTestSSHRunner(t)
+
+	release := make(chan struct{})
+	defer close(release)
+	server.handler = func(command string) (string, string, uint32) {
+		if strings.HasPrefix(command, "sleep") {
+			<-release
+		}
+		return "done", "", 0
+	}
+
+	runner.Timeout = 30 * time.Millisecond
+
+	start := time.Now()
+	result, err := runner.Run(context.Background(), "sleep", "60")
+	assert.True(t, errors.Is(err, ErrSSHCommandTimeout))
+	assert.Equal(t, -1, result.ExitCode)
+	assert.True(t, time.Since(start) < time.Second)
+
+	//A cancelled context is reported as such
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	runner.Timeout = 0
+	_, err = runner.Run(ctx, "sleep", "60")
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	//The connection outlives the timed out command
+	result, err = runner.Run(context.Background(), "qstat")
+	assert.Nil(t, err)
+	assert.Equal(t, "done", string(result.Stdout))
+
+	connections, _ := server.stats()
+	assert.Equal(t, 1, connections)
+}
+
+func TestSSHRunnerUnansweredConnection(t *testing.T) {
+	runner, server := newTestSSHRunner(t)
+	runner.KeepAlive = 100 * time.Millisecond
+
+	_, err := runner.Run(context.Background(), "qstat")
+	assert.Nil(t, err)
+
+	server.setStalled(true)
+	runner.Timeout = 30 * time.Millisecond
+
+	//Commands give up on a session that is never opened, without waiting on one another
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := runner.Run(context.Background(), "qstat")
+			assert.True(t, errors.Is(err, ErrSSHCommandTimeout))
+		}()
+	}
+	wg.Wait()
+	assert.True(t, time.Since(start) < time.Second)
+
+	//The unanswered keepalive drops the connection, so the next command connects again
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		runner.mu.Lock()
+		dropped := runner.client == nil
+		runner.mu.Unlock()
+
+		if dropped || time.Now().After(deadline) {
+			assert.True(t, dropped)
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	server.setStalled(false)
+	before, _ := server.stats()
+
+	_, err = runner.Run(context.Background(), "qstat")
+	assert.Nil(t, err)
+
+	connections, _ := server.stats()
+	assert.Equal(t, before+1, connections)
+
+	server.mu.Lock()
+	assert.NotZero(t, server.keepAlives)
+	server.mu.Unlock()
+}
+
+func TestSSHRunnerEnvironment(t *testing.T) {
+	runner, server := newTestSSHRunner(t)
+
+	for _, env := range []string{"SGE_ROOT", "SGE;touch /tmp/x=1", "1SGE=1", "=1"} {
+		runner.Env = []string{env}
+
+		_, err := runner.Run(context.Background(), "qstat")
+		assert.Equal(t, ErrInvalidSSHEnvironment, err, env)
+	}
+
+	_, commands := server.stats()
+	assert.Empty(t, commands)
+
+	runner.Env = []string{"_SGE_CELL2=default"}
+	_, err := runner.Run(context.Background(), "qstat")
+	assert.Nil(t, err)
+}
+
+func TestSSHRunnerHostKeyChecking(t *testing.T) {
+	runner, server := newTestSSHRunner(t)
+
+	//A known_hosts file listing another key for the host is refused
+	otherKey, _ := newTestSigner(t)
+	knownHosts := filepath.Join(t.TempDir(), "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(server.listener.Addr().String())}, otherKey.PublicKey())
+	assert.Nil(t, ioutil.WriteFile(knownHosts, []byte(line+"\n"), 0600))
+
+	callback, err := knownhosts.New(knownHosts)
+	assert.Nil(t, err)
+	runner.HostKeyCallback = callback
+
+	_, err = runner.Run(context.Background(), "qstat")
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "knownhosts: key mismatch")
+
+	//Host keys are never accepted without a callback
+	runner.HostKeyCallback = nil
+	_, err = runner.Run(context.Background(), "qstat")
+	assert.Equal(t, ErrNoHostKeyCallback, err)
+
+	connections, commands := server.stats()
+	assert.Equal(t, 0, connections)
+	assert.Empty(t, commands)
+}
+
+func TestSSHRunnerAuthentication(t *testing.T) {
+	runner, server := newTestSSHRunner(t)
+
+	otherKey, _ := newTestSigner(t)
+	runner.Auth = []ssh.AuthMethod{ssh.PublicKeys(otherKey)}
+
+	_, err := runner.Run(context.Background(), "qstat")
+	assert.NotNil(t, err)
+
+	connections, _ := server.stats()
+	assert.Equal(t, 0, connections)
+
+	_, err = NewSSHRunner("localhost", "sge", filepath.Join(t.TempDir(), "missing"), "")
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestSSHRunnerClient(t *testing.T) {
+	runner, server := newTestSSHRunner(t)
+
+	content, err := ioutil.ReadFile("test_data/small.xml")
+	assert.Nil(t, err)
+
+	server.handler = func(command string) (string, string, uint32) {
+		if strings.HasPrefix(command, "qstat") {
+			return string(content), "", 0
+		}
+		return "", "", 0
+	}
+
+	jobs, err := NewClient(runner).Jobs()
+	assert.Nil(t, err)
+	assert.NotEmpty(t, jobs)
+
+	_, commands := server.stats()
+	assert.Len(t, commands, 1)
+	assert.True(t, strings.HasPrefix(commands[0], "qstat "))
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{word: "-xml", want: "-xml"},
+		{word: "h_vmem=4G,h_rt=01:00:00", want: "h_vmem=4G,h_rt=01:00:00"},
+		{word: "", want: "''"},
+		{word: "*", want: "'*'"},
+		{word: "it's", want: `'it'\''s'`},
+		{word: "a; rm -rf /", want: "'a; rm -rf /'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			assert.Equal(t, tt.want, shellQuote(tt.word))
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package stream
+
+import (
+	"sync"
+	"time"
+
+	"github.com/metrumresearchgroup/gogridengine"
+)
+
+//Message is a published event along with its position in the stream
+type Message struct {
+	//ID increases by one for every message published, starting at 1
+	ID   uint64    `json:"id"`
+	Time time.Time `json:"time"`
+	gogridengine.Event
+}
+
+//buffer is a ring of the most recent messages. Subscribers are woken whenever messages are appended and read whatever they haven't seen yet, so a slow client never holds up publishing.
+type buffer struct {
+	mu       sync.Mutex
+	messages []Message
+	//start is the index of the oldest message in the ring, count how many it holds
+	start       int
+	count       int
+	next        uint64
+	subscribers map[chan struct{}]struct{}
+}
+
+func newBuffer(size int) *buffer {
+	return &buffer{
+		messages:    make([]Message, size),
+		next:        1,
+		subscribers: make(map[chan struct{}]struct{}),
+	}
+}
+
+//append assigns IDs to the events, evicting the oldest messages once the ring is full, then wakes every subscriber
+func (b *buffer) append(at time.Time, events ...gogridengine.Event) {
+	if len(events) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, e := range events {
+		m := Message{
+			ID:    b.next,
+			Time:  at,
+			Event: e,
+		}
+		b.next++
+
+		if b.count < len(b.messages) {
+			b.messages[(b.start+b.count)%len(b.messages)] = m
+			b.count++
+			continue
+		}
+
+		b.messages[b.start] = m
+		b.start = (b.start + 1) % len(b.messages)
+	}
+
+	for s := range b.subscribers {
+		select {
+		case s <- struct{}{}:
+		default:
+			//Already due to read
+		}
+	}
+}
+
+//since returns the messages published after the provided ID. It reports false when some of those messages were already evicted, or the ID was never handed out (eg: it came from before a restart)
+func (b *buffer) since(id uint64) ([]Message, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	last := b.next - 1
+
+	if id > last {
+		return nil, false
+	}
+
+	oldest := b.next - uint64(b.count)
+	complete := id+1 >= oldest
+
+	if id+1 < oldest {
+		id = oldest - 1
+	}
+
+	messages := make([]Message, 0, last-id)
+
+	for k := b.count - int(last-id); k < b.count; k++ {
+		messages = append(messages, b.messages[(b.start+k)%len(b.messages)])
+	}
+
+	return messages, complete
+}
+
+//last is the ID of the most recently published message, 0 when nothing has been
+func (b *buffer) last() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.next - 1
+}
+
+func (b *buffer) subscribe() chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	s := make(chan struct{}, 1)
+	b.subscribers[s] = struct{}{}
+
+	return s
+}
+
+func (b *buffer) unsubscribe(s chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.subscribers, s)
+}
+
+func (b *buffer) subscriberCount() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.subscribers)
+}
@@ -0,0 +1,94 @@
+package stream
+
+import (
+	"testing"
+	"time"
+
+	"github.com/metrumresearchgroup/gogridengine"
+	"github.com/stretchr/testify/assert"
+)
+
+func jobEvents(numbers ...int64) []gogridengine.Event {
+	var events []gogridengine.Event
+
+	for _, n := range numbers {
+		events = append(events, gogridengine.Event{
+			Type: gogridengine.JobSubmitted,
+			Key:  gogridengine.JobKey{JobNumber: n},
+		})
+	}
+
+	return events
+}
+
+func ids(messages []Message) []uint64 {
+	result := []uint64{}
+
+	for _, m := range messages {
+		result = append(result, m.ID)
+	}
+
+	return result
+}
+
+func TestBufferSince(t *testing.T) {
+	b := newBuffer(3)
+
+	messages, complete := b.since(0)
+	assert.True(t, complete)
+	assert.Empty(t, messages)
+
+	b.append(time.Now(), jobEvents(1, 2)...)
+
+	tests := []struct {
+		name     string
+		before   []int64
+		id       uint64
+		want     []uint64
+		complete bool
+	}{
+		{name: "from the start", id: 0, want: []uint64{1, 2}, complete: true},
+		{name: "caught up", id: 2, want: []uint64{}, complete: true},
+		{name: "unknown id", id: 7, want: []uint64{}, complete: false},
+		{name: "wrapped", before: []int64{3, 4}, id: 2, want: []uint64{3, 4}, complete: true},
+		{name: "evicted", id: 0, want: []uint64{2, 3, 4}, complete: false},
+		{name: "oldest still buffered", id: 1, want: []uint64{2, 3, 4}, complete: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b.append(time.Now(), jobEvents(tt.before...)...)
+
+			messages, complete := b.since(tt.id)
+			assert.Equal(t, tt.want, ids(messages))
+			assert.Equal(t, tt.complete, complete)
+		})
+	}
+
+	assert.Equal(t, uint64(4), b.last())
+}
+
+func TestBufferNotifiesSubscribers(t *testing.T) {
+	b := newBuffer(8)
+	s := b.subscribe()
+
+	b.append(time.Now(), jobEvents(1)...)
+	//Notifications coalesce rather than block publishing
+	b.append(time.Now(), jobEvents(2)...)
+
+	select {
+	case <-s:
+	default:
+		t.Fatal("subscriber was not notified")
+	}
+
+	select {
+	case <-s:
+		t.Fatal("notifications should coalesce")
+	default:
+	}
+
+	b.unsubscribe(s)
+	assert.Equal(t, 0, b.subscriberCount())
+	b.append(time.Now(), jobEvents(3)...)
+	assert.Len(t, s, 0)
+}
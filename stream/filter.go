@@ -0,0 +1,128 @@
+package stream
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/metrumresearchgroup/gogridengine"
+)
+
+//ErrUnknownEventType is returned for type filters naming no event type
+const ErrUnknownEventType = gogridengine.Error("Unknown event type")
+
+var eventTypes = map[gogridengine.EventType]bool{
+	gogridengine.JobSubmitted:     true,
+	gogridengine.JobStarted:       true,
+	gogridengine.JobStateChanged:  true,
+	gogridengine.JobEnteredError:  true,
+	gogridengine.JobDisappeared:   true,
+	gogridengine.HostAdded:        true,
+	gogridengine.HostRemoved:      true,
+	gogridengine.HostStateChanged: true,
+}
+
+//Filter limits the events sent to a client. Each field matches any of its values, and an event must match every field that is set.
+//It is read from the query parameters type, owner, job and queue (eg: ?type=job_started&type=job_disappeared&owner=darrellb).
+//Host events have no owner or job, so they are left out by owner and job filters.
+type Filter struct {
+	Types  []gogridengine.EventType
+	Owners []string
+	Jobs   []int64
+	//Queues are cluster queue names, matched against the queue instance of the event
+	Queues []string
+}
+
+//ParseFilter reads the filter from query parameters
+func ParseFilter(values url.Values) (Filter, error) {
+	filter := Filter{
+		Owners: values["owner"],
+		Queues: values["queue"],
+	}
+
+	for _, t := range values["type"] {
+		if !eventTypes[gogridengine.EventType(t)] {
+			return Filter{}, fmt.Errorf("%w: %s", ErrUnknownEventType, t)
+		}
+
+		filter.Types = append(filter.Types, gogridengine.EventType(t))
+	}
+
+	for _, j := range values["job"] {
+		number, err := strconv.ParseInt(j, 10, 64)
+		if err != nil {
+			return Filter{}, fmt.Errorf("invalid job %q: %w", j, err)
+		}
+
+		filter.Jobs = append(filter.Jobs, number)
+	}
+
+	return filter, nil
+}
+
+//Matches returns whether the event should be sent
+func (f Filter) Matches(e gogridengine.Event) bool {
+	if len(f.Types) > 0 && !containsType(f.Types, e.Type) {
+		return false
+	}
+
+	if len(f.Owners) > 0 && !containsString(f.Owners, owner(e)) {
+		return false
+	}
+
+	if len(f.Jobs) > 0 && !containsJob(f.Jobs, e.Key.JobNumber) {
+		return false
+	}
+
+	if len(f.Queues) > 0 {
+		queue, _ := gogridengine.SplitQueueInstance(e.Host)
+		if !containsString(f.Queues, queue) {
+			return false
+		}
+	}
+
+	return true
+}
+
+//owner of the job an event is about, empty for host events
+func owner(e gogridengine.Event) string {
+	if e.Job != nil {
+		return e.Job.JobOwner
+	}
+
+	if e.Previous != nil {
+		return e.Previous.JobOwner
+	}
+
+	return ""
+}
+
+func containsType(types []gogridengine.EventType, t gogridengine.EventType) bool {
+	for _, candidate := range types {
+		if candidate == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsString(values []string, s string) bool {
+	for _, candidate := range values {
+		if candidate == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func containsJob(jobs []int64, number int64) bool {
+	for _, candidate := range jobs {
+		if candidate == number {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,62 @@
+package stream
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/metrumresearchgroup/gogridengine"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterMatches(t *testing.T) {
+	started := gogridengine.Event{
+		Type: gogridengine.JobStarted,
+		Key:  gogridengine.JobKey{JobNumber: 1},
+		Job:  &gogridengine.Job{JBJobNumber: 1, JobOwner: "darrellb"},
+		Host: "all.q@node1",
+	}
+	finished := gogridengine.Event{
+		Type:     gogridengine.JobDisappeared,
+		Key:      gogridengine.JobKey{JobNumber: 2},
+		Previous: &gogridengine.Job{JBJobNumber: 2, JobOwner: "devinp"},
+		Host:     "gpu.q@node2",
+	}
+	disabled := gogridengine.Event{
+		Type:  gogridengine.HostStateChanged,
+		Host:  "all.q@node3",
+		State: "d",
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		want  []bool
+	}{
+		{name: "everything", query: "", want: []bool{true, true, true}},
+		{name: "type", query: "type=job_started&type=host_state_changed", want: []bool{true, false, true}},
+		{name: "owner of previous job", query: "owner=devinp", want: []bool{false, true, false}},
+		{name: "job", query: "job=1", want: []bool{true, false, false}},
+		{name: "queue", query: "queue=all.q", want: []bool{true, false, true}},
+		{name: "every field must match", query: "queue=all.q&owner=devinp", want: []bool{false, false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			assert.Nil(t, err)
+
+			filter, err := ParseFilter(values)
+			assert.Nil(t, err)
+
+			assert.Equal(t, tt.want, []bool{filter.Matches(started), filter.Matches(finished), filter.Matches(disabled)})
+		})
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	_, err := ParseFilter(url.Values{"type": {"job_exploded"}})
+	assert.True(t, errors.Is(err, ErrUnknownEventType))
+
+	_, err = ParseFilter(url.Values{"job": {"first"}})
+	assert.NotNil(t, err)
+}
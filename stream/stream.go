@@ -0,0 +1,231 @@
+//Package stream is an http.Handler pushing the events diffed between qstat snapshots to connected clients as server-sent events.
+//
+//Every event is sent with its ID, its type as the event name and the Message as JSON data:
+//
+//	id: 42
+//	event: job_started
+//	data: {"id":42,"time":"2019-12-18T14:00:00Z","type":"job_started","key":{"job_number":1,"task_id":0},...}
+//
+//Clients reconnecting with a Last-Event-ID header (or ?last_event_id=) are sent what they missed from the buffer of recent events.
+//When that is no longer possible a resync event is sent instead, after which the client should reload the full state (eg: from the api package) before applying further events.
+//Clients can limit what they are sent through the query parameters described by Filter.
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/metrumresearchgroup/gogridengine"
+	log "github.com/sirupsen/logrus"
+)
+
+//ResyncEvent is the event name sent when the events a client asked to resume from are no longer buffered
+const ResyncEvent = "resync"
+
+//Options tune the handler
+type Options struct {
+	//BufferSize is how many recent events are kept for clients resuming a stream. Defaults to 1024
+	BufferSize int
+	//Heartbeat is the interval comments are sent at on idle streams, keeping proxies from closing them. Defaults to 15 seconds
+	Heartbeat time.Duration
+	//Retry is the reconnection delay suggested to clients. Zero leaves it to the client
+	Retry time.Duration
+}
+
+//Handler buffers published events and streams them to every connected client
+type Handler struct {
+	opts   Options
+	buffer *buffer
+	now    func() time.Time
+}
+
+//Resync is the data of a resync event
+type Resync struct {
+	//LastEventID is the ID of the latest event, which the client is now following from
+	LastEventID uint64 `json:"last_event_id"`
+}
+
+//NewHandler creates a handler with an empty event buffer
+func NewHandler(opts Options) *Handler {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = 1024
+	}
+
+	if opts.Heartbeat <= 0 {
+		opts.Heartbeat = 15 * time.Second
+	}
+
+	return &Handler{
+		opts:   opts,
+		buffer: newBuffer(opts.BufferSize),
+		now:    time.Now,
+	}
+}
+
+//Publish buffers the events and sends them to every connected client
+func (h *Handler) Publish(events ...gogridengine.Event) {
+	h.buffer.append(h.now(), events...)
+}
+
+//Follow publishes the events of every successful update until the channel is closed, as it is when a Watcher's context is cancelled
+func (h *Handler) Follow(updates <-chan gogridengine.WatchUpdate) {
+	for update := range updates {
+		if update.Err == nil {
+			h.buffer.append(update.Time, update.Events...)
+		}
+	}
+}
+
+//Clients returns how many clients are connected
+func (h *Handler) Clients() int {
+	return h.buffer.subscriberCount()
+}
+
+//ServeHTTP streams events to the client until it disconnects
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	filter, err := ParseFilter(r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	//New clients are only sent what happens from now on
+	cursor := h.buffer.last()
+
+	if id := lastEventID(r); id != "" {
+		cursor, err = strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid last event ID", http.StatusBadRequest)
+			return
+		}
+	}
+
+	//Subscribe before reading the buffer so nothing published in between is missed
+	notify := h.buffer.subscribe()
+	defer h.buffer.unsubscribe(notify)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	//Stops nginx from buffering the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if h.opts.Retry > 0 {
+		fmt.Fprintf(w, "retry: %d\n\n", h.opts.Retry.Milliseconds())
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(h.opts.Heartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		cursor, err = h.send(w, cursor, filter)
+		if err != nil {
+			log.Debug("Event stream client went away: ", err)
+			return
+		}
+		flusher.Flush()
+
+		select {
+		case <-notify:
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+//send writes every message after the cursor that passes the filter, returning the new cursor
+func (h *Handler) send(w http.ResponseWriter, cursor uint64, filter Filter) (uint64, error) {
+	messages, complete := h.buffer.since(cursor)
+
+	if !complete {
+		//The client can't catch up from the buffer, so it starts over from the latest event
+		last := h.buffer.last()
+		if err := writeEvent(w, "", ResyncEvent, Resync{LastEventID: last}); err != nil {
+			return cursor, err
+		}
+
+		return last, nil
+	}
+
+	for _, m := range messages {
+		cursor = m.ID
+
+		if !filter.Matches(m.Event) {
+			continue
+		}
+
+		if err := writeEvent(w, strconv.FormatUint(m.ID, 10), string(m.Type), m); err != nil {
+			return cursor, err
+		}
+	}
+
+	return cursor, nil
+}
+
+//Run watches the cluster and publishes what changes until the context is cancelled.
+//The first successful poll is the baseline, so the jobs and hosts already there aren't reported as new
+func (h *Handler) Run(ctx context.Context, w gogridengine.Watcher) {
+	baseline := false
+
+	for update := range w.Watch(ctx) {
+		if update.Err != nil {
+			continue
+		}
+
+		if !baseline {
+			baseline = true
+			continue
+		}
+
+		h.buffer.append(update.Time, update.Events...)
+	}
+}
+
+func lastEventID(r *http.Request) string {
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+
+	//EventSource can't set headers on its first connection, so it can be provided in the URL too
+	return r.URL.Query().Get("last_event_id")
+}
+
+func writeEvent(w http.ResponseWriter, id string, event string, data interface{}) error {
+	content, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	if id != "" {
+		if _, err := fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, content)
+
+	return err
+}
@@ -0,0 +1,296 @@
+package stream
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/metrumresearchgroup/gogridengine"
+	"github.com/stretchr/testify/assert"
+)
+
+//received is a server-sent event or comment read off a stream
+type received struct {
+	id      string
+	event   string
+	data    string
+	comment string
+	retry   string
+}
+
+//connect opens a stream, returning the events it receives over a channel which is closed with the stream
+func connect(t *testing.T, url string, lastEventID string) <-chan received {
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	assert.Nil(t, err)
+
+	if lastEventID != "" {
+		request.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+	events := make(chan received, 64)
+
+	go func() {
+		defer close(events)
+		defer response.Body.Close()
+
+		scanner := bufio.NewScanner(response.Body)
+		var current received
+
+		for scanner.Scan() {
+			line := scanner.Text()
+
+			switch {
+			case line == "":
+				if current != (received{}) {
+					events <- current
+				}
+				current = received{}
+			case strings.HasPrefix(line, ":"):
+				current.comment = strings.TrimSpace(line[1:])
+			case strings.HasPrefix(line, "id: "):
+				current.id = line[4:]
+			case strings.HasPrefix(line, "event: "):
+				current.event = line[7:]
+			case strings.HasPrefix(line, "retry: "):
+				current.retry = line[7:]
+			case strings.HasPrefix(line, "data: "):
+				current.data = line[6:]
+			}
+		}
+	}()
+
+	return events
+}
+
+func next(t *testing.T, events <-chan received) received {
+	select {
+	case e, ok := <-events:
+		assert.True(t, ok, "stream closed")
+		return e
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for an event")
+		return received{}
+	}
+}
+
+//waitForClients waits for the handler to have subscribed the connected clients, so nothing published afterwards is missed
+func waitForClients(t *testing.T, h *Handler, count int) {
+	deadline := time.Now().Add(2 * time.Second)
+
+	for h.Clients() != count {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d clients, have %d", count, h.Clients())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestStream(t *testing.T) {
+	h := NewHandler(Options{})
+	h.Publish(jobEvents(1)...)
+
+	server := httptest.NewServer(h)
+	t.Cleanup(server.Close)
+
+	events := connect(t, server.URL, "")
+	waitForClients(t, h, 1)
+
+	//Event 1 was published before connecting, so only what follows is sent
+	h.Publish(jobEvents(2)...)
+
+	e := next(t, events)
+	assert.Equal(t, "2", e.id)
+	assert.Equal(t, "job_submitted", e.event)
+
+	var m Message
+	assert.Nil(t, json.Unmarshal([]byte(e.data), &m))
+	assert.Equal(t, uint64(2), m.ID)
+	assert.Equal(t, int64(2), m.Key.JobNumber)
+	assert.Equal(t, gogridengine.JobSubmitted, m.Type)
+}
+
+func TestStreamFilters(t *testing.T) {
+	h := NewHandler(Options{})
+
+	server := httptest.NewServer(h)
+	t.Cleanup(server.Close)
+
+	events := connect(t, server.URL+"?job=3&type=job_submitted", "")
+	waitForClients(t, h, 1)
+
+	h.Publish(jobEvents(1, 2, 3)...)
+	h.Publish(gogridengine.Event{Type: gogridengine.JobStarted, Key: gogridengine.JobKey{JobNumber: 3}})
+
+	e := next(t, events)
+	assert.Equal(t, "3", e.id)
+
+	response, err := http.Get(server.URL + "?type=job_exploded")
+	assert.Nil(t, err)
+	response.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+}
+
+func TestStreamResume(t *testing.T) {
+	h := NewHandler(Options{BufferSize: 4})
+	h.Publish(jobEvents(1, 2, 3)...)
+
+	server := httptest.NewServer(h)
+	t.Cleanup(server.Close)
+
+	//Events after the last one seen are replayed
+	events := connect(t, server.URL, "1")
+	assert.Equal(t, "2", next(t, events).id)
+	assert.Equal(t, "3", next(t, events).id)
+
+	h.Publish(jobEvents(4, 5, 6)...)
+
+	//Event 2 has been evicted, so the client must resync
+	events = connect(t, server.URL, "1")
+
+	e := next(t, events)
+	assert.Equal(t, ResyncEvent, e.event)
+	assert.Equal(t, "", e.id)
+	assert.JSONEq(t, `{"last_event_id":6}`, e.data)
+
+	waitForClients(t, h, 2)
+	h.Publish(jobEvents(7)...)
+	assert.Equal(t, "7", next(t, events).id)
+
+	//So must clients resuming from IDs handed out before a restart
+	e = next(t, connect(t, server.URL+"?last_event_id=100", ""))
+	assert.Equal(t, ResyncEvent, e.event)
+}
+
+func TestStreamHeartbeat(t *testing.T) {
+	h := NewHandler(Options{Heartbeat: 10 * time.Millisecond, Retry: 3 * time.Second})
+
+	server := httptest.NewServer(h)
+	t.Cleanup(server.Close)
+
+	events := connect(t, server.URL, "")
+
+	assert.Equal(t, "3000", next(t, events).retry)
+	assert.Equal(t, "heartbeat", next(t, events).comment)
+}
+
+func TestStreamDisconnect(t *testing.T) {
+	h := NewHandler(Options{})
+
+	server := httptest.NewServer(h)
+	t.Cleanup(server.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	assert.Nil(t, err)
+
+	response, err := http.DefaultClient.Do(request)
+	assert.Nil(t, err)
+	defer response.Body.Close()
+
+	waitForClients(t, h, 1)
+	cancel()
+	waitForClients(t, h, 0)
+}
+
+func TestFollow(t *testing.T) {
+	h := NewHandler(Options{})
+	updates := make(chan gogridengine.WatchUpdate, 2)
+
+	updates <- gogridengine.WatchUpdate{Events: jobEvents(1, 2)}
+	updates <- gogridengine.WatchUpdate{Err: gogridengine.ErrPollTimeout, Events: jobEvents(3)}
+	close(updates)
+
+	h.Follow(updates)
+
+	messages, _ := h.buffer.since(0)
+	assert.Equal(t, []uint64{1, 2}, ids(messages))
+}
+
+//snapshotSource serves a snapshot per poll, repeating the last one once exhausted
+type snapshotSource struct {
+	mu        sync.Mutex
+	snapshots []string
+	calls     int
+}
+
+func (s *snapshotSource) Get() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := s.calls
+	if index >= len(s.snapshots) {
+		index = len(s.snapshots) - 1
+	}
+	s.calls++
+
+	return s.snapshots[index], nil
+}
+
+func (s *snapshotSource) Calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.calls
+}
+
+func snapshot(t *testing.T, pending ...int64) string {
+	ji := gogridengine.JobInfo{}
+	for _, n := range pending {
+		ji.PendingJobs.JobList = append(ji.PendingJobs.JobList, gogridengine.Job{JBJobNumber: n, State: "qw", Slots: 1})
+	}
+
+	content, err := ji.GetXML()
+	assert.Nil(t, err)
+
+	return content
+}
+
+func TestRun(t *testing.T) {
+	h := NewHandler(Options{})
+	source := &snapshotSource{
+		snapshots: []string{snapshot(t, 1, 2), snapshot(t, 1, 2, 3)},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		h.Run(ctx, gogridengine.Watcher{Source: source, Interval: time.Millisecond})
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for source.Calls() < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	//The jobs queued before the handler started are the baseline rather than submissions
+	messages, _ := h.buffer.since(0)
+	if assert.Len(t, messages, 1) {
+		assert.Equal(t, gogridengine.JobSubmitted, messages[0].Type)
+		assert.Equal(t, int64(3), messages[0].Key.JobNumber)
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	NewHandler(Options{}).ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/events", nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+}
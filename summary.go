@@ -0,0 +1,164 @@
+package gogridengine
+
+import (
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	//PhaseRunning is the phase of jobs actively executing on a host
+	PhaseRunning string = "running"
+	//PhasePending is the phase of jobs waiting to be scheduled (queued, held or waiting)
+	PhasePending string = "pending"
+	//PhaseError is the phase of jobs whose state code indicates an error
+	PhaseError string = "error"
+	//PhaseOther is the phase of jobs whose state code doesn't fit any of the above (suspended, transferring etc)
+	PhaseOther string = "other"
+)
+
+//JobPhase buckets the state code of a job into one of the running, pending, error or other phases
+func JobPhase(job Job) string {
+	if IsJobInErrorState(job) == 1 {
+		return PhaseError
+	}
+
+	if strings.Contains(job.State, "r") {
+		return PhaseRunning
+	}
+
+	if strings.ContainsAny(job.State, "qwh") {
+		return PhasePending
+	}
+
+	return PhaseOther
+}
+
+//JobNamePrefix returns the portion of a job name preceding its first digit, minus any trailing separators (Run478 -> Run). Names without a usable prefix are returned whole.
+func JobNamePrefix(name string) string {
+	index := strings.IndexFunc(name, unicode.IsDigit)
+
+	if index < 0 {
+		return name
+	}
+
+	prefix := strings.TrimRight(name[:index], "_-.")
+
+	if prefix == "" {
+		return name
+	}
+
+	return prefix
+}
+
+//GroupSummary is the aggregate of job counts and slot totals for a single group of jobs
+type GroupSummary struct {
+	Jobs           int   `json:"jobs"`
+	Running        int   `json:"running"`
+	Pending        int   `json:"pending"`
+	Error          int   `json:"error"`
+	Other          int   `json:"other"`
+	Slots          int64 `json:"slots"`
+	RunningSlots   int64 `json:"running_slots"`
+	PendingSlots   int64 `json:"pending_slots"`
+	ErrorSlots     int64 `json:"error_slots"`
+	OldestPending  *Job  `json:"oldest_pending,omitempty"`
+	LongestRunning *Job  `json:"longest_running,omitempty"`
+}
+
+//JobSummary groups a JobList by owner, phase, cluster queue and job name prefix.
+//Jobs which haven't been scheduled onto a queue yet are not included in ByQueue. ByCluster is only set for jobs of a Federation, which carry their cluster name.
+type JobSummary struct {
+	Total        GroupSummary             `json:"total"`
+	ByOwner      map[string]*GroupSummary `json:"by_owner"`
+	ByPhase      map[string]*GroupSummary `json:"by_phase"`
+	ByQueue      map[string]*GroupSummary `json:"by_queue"`
+	ByNamePrefix map[string]*GroupSummary `json:"by_name_prefix"`
+	ByCluster    map[string]*GroupSummary `json:"by_cluster,omitempty"`
+}
+
+//Summarize computes job counts and slot totals for the JobList, grouped by owner, phase, queue and job name prefix
+func (jl JobList) Summarize() JobSummary {
+	summary := JobSummary{
+		ByOwner:      make(map[string]*GroupSummary),
+		ByPhase:      make(map[string]*GroupSummary),
+		ByQueue:      make(map[string]*GroupSummary),
+		ByNamePrefix: make(map[string]*GroupSummary),
+	}
+
+	for _, j := range jl {
+		summary.Total.add(j)
+		groupFor(summary.ByOwner, j.JobOwner).add(j)
+		groupFor(summary.ByPhase, JobPhase(j)).add(j)
+		groupFor(summary.ByNamePrefix, JobNamePrefix(j.JobName)).add(j)
+
+		if j.QueueName != "" {
+			queue, _ := SplitQueueInstance(j.QueueName)
+			groupFor(summary.ByQueue, queue).add(j)
+		}
+
+		if j.Cluster != "" {
+			if summary.ByCluster == nil {
+				summary.ByCluster = make(map[string]*GroupSummary)
+			}
+			groupFor(summary.ByCluster, j.Cluster).add(j)
+		}
+	}
+
+	return summary
+}
+
+func groupFor(groups map[string]*GroupSummary, key string) *GroupSummary {
+	group, ok := groups[key]
+
+	if !ok {
+		group = &GroupSummary{}
+		groups[key] = group
+	}
+
+	return group
+}
+
+func (g *GroupSummary) add(j Job) {
+	g.Jobs++
+	g.Slots += int64(j.Slots)
+
+	switch JobPhase(j) {
+	case PhaseRunning:
+		g.Running++
+		g.RunningSlots += int64(j.Slots)
+
+		if g.LongestRunning == nil || isEarlier(j.StartTime, g.LongestRunning.StartTime) {
+			longest := j
+			g.LongestRunning = &longest
+		}
+	case PhasePending:
+		g.Pending++
+		g.PendingSlots += int64(j.Slots)
+
+		if g.OldestPending == nil || isEarlier(j.SubmittedTime, g.OldestPending.SubmittedTime) {
+			oldest := j
+			g.OldestPending = &oldest
+		}
+	case PhaseError:
+		g.Error++
+		g.ErrorSlots += int64(j.Slots)
+	default:
+		g.Other++
+	}
+}
+
+//isEarlier reports whether candidate is a valid time earlier than current. An unparsable current time is always replaced by a valid candidate.
+func isEarlier(candidate string, current string) bool {
+	ct, err := time.Parse(ISO8601FMT, candidate)
+	if err != nil {
+		return false
+	}
+
+	cur, err := time.Parse(ISO8601FMT, current)
+	if err != nil {
+		return true
+	}
+
+	return ct.Before(cur)
+}
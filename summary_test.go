@@ -0,0 +1,116 @@
+package gogridengine
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobPhase(t *testing.T) {
+	tests := []struct {
+		state string
+		want  string
+	}{
+		{"r", PhaseRunning},
+		{"dr", PhaseRunning},
+		{"qw", PhasePending},
+		{"hqw", PhasePending},
+		{"Eqw", PhaseError},
+		{"dt", PhaseError},
+		{"s", PhaseOther},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.state, func(t *testing.T) {
+			assert.Equal(t, tt.want, JobPhase(Job{State: tt.state}))
+		})
+	}
+}
+
+func TestJobNamePrefix(t *testing.T) {
+	assert.Equal(t, "Run", JobNamePrefix("Run478"))
+	assert.Equal(t, "Executable_MTP", JobNamePrefix("Executable_MTP001.sh"))
+	assert.Equal(t, "task_array.sh", JobNamePrefix("task_array.sh"))
+	assert.Equal(t, "42", JobNamePrefix("42"))
+}
+
+func TestJobList_Summarize(t *testing.T) {
+	jl := JobList{
+		{
+			JBJobNumber: 1,
+			JobName:     "Run1",
+			JobOwner:    "alice",
+			State:       "r",
+			Slots:       4,
+			StartTime:   "2019-09-15T15:26:36",
+			QueueName:   "all.q@ip-10-0-1-80.ec2.internal",
+		},
+		{
+			JBJobNumber: 2,
+			JobName:     "Run2",
+			JobOwner:    "alice",
+			State:       "r",
+			Slots:       2,
+			StartTime:   "2019-09-14T15:26:36",
+			QueueName:   "all.q@ip-10-0-1-113.ec2.internal",
+		},
+		{
+			JBJobNumber:   3,
+			JobName:       "Run3",
+			JobOwner:      "alice",
+			State:         "qw",
+			Slots:         1,
+			SubmittedTime: "2019-09-16T15:26:36",
+		},
+		{
+			JBJobNumber:   4,
+			JobName:       "task_array.sh",
+			JobOwner:      "bob",
+			State:         "qw",
+			Slots:         8,
+			SubmittedTime: "2019-09-13T15:26:36",
+		},
+		{
+			JBJobNumber:   5,
+			JobName:       "task_array.sh",
+			JobOwner:      "bob",
+			State:         "Eqw",
+			Slots:         1,
+			SubmittedTime: "2019-09-12T15:26:36",
+		},
+	}
+
+	summary := jl.Summarize()
+
+	assert.Equal(t, 5, summary.Total.Jobs)
+	assert.Equal(t, int64(16), summary.Total.Slots)
+	assert.Equal(t, int64(2), summary.Total.LongestRunning.JBJobNumber)
+	assert.Equal(t, int64(4), summary.Total.OldestPending.JBJobNumber)
+
+	alice := summary.ByOwner["alice"]
+	assert.Equal(t, 3, alice.Jobs)
+	assert.Equal(t, 2, alice.Running)
+	assert.Equal(t, 1, alice.Pending)
+	assert.Equal(t, int64(6), alice.RunningSlots)
+	assert.Equal(t, int64(1), alice.PendingSlots)
+	assert.Equal(t, int64(3), alice.OldestPending.JBJobNumber)
+
+	bob := summary.ByOwner["bob"]
+	assert.Equal(t, 1, bob.Error)
+	assert.Equal(t, int64(1), bob.ErrorSlots)
+	assert.Nil(t, bob.LongestRunning)
+
+	assert.Equal(t, 2, summary.ByPhase[PhasePending].Jobs)
+	assert.Equal(t, 1, summary.ByPhase[PhaseError].Jobs)
+
+	assert.Len(t, summary.ByQueue, 1)
+	assert.Equal(t, int64(6), summary.ByQueue["all.q"].Slots)
+
+	assert.Equal(t, 3, summary.ByNamePrefix["Run"].Jobs)
+	assert.Equal(t, 2, summary.ByNamePrefix["task_array.sh"].Jobs)
+
+	encoded, err := json.Marshal(summary)
+	assert.Nil(t, err)
+	assert.Contains(t, string(encoded), `"by_owner":{"alice":{"jobs":3`)
+}
@@ -0,0 +1,525 @@
+package tui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/metrumresearchgroup/gogridengine"
+)
+
+var (
+	styleDefault  = tcell.StyleDefault
+	styleTitle    = tcell.StyleDefault.Reverse(true)
+	styleHeader   = tcell.StyleDefault.Bold(true).Underline(true)
+	styleSelected = tcell.StyleDefault.Reverse(true)
+	styleError    = tcell.StyleDefault.Foreground(tcell.ColorRed)
+	styleDim      = tcell.StyleDefault.Dim(true)
+	styleLow      = tcell.StyleDefault.Foreground(tcell.ColorGreen)
+	styleMedium   = tcell.StyleDefault.Foreground(tcell.ColorYellow)
+	styleHigh     = tcell.StyleDefault.Foreground(tcell.ColorRed)
+)
+
+//column of the job table. A width of zero takes whatever space the other columns leave
+type column struct {
+	title   string
+	width   int
+	value   func(j gogridengine.Job) string
+	sorters func(direction gogridengine.SortDirection) []gogridengine.JobSorter
+}
+
+var columns = []column{
+	{
+		title: "JOB",
+		width: 10,
+		value: func(j gogridengine.Job) string { return gogridengine.KeyForJob(j).String() },
+		sorters: func(d gogridengine.SortDirection) []gogridengine.JobSorter {
+			return []gogridengine.JobSorter{gogridengine.ByJobNumber(d), gogridengine.ByTaskID(d)}
+		},
+	},
+	{
+		title: "PRIOR",
+		width: 7,
+		value: func(j gogridengine.Job) string { return strconv.FormatFloat(j.JATPriority, 'f', 5, 64) },
+		sorters: func(d gogridengine.SortDirection) []gogridengine.JobSorter {
+			return []gogridengine.JobSorter{gogridengine.ByPriority(d)}
+		},
+	},
+	{
+		title: "NAME",
+		value: func(j gogridengine.Job) string { return j.JobName },
+		sorters: func(d gogridengine.SortDirection) []gogridengine.JobSorter {
+			return []gogridengine.JobSorter{byString(d, func(j gogridengine.Job) string { return j.JobName })}
+		},
+	},
+	{
+		title: "OWNER",
+		width: 10,
+		value: func(j gogridengine.Job) string { return j.JobOwner },
+		sorters: func(d gogridengine.SortDirection) []gogridengine.JobSorter {
+			return []gogridengine.JobSorter{gogridengine.ByOwner(d)}
+		},
+	},
+	{
+		title: "STATE",
+		width: 5,
+		value: func(j gogridengine.Job) string { return j.State },
+		sorters: func(d gogridengine.SortDirection) []gogridengine.JobSorter {
+			return []gogridengine.JobSorter{gogridengine.ByState(d)}
+		},
+	},
+	{
+		title: "SUBMIT/START AT",
+		width: 19,
+		value: jobTime,
+		sorters: func(d gogridengine.SortDirection) []gogridengine.JobSorter {
+			return []gogridengine.JobSorter{byString(d, jobTime)}
+		},
+	},
+	{
+		title: "QUEUE",
+		width: 20,
+		value: func(j gogridengine.Job) string { return j.QueueName },
+		sorters: func(d gogridengine.SortDirection) []gogridengine.JobSorter {
+			return []gogridengine.JobSorter{byString(d, func(j gogridengine.Job) string { return j.QueueName })}
+		},
+	},
+	{
+		title: "SLOTS",
+		width: 5,
+		value: func(j gogridengine.Job) string { return strconv.Itoa(int(j.Slots)) },
+		sorters: func(d gogridengine.SortDirection) []gogridengine.JobSorter {
+			return []gogridengine.JobSorter{func(a, b gogridengine.Job) int {
+				return directed(d, int(a.Slots)-int(b.Slots))
+			}}
+		},
+	},
+}
+
+//jobTime is when a running job started, or when a pending one was submitted, as qstat lists them
+func jobTime(j gogridengine.Job) string {
+	if j.StartTime != "" {
+		return strings.Replace(j.StartTime, "T", " ", 1)
+	}
+
+	return strings.Replace(j.SubmittedTime, "T", " ", 1)
+}
+
+func byString(direction gogridengine.SortDirection, value func(j gogridengine.Job) string) gogridengine.JobSorter {
+	return func(a, b gogridengine.Job) int {
+		return directed(direction, strings.Compare(value(a), value(b)))
+	}
+}
+
+func directed(direction gogridengine.SortDirection, result int) int {
+	if direction == gogridengine.Descending {
+		return -result
+	}
+
+	return result
+}
+
+//layout of the screen: the title, the host panel, the job table (or detail view) and the status line
+func (a *App) hostRows() int {
+	_, height := a.screen.Size()
+
+	rows := len(a.snapshot.QueueInfo.Queues)
+	limit := (height - 4) / 3
+	if limit < 1 {
+		limit = 1
+	}
+
+	if rows > limit {
+		rows = limit
+	}
+
+	return rows
+}
+
+func (a *App) tableTop() int {
+	//Title, host header, hosts and a blank line
+	return 2 + a.hostRows() + 1
+}
+
+//tableHeight is the number of job rows that fit, excluding the header
+func (a *App) tableHeight() int {
+	_, height := a.screen.Size()
+
+	return height - a.tableTop() - 2
+}
+
+func (a *App) draw() {
+	a.screen.Clear()
+
+	a.drawTitle()
+	a.drawHosts()
+
+	switch a.mode {
+	case modeDetail:
+		a.drawDetail()
+	case modeHelp:
+		a.drawHelp()
+	default:
+		a.drawJobs()
+	}
+
+	a.drawStatus()
+	a.screen.Show()
+}
+
+func (a *App) drawTitle() {
+	width, _ := a.screen.Size()
+	a.fill(0, width, styleTitle)
+
+	s := a.summary
+	title := fmt.Sprintf(" gge top  hosts %d  slots %d/%d (%d free)  running %d  pending %d", s.Hosts, s.SlotsUsed, s.SlotsTotal, s.SlotsFree, s.RunningJobs, s.PendingJobs)
+	a.put(0, 0, width, title, styleTitle)
+
+	var right string
+	style := styleTitle
+	switch {
+	case a.pollErr != nil:
+		right = "qstat failed: " + firstLine(a.pollErr.Error()) + " "
+		style = styleTitle.Foreground(tcell.ColorRed)
+	case a.updated.IsZero():
+		right = "waiting for qstat "
+	default:
+		right = fmt.Sprintf("%d changes  updated %s ", a.changes, a.updated.Format("15:04:05"))
+	}
+
+	if x := width - len(right); x > len(title) {
+		a.put(x, 0, width, right, style)
+	}
+}
+
+func (a *App) drawHosts() {
+	width, _ := a.screen.Size()
+	hosts := a.snapshot.QueueInfo.Queues
+	rows := a.hostRows()
+
+	a.put(0, 1, width, fmt.Sprintf("%-24s %-5s %-22s %-20s %s", "QUEUE INSTANCE", "STATE", "SLOTS", "LOAD", "MEMORY"), styleHeader)
+
+	for k := 0; k < rows; k++ {
+		y := 2 + k
+
+		//The last row becomes a count of what didn't fit
+		if k == rows-1 && len(hosts) > rows {
+			a.put(0, y, width, fmt.Sprintf("... %d more queue instances", len(hosts)-rows+1), styleDim)
+			break
+		}
+
+		a.drawHost(y, hosts[k])
+	}
+}
+
+func (a *App) drawHost(y int, h gogridengine.Host) {
+	width, _ := a.screen.Size()
+
+	style := styleDefault
+	if !gogridengine.IsHostAvailable(h) {
+		style = styleDim
+	}
+
+	x := a.put(0, y, width, fmt.Sprintf("%-24s %-5s ", truncate(h.Name, 24), h.State), style)
+
+	var slots float64
+	if h.SlotsTotal > 0 {
+		slots = float64(h.SlotsUsed+h.SlotsReserved) / float64(h.SlotsTotal)
+	}
+	x = a.bar(x, y, 10, slots)
+	x = a.put(x, y, width, fmt.Sprintf(" %-10s ", fmt.Sprintf("%d/%d", h.SlotsUsed, h.SlotsTotal)), style)
+
+	load, err := h.Resources.NPLoadAverage()
+	if err != nil {
+		load = 0
+	}
+	x = a.bar(x, y, 10, load)
+	x = a.put(x, y, width, fmt.Sprintf(" %-8s ", strconv.FormatFloat(load, 'f', 2, 64)), style)
+
+	total, totalErr := h.Resources.TotalMemory()
+	used, usedErr := h.Resources.MemoryUsed()
+	if totalErr != nil || usedErr != nil || total.Bytes == 0 {
+		a.put(x, y, width, "-", styleDim)
+		return
+	}
+
+	x = a.bar(x, y, 10, float64(used.Bytes)/float64(total.Bytes))
+	a.put(x, y, width, fmt.Sprintf(" %s/%s", formatBytes(used.Bytes), formatBytes(total.Bytes)), style)
+}
+
+//bar draws a meter of the fraction, coloured by how full it is, returning the column after it
+func (a *App) bar(x int, y int, size int, fraction float64) int {
+	filled := int(fraction*float64(size) + 0.5)
+	if filled > size {
+		filled = size
+	}
+	if filled < 0 {
+		filled = 0
+	}
+
+	style := styleLow
+	switch {
+	case fraction >= 0.9:
+		style = styleHigh
+	case fraction >= 0.6:
+		style = styleMedium
+	}
+
+	width, _ := a.screen.Size()
+
+	x = a.put(x, y, width, "[", styleDefault)
+	x = a.put(x, y, width, strings.Repeat("|", filled), style)
+	x = a.put(x, y, width, strings.Repeat(" ", size-filled), styleDefault)
+
+	return a.put(x, y, width, "]", styleDefault)
+}
+
+//widths of the job table columns, the flexible column taking the remaining space
+func columnWidths(width int) []int {
+	widths := make([]int, len(columns))
+	fixed := 0
+
+	for k, c := range columns {
+		widths[k] = c.width
+		fixed += c.width + 1
+	}
+
+	for k, c := range columns {
+		if c.width == 0 {
+			widths[k] = width - fixed
+			if widths[k] < 8 {
+				widths[k] = 8
+			}
+		}
+	}
+
+	return widths
+}
+
+func (a *App) drawJobs() {
+	width, _ := a.screen.Size()
+	top := a.tableTop()
+	widths := columnWidths(width)
+
+	x := 0
+	for k, c := range columns {
+		title := c.title
+		if k == a.sortColumn {
+			if a.descending {
+				title += "▼"
+			} else {
+				title += "▲"
+			}
+		}
+
+		a.put(x, top, width, pad(title, widths[k]), styleHeader)
+		x += widths[k] + 1
+	}
+
+	rows := a.tableHeight()
+
+	//Keep the selection in view
+	if a.selected < a.offset {
+		a.offset = a.selected
+	}
+	if rows > 0 && a.selected >= a.offset+rows {
+		a.offset = a.selected - rows + 1
+	}
+	if a.offset < 0 {
+		a.offset = 0
+	}
+
+	if len(a.jobs) == 0 {
+		message := "No jobs"
+		if a.filter != "" || a.mode == modeFilter {
+			message = "No jobs match the filter"
+		}
+
+		a.put(0, top+1, width, message, styleDim)
+		return
+	}
+
+	for row := 0; row < rows && a.offset+row < len(a.jobs); row++ {
+		k := a.offset + row
+		j := a.jobs[k]
+
+		style := styleDefault
+		switch gogridengine.JobPhase(j) {
+		case gogridengine.PhaseError:
+			style = styleError
+		case gogridengine.PhasePending:
+			style = styleDim
+		}
+
+		if k == a.selected {
+			style = styleSelected
+			a.fill(top+1+row, width, style)
+		}
+
+		x := 0
+		for c, col := range columns {
+			a.put(x, top+1+row, width, pad(truncate(col.value(j), widths[c]), widths[c]), style)
+			x += widths[c] + 1
+		}
+	}
+}
+
+func (a *App) drawDetail() {
+	width, height := a.screen.Size()
+	y := a.tableTop()
+
+	var job *gogridengine.Job
+	for _, j := range a.snapshot.Jobs() {
+		if gogridengine.KeyForJob(j) == a.detailKey {
+			j := j
+			job = &j
+			break
+		}
+	}
+
+	if job == nil {
+		a.put(0, y, width, "Job "+a.detailKey.String()+" is no longer reported by qstat", styleDim)
+		return
+	}
+
+	lines := [][2]string{
+		{"Job", a.detailKey.String()},
+		{"Name", job.JobName},
+		{"Owner", job.JobOwner},
+		{"State", job.State + " (" + gogridengine.JobPhase(*job) + ")"},
+		{"Priority", strconv.FormatFloat(job.JATPriority, 'f', 5, 64)},
+		{"Submitted", job.SubmittedTime},
+		{"Started", job.StartTime},
+		{"Queue", job.QueueName},
+		{"Slots", strconv.Itoa(int(job.Slots))},
+		{"Tasks", job.Tasks.Source},
+	}
+
+	var requests []string
+	for _, r := range job.HardRequests {
+		requests = append(requests, r.Name+"="+r.Value)
+	}
+	lines = append(lines, [2]string{"Requests", strings.Join(requests, " ")})
+
+	for _, h := range a.snapshot.QueueInfo.Queues {
+		if h.Name == job.QueueName && job.QueueName != "" {
+			load, _ := h.Resources.NPLoadAverage()
+			lines = append(lines, [2]string{"Host", fmt.Sprintf("%s  state %q  slots %d/%d  np_load %.2f", h.Name, h.State, h.SlotsUsed, h.SlotsTotal, load)})
+		}
+	}
+
+	a.put(0, y, width, "Job details", styleHeader)
+
+	for k, line := range lines {
+		if y+1+k >= height-1 {
+			break
+		}
+
+		x := a.put(0, y+1+k, width, fmt.Sprintf("%-10s ", line[0]), styleDim)
+		a.put(x, y+1+k, width, line[1], styleDefault)
+	}
+}
+
+var help = [][2]string{
+	{"Up/Down j/k", "Move the selection"},
+	{"PgUp/PgDn", "Move a page"},
+	{"Home/End g/G", "First or last job"},
+	{"Enter", "Job details (Esc to return)"},
+	{"s / S", "Sort by the next column / reverse the order"},
+	{"/", "Filter jobs: words or key=value terms (eg: owner=user phase=pending)"},
+	{"h / u / d", "Hold, release or delete the selected job"},
+	{"r", "Refresh now"},
+	{"q", "Quit"},
+}
+
+func (a *App) drawHelp() {
+	width, _ := a.screen.Size()
+	y := a.tableTop()
+
+	a.put(0, y, width, "Keys", styleHeader)
+
+	for k, line := range help {
+		x := a.put(0, y+1+k, width, fmt.Sprintf("%-14s ", line[0]), styleDim)
+		a.put(x, y+1+k, width, line[1], styleDefault)
+	}
+}
+
+func (a *App) drawStatus() {
+	width, height := a.screen.Size()
+	y := height - 1
+
+	switch {
+	case a.mode == modeFilter:
+		x := a.put(0, y, width, "/"+a.input+"_", styleDefault)
+		if a.statusErr && a.status != "" {
+			a.put(x+2, y, width, a.status, styleError)
+		}
+	case a.mode == modeConfirm && a.pending != nil:
+		a.put(0, y, width, fmt.Sprintf("%s job %s (%s)? [y/N]", a.pending.verb, a.pending.key, a.pending.name), styleTitle)
+	case a.status != "":
+		style := styleDefault
+		if a.statusErr {
+			style = styleError
+		}
+		a.put(0, y, width, a.status, style)
+	default:
+		hint := "? help  / filter  s sort  enter details  h hold  u release  d delete  q quit"
+		if a.filter != "" {
+			hint = "filter: " + a.filter + "  " + hint
+		}
+		a.put(0, y, width, hint, styleDim)
+	}
+}
+
+//put writes text from x up to the limit column, returning the column after it
+func (a *App) put(x int, y int, limit int, text string, style tcell.Style) int {
+	for _, r := range text {
+		if x >= limit {
+			break
+		}
+
+		a.screen.SetContent(x, y, r, nil, style)
+		x++
+	}
+
+	return x
+}
+
+func (a *App) fill(y int, width int, style tcell.Style) {
+	for x := 0; x < width; x++ {
+		a.screen.SetContent(x, y, ' ', nil, style)
+	}
+}
+
+func pad(s string, width int) string {
+	if n := len([]rune(s)); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+
+	return s
+}
+
+func truncate(s string, width int) string {
+	runes := []rune(s)
+	if len(runes) <= width {
+		return s
+	}
+
+	if width <= 1 {
+		return string(runes[:width])
+	}
+
+	return string(runes[:width-1]) + "…"
+}
+
+func formatBytes(bytes int64) string {
+	const megabyte = 1024 * 1024
+	const gigabyte = 1024 * megabyte
+
+	if bytes >= gigabyte {
+		return strconv.FormatFloat(float64(bytes)/gigabyte, 'f', 1, 64) + "G"
+	}
+
+	return strconv.FormatFloat(float64(bytes)/megabyte, 'f', 0, 64) + "M"
+}
@@ -0,0 +1,486 @@
+//Package tui is a top-like terminal interface to a grid engine cell: a panel of queue instances with slot, load and memory bars above a sortable and filterable job table.
+//Jobs can be held, released or deleted from it after confirmation, and inspected in a detail view.
+//
+//It draws onto any tcell.Screen, so it can be driven through a tcell.SimulationScreen in tests.
+package tui
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/metrumresearchgroup/gogridengine"
+	"github.com/metrumresearchgroup/gogridengine/filters"
+)
+
+//Options tune the interface
+type Options struct {
+	//Interval is the delay between qstat polls. Defaults to 10 seconds
+	Interval time.Duration
+	//Timeout bounds a single qstat poll. Zero disables it
+	Timeout time.Duration
+	//Filter is applied to the job table from the start, using the syntax of the / prompt
+	Filter string
+}
+
+type mode int
+
+const (
+	modeNormal mode = iota
+	modeFilter
+	modeConfirm
+	modeDetail
+	modeHelp
+)
+
+//action is a job action awaiting confirmation
+type action struct {
+	verb string
+	key  gogridengine.JobKey
+	name string
+	run  func(ctx context.Context, ids []string) (string, error)
+}
+
+//actionResult is posted back to the event loop once an action has run, along with a fresh snapshot of the cell
+type actionResult struct {
+	output   string
+	err      error
+	snapshot *gogridengine.JobInfo
+}
+
+//App is the state of the interface. It is only touched from the goroutine running Run
+type App struct {
+	screen tcell.Screen
+	client *gogridengine.Client
+	opts   Options
+	ctx    context.Context
+
+	snapshot gogridengine.JobInfo
+	summary  gogridengine.ClusterSummary
+	updated  time.Time
+	changes  int
+	pollErr  error
+
+	//jobs are the rows of the job table, filtered and sorted
+	jobs       gogridengine.JobList
+	selected   int
+	offset     int
+	sortColumn int
+	descending bool
+
+	filter     string
+	filterFunc func(j gogridengine.Job) bool
+	input      string
+
+	mode      mode
+	pending   *action
+	detailKey gogridengine.JobKey
+	status    string
+	statusErr bool
+}
+
+//New creates the interface, drawing onto an initialised screen which the caller remains responsible for finalising
+func New(screen tcell.Screen, client *gogridengine.Client, opts Options) *App {
+	if opts.Interval <= 0 {
+		opts.Interval = 10 * time.Second
+	}
+
+	a := &App{
+		screen:     screen,
+		client:     client,
+		opts:       opts,
+		filterFunc: func(j gogridengine.Job) bool { return true },
+	}
+
+	if opts.Filter != "" {
+		if f, err := compileFilter(opts.Filter); err == nil {
+			a.filter = opts.Filter
+			a.filterFunc = f
+		} else {
+			a.setError(err.Error())
+		}
+	}
+
+	return a
+}
+
+//Run polls the cell and handles input until the user quits or the context is cancelled
+func (a *App) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	a.ctx = ctx
+
+	w := gogridengine.Watcher{
+		Source:   a.client.DataSource(),
+		Interval: a.opts.Interval,
+		Timeout:  a.opts.Timeout,
+	}
+
+	go func() {
+		for update := range w.Watch(ctx) {
+			a.screen.PostEvent(tcell.NewEventInterrupt(update))
+		}
+	}()
+
+	//PollEvent has to be woken for cancellation to be noticed
+	go func() {
+		<-ctx.Done()
+		a.screen.PostEvent(tcell.NewEventInterrupt(ctx))
+	}()
+
+	a.draw()
+
+	for {
+		switch ev := a.screen.PollEvent().(type) {
+		case nil:
+			//The screen was finalised
+			return nil
+		case *tcell.EventResize:
+			a.screen.Sync()
+		case *tcell.EventKey:
+			if quit := a.handleKey(ev); quit {
+				return nil
+			}
+		case *tcell.EventInterrupt:
+			switch data := ev.Data().(type) {
+			case gogridengine.WatchUpdate:
+				a.applyUpdate(data)
+			case actionResult:
+				a.applyResult(data)
+			case context.Context:
+				return nil
+			}
+		}
+
+		a.draw()
+	}
+}
+
+func (a *App) applyUpdate(update gogridengine.WatchUpdate) {
+	if update.Err != nil {
+		a.pollErr = update.Err
+		return
+	}
+
+	a.pollErr = nil
+	a.changes = len(update.Events)
+	a.setSnapshot(update.Snapshot, update.Time)
+}
+
+func (a *App) applyResult(result actionResult) {
+	if result.err != nil {
+		a.setError(firstLine(result.err.Error()))
+	} else {
+		a.setStatus(firstLine(result.output))
+	}
+
+	if result.snapshot != nil {
+		a.changes = 0
+		a.setSnapshot(*result.snapshot, time.Now())
+	}
+}
+
+func (a *App) setSnapshot(ji gogridengine.JobInfo, at time.Time) {
+	a.snapshot = ji
+	a.summary = gogridengine.NewClusterSummary(ji)
+	a.updated = at
+	a.refreshJobs()
+}
+
+//refreshJobs rebuilds the job table, keeping the selected job selected when it is still listed
+func (a *App) refreshJobs() {
+	var current *gogridengine.JobKey
+	if a.selected < len(a.jobs) {
+		key := gogridengine.KeyForJob(a.jobs[a.selected])
+		current = &key
+	}
+
+	direction := gogridengine.Ascending
+	if a.descending {
+		direction = gogridengine.Descending
+	}
+
+	a.jobs = a.snapshot.Jobs().Filter(a.filterFunc).SortBy(append(columns[a.sortColumn].sorters(direction), gogridengine.ByJobNumber(gogridengine.Ascending), gogridengine.ByTaskID(gogridengine.Ascending))...)
+
+	if current != nil {
+		for k, j := range a.jobs {
+			if gogridengine.KeyForJob(j) == *current {
+				a.selected = k
+				return
+			}
+		}
+	}
+
+	a.clampSelection()
+}
+
+func (a *App) clampSelection() {
+	if a.selected >= len(a.jobs) {
+		a.selected = len(a.jobs) - 1
+	}
+
+	if a.selected < 0 {
+		a.selected = 0
+	}
+}
+
+func (a *App) selectedJob() (gogridengine.Job, bool) {
+	if a.selected < len(a.jobs) {
+		return a.jobs[a.selected], true
+	}
+
+	return gogridengine.Job{}, false
+}
+
+//handleKey applies a key press, returning whether the user asked to quit
+func (a *App) handleKey(ev *tcell.EventKey) bool {
+	if ev.Key() == tcell.KeyCtrlC {
+		return true
+	}
+
+	switch a.mode {
+	case modeFilter:
+		a.handleFilterKey(ev)
+	case modeConfirm:
+		a.handleConfirmKey(ev)
+	case modeHelp:
+		a.mode = modeNormal
+	case modeDetail:
+		if ev.Key() == tcell.KeyEscape || ev.Key() == tcell.KeyEnter || (ev.Key() == tcell.KeyRune && ev.Rune() == 'q') {
+			a.mode = modeNormal
+			return false
+		}
+
+		return a.handleNormalKey(ev)
+	default:
+		return a.handleNormalKey(ev)
+	}
+
+	return false
+}
+
+func (a *App) handleNormalKey(ev *tcell.EventKey) bool {
+	page := a.tableHeight() - 1
+	if page < 1 {
+		page = 1
+	}
+
+	switch ev.Key() {
+	case tcell.KeyUp:
+		a.selected--
+	case tcell.KeyDown:
+		a.selected++
+	case tcell.KeyPgUp:
+		a.selected -= page
+	case tcell.KeyPgDn:
+		a.selected += page
+	case tcell.KeyHome:
+		a.selected = 0
+	case tcell.KeyEnd:
+		a.selected = len(a.jobs) - 1
+	case tcell.KeyEnter:
+		if j, ok := a.selectedJob(); ok {
+			a.detailKey = gogridengine.KeyForJob(j)
+			a.mode = modeDetail
+		}
+	case tcell.KeyEscape:
+		a.status = ""
+	case tcell.KeyRune:
+		switch ev.Rune() {
+		case 'q':
+			return true
+		case 'k':
+			a.selected--
+		case 'j':
+			a.selected++
+		case 'g':
+			a.selected = 0
+		case 'G':
+			a.selected = len(a.jobs) - 1
+		case 's':
+			a.sortColumn = (a.sortColumn + 1) % len(columns)
+			a.refreshJobs()
+		case 'S':
+			a.descending = !a.descending
+			a.refreshJobs()
+		case '/':
+			a.input = a.filter
+			a.mode = modeFilter
+		case 'h':
+			a.confirm("Hold", a.client.Hold)
+		case 'u':
+			a.confirm("Release", a.client.Release)
+		case 'd':
+			a.confirm("Delete", a.client.Delete)
+		case 'r':
+			a.refresh()
+		case '?':
+			a.mode = modeHelp
+		}
+	}
+
+	a.clampSelection()
+
+	return false
+}
+
+func (a *App) handleFilterKey(ev *tcell.EventKey) {
+	switch ev.Key() {
+	case tcell.KeyEnter:
+		if err := a.applyFilter(a.input); err != nil {
+			a.setError(err.Error())
+			return
+		}
+
+		a.status = ""
+		a.mode = modeNormal
+	case tcell.KeyEscape:
+		//Restores the filter in place before the prompt was opened
+		a.applyFilter(a.filter)
+		a.status = ""
+		a.mode = modeNormal
+	case tcell.KeyBackspace, tcell.KeyBackspace2:
+		if len(a.input) > 0 {
+			runes := []rune(a.input)
+			a.input = string(runes[:len(runes)-1])
+		}
+		a.status = ""
+		a.previewFilter()
+	case tcell.KeyRune:
+		a.input += string(ev.Rune())
+		a.status = ""
+		a.previewFilter()
+	}
+}
+
+//previewFilter applies the filter being typed whenever it is valid, so the table follows along
+func (a *App) previewFilter() {
+	f, err := compileFilter(a.input)
+	if err != nil {
+		return
+	}
+
+	a.filterFunc = f
+	a.refreshJobs()
+}
+
+func (a *App) applyFilter(text string) error {
+	f, err := compileFilter(text)
+	if err != nil {
+		return err
+	}
+
+	a.filter = text
+	a.filterFunc = f
+	a.refreshJobs()
+
+	return nil
+}
+
+func (a *App) confirm(verb string, run func(ctx context.Context, ids []string) (string, error)) {
+	j, ok := a.selectedJob()
+	if !ok {
+		return
+	}
+
+	a.pending = &action{
+		verb: verb,
+		key:  gogridengine.KeyForJob(j),
+		name: j.JobName,
+		run:  run,
+	}
+	a.mode = modeConfirm
+}
+
+func (a *App) handleConfirmKey(ev *tcell.EventKey) {
+	pending := a.pending
+	a.pending = nil
+	a.mode = modeNormal
+
+	if ev.Key() != tcell.KeyRune || (ev.Rune() != 'y' && ev.Rune() != 'Y') {
+		a.setStatus(pending.verb + " cancelled")
+		return
+	}
+
+	a.setStatus(pending.verb + " job " + pending.key.String() + "...")
+
+	go func() {
+		output, err := pending.run(a.ctx, []string{pending.key.String()})
+		a.screen.PostEvent(tcell.NewEventInterrupt(a.fetch(actionResult{output: output, err: err})))
+	}()
+}
+
+//refresh reads the cell straight away rather than waiting for the next poll
+func (a *App) refresh() {
+	a.setStatus("Refreshing...")
+
+	go func() {
+		a.screen.PostEvent(tcell.NewEventInterrupt(a.fetch(actionResult{output: "Refreshed"})))
+	}()
+}
+
+func (a *App) fetch(result actionResult) actionResult {
+	ji, err := a.client.JobInfo()
+
+	if err != nil && result.err == nil {
+		result.err = err
+	}
+
+	if err == nil {
+		result.snapshot = &ji
+	}
+
+	return result
+}
+
+func (a *App) setStatus(status string) {
+	a.status = status
+	a.statusErr = false
+}
+
+func (a *App) setError(status string) {
+	a.status = status
+	a.statusErr = true
+}
+
+//compileFilter builds a job filter from space separated terms. Terms of the form key=value use the filters.JobQuery vocabulary (eg: owner=user phase=pending), any other term must appear in the job's ID, name, owner, state or queue
+func compileFilter(text string) (func(j gogridengine.Job) bool, error) {
+	values := url.Values{}
+	var words []string
+
+	for _, term := range strings.Fields(text) {
+		if pieces := strings.SplitN(term, "=", 2); len(pieces) == 2 {
+			values.Add(pieces[0], pieces[1])
+			continue
+		}
+
+		words = append(words, strings.ToLower(term))
+	}
+
+	query, err := filters.ParseJobQuery(values)
+	if err != nil {
+		return nil, err
+	}
+
+	return func(j gogridengine.Job) bool {
+		if !query.Matches(j) {
+			return false
+		}
+
+		haystack := strings.ToLower(strings.Join([]string{gogridengine.KeyForJob(j).String(), j.JobName, j.JobOwner, j.State, j.QueueName}, " "))
+
+		for _, w := range words {
+			if !strings.Contains(haystack, w) {
+				return false
+			}
+		}
+
+		return true
+	}, nil
+}
+
+func firstLine(s string) string {
+	return strings.TrimSpace(strings.SplitN(strings.TrimSpace(s), "\n", 2)[0])
+}
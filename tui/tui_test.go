@@ -0,0 +1,334 @@
+package tui
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/metrumresearchgroup/gogridengine"
+	"github.com/metrumresearchgroup/gogridengine/simulator"
+	"github.com/stretchr/testify/assert"
+)
+
+//lockedScreen guards the cells of the simulation screen, which GetContents shares with Show rather than copying
+type lockedScreen struct {
+	tcell.SimulationScreen
+	mu *sync.Mutex
+}
+
+func (s lockedScreen) Show() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.SimulationScreen.Show()
+}
+
+func (s lockedScreen) Sync() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.SimulationScreen.Sync()
+}
+
+//terminal is the interface running on a virtual terminal against a simulated cluster
+type terminal struct {
+	t       *testing.T
+	screen  lockedScreen
+	cluster *simulator.Cluster
+	done    chan error
+}
+
+const gigabyte = 1024 * 1024 * 1024
+
+func newTerminal(t *testing.T, opts Options) *terminal {
+	cluster := simulator.New(simulator.Options{
+		Hosts: []simulator.HostSpec{
+			{Name: "node1", Slots: 4, Memory: 16 * gigabyte},
+			{Name: "node2", Slots: 4, Memory: 16 * gigabyte, State: "d"},
+		},
+		Start: time.Date(2019, 12, 18, 14, 0, 0, 0, time.UTC),
+		User:  "darrellb",
+	})
+
+	for _, spec := range []simulator.JobSpec{
+		{Name: "Run1", Owner: "darrellb", Slots: 2, Priority: 100},
+		{Name: "Array", Owner: "devinp", Slots: 2, FirstTask: 1, LastTask: 3, TaskStep: 1},
+		{Name: "Held", Owner: "devinp", Hold: true},
+	} {
+		_, err := cluster.Submit(spec)
+		assert.Nil(t, err)
+	}
+
+	screen := lockedScreen{SimulationScreen: tcell.NewSimulationScreen("UTF-8"), mu: &sync.Mutex{}}
+	assert.Nil(t, screen.Init())
+	screen.SetSize(120, 24)
+
+	//Only the first poll happens during a test, actions refresh the view themselves
+	opts.Interval = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	term := &terminal{
+		t:       t,
+		screen:  screen,
+		cluster: cluster,
+		done:    make(chan error, 1),
+	}
+
+	app := New(screen, gogridengine.NewClient(cluster), opts)
+	go func() {
+		term.done <- app.Run(ctx)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-term.done
+		screen.Fini()
+	})
+
+	return term
+}
+
+//lines returns the text on the virtual terminal, with trailing spaces removed
+func (term *terminal) lines() []string {
+	term.screen.mu.Lock()
+	defer term.screen.mu.Unlock()
+
+	cells, width, height := term.screen.GetContents()
+	lines := make([]string, height)
+
+	for y := 0; y < height; y++ {
+		var b strings.Builder
+		for x := 0; x < width; x++ {
+			runes := cells[y*width+x].Runes
+			if len(runes) == 0 {
+				b.WriteRune(' ')
+				continue
+			}
+			b.WriteRune(runes[0])
+		}
+		lines[y] = strings.TrimRight(b.String(), " ")
+	}
+
+	return lines
+}
+
+func (term *terminal) text() string {
+	return strings.Join(term.lines(), "\n")
+}
+
+//waitFor waits until the condition holds for the text on the terminal
+func (term *terminal) waitFor(description string, condition func(text string) bool) {
+	term.t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+
+	for !condition(term.text()) {
+		if time.Now().After(deadline) {
+			term.t.Fatalf("timed out waiting for %s, the terminal shows:\n%s", description, term.text())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func (term *terminal) waitForText(text string) {
+	term.t.Helper()
+	term.waitFor(text, func(screen string) bool { return strings.Contains(screen, text) })
+}
+
+func (term *terminal) waitForNoText(text string) {
+	term.t.Helper()
+	term.waitFor("no "+text, func(screen string) bool { return !strings.Contains(screen, text) })
+}
+
+//keys types runes (given as strings) and keys. Unlike InjectKey, waiting for room in the event queue means no key is dropped
+func (term *terminal) keys(keys ...interface{}) {
+	for _, k := range keys {
+		switch key := k.(type) {
+		case string:
+			for _, r := range key {
+				term.screen.PostEventWait(tcell.NewEventKey(tcell.KeyRune, r, tcell.ModNone))
+			}
+		case tcell.Key:
+			term.screen.PostEventWait(tcell.NewEventKey(key, 0, tcell.ModNone))
+		}
+	}
+}
+
+//jobColumn returns the JOB column of the job table rows, in order
+func (term *terminal) jobColumn() []string {
+	var jobs []string
+	inTable := false
+
+	for _, line := range term.lines() {
+		if strings.HasPrefix(line, "JOB") {
+			inTable = true
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if inTable && len(fields) > 0 && len(fields[0]) > 0 && fields[0][0] >= '0' && fields[0][0] <= '9' {
+			jobs = append(jobs, fields[0])
+		}
+	}
+
+	return jobs
+}
+
+func TestRendersHostsAndJobs(t *testing.T) {
+	term := newTerminal(t, Options{})
+	term.waitForText("Run1")
+
+	lines := term.lines()
+	assert.Contains(t, lines[0], "gge top  hosts 2  slots 4/8 (0 free)  running 2  pending 3")
+	assert.Contains(t, lines[0], "9 changes  updated")
+	assert.Equal(t, "all.q@node1                    [||||||||||] 4/4        [||||||||||] 1.00     [          ] 0M/16.0G", lines[2])
+	assert.Equal(t, "all.q@node2              d     [          ] 0/4        [          ] 0.00     [          ] 0M/16.0G", lines[3])
+
+	assert.Equal(t, []string{"1", "2.1", "2.2", "2.3", "3"}, term.jobColumn())
+	assert.Contains(t, term.text(), "2.2        0.49976 Array")
+	assert.Contains(t, lines[23], "? help")
+}
+
+func TestSortAndFilter(t *testing.T) {
+	term := newTerminal(t, Options{})
+	term.waitForText("Run1")
+
+	//PRIOR, then reversed so the prioritised job leads
+	term.keys("s", "S")
+	term.waitForText("PRIOR▼")
+	assert.Equal(t, "1", term.jobColumn()[0])
+
+	//NAME
+	term.keys("s")
+	term.waitForText("NAME▼")
+	assert.Equal(t, []string{"1", "3", "2.1", "2.2", "2.3"}, term.jobColumn())
+
+	term.keys("/", "owner=devinp qw")
+	term.waitForText("/owner=devinp qw_")
+	term.waitFor("the filter applied", func(string) bool { return len(term.jobColumn()) == 3 })
+	term.keys(tcell.KeyEnter)
+	term.waitForText("filter: owner=devinp qw")
+	assert.Equal(t, []string{"3", "2.2", "2.3"}, term.jobColumn())
+
+	//Invalid filters are reported and leave the prompt open
+	term.keys("/", tcell.KeyBackspace2, tcell.KeyBackspace2, tcell.KeyBackspace2, " phase=asleep", tcell.KeyEnter)
+	term.waitForText("invalid phase")
+	term.keys(tcell.KeyEscape)
+	term.waitForText("filter: owner=devinp qw")
+
+	term.keys("/", "nothing", tcell.KeyEnter)
+	term.waitForText("No jobs match the filter")
+}
+
+func TestInitialFilter(t *testing.T) {
+	term := newTerminal(t, Options{Filter: "phase=running"})
+	term.waitForText("Run1")
+
+	assert.Equal(t, []string{"1", "2.1"}, term.jobColumn())
+}
+
+func TestActionsNeedConfirmation(t *testing.T) {
+	term := newTerminal(t, Options{})
+	term.waitForText("Run1")
+
+	//Job 3 is the last row
+	term.keys(tcell.KeyEnd, "u")
+	term.waitForText("Release job 3 (Held)? [y/N]")
+	term.keys("n")
+	term.waitForText("Release cancelled")
+
+	ji, err := term.cluster.Snapshot()
+	assert.Nil(t, err)
+	assert.Equal(t, "hqw", ji.Jobs()[4].State)
+
+	term.keys("u")
+	term.waitForText("Release job 3 (Held)? [y/N]")
+	term.keys("y")
+	term.waitForText("modified hold of job 3")
+
+	ji, err = term.cluster.Snapshot()
+	assert.Nil(t, err)
+	assert.Equal(t, "qw", ji.Jobs()[4].State)
+
+	term.keys(tcell.KeyHome, "d")
+	term.waitForText("Delete job 1 (Run1)? [y/N]")
+	term.keys("y")
+	term.waitForText("darrellb has registered the job 1 for deletion")
+	term.waitForNoText("Run1")
+	assert.NotContains(t, term.cluster.Jobs(), int64(1))
+
+	//Array tasks are acted upon alone
+	term.keys(tcell.KeyHome, tcell.KeyDown, "h", "y")
+	term.waitForText("modified hold of job-array task 2.2")
+
+	//Failures are reported on the status line. The job is deleted behind the view's back, which only refreshes after actions
+	_, err = term.cluster.Delete(2)
+	assert.Nil(t, err)
+
+	term.keys(tcell.KeyHome, "h", "y")
+	term.waitForText(`denied: job "2.1" does not exist`)
+}
+
+func TestDetailAndHelp(t *testing.T) {
+	term := newTerminal(t, Options{})
+	term.waitForText("Run1")
+
+	term.keys(tcell.KeyDown, tcell.KeyEnter)
+	term.waitForText("Job details")
+	assert.Contains(t, term.text(), "Job        2.1")
+	assert.Contains(t, term.text(), "Owner      devinp")
+	assert.Contains(t, term.text(), "State      r (running)")
+	assert.Contains(t, term.text(), `Host       all.q@node1  state ""  slots 4/4  np_load 1.00`)
+
+	term.keys(tcell.KeyEscape)
+	term.waitForNoText("Job details")
+
+	term.keys("?")
+	term.waitForText("Hold, release or delete the selected job")
+	term.keys("x")
+	term.waitForNoText("Hold, release or delete the selected job")
+}
+
+func TestQuit(t *testing.T) {
+	term := newTerminal(t, Options{})
+	term.waitForText("Run1")
+
+	term.keys("q")
+
+	select {
+	case err := <-term.done:
+		assert.Nil(t, err)
+		//Let the cleanup find it
+		term.done <- err
+	case <-time.After(2 * time.Second):
+		t.Fatal("the interface didn't quit")
+	}
+}
+
+func TestCompileFilter(t *testing.T) {
+	jobs := gogridengine.JobList{
+		{JBJobNumber: 1, JobName: "Run1", JobOwner: "darrellb", State: "r", QueueName: "all.q@node1"},
+		{JBJobNumber: 2, JobName: "Model", JobOwner: "devinp", State: "qw"},
+	}
+
+	tests := []struct {
+		filter string
+		want   int
+	}{
+		{filter: "", want: 2},
+		{filter: "RUN", want: 1},
+		{filter: "node1 darrellb", want: 1},
+		{filter: "owner=devinp", want: 1},
+		{filter: "owner=devinp run", want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			f, err := compileFilter(tt.filter)
+			assert.Nil(t, err)
+			assert.Len(t, jobs.Filter(f), tt.want)
+		})
+	}
+
+	_, err := compileFilter("sort=colour")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,30 @@
+package gogridengine
+
+import (
+	"encoding/xml"
+)
+
+//UnknownElement is an element of the qstat output the library doesn't model (arch, queue_name, granted_pe...), kept as is so it survives re-serialisation
+type UnknownElement struct {
+	XMLName xml.Name
+	Attrs   []UnknownAttr `xml:",any,attr"`
+	Content string        `xml:",innerxml"`
+}
+
+//UnknownAttr is an attribute of the qstat output the library doesn't model, kept so it survives re-serialisation
+type UnknownAttr xml.Attr
+
+//UnmarshalXMLAttr records the attribute
+func (a *UnknownAttr) UnmarshalXMLAttr(attr xml.Attr) error {
+	*a = UnknownAttr(attr)
+	return nil
+}
+
+//MarshalXMLAttr writes the attribute back. Namespace declarations (xmlns:xsd) are written by their prefixed name, which encoding/xml would otherwise mangle into a namespace of their own
+func (a UnknownAttr) MarshalXMLAttr(name xml.Name) (xml.Attr, error) {
+	if a.Name.Space == "xmlns" {
+		return xml.Attr{Name: xml.Name{Local: "xmlns:" + a.Name.Local}, Value: a.Value}, nil
+	}
+
+	return xml.Attr(a), nil
+}
@@ -0,0 +1,42 @@
+package gogridengine
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnknownContentRoundTrip(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "namespace declaration",
+			input: `<Queue-List xmlns:xsd="http://example.com/qstat.xsd"><name>all.q@node1</name></Queue-List>`,
+			want:  `<Queue-List xmlns:xsd="http://example.com/qstat.xsd"><name>all.q@node1</name>`,
+		},
+		{
+			name:  "attributes",
+			input: `<Queue-List zone="a" rack="12"><name>all.q@node1</name></Queue-List>`,
+			want:  `<Queue-List zone="a" rack="12"><name>all.q@node1</name>`,
+		},
+		{
+			name:  "nested elements",
+			input: `<Queue-List><name>all.q@node1</name><load_avg>0.5</load_avg><topology socket="1"><core id="0"/>text</topology></Queue-List>`,
+			want:  `<load_avg>0.5</load_avg><topology socket="1"><core id="0"/>text</topology>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var h Host
+			assert.Nil(t, xml.Unmarshal([]byte(tt.input), &h))
+
+			output, err := xml.Marshal(h)
+			assert.Nil(t, err)
+			assert.Contains(t, string(output), tt.want)
+		})
+	}
+}
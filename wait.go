@@ -0,0 +1,265 @@
+package gogridengine
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	//OutcomeFinished is the outcome of tasks that left the queue, whether they completed or were deleted
+	OutcomeFinished string = "finished"
+	//OutcomeError is the outcome of tasks that entered an error state while still queued
+	OutcomeError string = "error"
+)
+
+//WaitOptions tune how WaitForJobs polls for job completion
+type WaitOptions struct {
+	//Interval between polls. Defaults to 5 seconds
+	Interval time.Duration
+	//Source provides the qstat XML. A single query covers every job being waited on.
+	//Defaults to qstat, limited (qstat -u) to the Owners or, when there are none, to the owners of the jobs still being waited on once the first poll has found them
+	Source XmlResourceGetter
+	//Owners limits the default qstat query to the owners of the jobs waited on
+	Owners []string
+	//Accounting enriches finished tasks with their qacct record, including the exit status
+	Accounting bool
+	//Runner is used for qacct, and for qstat when there is no Source. Defaults to DefaultRunner
+	Runner CommandRunner
+}
+
+//TaskOutcome is the terminal outcome of a single job or array task
+type TaskOutcome struct {
+	Key     JobKey    `json:"key"`
+	Outcome string    `json:"outcome"`
+	Time    time.Time `json:"time"`
+	//LastSeen is the job as last reported by qstat, nil if it was never seen
+	LastSeen *Job `json:"last_seen,omitempty"`
+	//ExitStatus is only available when accounting was requested and qacct had a record for the task
+	ExitStatus *int              `json:"exit_status,omitempty"`
+	Accounting *AccountingRecord `json:"accounting,omitempty"`
+}
+
+//WaitForJobs blocks until every task of the provided jobs has left the queue or entered an error state, returning one outcome per task ordered by key.
+//Jobs that are never seen in qstat are considered to have already finished, with a single outcome for the whole job. When the context is cancelled, the outcomes gathered so far are returned alongside its error.
+func WaitForJobs(ctx context.Context, ids []int64, opts WaitOptions) ([]TaskOutcome, error) {
+	if opts.Interval <= 0 {
+		opts.Interval = 5 * time.Second
+	}
+
+	//The default query is narrowed to the owners of the jobs as they become known
+	var qstat *QstatDataSource
+
+	if opts.Source == nil {
+		qstat = &QstatDataSource{Filters: make(map[string]string), Runner: opts.Runner}
+		if len(opts.Owners) > 0 {
+			qstat.Filters["-u"] = strings.Join(opts.Owners, ",")
+		}
+		opts.Source = qstat
+	}
+
+	if opts.Runner == nil {
+		opts.Runner = DefaultRunner
+	}
+
+	wanted := make(map[int64]bool)
+	for _, id := range ids {
+		wanted[id] = true
+	}
+
+	//Every task seen so far that hasn't reached a terminal outcome
+	active := make(map[JobKey]Job)
+	//Jobs seen at least once
+	seen := make(map[int64]bool)
+	//Jobs missing from the first poll, by when they were found missing. Their tasks are tracked instead should they show up later
+	missing := make(map[int64]time.Time)
+	outcomes := make(map[JobKey]TaskOutcome)
+	first := true
+
+	for {
+		content, err := getContext(ctx, opts.Source)
+
+		if ctx.Err() != nil {
+			return mergedOutcomes(outcomes, missing), ctx.Err()
+		}
+
+		if err != nil {
+			log.Error("Polling qstat while waiting on jobs failed: ", err)
+		} else {
+			ji, err := NewJobInfo(content)
+
+			if err != nil {
+				return mergedOutcomes(outcomes, missing), err
+			}
+
+			current, _ := jobsByKey(ji)
+			now := time.Now()
+
+			for key, j := range current {
+				if !wanted[key.JobNumber] {
+					continue
+				}
+
+				if _, done := outcomes[key]; done {
+					continue
+				}
+
+				seen[key.JobNumber] = true
+				delete(missing, key.JobNumber)
+
+				if JobPhase(j) == PhaseError {
+					last := j
+					outcomes[key] = TaskOutcome{
+						Key:      key,
+						Outcome:  OutcomeError,
+						Time:     now,
+						LastSeen: &last,
+					}
+					delete(active, key)
+					continue
+				}
+
+				active[key] = j
+			}
+
+			for key, j := range active {
+				if _, ok := current[key]; ok {
+					continue
+				}
+
+				last := j
+				outcomes[key] = TaskOutcome{
+					Key:      key,
+					Outcome:  OutcomeFinished,
+					Time:     now,
+					LastSeen: &last,
+				}
+				delete(active, key)
+			}
+
+			if first {
+				for id := range wanted {
+					if !seen[id] {
+						missing[id] = now
+					}
+				}
+				first = false
+			}
+
+			if len(active) == 0 {
+				break
+			}
+
+			if qstat != nil && len(opts.Owners) == 0 {
+				qstat.Filters["-u"] = activeOwners(active)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return mergedOutcomes(outcomes, missing), ctx.Err()
+		case <-time.After(opts.Interval):
+		}
+	}
+
+	merged := make(map[JobKey]TaskOutcome)
+	for _, o := range mergedOutcomes(outcomes, missing) {
+		merged[o.Key] = o
+	}
+
+	if opts.Accounting {
+		enrichWithAccounting(ctx, opts.Runner, merged)
+	}
+
+	return sortedOutcomes(merged), nil
+}
+
+//mergedOutcomes adds a single outcome for every job which was never seen to the outcomes of the tasks seen
+func mergedOutcomes(outcomes map[JobKey]TaskOutcome, missing map[int64]time.Time) []TaskOutcome {
+	merged := make(map[JobKey]TaskOutcome)
+
+	for key, o := range outcomes {
+		merged[key] = o
+	}
+
+	for id, when := range missing {
+		key := JobKey{JobNumber: id}
+		merged[key] = TaskOutcome{
+			Key:     key,
+			Outcome: OutcomeFinished,
+			Time:    when,
+		}
+	}
+
+	return sortedOutcomes(merged)
+}
+
+//activeOwners lists the owners of the tasks still being waited on for qstat -u
+func activeOwners(active map[JobKey]Job) string {
+	unique := make(map[string]bool)
+	for _, j := range active {
+		unique[j.JobOwner] = true
+	}
+
+	owners := make([]string, 0, len(unique))
+	for o := range unique {
+		owners = append(owners, o)
+	}
+	sort.Strings(owners)
+
+	return strings.Join(owners, ",")
+}
+
+//enrichWithAccounting looks up finished tasks in qacct, one query per job number. Missing records are left alone since accounting may lag behind qstat.
+func enrichWithAccounting(ctx context.Context, runner CommandRunner, outcomes map[JobKey]TaskOutcome) {
+	records := make(map[int64][]AccountingRecord)
+
+	for key, outcome := range outcomes {
+		if outcome.Outcome != OutcomeFinished {
+			continue
+		}
+
+		jobRecords, ok := records[key.JobNumber]
+		if !ok {
+			var err error
+			jobRecords, err = GetAccounting(ctx, runner, key.JobNumber)
+
+			if err != nil {
+				log.Error("Unable to retrieve accounting for job ", key.JobNumber, ": ", err)
+			}
+
+			records[key.JobNumber] = jobRecords
+		}
+
+		for k, r := range jobRecords {
+			if r.TaskID != key.TaskID {
+				continue
+			}
+
+			exitStatus := r.ExitStatus
+			outcome.ExitStatus = &exitStatus
+			outcome.Accounting = &jobRecords[k]
+			outcomes[key] = outcome
+		}
+	}
+}
+
+func sortedOutcomes(outcomes map[JobKey]TaskOutcome) []TaskOutcome {
+	var sorted []TaskOutcome
+
+	for _, o := range outcomes {
+		sorted = append(sorted, o)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Key.JobNumber != sorted[j].Key.JobNumber {
+			return sorted[i].Key.JobNumber < sorted[j].Key.JobNumber
+		}
+		return sorted[i].Key.TaskID < sorted[j].Key.TaskID
+	})
+
+	return sorted
+}
@@ -0,0 +1,196 @@
+package gogridengine
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func waitSnapshot(t *testing.T, running []Job, pending []Job) pollResult {
+	ji := JobInfo{
+		QueueInfo: QueueInfo{
+			Queues: []Host{
+				{
+					Name:       "all.q@ip-10-0-1-80.ec2.internal",
+					SlotsTotal: 8,
+					JobList:    running,
+				},
+			},
+		},
+		PendingJobs: PendingJob{
+			JobList: pending,
+		},
+	}
+
+	content, err := ji.GetXML()
+	assert.Nil(t, err)
+
+	return pollResult{content: content}
+}
+
+func TestWaitForJobs(t *testing.T) {
+	task := func(number int64, id int64, state string) Job {
+		return Job{
+			JBJobNumber: number,
+			State:       state,
+			Slots:       1,
+			Tasks: Task{
+				Source: "",
+				TaskID: id,
+			},
+		}
+	}
+
+	source := &scriptedSource{
+		responses: []pollResult{
+			waitSnapshot(t,
+				[]Job{task(1006, 5, "r"), task(1006, 10, "r"), task(99, 1, "r")},
+				[]Job{{JBJobNumber: 1007, State: "qw", Slots: 1}},
+			),
+			{err: errors.New("qmaster unreachable")},
+			waitSnapshot(t,
+				[]Job{task(1006, 10, "r"), task(99, 1, "r")},
+				[]Job{{JBJobNumber: 1007, State: "Eqw", Slots: 1}},
+			),
+			waitSnapshot(t,
+				[]Job{task(99, 1, "r")},
+				nil,
+			),
+		},
+	}
+
+	runner := &fakeRunner{
+		responses: map[string]CommandResult{
+			"qacct -j 1006": {Stdout: []byte(accountingOutput)},
+		},
+	}
+
+	outcomes, err := WaitForJobs(context.Background(), []int64{1006, 1007, 4242}, WaitOptions{
+		Interval:   time.Millisecond,
+		Source:     source,
+		Accounting: true,
+		Runner:     runner,
+	})
+
+	assert.Nil(t, err)
+	assert.Len(t, outcomes, 4)
+
+	assert.Equal(t, JobKey{JobNumber: 1006, TaskID: 5}, outcomes[0].Key)
+	assert.Equal(t, OutcomeFinished, outcomes[0].Outcome)
+	assert.Equal(t, 0, *outcomes[0].ExitStatus)
+
+	assert.Equal(t, JobKey{JobNumber: 1006, TaskID: 10}, outcomes[1].Key)
+	assert.Equal(t, 137, *outcomes[1].ExitStatus)
+	assert.Equal(t, "ip-10-0-1-113.ec2.internal", outcomes[1].Accounting.Hostname)
+
+	assert.Equal(t, JobKey{JobNumber: 1007}, outcomes[2].Key)
+	assert.Equal(t, OutcomeError, outcomes[2].Outcome)
+	assert.Equal(t, "Eqw", outcomes[2].LastSeen.State)
+	assert.Nil(t, outcomes[2].ExitStatus)
+
+	//Never seen, so it has already left the queue. qacct has no record of it either
+	assert.Equal(t, JobKey{JobNumber: 4242}, outcomes[3].Key)
+	assert.Equal(t, OutcomeFinished, outcomes[3].Outcome)
+	assert.Nil(t, outcomes[3].LastSeen)
+	assert.Nil(t, outcomes[3].ExitStatus)
+
+	//One qacct query per job rather than per task
+	assert.ElementsMatch(t, []string{"qacct -j 1006", "qacct -j 4242"}, runner.calls)
+}
+
+func TestWaitForJobsCancelled(t *testing.T) {
+	source := &scriptedSource{
+		responses: []pollResult{
+			waitSnapshot(t, []Job{{JBJobNumber: 1, State: "r", Slots: 1}}, nil),
+		},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	outcomes, err := WaitForJobs(ctx, []int64{1}, WaitOptions{
+		Interval: time.Millisecond,
+		Source:   source,
+	})
+
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Empty(t, outcomes)
+}
+
+func TestWaitForJobsLateTasks(t *testing.T) {
+	task := func(number int64, id int64) Job {
+		return Job{
+			JBJobNumber: number,
+			State:       "r",
+			Slots:       1,
+			Tasks: Task{
+				TaskID: id,
+			},
+		}
+	}
+
+	//qstat lags behind qsub, so the array tasks of 5000 only show up on the second poll
+	source := &scriptedSource{
+		responses: []pollResult{
+			waitSnapshot(t, []Job{task(99, 1)}, nil),
+			waitSnapshot(t, []Job{task(99, 1), task(5000, 1), task(5000, 2)}, nil),
+			waitSnapshot(t, nil, nil),
+		},
+	}
+
+	outcomes, err := WaitForJobs(context.Background(), []int64{99, 5000}, WaitOptions{
+		Interval: time.Millisecond,
+		Source:   source,
+	})
+
+	assert.Nil(t, err)
+
+	var keys []JobKey
+	for _, o := range outcomes {
+		keys = append(keys, o.Key)
+		assert.Equal(t, OutcomeFinished, o.Outcome)
+		assert.NotNil(t, o.LastSeen)
+	}
+
+	//No outcome for the job as a whole alongside those of its tasks
+	assert.Equal(t, []JobKey{{JobNumber: 99, TaskID: 1}, {JobNumber: 5000, TaskID: 1}, {JobNumber: 5000, TaskID: 2}}, keys)
+}
+
+func TestWaitForJobsOwners(t *testing.T) {
+	owned := func(number int64, owner string) Job {
+		return Job{JBJobNumber: number, JobOwner: owner, State: "r", Slots: 1}
+	}
+
+	runner := &fakeRunner{
+		responses: map[string]CommandResult{
+			"qstat -u * -F -xml":              {Stdout: []byte(waitSnapshot(t, []Job{owned(1, "darrellb"), owned(2, "someone")}, nil).content)},
+			"qstat -u darrellb -F -xml":       {Stdout: []byte(waitSnapshot(t, nil, nil).content)},
+			"qstat -u darrellb,jenna -F -xml": {Stdout: []byte(waitSnapshot(t, nil, nil).content)},
+		},
+	}
+
+	outcomes, err := WaitForJobs(context.Background(), []int64{1}, WaitOptions{
+		Interval: time.Millisecond,
+		Runner:   runner,
+	})
+
+	assert.Nil(t, err)
+	assert.Len(t, outcomes, 1)
+	//Only the first poll lists every user, later ones are limited to the owners of the jobs waited on
+	assert.Equal(t, []string{"qstat -u * -F -xml", "qstat -u darrellb -F -xml"}, runner.calls)
+
+	runner.calls = nil
+
+	outcomes, err = WaitForJobs(context.Background(), []int64{1}, WaitOptions{
+		Interval: time.Millisecond,
+		Runner:   runner,
+		Owners:   []string{"darrellb", "jenna"},
+	})
+
+	assert.Nil(t, err)
+	assert.Len(t, outcomes, 1)
+	assert.Equal(t, []string{"qstat -u darrellb,jenna -F -xml"}, runner.calls)
+}
@@ -0,0 +1,199 @@
+package gogridengine
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+//DefaultWatchInterval is the delay between polls of a Watcher without a positive Interval
+const DefaultWatchInterval = 10 * time.Second
+
+//ErrPollTimeout is reported on a WatchUpdate when a single poll of the data source takes longer than the watcher's Timeout
+const ErrPollTimeout = Error("Polling the qstat data source timed out")
+
+//WatchUpdate is delivered for every poll made by a Watcher. Failed polls only carry the error along with the delay before the next attempt.
+type WatchUpdate struct {
+	Time     time.Time `json:"time"`
+	Snapshot JobInfo   `json:"snapshot"`
+	Events   []Event   `json:"events"`
+	Err      error     `json:"-"`
+	//NextPoll is how long the watcher will wait before polling again
+	NextPoll time.Duration `json:"next_poll"`
+}
+
+//Watcher polls a data source on an interval, diffing each snapshot against the last successful one.
+//Polls never overlap, and consecutive failures back off exponentially from Interval up to MaxBackoff before returning to Interval on the next success.
+type Watcher struct {
+	//Source is where the qstat XML comes from. Defaults to a QstatDataSource
+	Source XmlResourceGetter
+	//Interval is the delay between successful polls, and the shortest delay after a failed one. Defaults to DefaultWatchInterval
+	Interval time.Duration
+	//MaxBackoff caps the delay between failed polls. Defaults to 10 times the Interval
+	MaxBackoff time.Duration
+	//Timeout bounds a single poll. A timed out poll is reported as ErrPollTimeout and is waited on before polling again. Zero disables it.
+	//Sources implementing XmlResourceContextGetter are stopped when their poll times out or the watch is cancelled
+	Timeout time.Duration
+	//Filter limits the jobs included in each snapshot. Nil includes everything
+	Filter func(j Job) bool
+}
+
+//Watch polls qstat every interval until the context is cancelled, streaming filtered snapshots and the events between them over the returned channel.
+//The first update diffs against an empty snapshot, so every job is reported as submitted.
+func Watch(ctx context.Context, interval time.Duration, filter func(j Job) bool) <-chan WatchUpdate {
+	w := Watcher{
+		Source:   &QstatDataSource{},
+		Interval: interval,
+		Filter:   filter,
+	}
+
+	return w.Watch(ctx)
+}
+
+type pollResult struct {
+	content string
+	err     error
+}
+
+//Watch starts polling until the context is cancelled, at which point the returned channel is closed
+func (w Watcher) Watch(ctx context.Context) <-chan WatchUpdate {
+	updates := make(chan WatchUpdate, 1)
+
+	if w.Source == nil {
+		w.Source = &QstatDataSource{}
+	}
+
+	//Without a delay, the watcher would poll qmaster in a tight loop and never back off
+	if w.Interval <= 0 {
+		w.Interval = DefaultWatchInterval
+	}
+
+	if w.MaxBackoff <= 0 {
+		w.MaxBackoff = 10 * w.Interval
+	}
+
+	go func() {
+		defer close(updates)
+
+		var previous JobInfo
+		var inflight chan pollResult
+		delay := w.Interval
+		failing := false
+
+		//Stops the poll in flight when the watch is cancelled
+		cancelPoll := func() {}
+		defer func() { cancelPoll() }()
+
+		for {
+			//A previous poll that timed out must finish before another one is started
+			if inflight != nil {
+				select {
+				case <-inflight:
+				case <-ctx.Done():
+					return
+				}
+			}
+
+			pollCtx, cancel := context.WithCancel(ctx)
+			cancelPoll = cancel
+			inflight = w.poll(pollCtx)
+			update := WatchUpdate{}
+
+			var timer *time.Timer
+			var timeout <-chan time.Time
+			if w.Timeout > 0 {
+				timer = time.NewTimer(w.Timeout)
+				timeout = timer.C
+			}
+
+			select {
+			case result := <-inflight:
+				inflight = nil
+				update.Err = result.err
+
+				if result.err == nil {
+					ji, err := NewJobInfo(result.content)
+					update.Err = err
+
+					if err == nil {
+						if w.Filter != nil {
+							ji = ji.Filter(w.Filter)
+						}
+
+						update.Snapshot = ji
+						update.Events = Diff(previous, ji)
+						previous = ji
+					}
+				}
+			case <-timeout:
+				update.Err = ErrPollTimeout
+				cancelPoll()
+			case <-ctx.Done():
+				return
+			}
+
+			if timer != nil {
+				timer.Stop()
+			}
+
+			if update.Err != nil {
+				log.Error("Polling qstat failed, backing off: ", update.Err)
+				//The first failure retries after the usual interval, the following ones double it
+				if failing {
+					delay = delay * 2
+				} else {
+					delay = w.Interval
+				}
+				if delay > w.MaxBackoff {
+					delay = w.MaxBackoff
+				}
+				failing = true
+			} else {
+				delay = w.Interval
+				failing = false
+			}
+
+			update.Time = time.Now()
+			update.NextPoll = delay
+
+			select {
+			case updates <- update:
+			case <-ctx.Done():
+				return
+			}
+
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return updates
+}
+
+//poll reads the source in the background so the watcher can give up on it when cancelled or timed out.
+//Sources that can be stopped are handed the context, the others are left to finish on their own
+func (w Watcher) poll(ctx context.Context) chan pollResult {
+	result := make(chan pollResult, 1)
+
+	go func() {
+		var content string
+		var err error
+
+		if getter, ok := w.Source.(XmlResourceContextGetter); ok {
+			content, err = getter.GetContext(ctx)
+		} else {
+			content, err = w.Source.Get()
+		}
+
+		result <- pollResult{
+			content: content,
+			err:     err,
+		}
+	}()
+
+	return result
+}
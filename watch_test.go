@@ -0,0 +1,287 @@
+package gogridengine
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//scriptedSource replays a fixed set of responses, repeating the last one once exhausted
+type scriptedSource struct {
+	mu        sync.Mutex
+	responses []pollResult
+	calls     int
+	active    int
+	overlaps  int
+	delay     time.Duration
+}
+
+func (s *scriptedSource) Get() (string, error) {
+	s.mu.Lock()
+	s.active++
+	if s.active > 1 {
+		s.overlaps++
+	}
+	index := s.calls
+	if index >= len(s.responses) {
+		index = len(s.responses) - 1
+	}
+	s.calls++
+	s.mu.Unlock()
+
+	time.Sleep(s.delay)
+
+	s.mu.Lock()
+	s.active--
+	s.mu.Unlock()
+
+	return s.responses[index].content, s.responses[index].err
+}
+
+func TestWatcher_Watch(t *testing.T) {
+	content, err := ioutil.ReadFile("test_data/small.xml")
+	assert.Nil(t, err)
+
+	source := &scriptedSource{
+		responses: []pollResult{
+			{content: string(content)},
+			{err: errors.New("qmaster unreachable")},
+			{content: string(content)},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w := Watcher{
+		Source:     source,
+		Interval:   time.Millisecond,
+		MaxBackoff: 5 * time.Millisecond,
+		Filter: func(j Job) bool {
+			return j.JBJobNumber != 612
+		},
+	}
+
+	updates := w.Watch(ctx)
+
+	first := <-updates
+	assert.Nil(t, first.Err)
+	assert.Len(t, first.Snapshot.Jobs(), 8)
+	assert.Equal(t, HostAdded, first.Events[0].Type)
+
+	failed := <-updates
+	assert.NotNil(t, failed.Err)
+	assert.Equal(t, time.Millisecond, failed.NextPoll)
+
+	recovered := <-updates
+	assert.Nil(t, recovered.Err)
+	assert.Empty(t, recovered.Events)
+	assert.Equal(t, time.Millisecond, recovered.NextPoll)
+
+	cancel()
+
+	//The channel must be closed once cancelled
+	for range updates {
+	}
+}
+
+func TestWatcher_WatchWithoutInterval(t *testing.T) {
+	source := &scriptedSource{
+		responses: []pollResult{
+			{err: errors.New("qmaster unreachable")},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates := Watcher{Source: source}.Watch(ctx)
+
+	//A failed first poll retries after the default interval rather than straight away
+	failed := <-updates
+	assert.NotNil(t, failed.Err)
+	assert.Equal(t, DefaultWatchInterval, failed.NextPoll)
+
+	time.Sleep(20 * time.Millisecond)
+	source.mu.Lock()
+	assert.Equal(t, 1, source.calls)
+	source.mu.Unlock()
+
+	cancel()
+	for range updates {
+	}
+}
+
+func TestWatcher_WatchTimeoutDoesNotOverlap(t *testing.T) {
+	content, err := ioutil.ReadFile("test_data/small.xml")
+	assert.Nil(t, err)
+
+	source := &scriptedSource{
+		responses: []pollResult{
+			{content: string(content)},
+		},
+		delay: 20 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w := Watcher{
+		Source:   source,
+		Interval: time.Millisecond,
+		Timeout:  5 * time.Millisecond,
+	}
+
+	updates := w.Watch(ctx)
+
+	for i := 0; i < 3; i++ {
+		update := <-updates
+		assert.Equal(t, ErrPollTimeout, update.Err)
+	}
+
+	cancel()
+
+	for range updates {
+	}
+
+	source.mu.Lock()
+	defer source.mu.Unlock()
+	assert.Equal(t, 0, source.overlaps)
+}
+
+func TestWatcher_WatchBackoff(t *testing.T) {
+	content, err := ioutil.ReadFile("test_data/small.xml")
+	assert.Nil(t, err)
+
+	source := &scriptedSource{
+		responses: []pollResult{
+			{err: errors.New("qmaster unreachable")},
+			{err: errors.New("qmaster unreachable")},
+			{err: errors.New("qmaster unreachable")},
+			{err: errors.New("qmaster unreachable")},
+			{content: string(content)},
+			{err: errors.New("qmaster unreachable")},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	w := Watcher{
+		Source:     source,
+		Interval:   time.Millisecond,
+		MaxBackoff: 5 * time.Millisecond,
+	}
+
+	updates := w.Watch(ctx)
+
+	//Backs off from the interval, capped by MaxBackoff, and starts over after a success
+	var delays []time.Duration
+	for i := 0; i < 6; i++ {
+		delays = append(delays, (<-updates).NextPoll)
+	}
+
+	assert.Equal(t, []time.Duration{
+		time.Millisecond,
+		2 * time.Millisecond,
+		4 * time.Millisecond,
+		5 * time.Millisecond,
+		time.Millisecond,
+		time.Millisecond,
+	}, delays)
+
+	cancel()
+	for range updates {
+	}
+}
+
+//stoppableSource blocks until the context of the poll is done
+type stoppableSource struct {
+	mu      sync.Mutex
+	stopped int
+}
+
+func (s *stoppableSource) Get() (string, error) {
+	return s.GetContext(context.Background())
+}
+
+func (s *stoppableSource) GetContext(ctx context.Context) (string, error) {
+	<-ctx.Done()
+
+	s.mu.Lock()
+	s.stopped++
+	s.mu.Unlock()
+
+	return "", ctx.Err()
+}
+
+func (s *stoppableSource) Stopped() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stopped
+}
+
+func TestWatcher_WatchStopsPolls(t *testing.T) {
+	source := &stoppableSource{}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w := Watcher{
+		Source:   source,
+		Interval: time.Millisecond,
+		Timeout:  5 * time.Millisecond,
+	}
+
+	updates := w.Watch(ctx)
+
+	//A timed out poll is stopped rather than waited on
+	for i := 0; i < 3; i++ {
+		update := <-updates
+		assert.Equal(t, ErrPollTimeout, update.Err)
+	}
+	assert.True(t, source.Stopped() >= 2)
+
+	cancel()
+	for range updates {
+	}
+
+	//So is the poll in flight when the watch is cancelled
+	source = &stoppableSource{}
+	ctx, cancel = context.WithCancel(context.Background())
+
+	updates = Watcher{Source: source}.Watch(ctx)
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	for range updates {
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for source.Stopped() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, 1, source.Stopped())
+}
+
+func TestJobInfo_Filter(t *testing.T) {
+	content, err := ioutil.ReadFile("test_data/small.xml")
+	assert.Nil(t, err)
+
+	ji, err := NewJobInfo(string(content))
+	assert.Nil(t, err)
+
+	filtered := ji.Filter(func(j Job) bool {
+		return j.JBJobNumber == 613
+	})
+
+	assert.Len(t, filtered.QueueInfo.Queues, 2)
+	assert.Empty(t, filtered.QueueInfo.Queues[0].JobList)
+	assert.Len(t, filtered.QueueInfo.Queues[1].JobList, 1)
+
+	//The original must be left untouched
+	assert.Len(t, ji.QueueInfo.Queues[0].JobList, 5)
+}